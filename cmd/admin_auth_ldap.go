@@ -89,6 +89,34 @@ var (
 			Name:  "public-ssh-key-attribute",
 			Usage: "The attribute of the user’s LDAP record containing the user’s public ssh key.",
 		},
+		&cli.BoolFlag{
+			Name:  "enable-groups",
+			Usage: "Verify group membership in LDAP.",
+		},
+		&cli.StringFlag{
+			Name:  "group-search-base",
+			Usage: "The LDAP base at which groups will be searched for.",
+		},
+		&cli.StringFlag{
+			Name:  "group-filter",
+			Usage: "An LDAP filter declaring how to find valid groups.",
+		},
+		&cli.StringFlag{
+			Name:  "group-member-attribute",
+			Usage: "The attribute of the group’s LDAP record containing the list of users.",
+		},
+		&cli.StringFlag{
+			Name:  "user-attribute-in-group",
+			Usage: "The attribute of the user’s LDAP record listed in the group.",
+		},
+		&cli.StringFlag{
+			Name:  "group-team-map",
+			Usage: "A JSON object mapping group DNs to the teams of each organization.",
+		},
+		&cli.BoolFlag{
+			Name:  "group-team-map-removal",
+			Usage: "Remove users from mapped teams of groups they are no longer a member of.",
+		},
 	}
 
 	ldapBindDnCLIFlags = append(commonLdapCLIFlags,
@@ -245,6 +273,30 @@ func parseLdapConfig(c *cli.Context, config *models.LDAPConfig) error {
 	if c.IsSet("allow-deactivate-all") {
 		config.Source.AllowDeactivateAll = c.Bool("allow-deactivate-all")
 	}
+	if c.IsSet("enable-groups") {
+		config.Source.GroupsEnabled = c.Bool("enable-groups")
+	}
+	if c.IsSet("group-search-base") {
+		config.Source.GroupDN = c.String("group-search-base")
+	}
+	if c.IsSet("group-filter") {
+		config.Source.GroupFilter = c.String("group-filter")
+	}
+	if c.IsSet("group-member-attribute") {
+		config.Source.GroupMemberUID = c.String("group-member-attribute")
+	}
+	if c.IsSet("user-attribute-in-group") {
+		config.Source.UserUID = c.String("user-attribute-in-group")
+	}
+	if c.IsSet("group-team-map") {
+		config.Source.GroupTeamMap = c.String("group-team-map")
+		if _, err := config.Source.ParseGroupTeamMap(); err != nil {
+			return fmt.Errorf("Invalid group team map: %v", err)
+		}
+	}
+	if c.IsSet("group-team-map-removal") {
+		config.Source.GroupTeamMapRemoval = c.Bool("group-team-map-removal")
+	}
 	return nil
 }
 
@@ -907,6 +907,43 @@ func TestUpdateLdapBindDn(t *testing.T) {
 			},
 			errMsg: "Invalid authentication type. expected: LDAP (via BindDN), actual: OAuth2",
 		},
+		// case 24
+		{
+			args: []string{
+				"ldap-test",
+				"--id", "1",
+				"--enable-groups",
+				"--group-search-base", "ou=group,dc=domain,dc=org",
+				"--group-filter", "(objectClass=groupOfNames)",
+				"--group-member-attribute", "member",
+				"--user-attribute-in-group", "dn",
+				"--group-team-map", `{"cn=developers,ou=group,dc=domain,dc=org": {"org": ["developers"]}}`,
+				"--group-team-map-removal",
+			},
+			loginSource: &models.LoginSource{
+				Type: models.LoginLDAP,
+				Cfg: &models.LDAPConfig{
+					Source: &ldap.Source{
+						GroupsEnabled:       true,
+						GroupDN:             "ou=group,dc=domain,dc=org",
+						GroupFilter:         "(objectClass=groupOfNames)",
+						GroupMemberUID:      "member",
+						UserUID:             "dn",
+						GroupTeamMap:        `{"cn=developers,ou=group,dc=domain,dc=org": {"org": ["developers"]}}`,
+						GroupTeamMapRemoval: true,
+					},
+				},
+			},
+		},
+		// case 25
+		{
+			args: []string{
+				"ldap-test",
+				"--id", "1",
+				"--group-team-map", "developers",
+			},
+			errMsg: "Invalid group team map: ReadMapCB: expect { or n, but found d, error found in #1 byte of ...|developers|..., bigger context ...|developers|...",
+		},
 	}
 
 	for n, c := range cases {
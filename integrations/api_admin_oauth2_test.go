@@ -0,0 +1,64 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package integrations
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"go.wandrs.dev/framework/models"
+	api "go.wandrs.dev/framework/modules/structs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIAdminOAuth2Applications(t *testing.T) {
+	defer prepareTestEnv(t)()
+	session := loginUser(t, "user1")
+	token := getTokenForLoggedInUser(t, session)
+
+	req := NewRequestWithJSON(t, "POST", "/api/v1/admin/oauth2?token="+token, &api.AdminCreateOAuth2ApplicationOptions{
+		Owner:        "user2",
+		Name:         "admin-created",
+		RedirectURIs: []string{"https://example.com/callback"},
+		Trusted:      true,
+	})
+	resp := session.MakeRequest(t, req, http.StatusCreated)
+	var app api.OAuth2Application
+	DecodeJSON(t, resp, &app)
+	assert.Equal(t, "admin-created", app.Name)
+	assert.Equal(t, "user2", app.Owner)
+	assert.True(t, app.Trusted)
+	assert.NotEmpty(t, app.ClientSecret)
+	models.AssertExistsAndLoadBean(t, &models.OAuth2Application{ID: app.ID, UID: 2, Trusted: true})
+
+	req = NewRequest(t, "GET", "/api/v1/admin/oauth2?token="+token)
+	resp = session.MakeRequest(t, req, http.StatusOK)
+	var apps []*api.OAuth2Application
+	DecodeJSON(t, resp, &apps)
+	assert.Len(t, apps, 2)
+	assert.Empty(t, apps[0].ClientSecret)
+
+	// trusted is kept if omitted
+	req = NewRequestWithJSON(t, "PATCH", fmt.Sprintf("/api/v1/admin/oauth2/%d?token=%s", app.ID, token), &api.AdminEditOAuth2ApplicationOptions{
+		Name:         "admin-edited",
+		RedirectURIs: []string{"https://example.com/callback"},
+	})
+	resp = session.MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &app)
+	assert.Equal(t, "admin-edited", app.Name)
+	assert.True(t, app.Trusted)
+
+	req = NewRequest(t, "DELETE", fmt.Sprintf("/api/v1/admin/oauth2/%d?token=%s", app.ID, token))
+	session.MakeRequest(t, req, http.StatusNoContent)
+	models.AssertNotExistsBean(t, &models.OAuth2Application{ID: app.ID})
+
+	// only site administrators can manage applications of other users
+	session = loginUser(t, "user2")
+	token = getTokenForLoggedInUser(t, session)
+	req = NewRequest(t, "GET", "/api/v1/admin/oauth2?token="+token)
+	session.MakeRequest(t, req, http.StatusForbidden)
+}
@@ -0,0 +1,48 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package integrations
+
+import (
+	"net/http"
+	"testing"
+
+	"go.wandrs.dev/framework/models"
+	api "go.wandrs.dev/framework/modules/structs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIWhileImpersonating(t *testing.T) {
+	defer prepareTestEnv(t)()
+	session := loginUser(t, "user1")
+	req := NewRequestWithValues(t, "POST", "/admin/users/2/impersonate", map[string]string{
+		"_csrf": GetCSRF(t, session, "/admin/users/2"),
+	})
+	session.MakeRequest(t, req, http.StatusFound)
+	csrf := GetCSRF(t, session, "/user/settings")
+
+	req = NewRequest(t, "GET", "/api/v1/user")
+	req.Header.Add("X-Csrf-Token", csrf)
+	resp := session.MakeRequest(t, req, http.StatusOK)
+	var user api.User
+	DecodeJSON(t, resp, &user)
+	assert.EqualValues(t, 2, user.ID)
+
+	// the routes forbidden in the settings are forbidden in the API too
+	req = NewRequestWithJSON(t, "POST", "/api/v1/user/emails", &api.CreateEmailOption{
+		Emails: []string{"impersonated@example.com"},
+	})
+	req.Header.Add("X-Csrf-Token", csrf)
+	session.MakeRequest(t, req, http.StatusForbidden)
+	models.AssertNotExistsBean(t, &models.EmailAddress{Email: "impersonated@example.com"})
+
+	req = NewRequestWithJSON(t, "POST", "/api/v1/user/applications/oauth2", &api.CreateOAuth2ApplicationOptions{
+		Name:         "impersonated",
+		RedirectURIs: []string{"http://www.google.com"},
+	})
+	req.Header.Add("X-Csrf-Token", csrf)
+	session.MakeRequest(t, req, http.StatusForbidden)
+	models.AssertNotExistsBean(t, &models.OAuth2Application{Name: "impersonated"})
+}
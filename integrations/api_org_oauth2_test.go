@@ -0,0 +1,74 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package integrations
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"go.wandrs.dev/framework/models"
+	api "go.wandrs.dev/framework/modules/structs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIOrgOAuth2Applications(t *testing.T) {
+	defer prepareTestEnv(t)()
+	session := loginUser(t, "user2")
+	token := getTokenForLoggedInUser(t, session)
+
+	req := NewRequestWithJSON(t, "POST", "/api/v1/orgs/user3/applications?token="+token, &api.CreateOAuth2ApplicationOptions{
+		Name:         "org-app",
+		RedirectURIs: []string{"https://example.com/callback"},
+	})
+	resp := session.MakeRequest(t, req, http.StatusCreated)
+	var app api.OAuth2Application
+	DecodeJSON(t, resp, &app)
+	assert.Equal(t, "org-app", app.Name)
+	assert.NotEmpty(t, app.ClientSecret)
+	models.AssertExistsAndLoadBean(t, &models.OAuth2Application{ID: app.ID, UID: 3})
+
+	req = NewRequest(t, "GET", "/api/v1/orgs/user3/applications?token="+token)
+	resp = session.MakeRequest(t, req, http.StatusOK)
+	var apps []*api.OAuth2Application
+	DecodeJSON(t, resp, &apps)
+	assert.Len(t, apps, 1)
+	assert.Empty(t, apps[0].ClientSecret)
+
+	// applications of users can't be reached through an organization
+	req = NewRequest(t, "GET", "/api/v1/orgs/user3/applications/1?token="+token)
+	session.MakeRequest(t, req, http.StatusNotFound)
+
+	// members of a team without the permission can't manage the applications
+	session4 := loginUser(t, "user4")
+	token4 := getTokenForLoggedInUser(t, session4)
+	req = NewRequest(t, "GET", "/api/v1/orgs/user3/applications?token="+token4)
+	session4.MakeRequest(t, req, http.StatusForbidden)
+
+	canManage := true
+	req = NewRequestWithJSON(t, "PATCH", "/api/v1/teams/2?token="+token, &api.EditTeamOption{
+		Name:                "team1",
+		CanManageOAuth2Apps: &canManage,
+	})
+	session.MakeRequest(t, req, http.StatusOK)
+
+	req = NewRequestWithJSON(t, "PATCH", fmt.Sprintf("/api/v1/orgs/user3/applications/%d?token=%s", app.ID, token4), &api.CreateOAuth2ApplicationOptions{
+		Name:         "org-app-edited",
+		RedirectURIs: []string{"https://example.com/callback"},
+	})
+	resp = session4.MakeRequest(t, req, http.StatusOK)
+	DecodeJSON(t, resp, &app)
+	assert.Equal(t, "org-app-edited", app.Name)
+
+	// the application outlives the user who registered it
+	assert.NoError(t, models.RemoveOrgUser(3, 4))
+	assert.NoError(t, models.DeleteUser(models.AssertExistsAndLoadBean(t, &models.User{ID: 4}).(*models.User)))
+	models.AssertExistsAndLoadBean(t, &models.OAuth2Application{ID: app.ID, UID: 3})
+
+	req = NewRequest(t, "DELETE", fmt.Sprintf("/api/v1/orgs/user3/applications/%d?token=%s", app.ID, token))
+	session.MakeRequest(t, req, http.StatusNoContent)
+	models.AssertNotExistsBean(t, &models.OAuth2Application{ID: app.ID})
+}
@@ -8,9 +8,14 @@ import (
 	"bytes"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"go.wandrs.dev/framework/models"
 	"go.wandrs.dev/framework/modules/setting"
+	"go.wandrs.dev/framework/modules/test"
+	"go.wandrs.dev/framework/modules/util"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/stretchr/testify/assert"
@@ -53,6 +58,60 @@ func TestRedirectWithExistingGrant(t *testing.T) {
 	assert.Truef(t, len(u.Query().Get("code")) > 30, "authorization code '%s' should be longer then 30", u.Query().Get("code"))
 }
 
+func TestShowAuthorizeForAdditionalScopes(t *testing.T) {
+	defer prepareTestEnv(t)()
+	// user1 granted access to "openid profile" only
+	req := NewRequest(t, "GET", defaultAuthorize+"&scope=openid%20email")
+	ctx := loginUser(t, "user1")
+	resp := ctx.MakeRequest(t, req, 200)
+
+	htmlDoc := NewHTMLParser(t, resp.Body)
+	htmlDoc.AssertElement(t, "#authorize-app", true)
+	req = NewRequestWithValues(t, "POST", "/login/oauth/grant", map[string]string{
+		"_csrf":        htmlDoc.GetCSRF(),
+		"client_id":    "da7da3ba-9a13-4167-856f-3899de0b0138",
+		"redirect_uri": "a",
+		"state":        "thestate",
+		"scope":        "openid email",
+	})
+	ctx.MakeRequest(t, req, 302)
+	models.AssertExistsAndLoadBean(t, &models.OAuth2Grant{ID: 1, Scope: "openid profile email"})
+
+	// the user is not asked again
+	req = NewRequest(t, "GET", defaultAuthorize+"&scope=email%20openid")
+	ctx.MakeRequest(t, req, 302)
+}
+
+func TestRedirectForTrustedApplication(t *testing.T) {
+	defer prepareTestEnv(t)()
+	_, err := models.UpdateOAuth2Application(models.UpdateOAuth2ApplicationOptions{
+		ID:           1,
+		Name:         "Test",
+		UserID:       1,
+		RedirectURIs: []string{"a"},
+		Trusted:      util.OptionalBoolTrue,
+	})
+	assert.NoError(t, err)
+
+	// user4 has not granted access yet but is not asked for consent
+	req := NewRequest(t, "GET", defaultAuthorize)
+	ctx := loginUser(t, "user4")
+	resp := ctx.MakeRequest(t, req, 302)
+	u, err := resp.Result().Location()
+	assert.NoError(t, err)
+	assert.Equal(t, "thestate", u.Query().Get("state"))
+	assert.NotEmpty(t, u.Query().Get("code"))
+	models.AssertExistsAndLoadBean(t, &models.OAuth2Grant{UserID: 4, ApplicationID: 1})
+
+	// additional scopes are granted without asking either
+	req = NewRequest(t, "GET", defaultAuthorize+"&scope=openid%20groups")
+	ctx.MakeRequest(t, req, 302)
+	models.AssertExistsAndLoadBean(t, &models.OAuth2Grant{UserID: 1, ApplicationID: 1, Scope: "openid profile"})
+	req = NewRequest(t, "GET", defaultAuthorize+"&scope=openid%20groups")
+	loginUser(t, "user1").MakeRequest(t, req, 302)
+	models.AssertExistsAndLoadBean(t, &models.OAuth2Grant{UserID: 1, ApplicationID: 1, Scope: "openid profile groups"})
+}
+
 func TestAccessTokenExchange(t *testing.T) {
 	defer prepareTestEnv(t)()
 	req := NewRequestWithValues(t, "POST", "/login/oauth/access_token", map[string]string{
@@ -262,3 +321,287 @@ func TestRefreshTokenInvalidation(t *testing.T) {
 	refreshReq.Body = ioutil.NopCloser(bytes.NewReader(bs))
 	MakeRequest(t, refreshReq, 400)
 }
+
+func TestClientCredentialsGrant(t *testing.T) {
+	defer prepareTestEnv(t)()
+	req := NewRequestWithValues(t, "POST", "/login/oauth/access_token", map[string]string{
+		"grant_type":    "client_credentials",
+		"client_id":     "da7da3ba-9a13-4167-856f-3899de0b0138",
+		"client_secret": "4MK8Na6R55smdCY0WuCCumZ6hjRPnGY5saWVRHHjJiA=",
+	})
+	resp := MakeRequest(t, req, 200)
+	type response struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		ExpiresIn    int64  `json:"expires_in"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	parsed := new(response)
+
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), parsed))
+	assert.True(t, len(parsed.AccessToken) > 10)
+	assert.Empty(t, parsed.RefreshToken)
+
+	// the token acts as the owner of the application
+	req = NewRequest(t, "GET", "/api/v1/user?access_token="+parsed.AccessToken)
+	resp = MakeRequest(t, req, 200)
+	var user struct {
+		ID int64 `json:"id"`
+	}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &user))
+	assert.EqualValues(t, 1, user.ID)
+
+	req = NewRequestWithValues(t, "POST", "/login/oauth/access_token", map[string]string{
+		"grant_type":    "client_credentials",
+		"client_id":     "da7da3ba-9a13-4167-856f-3899de0b0138",
+		"client_secret": "invalid",
+	})
+	MakeRequest(t, req, 400)
+
+	// the application cannot authenticate anyone by OpenID Connect
+	req = NewRequestWithValues(t, "POST", "/login/oauth/access_token", map[string]string{
+		"grant_type":    "client_credentials",
+		"client_id":     "da7da3ba-9a13-4167-856f-3899de0b0138",
+		"client_secret": "4MK8Na6R55smdCY0WuCCumZ6hjRPnGY5saWVRHHjJiA=",
+		"scope":         "openid profile",
+	})
+	resp = MakeRequest(t, req, 400)
+	assert.Contains(t, resp.Body.String(), "invalid_scope")
+}
+
+func TestOAuthIntrospectionAndRevocation(t *testing.T) {
+	defer prepareTestEnv(t)()
+	req := NewRequestWithValues(t, "POST", "/login/oauth/access_token", map[string]string{
+		"grant_type":    "authorization_code",
+		"client_id":     "da7da3ba-9a13-4167-856f-3899de0b0138",
+		"client_secret": "4MK8Na6R55smdCY0WuCCumZ6hjRPnGY5saWVRHHjJiA=",
+		"redirect_uri":  "a",
+		"code":          "authcode",
+		"code_verifier": "N1Zo9-8Rfwhkt68r1r29ty8YwIraXR8eh_1Qwxg7yQXsonBt",
+	})
+	resp := MakeRequest(t, req, 200)
+	type response struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	parsed := new(response)
+
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), parsed))
+
+	type introspection struct {
+		Active   bool   `json:"active"`
+		Scope    string `json:"scope"`
+		ClientID string `json:"client_id"`
+		Username string `json:"username"`
+		Subject  string `json:"sub"`
+	}
+	introspect := func(token string) *introspection {
+		req := NewRequestWithValues(t, "POST", "/login/oauth/introspect", map[string]string{
+			"token": token,
+		})
+		req.SetBasicAuth("da7da3ba-9a13-4167-856f-3899de0b0138", "4MK8Na6R55smdCY0WuCCumZ6hjRPnGY5saWVRHHjJiA=")
+		resp := MakeRequest(t, req, 200)
+		result := new(introspection)
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), result))
+		return result
+	}
+
+	result := introspect(parsed.AccessToken)
+	assert.True(t, result.Active)
+	assert.Equal(t, "openid profile", result.Scope)
+	assert.Equal(t, "da7da3ba-9a13-4167-856f-3899de0b0138", result.ClientID)
+	assert.Equal(t, "user1", result.Username)
+	assert.Equal(t, "1", result.Subject)
+
+	assert.False(t, introspect("not-a-token").Active)
+
+	// other applications only learn about the token if they are allowed resource servers
+	other, err := models.CreateOAuth2Application(models.CreateOAuth2ApplicationOptions{Name: "Other", UserID: 2})
+	assert.NoError(t, err)
+	otherSecret, err := other.GenerateClientSecret()
+	assert.NoError(t, err)
+	introspectByOther := func() bool {
+		req := NewRequestWithValues(t, "POST", "/login/oauth/introspect", map[string]string{
+			"token": parsed.AccessToken,
+		})
+		req.SetBasicAuth(other.ClientID, otherSecret)
+		resp := MakeRequest(t, req, 200)
+		result := new(introspection)
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), result))
+		return result.Active
+	}
+	assert.False(t, introspectByOther())
+	defer func(clientIDs []string) { setting.OAuth2.IntrospectionClientIDs = clientIDs }(setting.OAuth2.IntrospectionClientIDs)
+	setting.OAuth2.IntrospectionClientIDs = []string{other.ClientID}
+	assert.True(t, introspectByOther())
+
+	// introspection requires client authentication
+	req = NewRequestWithValues(t, "POST", "/login/oauth/introspect", map[string]string{
+		"token": parsed.AccessToken,
+	})
+	MakeRequest(t, req, 401)
+
+	req = NewRequestWithValues(t, "POST", "/login/oauth/revoke", map[string]string{
+		"token":         parsed.RefreshToken,
+		"client_id":     "da7da3ba-9a13-4167-856f-3899de0b0138",
+		"client_secret": "4MK8Na6R55smdCY0WuCCumZ6hjRPnGY5saWVRHHjJiA=",
+	})
+	MakeRequest(t, req, 200)
+
+	// revoking the refresh token revokes the whole grant
+	assert.False(t, introspect(parsed.AccessToken).Active)
+	assert.False(t, introspect(parsed.RefreshToken).Active)
+}
+
+func TestDeviceAuthorizationGrant(t *testing.T) {
+	defer prepareTestEnv(t)()
+	req := NewRequestWithValues(t, "POST", "/login/oauth/device_authorization", map[string]string{
+		"client_id": "da7da3ba-9a13-4167-856f-3899de0b0138",
+		"scope":     "openid",
+	})
+	resp := MakeRequest(t, req, 200)
+	type deviceResponse struct {
+		DeviceCode      string `json:"device_code"`
+		UserCode        string `json:"user_code"`
+		VerificationURI string `json:"verification_uri"`
+		Interval        int64  `json:"interval"`
+	}
+	device := new(deviceResponse)
+
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), device))
+	assert.NotEmpty(t, device.DeviceCode)
+	assert.Equal(t, setting.AppURL+"login/device", device.VerificationURI)
+
+	poll := func(expectedError string) *httptest.ResponseRecorder {
+		req := NewRequestWithValues(t, "POST", "/login/oauth/access_token", map[string]string{
+			"grant_type":  "urn:ietf:params:oauth:grant-type:device_code",
+			"client_id":   "da7da3ba-9a13-4167-856f-3899de0b0138",
+			"device_code": device.DeviceCode,
+		})
+		if expectedError == "" {
+			return MakeRequest(t, req, 200)
+		}
+		resp := MakeRequest(t, req, 400)
+		var tokenErr struct {
+			Error string `json:"error"`
+		}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &tokenErr))
+		assert.Equal(t, expectedError, tokenErr.Error)
+		return resp
+	}
+	poll("authorization_pending")
+	poll("slow_down")
+
+	session := loginUser(t, "user2")
+	req = NewRequestWithValues(t, "POST", "/login/device", map[string]string{
+		"_csrf":     GetCSRF(t, session, "/login/device"),
+		"user_code": device.UserCode,
+		"granted":   "true",
+	})
+	session.MakeRequest(t, req, http.StatusFound)
+
+	resp = poll("")
+	type tokenResponse struct {
+		AccessToken string `json:"access_token"`
+	}
+	token := new(tokenResponse)
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), token))
+	assert.NotEmpty(t, token.AccessToken)
+
+	// the device code can only be used once
+	poll("invalid_grant")
+}
+
+func TestOAuthUserInfoClaims(t *testing.T) {
+	defer prepareTestEnv(t)()
+	req := NewRequestWithValues(t, "POST", "/login/oauth/access_token", map[string]string{
+		"grant_type":    "authorization_code",
+		"client_id":     "da7da3ba-9a13-4167-856f-3899de0b0138",
+		"client_secret": "4MK8Na6R55smdCY0WuCCumZ6hjRPnGY5saWVRHHjJiA=",
+		"redirect_uri":  "a",
+		"code":          "authcode",
+		"code_verifier": "N1Zo9-8Rfwhkt68r1r29ty8YwIraXR8eh_1Qwxg7yQXsonBt",
+	})
+	resp := MakeRequest(t, req, 200)
+	type response struct {
+		AccessToken string `json:"access_token"`
+		IDToken     string `json:"id_token"`
+	}
+	parsed := new(response)
+
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), parsed))
+	assert.NotEmpty(t, parsed.IDToken)
+
+	req = NewRequest(t, "GET", "/login/oauth/userinfo")
+	req.Header.Set("Authorization", "Bearer "+parsed.AccessToken)
+	resp = MakeRequest(t, req, 200)
+	var userInfo map[string]interface{}
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &userInfo))
+
+	// the grant of the fixture only has the openid and profile scopes
+	assert.Equal(t, "1", userInfo["sub"])
+	assert.Equal(t, "user1", userInfo["preferred_username"])
+	assert.NotContains(t, userInfo, "email")
+	assert.NotContains(t, userInfo, "groups")
+}
+
+func TestOAuthEndSession(t *testing.T) {
+	defer prepareTestEnv(t)()
+	req := NewRequestWithValues(t, "POST", "/login/oauth/access_token", map[string]string{
+		"grant_type":    "authorization_code",
+		"client_id":     "da7da3ba-9a13-4167-856f-3899de0b0138",
+		"client_secret": "4MK8Na6R55smdCY0WuCCumZ6hjRPnGY5saWVRHHjJiA=",
+		"redirect_uri":  "a",
+		"code":          "authcode",
+		"code_verifier": "N1Zo9-8Rfwhkt68r1r29ty8YwIraXR8eh_1Qwxg7yQXsonBt",
+	})
+	resp := MakeRequest(t, req, 200)
+	type response struct {
+		IDToken string `json:"id_token"`
+	}
+	parsed := new(response)
+	assert.NoError(t, jsoniter.Unmarshal(resp.Body.Bytes(), parsed))
+	assert.NotEmpty(t, parsed.IDToken)
+
+	session := loginUser(t, "user1")
+
+	// unregistered redirect URIs are rejected
+	req = NewRequest(t, "GET", "/login/oauth/logout?post_logout_redirect_uri=b&id_token_hint="+parsed.IDToken)
+	session.MakeRequest(t, req, http.StatusBadRequest)
+
+	// without a hint the sign out has to be confirmed
+	req = NewRequest(t, "GET", "/login/oauth/logout?client_id=da7da3ba-9a13-4167-856f-3899de0b0138&post_logout_redirect_uri=a")
+	resp = session.MakeRequest(t, req, http.StatusOK)
+	assert.Contains(t, resp.Body.String(), "/login/oauth/logout/confirm")
+
+	req = NewRequest(t, "GET", "/login/oauth/logout?post_logout_redirect_uri=a&state=thestate&id_token_hint="+parsed.IDToken)
+	resp = session.MakeRequest(t, req, http.StatusFound)
+	assert.Equal(t, "a?state=thestate", test.RedirectURL(resp))
+
+	// the user has been signed out
+	req = NewRequest(t, "GET", "/user/settings")
+	session.MakeRequest(t, req, http.StatusFound)
+	delete(loginSessionCache, "user1")
+}
+
+func TestFrontchannelLogout(t *testing.T) {
+	defer prepareTestEnv(t)()
+	_, err := models.UpdateOAuth2Application(models.UpdateOAuth2ApplicationOptions{
+		ID:                    1,
+		Name:                  "Test",
+		UserID:                1,
+		RedirectURIs:          []string{"a"},
+		FrontchannelLogoutURI: "https://example.com/logout",
+	})
+	assert.NoError(t, err)
+
+	session := loginUser(t, "user1")
+	req := NewRequest(t, "POST", "/user/logout")
+	resp := session.MakeRequest(t, req, http.StatusOK)
+	assert.Contains(t, resp.Body.String(), `src="https://example.com/logout"`)
+	delete(loginSessionCache, "user1")
+}
@@ -0,0 +1,324 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"go.wandrs.dev/framework/modules/log"
+	"go.wandrs.dev/framework/modules/timeutil"
+
+	"xorm.io/builder"
+)
+
+// AuditAction is the type of a change recorded in the audit log.
+// The part before the first dot is the type of the target of the change.
+type AuditAction string
+
+const (
+	// AuditTeamMemberAdd a user has been added to a team
+	AuditTeamMemberAdd AuditAction = "team.member.add"
+	// AuditTeamMemberRemove a user has been removed from a team
+	AuditTeamMemberRemove AuditAction = "team.member.remove"
+	// AuditTeamInviteCreate a person has been invited by email to join a team
+	AuditTeamInviteCreate AuditAction = "team.invite.create"
+	// AuditTeamInviteRevoke an invitation to join a team has been revoked
+	AuditTeamInviteRevoke AuditAction = "team.invite.revoke"
+	// AuditOrgMemberRemove a user has been removed from an organization and its teams
+	AuditOrgMemberRemove AuditAction = "org.member.remove"
+	// AuditOrgJoinRequestReject a request to join an organization has been rejected
+	AuditOrgJoinRequestReject AuditAction = "org.join_request.reject"
+	// AuditOrgVisibility the visibility of an organization has been changed
+	AuditOrgVisibility AuditAction = "org.visibility"
+	// AuditOrgTwoFactorPolicy the two-factor authentication policy of an organization has been changed
+	AuditOrgTwoFactorPolicy AuditAction = "org.two_factor_policy"
+	// AuditUserEdit a user has been edited by an administrator
+	AuditUserEdit AuditAction = "user.edit"
+	// AuditTwoFactorDisable the two-factor authentication of a user has been disabled
+	AuditTwoFactorDisable AuditAction = "user.two_factor.disable"
+	// AuditImpersonateStart an administrator has started impersonating a user
+	AuditImpersonateStart AuditAction = "user.impersonate.start"
+	// AuditImpersonateStop an administrator has stopped impersonating a user
+	AuditImpersonateStop AuditAction = "user.impersonate.stop"
+	// AuditLoginSourceCreate an authentication source has been created
+	AuditLoginSourceCreate AuditAction = "login_source.create"
+	// AuditLoginSourceEdit an authentication source has been edited
+	AuditLoginSourceEdit AuditAction = "login_source.edit"
+	// AuditLoginSourceDelete an authentication source has been deleted
+	AuditLoginSourceDelete AuditAction = "login_source.delete"
+	// AuditAccessTokenCreate an access token has been created
+	AuditAccessTokenCreate AuditAction = "access_token.create"
+	// AuditAccessTokenDelete an access token has been deleted
+	AuditAccessTokenDelete AuditAction = "access_token.delete"
+)
+
+// AuditActions are all the types of changes recorded in the audit log
+var AuditActions = []AuditAction{
+	AuditTeamMemberAdd,
+	AuditTeamMemberRemove,
+	AuditTeamInviteCreate,
+	AuditTeamInviteRevoke,
+	AuditOrgMemberRemove,
+	AuditOrgJoinRequestReject,
+	AuditOrgVisibility,
+	AuditOrgTwoFactorPolicy,
+	AuditUserEdit,
+	AuditTwoFactorDisable,
+	AuditImpersonateStart,
+	AuditImpersonateStop,
+	AuditLoginSourceCreate,
+	AuditLoginSourceEdit,
+	AuditLoginSourceDelete,
+	AuditAccessTokenCreate,
+	AuditAccessTokenDelete,
+}
+
+// TargetType returns the type of the target of the change, e.g. "team"
+func (a AuditAction) TargetType() string {
+	return strings.SplitN(string(a), ".", 2)[0]
+}
+
+// TrStr returns the translation key of the action
+func (a AuditAction) TrStr() string {
+	return "audit.action." + string(a)
+}
+
+// AuditEvent is a change recorded in the audit log. The names of the actor and the
+// target are kept so that the event stays readable after they have been deleted.
+// Events without an actor have been made by the system, e.g. by synchronizing users.
+type AuditEvent struct {
+	ID         int64       `xorm:"pk autoincr"`
+	Action     AuditAction `xorm:"VARCHAR(50) INDEX NOT NULL"`
+	ActorID    int64       `xorm:"INDEX"`
+	ActorName  string
+	OrgID      int64 `xorm:"INDEX"`
+	TargetID   int64 `xorm:"INDEX"`
+	TargetName string
+	IPAddress  string `xorm:"VARCHAR(64)"`
+
+	// Before and After contain the values which have been changed
+	Before map[string]string `xorm:"TEXT JSON"`
+	After  map[string]string `xorm:"TEXT JSON"`
+
+	CreatedUnix timeutil.TimeStamp `xorm:"INDEX created"`
+}
+
+// AuditChanges returns the values of before and after which differ, a value missing in one map is
+// considered empty
+func AuditChanges(before, after map[string]string) (map[string]string, map[string]string) {
+	changedBefore := make(map[string]string)
+	changedAfter := make(map[string]string)
+	for k, v := range before {
+		if after[k] != v {
+			changedBefore[k] = v
+			changedAfter[k] = after[k]
+		}
+	}
+	for k, v := range after {
+		if _, ok := before[k]; !ok && v != "" {
+			changedBefore[k] = ""
+			changedAfter[k] = v
+		}
+	}
+	return changedBefore, changedAfter
+}
+
+// AuditValues returns the values of the user which are recorded in the audit log when it is edited
+func (u *User) AuditValues() map[string]string {
+	return map[string]string{
+		"name":                      u.Name,
+		"login_source":              strconv.FormatInt(u.LoginSource, 10),
+		"login_name":                u.LoginName,
+		"full_name":                 u.FullName,
+		"email":                     u.Email,
+		"website":                   u.Website,
+		"location":                  u.Location,
+		"is_active":                 strconv.FormatBool(u.IsActive),
+		"is_admin":                  strconv.FormatBool(u.IsAdmin),
+		"is_restricted":             strconv.FormatBool(u.IsRestricted),
+		"allow_git_hook":            strconv.FormatBool(u.AllowGitHook),
+		"allow_import_local":        strconv.FormatBool(u.AllowImportLocal),
+		"allow_create_organization": strconv.FormatBool(u.AllowCreateOrganization),
+		"prohibit_login":            strconv.FormatBool(u.ProhibitLogin),
+	}
+}
+
+// AuditValues returns the values of the login source which are recorded in the audit log. The
+// configuration is left out as it contains secrets like bind passwords and client secrets.
+func (source *LoginSource) AuditValues() map[string]string {
+	return map[string]string{
+		"name":            source.Name,
+		"type":            source.TypeName(),
+		"is_active":       strconv.FormatBool(source.IsActived),
+		"is_sync_enabled": strconv.FormatBool(source.IsSyncEnabled),
+	}
+}
+
+// AuditValues returns the values of the access token which are recorded in the audit log
+func (t *AccessToken) AuditValues() map[string]string {
+	values := map[string]string{
+		"scope":            t.Scope,
+		"token_last_eight": t.TokenLastEight,
+	}
+	if t.ExpiredUnix > 0 {
+		values["expires"] = t.ExpiredUnix.AsTime().UTC().Format(time.RFC3339)
+	}
+	return values
+}
+
+// TeamMemberAuditEvent returns the event of adding the member to the team or removing it from the team
+func TeamMemberAuditEvent(action AuditAction, t *Team, member *User) *AuditEvent {
+	evt := &AuditEvent{
+		Action:     action,
+		OrgID:      t.OrgID,
+		TargetID:   t.ID,
+		TargetName: t.Name,
+	}
+	if action == AuditTeamMemberRemove {
+		evt.Before = map[string]string{"member": member.Name}
+	} else {
+		evt.After = map[string]string{"member": member.Name}
+	}
+	return evt
+}
+
+// TeamInviteAuditEvent returns the event of creating or revoking the invitation to join
+// the team
+func TeamInviteAuditEvent(action AuditAction, inv *OrgInvite) *AuditEvent {
+	evt := &AuditEvent{
+		Action:     action,
+		OrgID:      inv.OrgID,
+		TargetID:   inv.TeamID,
+		TargetName: inv.Team.Name,
+	}
+	if action == AuditTeamInviteRevoke {
+		evt.Before = map[string]string{"email": inv.Email}
+	} else {
+		evt.After = map[string]string{"email": inv.Email}
+	}
+	return evt
+}
+
+// OrgJoinRequestRejectAuditEvent returns the event of rejecting the request of the user to join the organization
+func OrgJoinRequestRejectAuditEvent(org, u *User) *AuditEvent {
+	return &AuditEvent{
+		Action:     AuditOrgJoinRequestReject,
+		OrgID:      org.ID,
+		TargetID:   org.ID,
+		TargetName: org.Name,
+		Before:     map[string]string{"user": u.Name},
+	}
+}
+
+// OrgTwoFactorPolicyAuditEvent returns the event of changing the two-factor authentication
+// policy of the organization from the given previous values
+func OrgTwoFactorPolicyAuditEvent(org *User, oldRequire, oldRemove bool) *AuditEvent {
+	return &AuditEvent{
+		Action:     AuditOrgTwoFactorPolicy,
+		OrgID:      org.ID,
+		TargetID:   org.ID,
+		TargetName: org.Name,
+		Before: map[string]string{
+			"require_two_factor":   strconv.FormatBool(oldRequire),
+			"remove_non_compliant": strconv.FormatBool(oldRemove),
+		},
+		After: map[string]string{
+			"require_two_factor":   strconv.FormatBool(org.RequireTwoFactor),
+			"remove_non_compliant": strconv.FormatBool(org.RemoveTwoFactorNonCompliant),
+		},
+	}
+}
+
+// OrgMemberRemoveAuditEvent returns the event of removing the member from the organization
+func OrgMemberRemoveAuditEvent(org, member *User) *AuditEvent {
+	return &AuditEvent{
+		Action:     AuditOrgMemberRemove,
+		OrgID:      org.ID,
+		TargetID:   org.ID,
+		TargetName: org.Name,
+		Before:     map[string]string{"member": member.Name},
+	}
+}
+
+// CreateAuditEvent writes an event to the audit log
+func CreateAuditEvent(evt *AuditEvent) error {
+	return createAuditEvent(x, evt)
+}
+
+func createAuditEvent(e Engine, evt *AuditEvent) error {
+	_, err := e.Insert(evt)
+	return err
+}
+
+// RecordAuditEvent writes an event to the audit log and only logs failures, so that
+// the change which has already been made is not reported as failed
+func RecordAuditEvent(evt *AuditEvent) {
+	if err := CreateAuditEvent(evt); err != nil {
+		log.Error("CreateAuditEvent[%s]: %v", evt.Action, err)
+	}
+}
+
+// SearchAuditEventsOptions contains the filters of the audit log
+type SearchAuditEventsOptions struct {
+	ListOptions
+	Action     AuditAction
+	ActorID    int64
+	ActorName  string
+	OrgID      int64
+	TargetType string
+	TargetID   int64
+	Since      int64 // unix timestamp
+	Before     int64 // unix timestamp
+}
+
+func (opts *SearchAuditEventsOptions) toConds() builder.Cond {
+	cond := builder.NewCond()
+	if opts.Action != "" {
+		cond = cond.And(builder.Eq{"action": opts.Action})
+	}
+	if opts.ActorID != 0 {
+		cond = cond.And(builder.Eq{"actor_id": opts.ActorID})
+	}
+	if opts.ActorName != "" {
+		cond = cond.And(builder.Eq{"LOWER(actor_name)": strings.ToLower(opts.ActorName)})
+	}
+	if opts.OrgID != 0 {
+		cond = cond.And(builder.Eq{"org_id": opts.OrgID})
+	}
+	if opts.TargetType != "" {
+		cond = cond.And(builder.Expr("action LIKE ?", opts.TargetType+".%"))
+	}
+	if opts.TargetID != 0 {
+		cond = cond.And(builder.Eq{"target_id": opts.TargetID})
+	}
+	if opts.Since != 0 {
+		cond = cond.And(builder.Gte{"created_unix": opts.Since})
+	}
+	if opts.Before != 0 {
+		cond = cond.And(builder.Lt{"created_unix": opts.Before})
+	}
+	return cond
+}
+
+// SearchAuditEvents returns a page of the events matching the options, newest first, and
+// the total number of matching events
+func SearchAuditEvents(opts *SearchAuditEventsOptions) ([]*AuditEvent, int64, error) {
+	cond := opts.toConds()
+	count, err := x.Where(cond).Count(new(AuditEvent))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sess := opts.setSessionPagination(x.Where(cond).Desc("id"))
+	events := make([]*AuditEvent, 0, opts.PageSize)
+	return events, count, sess.Find(&events)
+}
+
+// DeleteAuditEventsOlderThan deletes the events made before the given duration
+func DeleteAuditEventsOlderThan(olderThan time.Duration) (int64, error) {
+	return x.Where("created_unix < ?", time.Now().Add(-olderThan).Unix()).Delete(new(AuditEvent))
+}
@@ -0,0 +1,86 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+	"time"
+
+	"go.wandrs.dev/framework/modules/timeutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditChanges(t *testing.T) {
+	before, after := AuditChanges(
+		map[string]string{"name": "user2", "email": "user2@example.com", "website": "https://example.com"},
+		map[string]string{"name": "user2", "email": "new@example.com", "website": "", "location": "Earth"},
+	)
+	assert.Equal(t, map[string]string{"email": "user2@example.com", "website": "https://example.com", "location": ""}, before)
+	assert.Equal(t, map[string]string{"email": "new@example.com", "website": "", "location": "Earth"}, after)
+
+	before, after = AuditChanges(map[string]string{"name": "user2"}, map[string]string{"name": "user2"})
+	assert.Empty(t, before)
+	assert.Empty(t, after)
+}
+
+func TestSearchAuditEvents(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	org := AssertExistsAndLoadBean(t, &User{ID: 3}).(*User)
+	team := AssertExistsAndLoadBean(t, &Team{ID: 2}).(*Team)
+	member := AssertExistsAndLoadBean(t, &User{ID: 5}).(*User)
+
+	add := TeamMemberAuditEvent(AuditTeamMemberAdd, team, member)
+	add.ActorID, add.ActorName = 2, "user2"
+	assert.NoError(t, CreateAuditEvent(add))
+	remove := OrgMemberRemoveAuditEvent(org, member)
+	remove.ActorID, remove.ActorName = 2, "user2"
+	assert.NoError(t, CreateAuditEvent(remove))
+	assert.NoError(t, CreateAuditEvent(&AuditEvent{
+		Action:     AuditAccessTokenCreate,
+		ActorID:    1,
+		ActorName:  "user1",
+		TargetID:   1,
+		TargetName: "token",
+	}))
+
+	test := func(opts *SearchAuditEventsOptions, expected ...*AuditEvent) {
+		events, count, err := SearchAuditEvents(opts)
+		assert.NoError(t, err)
+		assert.EqualValues(t, len(expected), count)
+		if assert.Len(t, events, len(expected)) {
+			for i := range expected {
+				assert.Equal(t, expected[i].ID, events[i].ID)
+			}
+		}
+	}
+	test(&SearchAuditEventsOptions{OrgID: org.ID}, remove, add)
+	test(&SearchAuditEventsOptions{Action: AuditTeamMemberAdd}, add)
+	test(&SearchAuditEventsOptions{ActorName: "USER2"}, remove, add)
+	test(&SearchAuditEventsOptions{TargetType: "org"}, remove)
+	test(&SearchAuditEventsOptions{TargetType: "team", TargetID: team.ID}, add)
+	test(&SearchAuditEventsOptions{Before: add.CreatedUnix.AddDuration(-time.Hour).AsTime().Unix()})
+
+	events, _, err := SearchAuditEvents(&SearchAuditEventsOptions{Action: AuditTeamMemberAdd})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"member": "user5"}, events[0].After)
+}
+
+func TestDeleteAuditEventsOlderThan(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	old := &AuditEvent{Action: AuditUserEdit, CreatedUnix: timeutil.TimeStamp(time.Now().Add(-48 * time.Hour).Unix())}
+	_, err := x.NoAutoTime().Insert(old)
+	assert.NoError(t, err)
+	recent := &AuditEvent{Action: AuditUserEdit}
+	assert.NoError(t, CreateAuditEvent(recent))
+
+	deleted, err := DeleteAuditEventsOlderThan(24 * time.Hour)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, deleted)
+	AssertNotExistsBean(t, &AuditEvent{ID: old.ID})
+	AssertExistsAndLoadBean(t, &AuditEvent{ID: recent.ID})
+}
@@ -7,6 +7,9 @@ package models
 
 import (
 	"fmt"
+	"time"
+
+	"go.wandrs.dev/framework/modules/timeutil"
 )
 
 // ErrNotExist represents a non-exist error.
@@ -174,6 +177,38 @@ func (err ErrUserProhibitLogin) Error() string {
 	return fmt.Sprintf("user is not allowed login [uid: %d, name: %s]", err.UID, err.Name)
 }
 
+// ErrUserLockedOut represents a "ErrUserLockedOut" kind of error.
+type ErrUserLockedOut struct {
+	UID  int64
+	Name string
+}
+
+// IsErrUserLockedOut checks if an error is a ErrUserLockedOut
+func IsErrUserLockedOut(err error) bool {
+	_, ok := err.(ErrUserLockedOut)
+	return ok
+}
+
+func (err ErrUserLockedOut) Error() string {
+	return fmt.Sprintf("user is locked out after too many failed sign in attempts [uid: %d, name: %s]", err.UID, err.Name)
+}
+
+// ErrUserSessionNotExist represents a "UserSessionNotExist" kind of error.
+type ErrUserSessionNotExist struct {
+	ID  int64
+	UID int64
+}
+
+// IsErrUserSessionNotExist checks if an error is a ErrUserSessionNotExist.
+func IsErrUserSessionNotExist(err error) bool {
+	_, ok := err.(ErrUserSessionNotExist)
+	return ok
+}
+
+func (err ErrUserSessionNotExist) Error() string {
+	return fmt.Sprintf("user session does not exist [id: %d, uid: %d]", err.ID, err.UID)
+}
+
 // ErrUserInactive represents a "ErrUserInactive" kind of error.
 type ErrUserInactive struct {
 	UID  int64
@@ -328,6 +363,56 @@ func (err ErrAccessTokenEmpty) Error() string {
 	return "access token is empty"
 }
 
+// ErrAccessTokenExpired represents a "AccessTokenExpired" kind of error.
+type ErrAccessTokenExpired struct {
+	ID  int64
+	UID int64
+}
+
+// IsErrAccessTokenExpired checks if an error is a ErrAccessTokenExpired.
+func IsErrAccessTokenExpired(err error) bool {
+	_, ok := err.(ErrAccessTokenExpired)
+	return ok
+}
+
+func (err ErrAccessTokenExpired) Error() string {
+	return fmt.Sprintf("access token has expired [id: %d, uid: %d]", err.ID, err.UID)
+}
+
+// ErrAccessTokenInvalidExpiry represents a "AccessTokenInvalidExpiry" kind of error.
+type ErrAccessTokenInvalidExpiry struct {
+	Expiry      timeutil.TimeStamp
+	MaxLifetime time.Duration
+}
+
+// IsErrAccessTokenInvalidExpiry checks if an error is a ErrAccessTokenInvalidExpiry.
+func IsErrAccessTokenInvalidExpiry(err error) bool {
+	_, ok := err.(ErrAccessTokenInvalidExpiry)
+	return ok
+}
+
+func (err ErrAccessTokenInvalidExpiry) Error() string {
+	if err.MaxLifetime > 0 {
+		return fmt.Sprintf("access token expiry must be in the future and within %s [expiry: %s]", err.MaxLifetime, err.Expiry.FormatLong())
+	}
+	return fmt.Sprintf("access token expiry must be in the future [expiry: %s]", err.Expiry.FormatLong())
+}
+
+// ErrAccessTokenInvalidScope represents a "AccessTokenInvalidScope" kind of error.
+type ErrAccessTokenInvalidScope struct {
+	Scope string
+}
+
+// IsErrAccessTokenInvalidScope checks if an error is a ErrAccessTokenInvalidScope.
+func IsErrAccessTokenInvalidScope(err error) bool {
+	_, ok := err.(ErrAccessTokenInvalidScope)
+	return ok
+}
+
+func (err ErrAccessTokenInvalidScope) Error() string {
+	return fmt.Sprintf("access token scope is invalid [scope: %s]", err.Scope)
+}
+
 // ________                            .__                __  .__
 // \_____  \_______  _________    ____ |__|____________ _/  |_|__| ____   ____
 //  /   |   \_  __ \/ ___\__  \  /    \|  \___   /\__  \\   __\  |/  _ \ /    \
@@ -502,6 +587,210 @@ func (err ErrTeamNotExist) Error() string {
 	return fmt.Sprintf("team does not exist [org_id %d, team_id %d, name: %s]", err.OrgID, err.TeamID, err.Name)
 }
 
+// ErrInvalidTeamParent represents a "InvalidTeamParent" kind of error, the parent would
+// create a cycle or is the owners team.
+type ErrInvalidTeamParent struct {
+	TeamID   int64
+	ParentID int64
+}
+
+// IsErrInvalidTeamParent checks if an error is a ErrInvalidTeamParent.
+func IsErrInvalidTeamParent(err error) bool {
+	_, ok := err.(ErrInvalidTeamParent)
+	return ok
+}
+
+func (err ErrInvalidTeamParent) Error() string {
+	return fmt.Sprintf("invalid team parent [team_id: %d, parent_id: %d]", err.TeamID, err.ParentID)
+}
+
+// ErrOrgRoleAlreadyExist represents a "OrgRoleAlreadyExist" kind of error.
+type ErrOrgRoleAlreadyExist struct {
+	OrgID int64
+	Name  string
+}
+
+// IsErrOrgRoleAlreadyExist checks if an error is a ErrOrgRoleAlreadyExist.
+func IsErrOrgRoleAlreadyExist(err error) bool {
+	_, ok := err.(ErrOrgRoleAlreadyExist)
+	return ok
+}
+
+func (err ErrOrgRoleAlreadyExist) Error() string {
+	return fmt.Sprintf("role already exists [org_id: %d, name: %s]", err.OrgID, err.Name)
+}
+
+// ErrOrgRoleNotExist represents a "OrgRoleNotExist" kind of error.
+type ErrOrgRoleNotExist struct {
+	OrgID int64
+	ID    int64
+}
+
+// IsErrOrgRoleNotExist checks if an error is a ErrOrgRoleNotExist.
+func IsErrOrgRoleNotExist(err error) bool {
+	_, ok := err.(ErrOrgRoleNotExist)
+	return ok
+}
+
+func (err ErrOrgRoleNotExist) Error() string {
+	return fmt.Sprintf("role does not exist [org_id: %d, id: %d]", err.OrgID, err.ID)
+}
+
+// ErrOrgRoleInUse represents a "OrgRoleInUse" kind of error.
+type ErrOrgRoleInUse struct {
+	ID int64
+}
+
+// IsErrOrgRoleInUse checks if an error is a ErrOrgRoleInUse.
+func IsErrOrgRoleInUse(err error) bool {
+	_, ok := err.(ErrOrgRoleInUse)
+	return ok
+}
+
+func (err ErrOrgRoleInUse) Error() string {
+	return fmt.Sprintf("role is still used by teams [id: %d]", err.ID)
+}
+
+// ErrOrgInviteAlreadyExist represents a "OrgInviteAlreadyExist" kind of error.
+type ErrOrgInviteAlreadyExist struct {
+	TeamID int64
+	Email  string
+}
+
+// IsErrOrgInviteAlreadyExist checks if an error is a ErrOrgInviteAlreadyExist.
+func IsErrOrgInviteAlreadyExist(err error) bool {
+	_, ok := err.(ErrOrgInviteAlreadyExist)
+	return ok
+}
+
+func (err ErrOrgInviteAlreadyExist) Error() string {
+	return fmt.Sprintf("invitation already exists [team_id: %d, email: %s]", err.TeamID, err.Email)
+}
+
+// ErrOrgInviteAlreadyMember represents a "OrgInviteAlreadyMember" kind of error.
+type ErrOrgInviteAlreadyMember struct {
+	TeamID int64
+	Email  string
+}
+
+// IsErrOrgInviteAlreadyMember checks if an error is a ErrOrgInviteAlreadyMember.
+func IsErrOrgInviteAlreadyMember(err error) bool {
+	_, ok := err.(ErrOrgInviteAlreadyMember)
+	return ok
+}
+
+func (err ErrOrgInviteAlreadyMember) Error() string {
+	return fmt.Sprintf("invited user is already a team member [team_id: %d, email: %s]", err.TeamID, err.Email)
+}
+
+// ErrOrgInviteNotExist represents a "OrgInviteNotExist" kind of error.
+type ErrOrgInviteNotExist struct {
+	ID int64
+}
+
+// IsErrOrgInviteNotExist checks if an error is a ErrOrgInviteNotExist.
+func IsErrOrgInviteNotExist(err error) bool {
+	_, ok := err.(ErrOrgInviteNotExist)
+	return ok
+}
+
+func (err ErrOrgInviteNotExist) Error() string {
+	return fmt.Sprintf("invitation does not exist or has expired [id: %d]", err.ID)
+}
+
+// ErrOrgInviteNotInvitee represents a "OrgInviteNotInvitee" kind of error.
+type ErrOrgInviteNotInvitee struct {
+	ID  int64
+	UID int64
+}
+
+// IsErrOrgInviteNotInvitee checks if an error is a ErrOrgInviteNotInvitee.
+func IsErrOrgInviteNotInvitee(err error) bool {
+	_, ok := err.(ErrOrgInviteNotInvitee)
+	return ok
+}
+
+func (err ErrOrgInviteNotInvitee) Error() string {
+	return fmt.Sprintf("invitation was sent to another email address [id: %d, uid: %d]", err.ID, err.UID)
+}
+
+// ErrOrgJoinRequestsDisabled represents a "OrgJoinRequestsDisabled" kind of error.
+type ErrOrgJoinRequestsDisabled struct {
+	OrgID int64
+}
+
+// IsErrOrgJoinRequestsDisabled checks if an error is a ErrOrgJoinRequestsDisabled.
+func IsErrOrgJoinRequestsDisabled(err error) bool {
+	_, ok := err.(ErrOrgJoinRequestsDisabled)
+	return ok
+}
+
+func (err ErrOrgJoinRequestsDisabled) Error() string {
+	return fmt.Sprintf("organization does not accept requests to join [org_id: %d]", err.OrgID)
+}
+
+// ErrOrgJoinRequestAlreadyExist represents a "OrgJoinRequestAlreadyExist" kind of error.
+type ErrOrgJoinRequestAlreadyExist struct {
+	OrgID  int64
+	UserID int64
+}
+
+// IsErrOrgJoinRequestAlreadyExist checks if an error is a ErrOrgJoinRequestAlreadyExist.
+func IsErrOrgJoinRequestAlreadyExist(err error) bool {
+	_, ok := err.(ErrOrgJoinRequestAlreadyExist)
+	return ok
+}
+
+func (err ErrOrgJoinRequestAlreadyExist) Error() string {
+	return fmt.Sprintf("request to join already exists [org_id: %d, user_id: %d]", err.OrgID, err.UserID)
+}
+
+// ErrOrgJoinRequestAlreadyMember represents a "OrgJoinRequestAlreadyMember" kind of error.
+type ErrOrgJoinRequestAlreadyMember struct {
+	OrgID  int64
+	UserID int64
+}
+
+// IsErrOrgJoinRequestAlreadyMember checks if an error is a ErrOrgJoinRequestAlreadyMember.
+func IsErrOrgJoinRequestAlreadyMember(err error) bool {
+	_, ok := err.(ErrOrgJoinRequestAlreadyMember)
+	return ok
+}
+
+func (err ErrOrgJoinRequestAlreadyMember) Error() string {
+	return fmt.Sprintf("user is already a member of the organization [org_id: %d, user_id: %d]", err.OrgID, err.UserID)
+}
+
+// ErrOrgJoinRequestNotExist represents a "OrgJoinRequestNotExist" kind of error.
+type ErrOrgJoinRequestNotExist struct {
+	ID int64
+}
+
+// IsErrOrgJoinRequestNotExist checks if an error is a ErrOrgJoinRequestNotExist.
+func IsErrOrgJoinRequestNotExist(err error) bool {
+	_, ok := err.(ErrOrgJoinRequestNotExist)
+	return ok
+}
+
+func (err ErrOrgJoinRequestNotExist) Error() string {
+	return fmt.Sprintf("request to join does not exist [id: %d]", err.ID)
+}
+
+// ErrUnitNotExist represents a "UnitNotExist" kind of error.
+type ErrUnitNotExist struct {
+	Name string
+}
+
+// IsErrUnitNotExist checks if an error is a ErrUnitNotExist.
+func IsErrUnitNotExist(err error) bool {
+	_, ok := err.(ErrUnitNotExist)
+	return ok
+}
+
+func (err ErrUnitNotExist) Error() string {
+	return fmt.Sprintf("permission unit is not registered [name: %s]", err.Name)
+}
+
 //
 // Two-factor authentication
 //
@@ -521,6 +810,25 @@ func (err ErrTwoFactorNotEnrolled) Error() string {
 	return fmt.Sprintf("user not enrolled in 2FA [uid: %d]", err.UID)
 }
 
+// ErrWebAuthnCredentialNotExist represents a "ErrWebAuthnCredentialNotExist" kind of error.
+type ErrWebAuthnCredentialNotExist struct {
+	ID           int64
+	CredentialID string
+}
+
+func (err ErrWebAuthnCredentialNotExist) Error() string {
+	if err.CredentialID == "" {
+		return fmt.Sprintf("WebAuthn credential does not exist [id: %d]", err.ID)
+	}
+	return fmt.Sprintf("WebAuthn credential does not exist [credential_id: %s]", err.CredentialID)
+}
+
+// IsErrWebAuthnCredentialNotExist checks if an error is a ErrWebAuthnCredentialNotExist.
+func IsErrWebAuthnCredentialNotExist(err error) bool {
+	_, ok := err.(ErrWebAuthnCredentialNotExist)
+	return ok
+}
+
 //  ___________         __                             .__    .____                 .__          ____ ___
 //  \_   _____/__  ____/  |_  ___________  ____ _____  |  |   |    |    ____   ____ |__| ____   |    |   \______ ___________
 //   |    __)_\  \/  /\   __\/ __ \_  __ \/    \\__  \ |  |   |    |   /  _ \ / ___\|  |/    \  |    |   /  ___// __ \_  __ \
@@ -561,28 +869,6 @@ func (err ErrExternalLoginUserNotExist) Error() string {
 	return fmt.Sprintf("external login user link does not exists [userID: %d, loginSourceID: %d]", err.UserID, err.LoginSourceID)
 }
 
-// ____ ________________________________              .__          __                 __  .__
-// |    |   \_____  \_   _____/\______   \ ____   ____ |__| _______/  |_____________ _/  |_|__| ____   ____
-// |    |   //  ____/|    __)   |       _// __ \ / ___\|  |/  ___/\   __\_  __ \__  \\   __\  |/  _ \ /    \
-// |    |  //       \|     \    |    |   \  ___// /_/  >  |\___ \  |  |  |  | \// __ \|  | |  (  <_> )   |  \
-// |______/ \_______ \___  /    |____|_  /\___  >___  /|__/____  > |__|  |__|  (____  /__| |__|\____/|___|  /
-// \/   \/            \/     \/_____/         \/                   \/                    \/
-
-// ErrU2FRegistrationNotExist represents a "ErrU2FRegistrationNotExist" kind of error.
-type ErrU2FRegistrationNotExist struct {
-	ID int64
-}
-
-func (err ErrU2FRegistrationNotExist) Error() string {
-	return fmt.Sprintf("U2F registration does not exist [id: %d]", err.ID)
-}
-
-// IsErrU2FRegistrationNotExist checks if an error is a ErrU2FRegistrationNotExist.
-func IsErrU2FRegistrationNotExist(err error) bool {
-	_, ok := err.(ErrU2FRegistrationNotExist)
-	return ok
-}
-
 //  ________      _____          __  .__
 //  \_____  \    /  _  \  __ ___/  |_|  |__
 //   /   |   \  /  /_\  \|  |  \   __\  |  \
@@ -15,6 +15,7 @@ import (
 	"strings"
 
 	"go.wandrs.dev/framework/modules/auth/ldap"
+	"go.wandrs.dev/framework/modules/auth/lockout"
 	"go.wandrs.dev/framework/modules/auth/oauth2"
 	"go.wandrs.dev/framework/modules/auth/pam"
 	"go.wandrs.dev/framework/modules/log"
@@ -25,6 +26,7 @@ import (
 
 	gouuid "github.com/google/uuid"
 	jsoniter "github.com/json-iterator/go"
+	"github.com/markbates/goth"
 	"xorm.io/xorm"
 	"xorm.io/xorm/convert"
 )
@@ -42,6 +44,7 @@ const (
 	LoginDLDAP            // 5
 	LoginOAuth2           // 6
 	LoginSSPI             // 7
+	LoginSAML             // 8
 )
 
 // LoginNames contains the name of LoginType values.
@@ -52,6 +55,7 @@ var LoginNames = map[LoginType]string{
 	LoginPAM:    "PAM",
 	LoginOAuth2: "OAuth2",
 	LoginSSPI:   "SPNEGO with SSPI",
+	LoginSAML:   "SAML 2.0",
 }
 
 // SecurityProtocolNames contains the name of SecurityProtocol values.
@@ -68,6 +72,7 @@ var (
 	_ convert.Conversion = &PAMConfig{}
 	_ convert.Conversion = &OAuth2Config{}
 	_ convert.Conversion = &SSPIConfig{}
+	_ convert.Conversion = &SAMLConfig{}
 )
 
 // LDAPConfig holds configuration for LDAP login source.
@@ -155,6 +160,11 @@ type OAuth2Config struct {
 	OpenIDConnectAutoDiscoveryURL string
 	CustomURLMapping              *oauth2.CustomURLMapping
 	IconURL                       string
+	GroupClaimName                string // claim of the provider's user data listing the groups of the user
+	AdminGroup                    string // group granting administrator permissions
+	RestrictedGroup               string // group restricting the user
+	GroupTeamMap                  string // JSON mapping of groups to organization teams
+	GroupTeamMapRemoval           bool   // remove the user from mapped teams of groups it is no longer a member of
 }
 
 // FromDB fills up an OAuth2Config from serialized format.
@@ -169,6 +179,41 @@ func (cfg *OAuth2Config) ToDB() ([]byte, error) {
 	return json.Marshal(cfg)
 }
 
+// ParseGroupTeamMap returns the teams mapped to each group, keyed by organization name
+func (cfg *OAuth2Config) ParseGroupTeamMap() (map[string]map[string][]string, error) {
+	groupTeamMap := make(map[string]map[string][]string)
+	if strings.TrimSpace(cfg.GroupTeamMap) == "" {
+		return groupTeamMap, nil
+	}
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	if err := json.Unmarshal([]byte(cfg.GroupTeamMap), &groupTeamMap); err != nil {
+		return nil, err
+	}
+	return groupTeamMap, nil
+}
+
+// Groups returns the groups listed in the group claim of the user data returned by the provider
+func (cfg *OAuth2Config) Groups(gothUser goth.User) []string {
+	if cfg.GroupClaimName == "" {
+		return nil
+	}
+	switch claim := gothUser.RawData[cfg.GroupClaimName].(type) {
+	case string:
+		return []string{claim}
+	case []string:
+		return claim
+	case []interface{}:
+		groups := make([]string, 0, len(claim))
+		for _, group := range claim {
+			if name, ok := group.(string); ok {
+				groups = append(groups, name)
+			}
+		}
+		return groups
+	}
+	return nil
+}
+
 // SSPIConfig holds configuration for SSPI single sign-on.
 type SSPIConfig struct {
 	AutoCreateUsers      bool
@@ -190,6 +235,37 @@ func (cfg *SSPIConfig) ToDB() ([]byte, error) {
 	return json.Marshal(cfg)
 }
 
+// SAMLConfig holds configuration for the SAML 2.0 login source.
+type SAMLConfig struct {
+	IdPEntityID    string
+	IdPSSOURL      string
+	IdPCertificate string
+	// SPEntityID is the entity id of this service provider, the metadata URL of the source if empty
+	SPEntityID   string
+	NameIDFormat string
+
+	AttributeUsername string // NameID if empty
+	AttributeEmail    string
+	AttributeFullName string
+	AttributeAdmin    string
+	AdminValue        string // users are administrators if the admin attribute has this value
+
+	AllowIdPInitiated      bool
+	EnableAutoRegistration bool
+}
+
+// FromDB fills up a SAMLConfig from serialized format.
+func (cfg *SAMLConfig) FromDB(bs []byte) error {
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	return json.Unmarshal(bs, cfg)
+}
+
+// ToDB exports a SAMLConfig to a serialized format.
+func (cfg *SAMLConfig) ToDB() ([]byte, error) {
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	return json.Marshal(cfg)
+}
+
 // LoginSource represents an external way for authorizing users.
 type LoginSource struct {
 	ID            int64 `xorm:"pk autoincr"`
@@ -230,6 +306,8 @@ func (source *LoginSource) BeforeSet(colName string, val xorm.Cell) {
 			source.Cfg = new(OAuth2Config)
 		case LoginSSPI:
 			source.Cfg = new(SSPIConfig)
+		case LoginSAML:
+			source.Cfg = new(SAMLConfig)
 		default:
 			panic(fmt.Sprintf("unrecognized login source type: %v", *val))
 		}
@@ -271,6 +349,11 @@ func (source *LoginSource) IsSSPI() bool {
 	return source.Type == LoginSSPI
 }
 
+// IsSAML returns true of this source is of the SAML type.
+func (source *LoginSource) IsSAML() bool {
+	return source.Type == LoginSAML
+}
+
 // HasTLS returns true of this source supports TLS.
 func (source *LoginSource) HasTLS() bool {
 	return ((source.IsLDAP() || source.IsDLDAP()) &&
@@ -328,6 +411,11 @@ func (source *LoginSource) SSPI() *SSPIConfig {
 	return source.Cfg.(*SSPIConfig)
 }
 
+// SAML returns SAMLConfig for this source, if of SAML type.
+func (source *LoginSource) SAML() *SAMLConfig {
+	return source.Cfg.(*SAMLConfig)
+}
+
 // CreateLoginSource inserts a LoginSource in the DB if not already
 // existing with the given name.
 func CreateLoginSource(source *LoginSource) error {
@@ -396,6 +484,29 @@ func IsSSPIEnabled() bool {
 	return len(sources) > 0
 }
 
+// GetActiveSAMLLoginSourceByName returns a SAML LoginSource based on the given name
+func GetActiveSAMLLoginSourceByName(name string) (*LoginSource, error) {
+	loginSource := new(LoginSource)
+	has, err := x.Where("name = ? and type = ? and is_actived = ?", name, LoginSAML, true).Get(loginSource)
+	if !has || err != nil {
+		return nil, err
+	}
+	return loginSource, nil
+}
+
+// GetActiveExternalLoginSourceByName returns the active OAuth2 or SAML LoginSource with the given name,
+// these are the sources users are linked to through an ExternalLoginUser
+func GetActiveExternalLoginSourceByName(name string) (*LoginSource, error) {
+	loginSource := new(LoginSource)
+	has, err := x.Where("name = ? and is_actived = ?", name, true).In("type", LoginOAuth2, LoginSAML).Get(loginSource)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrLoginSourceNotExist{}
+	}
+	return loginSource, nil
+}
+
 // GetLoginSourceByID returns login source by given ID.
 func GetLoginSourceByID(id int64) (*LoginSource, error) {
 	source := new(LoginSource)
@@ -526,6 +637,9 @@ func LoginViaLDAP(user *User, login, password string, source *LoginSource) (*Use
 	}
 
 	if user != nil {
+		if err := syncLDAPGroupTeams(source, user, sr.Groups); err != nil {
+			log.Error("Unable to sync LDAP groups of %s to teams: %v", user.Name, err)
+		}
 		return user, nil
 	}
 
@@ -551,9 +665,108 @@ func LoginViaLDAP(user *User, login, password string, source *LoginSource) (*Use
 		IsRestricted: sr.IsRestricted,
 	}
 
-	err := CreateUser(user)
+	if err := CreateUser(user); err != nil {
+		return user, err
+	}
+
+	if err := syncLDAPGroupTeams(source, user, sr.Groups); err != nil {
+		log.Error("Unable to sync LDAP groups of %s to teams: %v", user.Name, err)
+	}
+	return user, nil
+}
+
+// syncLDAPGroupTeams adds the user to the teams mapped to the LDAP groups it is a member of
+// and, if the source is configured to do so, removes it from the other mapped teams.
+func syncLDAPGroupTeams(source *LoginSource, user *User, groups []string) error {
+	cfg := source.LDAP()
+	if !cfg.GroupsEnabled {
+		return nil
+	}
+	groupTeamMap, err := cfg.ParseGroupTeamMap()
+	if err != nil {
+		return err
+	}
 
-	return user, err
+	// DNs are compared case-insensitively
+	dnTeamMap := make(map[string]map[string][]string, len(groupTeamMap))
+	for dn, orgTeams := range groupTeamMap {
+		dnTeamMap[strings.ToLower(dn)] = orgTeams
+	}
+	dns := make([]string, 0, len(groups))
+	for _, dn := range groups {
+		dns = append(dns, strings.ToLower(dn))
+	}
+	return syncGroupTeams(source, user, dnTeamMap, dns, cfg.GroupTeamMapRemoval)
+}
+
+// syncGroupTeams adds the user to the teams the group team map of the login source maps its groups to
+// and, if removeFromOthers is set, removes it from the teams mapped to the groups it is not a member of.
+func syncGroupTeams(source *LoginSource, user *User, groupTeamMap map[string]map[string][]string, groups []string, removeFromOthers bool) error {
+	// teams of all mapped groups, true for those of the groups the user is a member of
+	teams := make(map[string]map[string]bool)
+	for mappedGroup, orgTeams := range groupTeamMap {
+		isMember := false
+		for _, group := range groups {
+			if group == mappedGroup {
+				isMember = true
+				break
+			}
+		}
+		for orgName, teamNames := range orgTeams {
+			orgName = strings.ToLower(orgName)
+			if teams[orgName] == nil {
+				teams[orgName] = make(map[string]bool)
+			}
+			for _, teamName := range teamNames {
+				teamName = strings.ToLower(teamName)
+				teams[orgName][teamName] = teams[orgName][teamName] || isMember
+			}
+		}
+	}
+
+	for orgName, orgTeams := range teams {
+		org, err := GetOrgByName(orgName)
+		if err != nil {
+			if IsErrOrgNotExist(err) {
+				log.Warn("Group team mapping of %s refers to unknown organization %s", source.Name, orgName)
+				continue
+			}
+			return err
+		}
+		for teamName, shouldBeMember := range orgTeams {
+			if !shouldBeMember && !removeFromOthers {
+				continue
+			}
+			team, err := GetTeam(org.ID, teamName)
+			if err != nil {
+				if IsErrTeamNotExist(err) {
+					log.Warn("Group team mapping of %s refers to unknown team %s/%s", source.Name, orgName, teamName)
+					continue
+				}
+				return err
+			}
+			isMember, err := IsTeamMember(org.ID, team.ID, user.ID)
+			if err != nil {
+				return err
+			}
+			if shouldBeMember && !isMember {
+				if err := AddTeamMember(team, user.ID); err != nil {
+					return err
+				}
+				RecordAuditEvent(TeamMemberAuditEvent(AuditTeamMemberAdd, team, user))
+			} else if !shouldBeMember && isMember {
+				if err := RemoveTeamMember(team, user.ID); err != nil {
+					if IsErrLastOrgOwner(err) {
+						log.Warn("Group team mapping of %s cannot remove the last owner %s of %s", source.Name, user.Name, orgName)
+						continue
+					}
+					return err
+				}
+				RecordAuditEvent(TeamMemberAuditEvent(AuditTeamMemberRemove, team, user))
+			}
+		}
+	}
+	return nil
 }
 
 //   _________   __________________________
@@ -763,6 +976,46 @@ func ExternalUserLogin(user *User, login, password string, source *LoginSource)
 	return user, nil
 }
 
+// signInExistingUser validates the password of the user through its login source
+func signInExistingUser(user *User, password string) (*User, error) {
+	switch user.LoginType {
+	case LoginNoType, LoginPlain, LoginOAuth2, LoginSAML:
+		if user.IsPasswordSet() && user.ValidatePassword(password) {
+
+			// Update password hash if server password hash algorithm have changed
+			if user.PasswdHashAlgo != setting.PasswordHashAlgo {
+				if err := user.SetPassword(password); err != nil {
+					return nil, err
+				}
+				if err := UpdateUserCols(user, "passwd", "passwd_hash_algo", "salt"); err != nil {
+					return nil, err
+				}
+			}
+
+			// WARN: DON'T check user.IsActive, that will be checked on reqSign so that
+			// user could be hint to resend confirm email.
+			if user.ProhibitLogin {
+				return nil, ErrUserProhibitLogin{user.ID, user.Name}
+			}
+
+			return user, nil
+		}
+
+		return nil, ErrUserNotExist{user.ID, user.Name, 0}
+
+	default:
+		var source LoginSource
+		hasSource, err := x.ID(user.LoginSource).Get(&source)
+		if err != nil {
+			return nil, err
+		} else if !hasSource {
+			return nil, ErrLoginSourceNotExist{user.LoginSource}
+		}
+
+		return ExternalUserLogin(user, user.LoginName, password, &source)
+	}
+}
+
 // UserSignIn validates user name and password.
 func UserSignIn(username, password string) (*User, error) {
 	var user *User
@@ -793,42 +1046,14 @@ func UserSignIn(username, password string) (*User, error) {
 	}
 
 	if hasUser {
-		switch user.LoginType {
-		case LoginNoType, LoginPlain, LoginOAuth2:
-			if user.IsPasswordSet() && user.ValidatePassword(password) {
-
-				// Update password hash if server password hash algorithm have changed
-				if user.PasswdHashAlgo != setting.PasswordHashAlgo {
-					if err = user.SetPassword(password); err != nil {
-						return nil, err
-					}
-					if err = UpdateUserCols(user, "passwd", "passwd_hash_algo", "salt"); err != nil {
-						return nil, err
-					}
-				}
-
-				// WARN: DON'T check user.IsActive, that will be checked on reqSign so that
-				// user could be hint to resend confirm email.
-				if user.ProhibitLogin {
-					return nil, ErrUserProhibitLogin{user.ID, user.Name}
-				}
-
-				return user, nil
-			}
-
-			return nil, ErrUserNotExist{user.ID, user.Name, 0}
-
-		default:
-			var source LoginSource
-			hasSource, err := x.ID(user.LoginSource).Get(&source)
-			if err != nil {
-				return nil, err
-			} else if !hasSource {
-				return nil, ErrLoginSourceNotExist{user.LoginSource}
-			}
-
-			return ExternalUserLogin(user, user.LoginName, password, &source)
+		if lockout.IsAccountLocked(user.ID) {
+			return nil, ErrUserLockedOut{user.ID, user.Name}
+		}
+		authUser, err := signInExistingUser(user, password)
+		if err != nil && IsErrUserNotExist(err) {
+			RecordFailedSignIn(user)
 		}
+		return authUser, err
 	}
 
 	sources := make([]*LoginSource, 0, 5)
@@ -837,8 +1062,8 @@ func UserSignIn(username, password string) (*User, error) {
 	}
 
 	for _, source := range sources {
-		if source.IsOAuth2() || source.IsSSPI() {
-			// don't try to authenticate against OAuth2 and SSPI sources here
+		if source.IsOAuth2() || source.IsSSPI() || source.IsSAML() {
+			// don't try to authenticate against OAuth2, SSPI and SAML sources here
 			continue
 		}
 		authUser, err := ExternalUserLogin(nil, username, password, source)
@@ -0,0 +1,125 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+
+	"go.wandrs.dev/framework/modules/auth/ldap"
+
+	"github.com/markbates/goth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncLDAPGroupTeams(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	user := AssertExistsAndLoadBean(t, &User{ID: 4}).(*User)
+	source := &LoginSource{
+		Name: "ldap",
+		Type: LoginLDAP,
+		Cfg: &LDAPConfig{
+			Source: &ldap.Source{
+				GroupsEnabled: true,
+				GroupTeamMap: `{
+					"cn=owners,ou=group,dc=example,dc=com": {"user3": ["Owners"]},
+					"cn=developers,ou=group,dc=example,dc=com": {"user3": ["team1"], "user6": ["Unknown"]},
+					"cn=others,ou=group,dc=example,dc=com": {"unknown": ["Owners"]}
+				}`,
+			},
+		},
+	}
+
+	// group DNs are compared case-insensitively and memberships are only added
+	assert.NoError(t, syncLDAPGroupTeams(source, user, []string{"CN=Owners,OU=group,DC=example,DC=com"}))
+	AssertExistsAndLoadBean(t, &TeamUser{TeamID: 1, UID: user.ID})
+	AssertExistsAndLoadBean(t, &TeamUser{TeamID: 2, UID: user.ID})
+
+	// memberships of mapped teams are removed if the source is configured to do so
+	source.LDAP().GroupTeamMapRemoval = true
+	assert.NoError(t, syncLDAPGroupTeams(source, user, []string{"cn=developers,ou=group,dc=example,dc=com"}))
+	AssertNotExistsBean(t, &TeamUser{TeamID: 1, UID: user.ID})
+	AssertExistsAndLoadBean(t, &TeamUser{TeamID: 2, UID: user.ID})
+
+	// the last owner of an organization is not removed
+	owner := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	assert.NoError(t, syncLDAPGroupTeams(source, owner, nil))
+	AssertExistsAndLoadBean(t, &TeamUser{TeamID: 1, UID: owner.ID})
+	AssertNotExistsBean(t, &TeamUser{TeamID: 2, UID: owner.ID})
+
+	// the mapping is ignored without group membership verification
+	source.LDAP().GroupsEnabled = false
+	assert.NoError(t, syncLDAPGroupTeams(source, user, nil))
+	AssertExistsAndLoadBean(t, &TeamUser{TeamID: 2, UID: user.ID})
+
+	source.LDAP().GroupsEnabled = true
+	source.LDAP().GroupTeamMap = `["invalid"]`
+	assert.Error(t, syncLDAPGroupTeams(source, user, nil))
+}
+
+func TestOAuth2Config_Groups(t *testing.T) {
+	cfg := &OAuth2Config{GroupClaimName: "groups"}
+	assert.Equal(t, []string{"admins", "developers"}, cfg.Groups(goth.User{RawData: map[string]interface{}{
+		"groups": []interface{}{"admins", 42, "developers"},
+	}}))
+	assert.Equal(t, []string{"admins"}, cfg.Groups(goth.User{RawData: map[string]interface{}{
+		"groups": "admins",
+	}}))
+	assert.Empty(t, cfg.Groups(goth.User{RawData: map[string]interface{}{
+		"roles": []interface{}{"admins"},
+	}}))
+
+	cfg.GroupClaimName = ""
+	assert.Empty(t, cfg.Groups(goth.User{RawData: map[string]interface{}{
+		"groups": []interface{}{"admins"},
+	}}))
+}
+
+func TestSyncOAuth2UserGroups(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	user := AssertExistsAndLoadBean(t, &User{ID: 4}).(*User)
+	source := &LoginSource{
+		Name: "keycloak",
+		Type: LoginOAuth2,
+		Cfg: &OAuth2Config{
+			GroupClaimName:      "groups",
+			AdminGroup:          "admins",
+			RestrictedGroup:     "contractors",
+			GroupTeamMap:        `{"owners": {"user3": ["Owners"]}, "developers": {"user3": ["team1"]}}`,
+			GroupTeamMapRemoval: true,
+		},
+	}
+	withGroups := func(groups ...interface{}) goth.User {
+		return goth.User{RawData: map[string]interface{}{"groups": groups}}
+	}
+
+	assert.NoError(t, SyncOAuth2UserGroups(source, user, withGroups("contractors", "owners")))
+	user = AssertExistsAndLoadBean(t, &User{ID: 4}).(*User)
+	assert.False(t, user.IsAdmin)
+	assert.True(t, user.IsRestricted)
+	AssertExistsAndLoadBean(t, &TeamUser{TeamID: 1, UID: user.ID})
+	AssertNotExistsBean(t, &TeamUser{TeamID: 2, UID: user.ID})
+
+	// group values are compared case-sensitively
+	assert.NoError(t, SyncOAuth2UserGroups(source, user, withGroups("Admins", "developers")))
+	user = AssertExistsAndLoadBean(t, &User{ID: 4}).(*User)
+	assert.False(t, user.IsAdmin)
+	assert.False(t, user.IsRestricted)
+	AssertNotExistsBean(t, &TeamUser{TeamID: 1, UID: user.ID})
+	AssertExistsAndLoadBean(t, &TeamUser{TeamID: 2, UID: user.ID})
+
+	assert.NoError(t, SyncOAuth2UserGroups(source, user, withGroups("admins", "contractors", "developers")))
+	user = AssertExistsAndLoadBean(t, &User{ID: 4}).(*User)
+	assert.True(t, user.IsAdmin)
+	assert.False(t, user.IsRestricted)
+
+	// nothing is mapped without a group claim
+	source.OAuth2().GroupClaimName = ""
+	assert.NoError(t, SyncOAuth2UserGroups(source, user, withGroups()))
+	user = AssertExistsAndLoadBean(t, &User{ID: 4}).(*User)
+	assert.True(t, user.IsAdmin)
+	AssertExistsAndLoadBean(t, &TeamUser{TeamID: 2, UID: user.ID})
+}
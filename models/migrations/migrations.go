@@ -58,7 +58,28 @@ type Version struct {
 // This is a sequence of migrations. Add new migrations to the bottom of the list.
 // If you want to "retire" a migration, remove it from the top of the list and
 // update minDBVersion accordingly
-var migrations = []Migration{}
+var migrations = []Migration{
+	// v70 -> v71
+	NewMigration("add webauthn_credential table and migrate u2f registrations", addWebAuthnCredentialAndMigrateU2F),
+	// v71 -> v72
+	NewMigration("add scim_resource table", addSCIMResourceTable),
+	// v72 -> v73
+	NewMigration("add audit_event table", addAuditEventTable),
+	// v73 -> v74
+	NewMigration("add user_session table", addUserSessionTable),
+	// v74 -> v75
+	NewMigration("add team_unit and org_role tables", addTeamUnitAndOrgRoleTables),
+	// v75 -> v76
+	NewMigration("add parent_id to team", addParentIDToTeam),
+	// v76 -> v77
+	NewMigration("add org_invite table", addOrgInviteTable),
+	// v77 -> v78
+	NewMigration("add org_join_request table and allow_join_requests to user", addOrgJoinRequests),
+	// v78 -> v79
+	NewMigration("add two-factor authentication policy to user", addTwoFactorPolicyToUser),
+	// v79 -> v80
+	NewMigration("add client_credentials to oauth2_grant", addClientCredentialsToOAuth2Grant),
+}
 
 // GetCurrentDBVersion returns the current db version
 func GetCurrentDBVersion(x *xorm.Engine) (int64, error) {
@@ -0,0 +1,97 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"encoding/base64"
+
+	"go.wandrs.dev/framework/modules/auth/webauthn"
+	"go.wandrs.dev/framework/modules/log"
+	"go.wandrs.dev/framework/modules/timeutil"
+
+	"github.com/tstranex/u2f"
+	"xorm.io/xorm"
+)
+
+func addWebAuthnCredentialAndMigrateU2F(x *xorm.Engine) error {
+	type u2fRegistration struct {
+		ID          int64 `xorm:"pk autoincr"`
+		Name        string
+		UserID      int64 `xorm:"INDEX"`
+		Raw         []byte
+		Counter     uint32             `xorm:"BIGINT"`
+		CreatedUnix timeutil.TimeStamp `xorm:"INDEX created"`
+		UpdatedUnix timeutil.TimeStamp `xorm:"INDEX updated"`
+	}
+
+	// named so that the mapper yields the webauthn_credential table
+	type webauthnCredential struct {
+		ID              int64 `xorm:"pk autoincr"`
+		Name            string
+		UserID          int64  `xorm:"INDEX"`
+		CredentialID    string `xorm:"INDEX VARCHAR(410)"`
+		PublicKey       []byte
+		AttestationType string
+		AAGUID          []byte
+		SignCount       uint32             `xorm:"BIGINT"`
+		Transports      []string           `xorm:"TEXT JSON"`
+		Legacy          bool               `xorm:"NOT NULL DEFAULT false"`
+		CreatedUnix     timeutil.TimeStamp `xorm:"INDEX created"`
+		UpdatedUnix     timeutil.TimeStamp `xorm:"INDEX updated"`
+	}
+
+	if err := x.Sync2(new(webauthnCredential)); err != nil {
+		return err
+	}
+
+	exist, err := x.IsTableExist("u2f_registration")
+	if err != nil || !exist {
+		return err
+	}
+
+	sess := x.NewSession()
+	defer sess.Close()
+	if err := sess.Begin(); err != nil {
+		return err
+	}
+
+	const batchSize = 100
+	for start := 0; ; start += batchSize {
+		regs := make([]*u2fRegistration, 0, batchSize)
+		if err := sess.Asc("id").Limit(batchSize, start).Find(&regs); err != nil {
+			return err
+		}
+		if len(regs) == 0 {
+			break
+		}
+
+		for _, reg := range regs {
+			parsed := new(u2f.Registration)
+			if err := parsed.UnmarshalBinary(reg.Raw); err != nil {
+				log.Warn("Unable to migrate U2F registration %d of user %d: %v", reg.ID, reg.UserID, err)
+				continue
+			}
+
+			// U2F key handles and public keys are valid WebAuthn credentials scoped to the U2F application id
+			cred := &webauthnCredential{
+				Name:            reg.Name,
+				UserID:          reg.UserID,
+				CredentialID:    base64.RawURLEncoding.EncodeToString(parsed.KeyHandle),
+				PublicKey:       webauthn.MarshalECDSAPublicKey(&parsed.PubKey),
+				AttestationType: "fido-u2f",
+				SignCount:       reg.Counter,
+				Legacy:          true,
+				CreatedUnix:     reg.CreatedUnix,
+				UpdatedUnix:     reg.UpdatedUnix,
+			}
+			if _, err := sess.NoAutoTime().Insert(cred); err != nil {
+				return err
+			}
+		}
+	}
+
+	// The u2f_registration table is left in place so that the raw registrations are not lost.
+	return sess.Commit()
+}
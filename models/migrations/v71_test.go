@@ -0,0 +1,101 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"testing"
+	"time"
+
+	"go.wandrs.dev/framework/modules/auth/webauthn"
+	"go.wandrs.dev/framework/modules/timeutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rawU2FRegistration builds the registration data a U2F security key returns on registration
+func rawU2FRegistration(t *testing.T, keyHandle []byte) ([]byte, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "U2F attestation"},
+		NotBefore:    time.Unix(0, 0),
+		NotAfter:     time.Unix(0, 0).AddDate(100, 0, 0),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	raw := []byte{0x05}
+	raw = append(raw, elliptic.Marshal(elliptic.P256(), key.PublicKey.X, key.PublicKey.Y)...)
+	raw = append(raw, byte(len(keyHandle)))
+	raw = append(raw, keyHandle...)
+	raw = append(raw, cert...)
+	// the signature is not verified when the registration is parsed
+	raw = append(raw, 0x30, 0x00)
+	return raw, key
+}
+
+func Test_addWebAuthnCredentialAndMigrateU2F(t *testing.T) {
+	type u2fRegistration struct {
+		ID          int64 `xorm:"pk autoincr"`
+		Name        string
+		UserID      int64 `xorm:"INDEX"`
+		Raw         []byte
+		Counter     uint32             `xorm:"BIGINT"`
+		CreatedUnix timeutil.TimeStamp `xorm:"INDEX created"`
+		UpdatedUnix timeutil.TimeStamp `xorm:"INDEX updated"`
+	}
+
+	type webauthnCredential struct {
+		ID              int64 `xorm:"pk autoincr"`
+		Name            string
+		UserID          int64
+		CredentialID    string
+		PublicKey       []byte
+		AttestationType string
+		SignCount       uint32 `xorm:"BIGINT"`
+		Legacy          bool
+	}
+
+	x, deferable := prepareTestEnv(t, 0, new(u2fRegistration))
+	if x == nil || t.Failed() {
+		defer deferable()
+		return
+	}
+	defer deferable()
+
+	raw, key := rawU2FRegistration(t, []byte("key-handle"))
+	_, err := x.Insert(
+		&u2fRegistration{Name: "Security key", UserID: 2, Raw: raw, Counter: 42},
+		&u2fRegistration{Name: "Broken key", UserID: 2, Raw: []byte("invalid")},
+	)
+	assert.NoError(t, err)
+
+	if err := addWebAuthnCredentialAndMigrateU2F(x); err != nil {
+		assert.NoError(t, err)
+		return
+	}
+
+	creds := make([]*webauthnCredential, 0)
+	assert.NoError(t, x.Find(&creds))
+	if assert.Len(t, creds, 1) {
+		cred := creds[0]
+		assert.Equal(t, "Security key", cred.Name)
+		assert.EqualValues(t, 2, cred.UserID)
+		assert.Equal(t, base64.RawURLEncoding.EncodeToString([]byte("key-handle")), cred.CredentialID)
+		assert.Equal(t, webauthn.MarshalECDSAPublicKey(&key.PublicKey), cred.PublicKey)
+		assert.Equal(t, "fido-u2f", cred.AttestationType)
+		assert.EqualValues(t, 42, cred.SignCount)
+		assert.True(t, cred.Legacy)
+	}
+}
@@ -0,0 +1,24 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"go.wandrs.dev/framework/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func addSCIMResourceTable(x *xorm.Engine) error {
+	type SCIMResource struct {
+		ID           int64              `xorm:"pk autoincr"`
+		ResourceType int                `xorm:"UNIQUE(s) NOT NULL"`
+		ResourceID   int64              `xorm:"UNIQUE(s) NOT NULL"`
+		ExternalID   string             `xorm:"INDEX"`
+		CreatedUnix  timeutil.TimeStamp `xorm:"created"`
+		UpdatedUnix  timeutil.TimeStamp `xorm:"updated"`
+	}
+
+	return x.Sync2(new(SCIMResource))
+}
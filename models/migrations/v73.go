@@ -0,0 +1,29 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"go.wandrs.dev/framework/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func addAuditEventTable(x *xorm.Engine) error {
+	type AuditEvent struct {
+		ID          int64  `xorm:"pk autoincr"`
+		Action      string `xorm:"VARCHAR(50) INDEX NOT NULL"`
+		ActorID     int64  `xorm:"INDEX"`
+		ActorName   string
+		OrgID       int64 `xorm:"INDEX"`
+		TargetID    int64 `xorm:"INDEX"`
+		TargetName  string
+		IPAddress   string             `xorm:"VARCHAR(64)"`
+		Before      map[string]string  `xorm:"TEXT JSON"`
+		After       map[string]string  `xorm:"TEXT JSON"`
+		CreatedUnix timeutil.TimeStamp `xorm:"INDEX created"`
+	}
+
+	return x.Sync2(new(AuditEvent))
+}
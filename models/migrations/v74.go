@@ -0,0 +1,24 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"go.wandrs.dev/framework/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func addUserSessionTable(x *xorm.Engine) error {
+	type UserSession struct {
+		ID          int64              `xorm:"pk autoincr"`
+		UID         int64              `xorm:"INDEX NOT NULL"`
+		UserAgent   string             `xorm:"TEXT"`
+		IPAddress   string             `xorm:"VARCHAR(64)"`
+		CreatedUnix timeutil.TimeStamp `xorm:"created"`
+		UpdatedUnix timeutil.TimeStamp `xorm:"INDEX"`
+	}
+
+	return x.Sync2(new(UserSession))
+}
@@ -0,0 +1,52 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"go.wandrs.dev/framework/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func addTeamUnitAndOrgRoleTables(x *xorm.Engine) error {
+	type Team struct {
+		RoleID int64 `xorm:"INDEX NOT NULL DEFAULT 0"`
+	}
+
+	type TeamUnit struct {
+		ID         int64  `xorm:"pk autoincr"`
+		OrgID      int64  `xorm:"INDEX"`
+		TeamID     int64  `xorm:"UNIQUE(s)"`
+		Unit       string `xorm:"VARCHAR(50) UNIQUE(s) NOT NULL"`
+		AccessMode int    `xorm:"NOT NULL DEFAULT 0"`
+	}
+
+	type OrgRole struct {
+		ID          int64              `xorm:"pk autoincr"`
+		OrgID       int64              `xorm:"INDEX UNIQUE(s)"`
+		LowerName   string             `xorm:"UNIQUE(s) NOT NULL"`
+		Name        string             `xorm:"NOT NULL"`
+		Description string             `xorm:"TEXT"`
+		Units       map[string]int     `xorm:"TEXT JSON"`
+		CreatedUnix timeutil.TimeStamp `xorm:"created"`
+		UpdatedUnix timeutil.TimeStamp `xorm:"updated"`
+	}
+
+	// databases upgraded from Gitea have a team_unit table of the repository units, which
+	// are not used by the framework
+	tables, err := x.DBMetas()
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		if table.Name == "team_unit" && table.GetColumn("type") != nil {
+			if err := x.DropTables("team_unit"); err != nil {
+				return err
+			}
+		}
+	}
+
+	return x.Sync2(new(Team), new(TeamUnit), new(OrgRole))
+}
@@ -0,0 +1,25 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"go.wandrs.dev/framework/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func addOrgInviteTable(x *xorm.Engine) error {
+	type OrgInvite struct {
+		ID          int64              `xorm:"pk autoincr"`
+		OrgID       int64              `xorm:"INDEX"`
+		TeamID      int64              `xorm:"INDEX"`
+		InviterID   int64              `xorm:"INDEX"`
+		Email       string             `xorm:"VARCHAR(255) INDEX NOT NULL"`
+		CreatedUnix timeutil.TimeStamp `xorm:"created"`
+		ExpiresUnix timeutil.TimeStamp `xorm:"INDEX"`
+	}
+
+	return x.Sync2(new(OrgInvite))
+}
@@ -0,0 +1,30 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"go.wandrs.dev/framework/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func addOrgJoinRequests(x *xorm.Engine) error {
+	type User struct {
+		AllowJoinRequests bool `xorm:"NOT NULL DEFAULT false"`
+	}
+
+	type OrgJoinRequest struct {
+		ID          int64              `xorm:"pk autoincr"`
+		OrgID       int64              `xorm:"UNIQUE(s)"`
+		UserID      int64              `xorm:"UNIQUE(s) INDEX"`
+		Message     string             `xorm:"TEXT"`
+		CreatedUnix timeutil.TimeStamp `xorm:"created"`
+	}
+
+	if err := x.Sync2(new(User)); err != nil {
+		return err
+	}
+	return x.Sync2(new(OrgJoinRequest))
+}
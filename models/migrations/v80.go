@@ -0,0 +1,20 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+)
+
+func addClientCredentialsToOAuth2Grant(x *xorm.Engine) error {
+	type OAuth2Grant struct {
+		ID                int64 `xorm:"pk autoincr"`
+		UserID            int64 `xorm:"INDEX unique(user_application)"`
+		ApplicationID     int64 `xorm:"INDEX unique(user_application)"`
+		ClientCredentials bool  `xorm:"NOT NULL DEFAULT false unique(user_application)"`
+	}
+
+	return x.Table("oauth2_grant").Sync2(new(OAuth2Grant))
+}
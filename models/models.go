@@ -79,13 +79,21 @@ func init() {
 		new(TwoFactor),
 		new(ExternalLoginUser),
 		new(UserOpenID),
-		new(U2FRegistration),
+		new(WebAuthnCredential),
 		new(OAuth2Application),
 		new(OAuth2AuthorizationCode),
 		new(OAuth2Grant),
+		new(OAuth2DeviceAuthorization),
 		new(EmailHash),
 		new(UserRedirect),
 		new(Session),
+		new(SCIMResource),
+		new(AuditEvent),
+		new(UserSession),
+		new(TeamUnit),
+		new(OrgRole),
+		new(OrgInvite),
+		new(OrgJoinRequest),
 	)
 
 	gonicNames := []string{"SSL", "UID"}
@@ -9,6 +9,8 @@ import (
 
 	"go.wandrs.dev/framework/modules/auth/oauth2"
 	"go.wandrs.dev/framework/modules/log"
+
+	"github.com/markbates/goth"
 )
 
 // OAuth2Provider describes the display values of a single OAuth2 provider
@@ -170,3 +172,42 @@ func wrapOpenIDConnectInitializeError(err error, providerName string, oAuth2Conf
 	}
 	return err
 }
+
+// SyncOAuth2UserGroups applies the administrator and restricted status and the team memberships
+// the groups claim of the OAuth2 login source grants to the user
+func SyncOAuth2UserGroups(source *LoginSource, user *User, gothUser goth.User) error {
+	cfg := source.OAuth2()
+	if cfg.GroupClaimName == "" {
+		return nil
+	}
+	groups := cfg.Groups(gothUser)
+	isMember := func(name string) bool {
+		for _, group := range groups {
+			if group == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	var cols []string
+	if cfg.AdminGroup != "" && user.IsAdmin != isMember(cfg.AdminGroup) {
+		user.IsAdmin = !user.IsAdmin
+		cols = append(cols, "is_admin")
+	}
+	if cfg.RestrictedGroup != "" && !user.IsAdmin && user.IsRestricted != isMember(cfg.RestrictedGroup) {
+		user.IsRestricted = !user.IsRestricted
+		cols = append(cols, "is_restricted")
+	}
+	if len(cols) > 0 {
+		if err := UpdateUserCols(user, cols...); err != nil {
+			return err
+		}
+	}
+
+	groupTeamMap, err := cfg.ParseGroupTeamMap()
+	if err != nil {
+		return err
+	}
+	return syncGroupTeams(source, user, groupTeamMap, groups, cfg.GroupTeamMapRemoval)
+}
@@ -12,6 +12,7 @@ import (
 	"strings"
 	"time"
 
+	"go.wandrs.dev/framework/modules/auth/oauth2"
 	"go.wandrs.dev/framework/modules/secret"
 	"go.wandrs.dev/framework/modules/setting"
 	"go.wandrs.dev/framework/modules/timeutil"
@@ -35,6 +36,10 @@ type OAuth2Application struct {
 	ClientSecret string
 
 	RedirectURIs []string `xorm:"redirect_uris JSON TEXT"`
+	// FrontchannelLogoutURI is loaded in the browser of a user who signs out (OpenID Connect Front-Channel Logout)
+	FrontchannelLogoutURI string `xorm:"TEXT"`
+	// Trusted applications are authorized by users without asking for their consent
+	Trusted bool `xorm:"NOT NULL DEFAULT false"`
 
 	CreatedUnix timeutil.TimeStamp `xorm:"INDEX created"`
 	UpdatedUnix timeutil.TimeStamp `xorm:"INDEX updated"`
@@ -95,7 +100,7 @@ func (app *OAuth2Application) GetGrantByUserID(userID int64) (*OAuth2Grant, erro
 
 func (app *OAuth2Application) getGrantByUserID(e Engine, userID int64) (grant *OAuth2Grant, err error) {
 	grant = new(OAuth2Grant)
-	if has, err := e.Where("user_id = ? AND application_id = ?", userID, app.ID).Get(grant); err != nil {
+	if has, err := e.Where("user_id = ? AND application_id = ? AND client_credentials = ?", userID, app.ID, false).Get(grant); err != nil {
 		return nil, err
 	} else if !has {
 		return nil, nil
@@ -121,6 +126,39 @@ func (app *OAuth2Application) createGrant(e Engine, userID int64, scope string)
 	return grant, nil
 }
 
+// GetClientCredentialsGrant returns the grant of the application acting as its owner,
+// creating it if needed, and updates its scope to the requested one
+func (app *OAuth2Application) GetClientCredentialsGrant(scope string) (*OAuth2Grant, error) {
+	sess := x.NewSession()
+	defer sess.Close()
+	if err := sess.Begin(); err != nil {
+		return nil, err
+	}
+
+	grant := new(OAuth2Grant)
+	has, err := sess.Where("user_id = ? AND application_id = ? AND client_credentials = ?", app.UID, app.ID, true).Get(grant)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		grant = &OAuth2Grant{
+			ApplicationID:     app.ID,
+			UserID:            app.UID,
+			ClientCredentials: true,
+			Scope:             scope,
+		}
+		if _, err := sess.Insert(grant); err != nil {
+			return nil, err
+		}
+	} else if grant.Scope != scope {
+		grant.Scope = scope
+		if _, err := sess.ID(grant.ID).Cols("scope").Update(grant); err != nil {
+			return nil, err
+		}
+	}
+	return grant, sess.Commit()
+}
+
 // GetOAuth2ApplicationByClientID returns the oauth2 application with the given client_id. Returns an error if not found.
 func GetOAuth2ApplicationByClientID(clientID string) (app *OAuth2Application, err error) {
 	return getOAuth2ApplicationByClientID(x, clientID)
@@ -163,11 +201,27 @@ func getOAuth2ApplicationsByUserID(e Engine, userID int64) (apps []*OAuth2Applic
 	return
 }
 
+// deleteOAuth2ApplicationsByUserID deletes the applications owned by the given user or organization
+func deleteOAuth2ApplicationsByUserID(e Engine, userID int64) error {
+	apps, err := getOAuth2ApplicationsByUserID(e, userID)
+	if err != nil {
+		return err
+	}
+	for _, app := range apps {
+		if err = deleteOAuth2Application(e, app.ID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // CreateOAuth2ApplicationOptions holds options to create an oauth2 application
 type CreateOAuth2ApplicationOptions struct {
-	Name         string
-	UserID       int64
-	RedirectURIs []string
+	Name                  string
+	UserID                int64
+	RedirectURIs          []string
+	FrontchannelLogoutURI string
+	Trusted               bool
 }
 
 // CreateOAuth2Application inserts a new oauth2 application
@@ -178,10 +232,12 @@ func CreateOAuth2Application(opts CreateOAuth2ApplicationOptions) (*OAuth2Applic
 func createOAuth2Application(e Engine, opts CreateOAuth2ApplicationOptions) (*OAuth2Application, error) {
 	clientID := uuid.New().String()
 	app := &OAuth2Application{
-		UID:          opts.UserID,
-		Name:         opts.Name,
-		ClientID:     clientID,
-		RedirectURIs: opts.RedirectURIs,
+		UID:                   opts.UserID,
+		Name:                  opts.Name,
+		ClientID:              clientID,
+		RedirectURIs:          opts.RedirectURIs,
+		FrontchannelLogoutURI: opts.FrontchannelLogoutURI,
+		Trusted:               opts.Trusted,
 	}
 	if _, err := e.Insert(app); err != nil {
 		return nil, err
@@ -191,10 +247,14 @@ func createOAuth2Application(e Engine, opts CreateOAuth2ApplicationOptions) (*OA
 
 // UpdateOAuth2ApplicationOptions holds options to update an oauth2 application
 type UpdateOAuth2ApplicationOptions struct {
-	ID           int64
-	Name         string
-	UserID       int64
-	RedirectURIs []string
+	ID                    int64
+	Name                  string
+	UserID                int64
+	RedirectURIs          []string
+	FrontchannelLogoutURI string
+	// Trusted is only changed if it is not OptionalBoolNone. If it is, adding
+	// a redirect URI clears it, since the application was trusted for its old ones.
+	Trusted util.OptionalBool
 }
 
 // UpdateOAuth2Application updates an oauth2 application
@@ -213,8 +273,19 @@ func UpdateOAuth2Application(opts UpdateOAuth2ApplicationOptions) (*OAuth2Applic
 		return nil, fmt.Errorf("UID missmatch")
 	}
 
+	if !opts.Trusted.IsNone() {
+		app.Trusted = opts.Trusted.IsTrue()
+	} else if app.Trusted {
+		for _, uri := range opts.RedirectURIs {
+			if !util.IsStringInSlice(uri, app.RedirectURIs) {
+				app.Trusted = false
+				break
+			}
+		}
+	}
 	app.Name = opts.Name
 	app.RedirectURIs = opts.RedirectURIs
+	app.FrontchannelLogoutURI = opts.FrontchannelLogoutURI
 
 	if err = updateOAuth2Application(sess, app); err != nil {
 		return nil, err
@@ -225,13 +296,13 @@ func UpdateOAuth2Application(opts UpdateOAuth2ApplicationOptions) (*OAuth2Applic
 }
 
 func updateOAuth2Application(e Engine, app *OAuth2Application) error {
-	if _, err := e.ID(app.ID).Update(app); err != nil {
+	if _, err := e.ID(app.ID).MustCols("frontchannel_logout_uri", "trusted").Update(app); err != nil {
 		return err
 	}
 	return nil
 }
 
-func deleteOAuth2Application(sess *xorm.Session, id, userid int64) error {
+func deleteOAuth2Application(sess Engine, id, userid int64) error {
 	if deleted, err := sess.Delete(&OAuth2Application{ID: id, UID: userid}); err != nil {
 		return err
 	} else if deleted == 0 {
@@ -255,6 +326,10 @@ func deleteOAuth2Application(sess *xorm.Session, id, userid int64) error {
 	if _, err := sess.Where("application_id = ?", id).Delete(new(OAuth2Grant)); err != nil {
 		return err
 	}
+
+	if _, err := sess.Where("application_id = ?", id).Delete(new(OAuth2DeviceAuthorization)); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -288,6 +363,37 @@ func ListOAuth2Applications(uid int64, listOptions ListOptions) ([]*OAuth2Applic
 	return apps, sess.Find(&apps)
 }
 
+// ListAllOAuth2Applications returns a page of the oauth2 applications of all users with their owners
+// loaded, and the total number of applications.
+func ListAllOAuth2Applications(listOptions ListOptions) ([]*OAuth2Application, int64, error) {
+	count, err := x.Count(new(OAuth2Application))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sess := x.Desc("id")
+	if listOptions.Page != 0 {
+		sess = listOptions.setSessionPagination(sess)
+	}
+	apps := make([]*OAuth2Application, 0, listOptions.PageSize)
+	if err := sess.Find(&apps); err != nil {
+		return nil, 0, err
+	}
+
+	uids := make([]int64, 0, len(apps))
+	for _, app := range apps {
+		uids = append(uids, app.UID)
+	}
+	users := make(map[int64]*User, len(uids))
+	if err := x.In("id", uids).Find(&users); err != nil {
+		return nil, 0, err
+	}
+	for _, app := range apps {
+		app.User = users[app.UID]
+	}
+	return apps, count, nil
+}
+
 //////////////////////////////////////////////////////
 
 // OAuth2AuthorizationCode is a code to obtain an access token in combination with the client secret once. It has a limited lifetime.
@@ -382,11 +488,13 @@ type OAuth2Grant struct {
 	UserID        int64              `xorm:"INDEX unique(user_application)"`
 	Application   *OAuth2Application `xorm:"-"`
 	ApplicationID int64              `xorm:"INDEX unique(user_application)"`
-	Counter       int64              `xorm:"NOT NULL DEFAULT 1"`
-	Scope         string             `xorm:"TEXT"`
-	Nonce         string             `xorm:"TEXT"`
-	CreatedUnix   timeutil.TimeStamp `xorm:"created"`
-	UpdatedUnix   timeutil.TimeStamp `xorm:"updated"`
+	// ClientCredentials grants are issued to the application itself by the client credentials grant
+	ClientCredentials bool               `xorm:"NOT NULL DEFAULT false unique(user_application)"`
+	Counter           int64              `xorm:"NOT NULL DEFAULT 1"`
+	Scope             string             `xorm:"TEXT"`
+	Nonce             string             `xorm:"TEXT"`
+	CreatedUnix       timeutil.TimeStamp `xorm:"created"`
+	UpdatedUnix       timeutil.TimeStamp `xorm:"updated"`
 }
 
 // TableName sets the table name to `oauth2_grant`
@@ -446,6 +554,36 @@ func (grant *OAuth2Grant) ScopeContains(scope string) bool {
 	return false
 }
 
+// ScopeContainsAll returns true if the grant scope contains all of the space separated scopes
+func (grant *OAuth2Grant) ScopeContainsAll(scope string) bool {
+	for _, s := range strings.Fields(scope) {
+		if !grant.ScopeContains(s) {
+			return false
+		}
+	}
+	return true
+}
+
+// AddScope adds the space separated scopes the grant does not contain yet to its scope
+func (grant *OAuth2Grant) AddScope(scope string) error {
+	return grant.addScope(x, scope)
+}
+
+func (grant *OAuth2Grant) addScope(e Engine, scope string) error {
+	if grant.ScopeContainsAll(scope) {
+		return nil
+	}
+	scopes := strings.Fields(grant.Scope)
+	for _, s := range strings.Fields(scope) {
+		if !util.IsStringInSlice(s, scopes) {
+			scopes = append(scopes, s)
+		}
+	}
+	grant.Scope = strings.Join(scopes, " ")
+	_, err := e.ID(grant.ID).Cols("scope").Update(grant)
+	return err
+}
+
 // SetNonce updates the current nonce value of a grant
 func (grant *OAuth2Grant) SetNonce(nonce string) error {
 	return grant.setNonce(x, nonce)
@@ -489,7 +627,7 @@ func getOAuth2GrantsByUserID(e Engine, uid int64) ([]*OAuth2Grant, error) {
 	var err error
 	if results, err = e.
 		Table("oauth2_grant").
-		Where("user_id = ?", uid).
+		Where("user_id = ? AND client_credentials = ?", uid, false).
 		Join("INNER", "oauth2_application", "application_id = oauth2_application.id").
 		Rows(new(joinedOAuth2Grant)); err != nil {
 		return nil, err
@@ -507,6 +645,16 @@ func getOAuth2GrantsByUserID(e Engine, uid int64) ([]*OAuth2Grant, error) {
 	return grants, nil
 }
 
+// GetFrontchannelLogoutURIs returns the front-channel logout URIs of all applications the user has granted access to
+func GetFrontchannelLogoutURIs(uid int64) ([]string, error) {
+	uris := make([]string, 0, 2)
+	return uris, x.Table("oauth2_application").
+		Join("INNER", "oauth2_grant", "oauth2_grant.application_id = oauth2_application.id").
+		Where("oauth2_grant.user_id = ? AND oauth2_grant.client_credentials = ? AND oauth2_application.frontchannel_logout_uri <> ''", uid, false).
+		Cols("oauth2_application.frontchannel_logout_uri").
+		Find(&uris)
+}
+
 // RevokeOAuth2Grant deletes the grant with grantID and userID
 func RevokeOAuth2Grant(grantID, userID int64) error {
 	return revokeOAuth2Grant(x, grantID, userID)
@@ -540,10 +688,23 @@ type OAuth2Token struct {
 // ParseOAuth2Token parses a singed jwt string
 func ParseOAuth2Token(jwtToken string) (*OAuth2Token, error) {
 	parsedToken, err := jwt.ParseWithClaims(jwtToken, &OAuth2Token{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			// tokens without key id are signed with the symmetric JWT secret
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing algo: %v", token.Header["alg"])
+			}
+			return setting.OAuth2.JWTSecretBytes, nil
+		}
+
+		signingKey := oauth2.GetSigningKeyByID(kid)
+		if signingKey == nil {
+			return nil, fmt.Errorf("unknown signing key: %s", kid)
+		}
+		if token.Method == nil || token.Method.Alg() != signingKey.SigningMethod().Alg() {
 			return nil, fmt.Errorf("unexpected signing algo: %v", token.Header["alg"])
 		}
-		return setting.OAuth2.JWTSecretBytes, nil
+		return signingKey.VerifyKey(), nil
 	})
 	if err != nil {
 		return nil, err
@@ -556,22 +717,75 @@ func ParseOAuth2Token(jwtToken string) (*OAuth2Token, error) {
 	return token, nil
 }
 
-// SignToken signs the token with the JWT secret
+// SignToken signs the token with the default JWT signing key
 func (token *OAuth2Token) SignToken() (string, error) {
+	signingKey := oauth2.DefaultSigningKey()
+	if signingKey == nil {
+		return "", fmt.Errorf("JWT signing key is not initialized")
+	}
 	token.IssuedAt = time.Now().Unix()
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS512, token)
-	return jwtToken.SignedString(setting.OAuth2.JWTSecretBytes)
+	jwtToken := jwt.NewWithClaims(signingKey.SigningMethod(), token)
+	signingKey.PreProcessToken(jwtToken)
+	return jwtToken.SignedString(signingKey.SignKey())
 }
 
 // OIDCToken represents an OpenID Connect id_token
 type OIDCToken struct {
 	jwt.StandardClaims
 	Nonce string `json:"nonce,omitempty"`
+
+	// Scope profile
+	Name              string             `json:"name,omitempty"`
+	PreferredUsername string             `json:"preferred_username,omitempty"`
+	Profile           string             `json:"profile,omitempty"`
+	Picture           string             `json:"picture,omitempty"`
+	Website           string             `json:"website,omitempty"`
+	Locale            string             `json:"locale,omitempty"`
+	UpdatedAt         timeutil.TimeStamp `json:"updated_at,omitempty"`
+
+	// Scope email
+	Email         string `json:"email,omitempty"`
+	EmailVerified bool   `json:"email_verified,omitempty"`
+
+	// Scope groups
+	Groups []string `json:"groups,omitempty"`
 }
 
-// SignToken signs an id_token with the (symmetric) client secret key
-func (token *OIDCToken) SignToken(clientSecret string) (string, error) {
+// SignToken signs an id_token with the given signing key
+func (token *OIDCToken) SignToken(signingKey oauth2.JWTSigningKey) (string, error) {
 	token.IssuedAt = time.Now().Unix()
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, token)
-	return jwtToken.SignedString([]byte(clientSecret))
+	jwtToken := jwt.NewWithClaims(signingKey.SigningMethod(), token)
+	signingKey.PreProcessToken(jwtToken)
+	return jwtToken.SignedString(signingKey.SignKey())
+}
+
+// ParseOIDCTokenHint parses an id_token previously issued by us, e.g. an id_token_hint of a logout request.
+// Expired tokens are accepted. Tokens signed with a key derived from a client secret cannot be verified,
+// as only the hash of the secret is stored; verified is false for those.
+func ParseOIDCTokenHint(hint string) (token *OIDCToken, verified bool, err error) {
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	token = new(OIDCToken)
+	unverified, _, err := parser.ParseUnverified(hint, token)
+	if err != nil {
+		return nil, false, err
+	}
+	kid, ok := unverified.Header["kid"].(string)
+	if !ok {
+		return token, false, nil
+	}
+
+	token = new(OIDCToken)
+	if _, err = parser.ParseWithClaims(hint, token, func(t *jwt.Token) (interface{}, error) {
+		signingKey := oauth2.GetSigningKeyByID(kid)
+		if signingKey == nil {
+			return nil, fmt.Errorf("unknown signing key: %s", kid)
+		}
+		if t.Method == nil || t.Method.Alg() != signingKey.SigningMethod().Alg() {
+			return nil, fmt.Errorf("unexpected signing algo: %v", t.Header["alg"])
+		}
+		return signingKey.VerifyKey(), nil
+	}); err != nil {
+		return nil, false, err
+	}
+	return token, true, nil
 }
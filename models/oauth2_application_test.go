@@ -5,8 +5,17 @@
 package models
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"go.wandrs.dev/framework/modules/auth/oauth2"
+	"go.wandrs.dev/framework/modules/setting"
+	"go.wandrs.dev/framework/modules/util"
+
+	"github.com/dgrijalva/jwt-go"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -68,6 +77,67 @@ func TestCreateOAuth2Application(t *testing.T) {
 	AssertExistsAndLoadBean(t, &OAuth2Application{Name: "newapp"})
 }
 
+func TestUpdateOAuth2Application(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	opts := UpdateOAuth2ApplicationOptions{
+		ID:           1,
+		Name:         "Trusted",
+		UserID:       1,
+		RedirectURIs: []string{"a"},
+		Trusted:      util.OptionalBoolTrue,
+	}
+	app, err := UpdateOAuth2Application(opts)
+	assert.NoError(t, err)
+	assert.True(t, app.Trusted)
+	AssertExistsAndLoadBean(t, &OAuth2Application{ID: 1, Name: "Trusted", Trusted: true})
+
+	// the trusted flag is kept if not specified
+	opts.Trusted = util.OptionalBoolNone
+	_, err = UpdateOAuth2Application(opts)
+	assert.NoError(t, err)
+	assert.True(t, AssertExistsAndLoadBean(t, &OAuth2Application{ID: 1}).(*OAuth2Application).Trusted)
+
+	// but it is cleared if the owner adds a redirect URI
+	opts.RedirectURIs = []string{"a", "b"}
+	app, err = UpdateOAuth2Application(opts)
+	assert.NoError(t, err)
+	assert.False(t, app.Trusted)
+	assert.False(t, AssertExistsAndLoadBean(t, &OAuth2Application{ID: 1}).(*OAuth2Application).Trusted)
+
+	opts.Trusted = util.OptionalBoolTrue
+	_, err = UpdateOAuth2Application(opts)
+	assert.NoError(t, err)
+	opts.RedirectURIs = []string{"b"}
+	opts.Trusted = util.OptionalBoolNone
+	_, err = UpdateOAuth2Application(opts)
+	assert.NoError(t, err)
+	assert.True(t, AssertExistsAndLoadBean(t, &OAuth2Application{ID: 1}).(*OAuth2Application).Trusted)
+
+	opts.Trusted = util.OptionalBoolFalse
+	_, err = UpdateOAuth2Application(opts)
+	assert.NoError(t, err)
+	assert.False(t, AssertExistsAndLoadBean(t, &OAuth2Application{ID: 1}).(*OAuth2Application).Trusted)
+
+	opts.UserID = 2
+	_, err = UpdateOAuth2Application(opts)
+	assert.Error(t, err)
+}
+
+func TestListAllOAuth2Applications(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	_, err := CreateOAuth2Application(CreateOAuth2ApplicationOptions{Name: "newapp", UserID: 2, Trusted: true})
+	assert.NoError(t, err)
+
+	apps, count, err := ListAllOAuth2Applications(ListOptions{Page: 1, PageSize: 1})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+	if assert.Len(t, apps, 1) {
+		assert.Equal(t, "newapp", apps[0].Name)
+		assert.True(t, apps[0].Trusted)
+		assert.Equal(t, int64(2), apps[0].User.ID)
+	}
+}
+
 func TestOAuth2Application_LoadUser(t *testing.T) {
 	assert.NoError(t, PrepareTestDatabase())
 	app := AssertExistsAndLoadBean(t, &OAuth2Application{ID: 1}).(*OAuth2Application)
@@ -102,6 +172,31 @@ func TestOAuth2Application_CreateGrant(t *testing.T) {
 	assert.Equal(t, "", grant.Scope)
 }
 
+func TestOAuth2Application_GetClientCredentialsGrant(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	app := AssertExistsAndLoadBean(t, &OAuth2Application{ID: 1}).(*OAuth2Application)
+	grant, err := app.GetClientCredentialsGrant("profile")
+	assert.NoError(t, err)
+	assert.NotEqual(t, int64(1), grant.ID)
+	assert.Equal(t, app.UID, grant.UserID)
+	assert.True(t, grant.ClientCredentials)
+	assert.Equal(t, "profile", grant.Scope)
+
+	again, err := app.GetClientCredentialsGrant("")
+	assert.NoError(t, err)
+	assert.Equal(t, grant.ID, again.ID)
+	assert.Empty(t, AssertExistsAndLoadBean(t, &OAuth2Grant{ID: grant.ID}).(*OAuth2Grant).Scope)
+
+	// the consent of the owner is unchanged
+	AssertExistsAndLoadBean(t, &OAuth2Grant{ID: 1, Scope: "openid profile"})
+	consent, err := app.GetGrantByUserID(app.UID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), consent.ID)
+	grants, err := GetOAuth2GrantsByUserID(app.UID)
+	assert.NoError(t, err)
+	assert.Len(t, grants, 1)
+}
+
 //////////////////// Grant
 
 func TestGetOAuth2GrantByID(t *testing.T) {
@@ -132,6 +227,18 @@ func TestOAuth2Grant_ScopeContains(t *testing.T) {
 	assert.False(t, grant.ScopeContains("profile2"))
 }
 
+func TestOAuth2Grant_AddScope(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	grant := AssertExistsAndLoadBean(t, &OAuth2Grant{ID: 1, Scope: "openid profile"}).(*OAuth2Grant)
+	assert.True(t, grant.ScopeContainsAll("profile openid"))
+	assert.True(t, grant.ScopeContainsAll(""))
+	assert.False(t, grant.ScopeContainsAll("openid email"))
+
+	assert.NoError(t, grant.AddScope("email openid groups"))
+	assert.Equal(t, "openid profile email groups", grant.Scope)
+	AssertExistsAndLoadBean(t, &OAuth2Grant{ID: 1, Scope: "openid profile email groups"})
+}
+
 func TestOAuth2Grant_GenerateNewAuthorizationCode(t *testing.T) {
 	assert.NoError(t, PrepareTestDatabase())
 	grant := AssertExistsAndLoadBean(t, &OAuth2Grant{ID: 1}).(*OAuth2Grant)
@@ -158,6 +265,31 @@ func TestGetOAuth2GrantsByUserID(t *testing.T) {
 	assert.Empty(t, result)
 }
 
+func TestGetFrontchannelLogoutURIs(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	uris, err := GetFrontchannelLogoutURIs(1)
+	assert.NoError(t, err)
+	assert.Empty(t, uris)
+
+	app, err := UpdateOAuth2Application(UpdateOAuth2ApplicationOptions{
+		ID:                    1,
+		Name:                  "Test",
+		UserID:                1,
+		RedirectURIs:          []string{"a"},
+		FrontchannelLogoutURI: "https://example.com/logout",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/logout", app.FrontchannelLogoutURI)
+
+	uris, err = GetFrontchannelLogoutURIs(1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/logout"}, uris)
+
+	uris, err = GetFrontchannelLogoutURIs(34134)
+	assert.NoError(t, err)
+	assert.Empty(t, uris)
+}
+
 func TestRevokeOAuth2Grant(t *testing.T) {
 	assert.NoError(t, PrepareTestDatabase())
 	assert.NoError(t, RevokeOAuth2Grant(1, 1))
@@ -236,3 +368,49 @@ func TestOAuth2AuthorizationCode_Invalidate(t *testing.T) {
 func TestOAuth2AuthorizationCode_TableName(t *testing.T) {
 	assert.Equal(t, "oauth2_authorization_code", new(OAuth2AuthorizationCode).TableName())
 }
+
+//////////////////// Tokens
+
+func TestParseOIDCTokenHint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwt")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	oldSetting := setting.OAuth2
+	defer func() {
+		setting.OAuth2 = oldSetting
+	}()
+	setting.OAuth2.JWTSigningAlgorithm = "ES256"
+	setting.OAuth2.JWTSigningPrivateKeyFile = filepath.Join(dir, "private.pem")
+	assert.NoError(t, oauth2.InitSigningKey())
+
+	// expired tokens are accepted as hint
+	idToken := &OIDCToken{StandardClaims: jwt.StandardClaims{
+		Audience:  "da7da3ba-9a13-4167-856f-3899de0b0138",
+		Subject:   "1",
+		ExpiresAt: time.Now().Add(-time.Hour).Unix(),
+	}}
+	signed, err := idToken.SignToken(oauth2.DefaultSigningKey())
+	assert.NoError(t, err)
+	token, verified, err := ParseOIDCTokenHint(signed)
+	assert.NoError(t, err)
+	assert.True(t, verified)
+	assert.Equal(t, "1", token.Subject)
+	assert.Equal(t, "da7da3ba-9a13-4167-856f-3899de0b0138", token.Audience)
+
+	_, _, err = ParseOIDCTokenHint(signed[:len(signed)-4] + "AAAA")
+	assert.Error(t, err)
+
+	// tokens signed with a key derived from the client secret cannot be verified
+	clientKey, err := oauth2.CreateJWTSigningKey("HS256", []byte("secret"))
+	assert.NoError(t, err)
+	signed, err = idToken.SignToken(clientKey)
+	assert.NoError(t, err)
+	token, verified, err = ParseOIDCTokenHint(signed)
+	assert.NoError(t, err)
+	assert.False(t, verified)
+	assert.Equal(t, "1", token.Subject)
+
+	_, _, err = ParseOIDCTokenHint("invalid")
+	assert.Error(t, err)
+}
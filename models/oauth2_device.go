@@ -0,0 +1,212 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"go.wandrs.dev/framework/modules/secret"
+	"go.wandrs.dev/framework/modules/setting"
+	"go.wandrs.dev/framework/modules/timeutil"
+	"go.wandrs.dev/framework/modules/util"
+)
+
+// OAuth2DeviceAuthorizationStatus represents the state of a device authorization request
+type OAuth2DeviceAuthorizationStatus int
+
+// Device authorization states
+const (
+	OAuth2DeviceAuthorizationPending OAuth2DeviceAuthorizationStatus = iota
+	OAuth2DeviceAuthorizationApproved
+	OAuth2DeviceAuthorizationDenied
+)
+
+// userCodeCharacters omits vowels and ambiguous characters as recommended by RFC 8628 section 6.1
+const userCodeCharacters = "BCDFGHJKLMNPQRSTVWXZ"
+
+// userCodeLength is the length of a user code without separator
+const userCodeLength = 8
+
+// OAuth2DeviceAuthorization is a device authorization request (RFC 8628) which a user
+// approves or denies by entering its user code. The device polls the token endpoint
+// with the device code until then.
+type OAuth2DeviceAuthorization struct {
+	ID             int64              `xorm:"pk autoincr"`
+	Application    *OAuth2Application `xorm:"-"`
+	ApplicationID  int64              `xorm:"INDEX"`
+	DeviceCode     string             `xorm:"INDEX unique"`
+	UserCode       string             `xorm:"INDEX unique"`
+	Scope          string             `xorm:"TEXT"`
+	UserID         int64
+	Status         OAuth2DeviceAuthorizationStatus `xorm:"NOT NULL DEFAULT 0"`
+	PollInterval   int64                           `xorm:"NOT NULL DEFAULT 5"`
+	LastPolledUnix timeutil.TimeStamp
+	ValidUntil     timeutil.TimeStamp `xorm:"INDEX"`
+	CreatedUnix    timeutil.TimeStamp `xorm:"created"`
+}
+
+// TableName sets the table name to `oauth2_device_authorization`
+func (auth *OAuth2DeviceAuthorization) TableName() string {
+	return "oauth2_device_authorization"
+}
+
+// IsExpired returns true if the device authorization can no longer be used
+func (auth *OAuth2DeviceAuthorization) IsExpired() bool {
+	return auth.ValidUntil <= timeutil.TimeStampNow()
+}
+
+// FormattedUserCode returns the user code in the XXXX-XXXX form shown to users
+func (auth *OAuth2DeviceAuthorization) FormattedUserCode() string {
+	return auth.UserCode[:userCodeLength/2] + "-" + auth.UserCode[userCodeLength/2:]
+}
+
+// LoadApplication loads the application of the device authorization
+func (auth *OAuth2DeviceAuthorization) LoadApplication() (err error) {
+	if auth.Application == nil {
+		auth.Application, err = GetOAuth2ApplicationByID(auth.ApplicationID)
+	}
+	return err
+}
+
+// NormalizeOAuth2UserCode converts user input into the stored form of a user code
+// by upper casing it and removing separators and whitespace.
+func NormalizeOAuth2UserCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		if r >= 'A' && r <= 'Z' {
+			return r
+		}
+		return -1
+	}, code)
+}
+
+func generateOAuth2UserCode() (string, error) {
+	code := make([]byte, userCodeLength)
+	for i := range code {
+		num, err := util.RandomInt(int64(len(userCodeCharacters)))
+		if err != nil {
+			return "", err
+		}
+		code[i] = userCodeCharacters[num]
+	}
+	return string(code), nil
+}
+
+// CreateDeviceAuthorization creates a new pending device authorization for the application
+func (app *OAuth2Application) CreateDeviceAuthorization(scope string) (*OAuth2DeviceAuthorization, error) {
+	return app.createDeviceAuthorization(x, scope)
+}
+
+func (app *OAuth2Application) createDeviceAuthorization(e Engine, scope string) (*OAuth2DeviceAuthorization, error) {
+	if err := deleteExpiredOAuth2DeviceAuthorizations(e); err != nil {
+		return nil, err
+	}
+
+	deviceCode, err := secret.New()
+	if err != nil {
+		return nil, err
+	}
+	auth := &OAuth2DeviceAuthorization{
+		Application:   app,
+		ApplicationID: app.ID,
+		DeviceCode:    deviceCode,
+		Scope:         scope,
+		PollInterval:  setting.OAuth2.DeviceCodePollInterval,
+		ValidUntil:    timeutil.TimeStampNow().Add(setting.OAuth2.DeviceCodeExpirationTime),
+	}
+	// user codes are short, retry on the unlikely collision with a pending code
+	for i := 0; i < 5; i++ {
+		if auth.UserCode, err = generateOAuth2UserCode(); err != nil {
+			return nil, err
+		}
+		has, err := e.Where("user_code = ?", auth.UserCode).Exist(new(OAuth2DeviceAuthorization))
+		if err != nil {
+			return nil, err
+		} else if !has {
+			if _, err := e.Insert(auth); err != nil {
+				return nil, err
+			}
+			return auth, nil
+		}
+	}
+	return nil, fmt.Errorf("unable to generate a unique user code")
+}
+
+// GetOAuth2DeviceAuthorizationByDeviceCode returns the device authorization with the given device code or nil if it does not exist
+func GetOAuth2DeviceAuthorizationByDeviceCode(deviceCode string) (*OAuth2DeviceAuthorization, error) {
+	if deviceCode == "" {
+		return nil, nil
+	}
+	return getOAuth2DeviceAuthorization(x, &OAuth2DeviceAuthorization{DeviceCode: deviceCode})
+}
+
+// GetOAuth2DeviceAuthorizationByUserCode returns the device authorization with the given user code or nil if it does not exist
+func GetOAuth2DeviceAuthorizationByUserCode(userCode string) (*OAuth2DeviceAuthorization, error) {
+	userCode = NormalizeOAuth2UserCode(userCode)
+	if len(userCode) != userCodeLength {
+		return nil, nil
+	}
+	return getOAuth2DeviceAuthorization(x, &OAuth2DeviceAuthorization{UserCode: userCode})
+}
+
+func getOAuth2DeviceAuthorization(e Engine, cond *OAuth2DeviceAuthorization) (*OAuth2DeviceAuthorization, error) {
+	has, err := e.Get(cond)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, nil
+	}
+	return cond, nil
+}
+
+// Approve marks the device authorization as approved by the given user
+func (auth *OAuth2DeviceAuthorization) Approve(userID int64) error {
+	return auth.setStatus(x, userID, OAuth2DeviceAuthorizationApproved)
+}
+
+// Deny marks the device authorization as denied by the given user
+func (auth *OAuth2DeviceAuthorization) Deny(userID int64) error {
+	return auth.setStatus(x, userID, OAuth2DeviceAuthorizationDenied)
+}
+
+func (auth *OAuth2DeviceAuthorization) setStatus(e Engine, userID int64, status OAuth2DeviceAuthorizationStatus) error {
+	auth.UserID = userID
+	auth.Status = status
+	_, err := e.ID(auth.ID).Cols("user_id", "status").Update(auth)
+	return err
+}
+
+// Poll records a poll of the device and returns true if the device polls faster than
+// its interval, in which case the interval is increased by 5 seconds (RFC 8628 section 3.5).
+func (auth *OAuth2DeviceAuthorization) Poll() (slowDown bool, err error) {
+	return auth.poll(x)
+}
+
+func (auth *OAuth2DeviceAuthorization) poll(e Engine) (bool, error) {
+	now := timeutil.TimeStampNow()
+	slowDown := auth.LastPolledUnix.Add(auth.PollInterval) > now
+	if slowDown {
+		auth.PollInterval += 5
+	}
+	auth.LastPolledUnix = now
+	_, err := e.ID(auth.ID).Cols("poll_interval", "last_polled_unix").Update(auth)
+	return slowDown, err
+}
+
+// Invalidate deletes the device authorization so its device code cannot be used again.
+// It returns false if the device authorization has already been invalidated or its
+// status changed since it was loaded, e.g. by a concurrent request of the device.
+func (auth *OAuth2DeviceAuthorization) Invalidate() (bool, error) {
+	affected, err := x.Where("id = ? AND status = ?", auth.ID, auth.Status).Delete(new(OAuth2DeviceAuthorization))
+	return affected == 1, err
+}
+
+func deleteExpiredOAuth2DeviceAuthorizations(e Engine) error {
+	_, err := e.Where("valid_until <= ?", timeutil.TimeStampNow()).Delete(new(OAuth2DeviceAuthorization))
+	return err
+}
@@ -0,0 +1,92 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+
+	"go.wandrs.dev/framework/modules/timeutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeOAuth2UserCode(t *testing.T) {
+	assert.Equal(t, "BCDFGHJK", NormalizeOAuth2UserCode("bcdf-ghjk"))
+	assert.Equal(t, "BCDFGHJK", NormalizeOAuth2UserCode(" BCDF GHJK "))
+}
+
+func TestOAuth2Application_CreateDeviceAuthorization(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	app := AssertExistsAndLoadBean(t, &OAuth2Application{ID: 1}).(*OAuth2Application)
+
+	auth, err := app.CreateDeviceAuthorization("openid")
+	assert.NoError(t, err)
+	assert.Len(t, auth.UserCode, 8)
+	assert.Regexp(t, "^[A-Z]{4}-[A-Z]{4}$", auth.FormattedUserCode())
+	assert.False(t, auth.IsExpired())
+
+	loaded, err := GetOAuth2DeviceAuthorizationByUserCode(auth.FormattedUserCode())
+	assert.NoError(t, err)
+	if assert.NotNil(t, loaded) {
+		assert.Equal(t, auth.ID, loaded.ID)
+	}
+
+	loaded, err = GetOAuth2DeviceAuthorizationByDeviceCode(auth.DeviceCode)
+	assert.NoError(t, err)
+	if assert.NotNil(t, loaded) {
+		assert.Equal(t, OAuth2DeviceAuthorizationPending, loaded.Status)
+	}
+
+	loaded, err = GetOAuth2DeviceAuthorizationByDeviceCode("invalid")
+	assert.NoError(t, err)
+	assert.Nil(t, loaded)
+
+	// a copy loaded before the approval is stale
+	stale, err := GetOAuth2DeviceAuthorizationByDeviceCode(auth.DeviceCode)
+	assert.NoError(t, err)
+	assert.NoError(t, auth.Approve(2))
+	AssertExistsAndLoadBean(t, &OAuth2DeviceAuthorization{ID: auth.ID, UserID: 2, Status: OAuth2DeviceAuthorizationApproved})
+	invalidated, err := stale.Invalidate()
+	assert.NoError(t, err)
+	assert.False(t, invalidated)
+
+	// only one of concurrent polls can use the device code
+	concurrent, err := GetOAuth2DeviceAuthorizationByDeviceCode(auth.DeviceCode)
+	assert.NoError(t, err)
+	invalidated, err = auth.Invalidate()
+	assert.NoError(t, err)
+	assert.True(t, invalidated)
+	AssertNotExistsBean(t, &OAuth2DeviceAuthorization{ID: auth.ID})
+	invalidated, err = concurrent.Invalidate()
+	assert.NoError(t, err)
+	assert.False(t, invalidated)
+}
+
+func TestOAuth2DeviceAuthorization_Poll(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	app := AssertExistsAndLoadBean(t, &OAuth2Application{ID: 1}).(*OAuth2Application)
+
+	auth, err := app.CreateDeviceAuthorization("")
+	assert.NoError(t, err)
+	interval := auth.PollInterval
+
+	slowDown, err := auth.Poll()
+	assert.NoError(t, err)
+	assert.False(t, slowDown)
+
+	slowDown, err = auth.Poll()
+	assert.NoError(t, err)
+	assert.True(t, slowDown)
+	assert.Equal(t, interval+5, auth.PollInterval)
+
+	// expired authorizations are removed when new ones are created
+	auth.ValidUntil = timeutil.TimeStampNow().Add(-1)
+	_, err = x.ID(auth.ID).Cols("valid_until").Update(auth)
+	assert.NoError(t, err)
+	assert.True(t, auth.IsExpired())
+	_, err = app.CreateDeviceAuthorization("")
+	assert.NoError(t, err)
+	AssertNotExistsBean(t, &OAuth2DeviceAuthorization{ID: auth.ID})
+}
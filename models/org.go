@@ -28,6 +28,25 @@ func (org *User) IsOrgMember(uid int64) (bool, error) {
 	return IsOrganizationMember(org.ID, uid)
 }
 
+// CanManageOAuth2ApplicationsBy returns true if given user may manage the oauth2 applications
+// of the organization, that is an owner or a member of a team allowed to manage them or of
+// one of its sub-teams.
+func (org *User) CanManageOAuth2ApplicationsBy(uid int64) (bool, error) {
+	if isOwner, err := org.IsOwnedBy(uid); err != nil || isOwner {
+		return isOwner, err
+	}
+	teams, err := getUserOrgEffectiveTeams(x, org.ID, uid)
+	if err != nil {
+		return false, err
+	}
+	for _, t := range teams {
+		if t.CanManageOAuth2Apps {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func (org *User) getTeam(e Engine, name string) (*Team, error) {
 	return getTeam(e, org.ID, name)
 }
@@ -46,6 +65,18 @@ func (org *User) GetOwnerTeam() (*Team, error) {
 	return org.getOwnerTeam(x)
 }
 
+// GetOwners returns the members of the owner team of organization.
+func (org *User) GetOwners() ([]*User, error) {
+	t, err := org.getOwnerTeam(x)
+	if err != nil {
+		return nil, err
+	}
+	if err = t.getMembers(x); err != nil {
+		return nil, err
+	}
+	return t.Members, nil
+}
+
 func (org *User) getTeams(e Engine) error {
 	if org.Teams != nil {
 		return nil
@@ -244,14 +275,29 @@ func DeleteOrganization(org *User) (err error) {
 }
 
 func deleteOrg(e *xorm.Session, u *User) error {
+	if _, err := e.
+		Where("resource_type = ?", SCIMResourceGroup).
+		And("resource_id IN (SELECT id FROM team WHERE org_id = ?)", u.ID).
+		Delete(new(SCIMResource)); err != nil {
+		return fmt.Errorf("delete scim resources: %v", err)
+	}
+
 	if err := deleteBeans(e,
 		&Team{OrgID: u.ID},
 		&OrgUser{OrgID: u.ID},
 		&TeamUser{OrgID: u.ID},
+		&TeamUnit{OrgID: u.ID},
+		&OrgRole{OrgID: u.ID},
+		&OrgInvite{OrgID: u.ID},
+		&OrgJoinRequest{OrgID: u.ID},
 	); err != nil {
 		return fmt.Errorf("deleteBeans: %v", err)
 	}
 
+	if err := deleteOAuth2ApplicationsByUserID(e, u.ID); err != nil {
+		return fmt.Errorf("deleteOAuth2ApplicationsByUserID: %v", err)
+	}
+
 	if _, err := e.ID(u.ID).Delete(new(User)); err != nil {
 		return fmt.Errorf("Delete: %v", err)
 	}
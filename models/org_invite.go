@@ -0,0 +1,236 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.wandrs.dev/framework/modules/base"
+	"go.wandrs.dev/framework/modules/setting"
+	"go.wandrs.dev/framework/modules/timeutil"
+)
+
+// OrgInvite is an invitation sent by email to join a team of an organization
+type OrgInvite struct {
+	ID          int64              `xorm:"pk autoincr"`
+	OrgID       int64              `xorm:"INDEX"`
+	TeamID      int64              `xorm:"INDEX"`
+	InviterID   int64              `xorm:"INDEX"`
+	Email       string             `xorm:"VARCHAR(255) INDEX NOT NULL"`
+	CreatedUnix timeutil.TimeStamp `xorm:"created"`
+	ExpiresUnix timeutil.TimeStamp `xorm:"INDEX"`
+
+	Org     *User `xorm:"-"`
+	Team    *Team `xorm:"-"`
+	Inviter *User `xorm:"-"`
+}
+
+func (inv *OrgInvite) codeData() string {
+	return fmt.Sprintf("%d%d%d%s%d", inv.ID, inv.OrgID, inv.TeamID, inv.Email, inv.CreatedUnix)
+}
+
+// GenerateCode returns the signed code sent to the invited email address, it expires
+// with the invitation
+func (inv *OrgInvite) GenerateCode() string {
+	minutes := int(inv.ExpiresUnix-inv.CreatedUnix) / 60
+	code := base.CreateTimeLimitCode(inv.codeData(), minutes, inv.CreatedUnix.AsTimeInLocation(time.Local).Format("200601021504"))
+
+	// Add tail id
+	return code + strconv.FormatInt(inv.ID, 16)
+}
+
+// IsExpired returns true if the invitation can no longer be accepted
+func (inv *OrgInvite) IsExpired() bool {
+	return inv.ExpiresUnix <= timeutil.TimeStampNow()
+}
+
+func (inv *OrgInvite) loadAttributes(e Engine) (err error) {
+	if inv.Org == nil {
+		if inv.Org, err = getUserByID(e, inv.OrgID); err != nil {
+			return err
+		}
+	}
+	if inv.Team == nil {
+		if inv.Team, err = getTeamByID(e, inv.TeamID); err != nil {
+			return err
+		}
+	}
+	if inv.Inviter == nil {
+		inv.Inviter, err = getUserByID(e, inv.InviterID)
+		if IsErrUserNotExist(err) {
+			inv.Inviter = NewGhostUser()
+			err = nil
+		}
+	}
+	return err
+}
+
+// LoadAttributes loads the organization, the team and the inviter of the invitation
+func (inv *OrgInvite) LoadAttributes() error {
+	return inv.loadAttributes(x)
+}
+
+// CreateOrgInvite creates an invitation to join the team, which expires after
+// ORG_INVITE_CODE_LIVE_MINUTES. The team is expected to be loaded.
+func CreateOrgInvite(team *Team, inviter *User, email string) (*OrgInvite, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if len(email) == 0 {
+		return nil, ErrEmailInvalid{email}
+	} else if err := ValidateEmail(email); err != nil {
+		return nil, err
+	}
+
+	has, err := x.Where("team_id = ?", team.ID).
+		And("email = ?", email).
+		And("expires_unix > ?", timeutil.TimeStampNow()).
+		Exist(new(OrgInvite))
+	if err != nil {
+		return nil, err
+	} else if has {
+		return nil, ErrOrgInviteAlreadyExist{team.ID, email}
+	}
+
+	// Emails are unique and case insensitive, see isEmailUsed
+	u := new(User)
+	has, err = x.Where("lower(email) = ?", email).Get(u)
+	if err != nil {
+		return nil, err
+	} else if has {
+		isMember, err := IsTeamMember(team.OrgID, team.ID, u.ID)
+		if err != nil {
+			return nil, err
+		} else if isMember {
+			return nil, ErrOrgInviteAlreadyMember{team.ID, email}
+		}
+	}
+
+	// Expired invitations are only kept until the next invitation to the team
+	now := timeutil.TimeStampNow()
+	if _, err = x.Where("team_id = ?", team.ID).And("expires_unix <= ?", now).Delete(new(OrgInvite)); err != nil {
+		return nil, err
+	}
+
+	inv := &OrgInvite{
+		OrgID:       team.OrgID,
+		TeamID:      team.ID,
+		InviterID:   inviter.ID,
+		Email:       email,
+		CreatedUnix: now,
+		ExpiresUnix: now.Add(int64(setting.Service.OrgInviteCodeLives) * 60),
+		Team:        team,
+		Inviter:     inviter,
+	}
+	if _, err = x.NoAutoTime().Insert(inv); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// GetOrgInviteByID returns the pending invitation of the organization with the given ID
+func GetOrgInviteByID(orgID, id int64) (*OrgInvite, error) {
+	inv := new(OrgInvite)
+	has, err := x.Where("org_id = ?", orgID).
+		And("id = ?", id).
+		And("expires_unix > ?", timeutil.TimeStampNow()).
+		Get(inv)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrOrgInviteNotExist{id}
+	}
+	return inv, nil
+}
+
+// VerifyOrgInviteCode returns the pending invitation of the code, the code must be valid
+// and not expired
+func VerifyOrgInviteCode(code string) (*OrgInvite, error) {
+	if len(code) <= base.TimeLimitCodeLength {
+		return nil, ErrOrgInviteNotExist{}
+	}
+	id, err := strconv.ParseInt(code[base.TimeLimitCodeLength:], 16, 64)
+	if err != nil {
+		return nil, ErrOrgInviteNotExist{}
+	}
+
+	inv := new(OrgInvite)
+	has, err := x.ID(id).Get(inv)
+	if err != nil {
+		return nil, err
+	} else if !has || inv.IsExpired() {
+		return nil, ErrOrgInviteNotExist{id}
+	}
+
+	minutes := int(inv.ExpiresUnix-inv.CreatedUnix) / 60
+	if !base.VerifyTimeLimitCode(inv.codeData(), minutes, code[:base.TimeLimitCodeLength]) {
+		return nil, ErrOrgInviteNotExist{id}
+	}
+	return inv, inv.LoadAttributes()
+}
+
+// FindOrgInvitesOptions represents the options to find the pending invitations
+type FindOrgInvitesOptions struct {
+	ListOptions
+	OrgID  int64
+	TeamID int64
+}
+
+// FindOrgInvites returns the pending invitations of an organization or a team, newest first
+func FindOrgInvites(opts *FindOrgInvitesOptions) ([]*OrgInvite, error) {
+	sess := x.Where("org_id = ?", opts.OrgID).
+		And("expires_unix > ?", timeutil.TimeStampNow())
+	if opts.TeamID > 0 {
+		sess.And("team_id = ?", opts.TeamID)
+	}
+	if opts.Page != 0 {
+		sess = opts.setSessionPagination(sess)
+	}
+
+	invites := make([]*OrgInvite, 0, 10)
+	if err := sess.Desc("id").Find(&invites); err != nil {
+		return nil, err
+	}
+	for _, inv := range invites {
+		if err := inv.LoadAttributes(); err != nil {
+			return nil, err
+		}
+	}
+	return invites, nil
+}
+
+// DeleteOrgInvite revokes the invitation
+func DeleteOrgInvite(inv *OrgInvite) error {
+	_, err := x.ID(inv.ID).Delete(new(OrgInvite))
+	return err
+}
+
+// IsInvitee returns true if the invited email address is an activated email address of the user
+func (inv *OrgInvite) IsInvitee(u *User) (bool, error) {
+	if u.IsOrganization() {
+		return false, nil
+	} else if u.IsActive && strings.EqualFold(u.Email, inv.Email) {
+		return true, nil
+	}
+	return x.Where("uid = ? AND lower(email) = ? AND is_activated = ?", u.ID, inv.Email, true).Exist(new(EmailAddress))
+}
+
+// AcceptOrgInvite adds the user to the team of the invitation and deletes it.
+// The invited email address must be an activated email address of the user.
+func AcceptOrgInvite(inv *OrgInvite, u *User) error {
+	if u.IsOrganization() {
+		return ErrOrgInviteNotExist{inv.ID}
+	}
+	if isInvitee, err := inv.IsInvitee(u); err != nil {
+		return err
+	} else if !isInvitee {
+		return ErrOrgInviteNotInvitee{inv.ID, u.ID}
+	}
+	if err := AddTeamMember(inv.Team, u.ID); err != nil {
+		return err
+	}
+	return DeleteOrgInvite(inv)
+}
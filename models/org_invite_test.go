@@ -0,0 +1,124 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+
+	"go.wandrs.dev/framework/modules/setting"
+	"go.wandrs.dev/framework/modules/timeutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func prepareOrgInviteTest(t *testing.T) func() {
+	assert.NoError(t, PrepareTestDatabase())
+	lives := setting.Service.OrgInviteCodeLives
+	setting.Service.OrgInviteCodeLives = 60
+	return func() {
+		setting.Service.OrgInviteCodeLives = lives
+	}
+}
+
+func TestCreateOrgInvite(t *testing.T) {
+	defer prepareOrgInviteTest(t)()
+	team := AssertExistsAndLoadBean(t, &Team{ID: 2}).(*Team)
+	inviter := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+
+	inv, err := CreateOrgInvite(team, inviter, " New.Person@Example.com ")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, inv.OrgID)
+	assert.Equal(t, "new.person@example.com", inv.Email)
+	assert.False(t, inv.IsExpired())
+	AssertExistsAndLoadBean(t, &OrgInvite{ID: inv.ID, TeamID: 2})
+
+	_, err = CreateOrgInvite(team, inviter, "new.person@example.com")
+	assert.True(t, IsErrOrgInviteAlreadyExist(err))
+	_, err = CreateOrgInvite(team, inviter, "not an email")
+	assert.True(t, IsErrEmailInvalid(err))
+
+	// user 4 is already a member of the team
+	member := AssertExistsAndLoadBean(t, &User{ID: 4}).(*User)
+	_, err = CreateOrgInvite(team, inviter, member.Email)
+	assert.True(t, IsErrOrgInviteAlreadyMember(err))
+
+	invites, err := FindOrgInvites(&FindOrgInvitesOptions{OrgID: 3, TeamID: 2})
+	assert.NoError(t, err)
+	if assert.Len(t, invites, 1) {
+		assert.EqualValues(t, inv.ID, invites[0].ID)
+		assert.EqualValues(t, 2, invites[0].Inviter.ID)
+	}
+}
+
+func TestVerifyOrgInviteCode(t *testing.T) {
+	defer prepareOrgInviteTest(t)()
+	team := AssertExistsAndLoadBean(t, &Team{ID: 2}).(*Team)
+	inviter := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+
+	inv, err := CreateOrgInvite(team, inviter, "new.person@example.com")
+	assert.NoError(t, err)
+	code := inv.GenerateCode()
+
+	verified, err := VerifyOrgInviteCode(code)
+	assert.NoError(t, err)
+	assert.EqualValues(t, inv.ID, verified.ID)
+	assert.EqualValues(t, 2, verified.Team.ID)
+	assert.EqualValues(t, 3, verified.Org.ID)
+
+	tampered := []byte(code)
+	tampered[0] ^= 1
+	_, err = VerifyOrgInviteCode(string(tampered))
+	assert.True(t, IsErrOrgInviteNotExist(err))
+	_, err = VerifyOrgInviteCode("abc")
+	assert.True(t, IsErrOrgInviteNotExist(err))
+
+	// expired invitations cannot be accepted
+	inv.ExpiresUnix = timeutil.TimeStampNow() - 1
+	_, err = x.ID(inv.ID).Cols("expires_unix").Update(inv)
+	assert.NoError(t, err)
+	_, err = VerifyOrgInviteCode(code)
+	assert.True(t, IsErrOrgInviteNotExist(err))
+	_, err = GetOrgInviteByID(3, inv.ID)
+	assert.True(t, IsErrOrgInviteNotExist(err))
+}
+
+func TestAcceptOrgInvite(t *testing.T) {
+	defer prepareOrgInviteTest(t)()
+	team := AssertExistsAndLoadBean(t, &Team{ID: 7}).(*Team)
+	inviter := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	user := AssertExistsAndLoadBean(t, &User{ID: 5}).(*User)
+
+	inv, err := CreateOrgInvite(team, inviter, "New.Person@example.com")
+	assert.NoError(t, err)
+
+	// the invitation can only be accepted with the invited email address
+	isInvitee, err := inv.IsInvitee(user)
+	assert.NoError(t, err)
+	assert.False(t, isInvitee)
+	assert.True(t, IsErrOrgInviteNotInvitee(AcceptOrgInvite(inv, user)))
+	AssertNotExistsBean(t, &TeamUser{TeamID: 7, UID: 5})
+	email := &EmailAddress{UID: 5, Email: "new.person@example.com"}
+	assert.NoError(t, AddEmailAddress(email))
+	assert.True(t, IsErrOrgInviteNotInvitee(AcceptOrgInvite(inv, user)))
+	assert.NoError(t, email.Activate())
+
+	assert.NoError(t, AcceptOrgInvite(inv, user))
+	AssertExistsAndLoadBean(t, &TeamUser{TeamID: 7, UID: 5})
+	AssertNotExistsBean(t, &OrgInvite{ID: inv.ID})
+
+	// the primary email address of the user
+	inv, err = CreateOrgInvite(AssertExistsAndLoadBean(t, &Team{ID: 2}).(*Team), inviter, "user5@example.com")
+	assert.NoError(t, err)
+	isInvitee, err = inv.IsInvitee(user)
+	assert.NoError(t, err)
+	assert.True(t, isInvitee)
+
+	// revoked invitations are deleted
+	inv, err = CreateOrgInvite(team, inviter, "other.person@example.com")
+	assert.NoError(t, err)
+	assert.NoError(t, DeleteOrgInvite(inv))
+	_, err = GetOrgInviteByID(3, inv.ID)
+	assert.True(t, IsErrOrgInviteNotExist(err))
+}
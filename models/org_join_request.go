@@ -0,0 +1,140 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"strings"
+
+	"go.wandrs.dev/framework/modules/timeutil"
+)
+
+// OrgJoinRequest is a request of a user to become a member of an organization,
+// pending until an owner approves it to a team or rejects it
+type OrgJoinRequest struct {
+	ID          int64              `xorm:"pk autoincr"`
+	OrgID       int64              `xorm:"UNIQUE(s)"`
+	UserID      int64              `xorm:"UNIQUE(s) INDEX"`
+	Message     string             `xorm:"TEXT"`
+	CreatedUnix timeutil.TimeStamp `xorm:"created"`
+
+	Org  *User `xorm:"-"`
+	User *User `xorm:"-"`
+}
+
+func (r *OrgJoinRequest) loadAttributes(e Engine) (err error) {
+	if r.Org == nil {
+		if r.Org, err = getUserByID(e, r.OrgID); err != nil {
+			return err
+		}
+	}
+	if r.User == nil {
+		r.User, err = getUserByID(e, r.UserID)
+	}
+	return err
+}
+
+// LoadAttributes loads the organization and the user of the request
+func (r *OrgJoinRequest) LoadAttributes() error {
+	return r.loadAttributes(x)
+}
+
+// CreateOrgJoinRequest creates a request of the user to join the organization, which
+// must accept requests to join
+func CreateOrgJoinRequest(org, u *User, message string) (*OrgJoinRequest, error) {
+	if !org.AllowJoinRequests || u.IsOrganization() {
+		return nil, ErrOrgJoinRequestsDisabled{org.ID}
+	}
+
+	isMember, err := org.IsOrgMember(u.ID)
+	if err != nil {
+		return nil, err
+	} else if isMember {
+		return nil, ErrOrgJoinRequestAlreadyMember{org.ID, u.ID}
+	}
+
+	has, err := x.Exist(&OrgJoinRequest{OrgID: org.ID, UserID: u.ID})
+	if err != nil {
+		return nil, err
+	} else if has {
+		return nil, ErrOrgJoinRequestAlreadyExist{org.ID, u.ID}
+	}
+
+	r := &OrgJoinRequest{
+		OrgID:   org.ID,
+		UserID:  u.ID,
+		Message: strings.TrimSpace(message),
+		Org:     org,
+		User:    u,
+	}
+	if _, err = x.Insert(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetOrgJoinRequestByID returns the request to join the organization with the given ID
+func GetOrgJoinRequestByID(orgID, id int64) (*OrgJoinRequest, error) {
+	r := new(OrgJoinRequest)
+	has, err := x.Where("org_id = ?", orgID).And("id = ?", id).Get(r)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrOrgJoinRequestNotExist{id}
+	}
+	return r, nil
+}
+
+// GetOrgJoinRequest returns the pending request of the user to join the organization,
+// or nil if there is none
+func GetOrgJoinRequest(orgID, userID int64) (*OrgJoinRequest, error) {
+	r := new(OrgJoinRequest)
+	has, err := x.Where("org_id = ?", orgID).And("user_id = ?", userID).Get(r)
+	if err != nil || !has {
+		return nil, err
+	}
+	return r, nil
+}
+
+// FindOrgJoinRequests returns the pending requests to join the organization, oldest first
+func FindOrgJoinRequests(orgID int64, opts ListOptions) ([]*OrgJoinRequest, error) {
+	sess := x.Where("org_id = ?", orgID)
+	if opts.Page != 0 {
+		sess = opts.setSessionPagination(sess)
+	}
+
+	requests := make([]*OrgJoinRequest, 0, 10)
+	if err := sess.Asc("id").Find(&requests); err != nil {
+		return nil, err
+	}
+	for _, r := range requests {
+		if err := r.LoadAttributes(); err != nil {
+			return nil, err
+		}
+	}
+	return requests, nil
+}
+
+// CountOrgJoinRequests returns the number of pending requests to join the organization
+func CountOrgJoinRequests(orgID int64) (int64, error) {
+	return x.Where("org_id = ?", orgID).Count(new(OrgJoinRequest))
+}
+
+// DeleteOrgJoinRequest rejects or cancels the request
+func DeleteOrgJoinRequest(r *OrgJoinRequest) error {
+	_, err := x.ID(r.ID).Delete(new(OrgJoinRequest))
+	return err
+}
+
+// ApproveOrgJoinRequest adds the user of the request to the team of the organization
+// and deletes the request
+func ApproveOrgJoinRequest(r *OrgJoinRequest, team *Team) error {
+	if team.OrgID != r.OrgID {
+		return ErrTeamNotExist{r.OrgID, team.ID, ""}
+	}
+	if err := AddTeamMember(team, r.UserID); err != nil {
+		return err
+	}
+	return DeleteOrgJoinRequest(r)
+}
@@ -0,0 +1,81 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateOrgJoinRequest(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	org := AssertExistsAndLoadBean(t, &User{ID: 3}).(*User)
+	user := AssertExistsAndLoadBean(t, &User{ID: 5}).(*User)
+
+	_, err := CreateOrgJoinRequest(org, user, "")
+	assert.True(t, IsErrOrgJoinRequestsDisabled(err))
+
+	org.AllowJoinRequests = true
+	r, err := CreateOrgJoinRequest(org, user, " hello ")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", r.Message)
+	AssertExistsAndLoadBean(t, &OrgJoinRequest{ID: r.ID, OrgID: 3, UserID: 5})
+
+	_, err = CreateOrgJoinRequest(org, user, "")
+	assert.True(t, IsErrOrgJoinRequestAlreadyExist(err))
+	member := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	_, err = CreateOrgJoinRequest(org, member, "")
+	assert.True(t, IsErrOrgJoinRequestAlreadyMember(err))
+
+	pending, err := GetOrgJoinRequest(3, 5)
+	assert.NoError(t, err)
+	assert.EqualValues(t, r.ID, pending.ID)
+	pending, err = GetOrgJoinRequest(3, 2)
+	assert.NoError(t, err)
+	assert.Nil(t, pending)
+
+	requests, err := FindOrgJoinRequests(3, ListOptions{})
+	assert.NoError(t, err)
+	if assert.Len(t, requests, 1) {
+		assert.EqualValues(t, 5, requests[0].User.ID)
+	}
+	count, err := CountOrgJoinRequests(3)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+}
+
+func TestApproveOrgJoinRequest(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	org := AssertExistsAndLoadBean(t, &User{ID: 3}).(*User)
+	org.AllowJoinRequests = true
+	user := AssertExistsAndLoadBean(t, &User{ID: 5}).(*User)
+
+	r, err := CreateOrgJoinRequest(org, user, "")
+	assert.NoError(t, err)
+
+	// teams of other organizations
+	other := AssertExistsAndLoadBean(t, &Team{ID: 3}).(*Team)
+	assert.True(t, IsErrTeamNotExist(ApproveOrgJoinRequest(r, other)))
+
+	team := AssertExistsAndLoadBean(t, &Team{ID: 7}).(*Team)
+	assert.NoError(t, ApproveOrgJoinRequest(r, team))
+	AssertExistsAndLoadBean(t, &TeamUser{TeamID: 7, UID: 5})
+	AssertExistsAndLoadBean(t, &OrgUser{OrgID: 3, UID: 5})
+	AssertNotExistsBean(t, &OrgJoinRequest{ID: r.ID})
+}
+
+func TestAddTeamMember_DeletesOrgJoinRequest(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	org := AssertExistsAndLoadBean(t, &User{ID: 3}).(*User)
+	org.AllowJoinRequests = true
+	user := AssertExistsAndLoadBean(t, &User{ID: 5}).(*User)
+
+	r, err := CreateOrgJoinRequest(org, user, "")
+	assert.NoError(t, err)
+	team := AssertExistsAndLoadBean(t, &Team{ID: 2}).(*Team)
+	assert.NoError(t, AddTeamMember(team, 5))
+	AssertNotExistsBean(t, &OrgJoinRequest{ID: r.ID})
+}
@@ -0,0 +1,142 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"errors"
+	"strings"
+
+	"go.wandrs.dev/framework/modules/timeutil"
+)
+
+// OrgRole is a reusable set of access modes to the permission units, defined by the owners
+// of an organization. Teams with a role are granted the access modes of the role, so that
+// changing the role changes the access of all its teams.
+type OrgRole struct {
+	ID          int64                 `xorm:"pk autoincr"`
+	OrgID       int64                 `xorm:"INDEX UNIQUE(s)"`
+	LowerName   string                `xorm:"UNIQUE(s) NOT NULL"`
+	Name        string                `xorm:"NOT NULL"`
+	Description string                `xorm:"TEXT"`
+	Units       map[string]AccessMode `xorm:"TEXT JSON"`
+	NumTeams    int                   `xorm:"-"`
+
+	CreatedUnix timeutil.TimeStamp `xorm:"created"`
+	UpdatedUnix timeutil.TimeStamp `xorm:"updated"`
+}
+
+// UnitAccessMode returns the access mode of the role to the permission unit
+func (r *OrgRole) UnitAccessMode(unit string) AccessMode {
+	return r.Units[unit]
+}
+
+// SetUnitAccessModes replaces the access modes of the role, units without access and
+// units which are not registered are left out
+func (r *OrgRole) SetUnitAccessModes(modes map[string]AccessMode) {
+	r.Units = make(map[string]AccessMode, len(modes))
+	for _, u := range Units() {
+		if mode := modes[u.Name]; mode > AccessModeNone {
+			r.Units[u.Name] = mode
+		}
+	}
+}
+
+func isOrgRoleNameUsed(e Engine, orgID, id int64, lowerName string) (bool, error) {
+	return e.Where("org_id = ?", orgID).
+		And("lower_name = ?", lowerName).
+		And("id != ?", id).
+		Exist(new(OrgRole))
+}
+
+// NewOrgRole creates a role of an organization
+func NewOrgRole(r *OrgRole) error {
+	if len(r.Name) == 0 {
+		return errors.New("empty role name")
+	}
+
+	r.LowerName = strings.ToLower(r.Name)
+	used, err := isOrgRoleNameUsed(x, r.OrgID, 0, r.LowerName)
+	if err != nil {
+		return err
+	} else if used {
+		return ErrOrgRoleAlreadyExist{r.OrgID, r.Name}
+	}
+
+	_, err = x.Insert(r)
+	return err
+}
+
+func getOrgRoleByID(e Engine, orgID, id int64) (*OrgRole, error) {
+	r := new(OrgRole)
+	has, err := e.Where("org_id = ?", orgID).And("id = ?", id).Get(r)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrOrgRoleNotExist{orgID, id}
+	}
+	return r, nil
+}
+
+// GetOrgRoleByID returns the role of the organization with the given ID
+func GetOrgRoleByID(orgID, id int64) (*OrgRole, error) {
+	return getOrgRoleByID(x, orgID, id)
+}
+
+// GetOrgRoles returns the roles of the organization ordered by name, including the
+// number of teams having them
+func GetOrgRoles(orgID int64) ([]*OrgRole, error) {
+	roles := make([]*OrgRole, 0, 5)
+	if err := x.Where("org_id = ?", orgID).Asc("lower_name").Find(&roles); err != nil {
+		return nil, err
+	}
+
+	for _, r := range roles {
+		cnt, err := x.Where("role_id = ?", r.ID).Count(new(Team))
+		if err != nil {
+			return nil, err
+		}
+		r.NumTeams = int(cnt)
+	}
+	return roles, nil
+}
+
+// UpdateOrgRole updates the name, the description and the access modes of the role
+func UpdateOrgRole(r *OrgRole) error {
+	if len(r.Name) == 0 {
+		return errors.New("empty role name")
+	}
+
+	r.LowerName = strings.ToLower(r.Name)
+	used, err := isOrgRoleNameUsed(x, r.OrgID, r.ID, r.LowerName)
+	if err != nil {
+		return err
+	} else if used {
+		return ErrOrgRoleAlreadyExist{r.OrgID, r.Name}
+	}
+
+	_, err = x.ID(r.ID).Cols("lower_name", "name", "description", "units").Update(r)
+	return err
+}
+
+// DeleteOrgRole deletes a role which is not used by any team
+func DeleteOrgRole(r *OrgRole) error {
+	sess := x.NewSession()
+	defer sess.Close()
+	if err := sess.Begin(); err != nil {
+		return err
+	}
+
+	inUse, err := sess.Where("role_id = ?", r.ID).Exist(new(Team))
+	if err != nil {
+		return err
+	} else if inUse {
+		return ErrOrgRoleInUse{r.ID}
+	}
+
+	if _, err := sess.ID(r.ID).Delete(new(OrgRole)); err != nil {
+		return err
+	}
+	return sess.Commit()
+}
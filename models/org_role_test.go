@@ -0,0 +1,49 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrgRoles(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	defer registerTestUnits()()
+
+	role := &OrgRole{OrgID: 3, Name: "Analyst", Description: "Reads the reports"}
+	role.SetUnitAccessModes(map[string]AccessMode{"reports": AccessModeRead, "billing": AccessModeNone})
+	assert.NoError(t, NewOrgRole(role))
+	assert.EqualValues(t, map[string]AccessMode{"reports": AccessModeRead}, role.Units)
+	assert.True(t, IsErrOrgRoleAlreadyExist(NewOrgRole(&OrgRole{OrgID: 3, Name: "analyst"})))
+	assert.NoError(t, NewOrgRole(&OrgRole{OrgID: 6, Name: "Analyst"}))
+
+	role.Name = "Analysts"
+	role.SetUnitAccessModes(map[string]AccessMode{"reports": AccessModeWrite})
+	assert.NoError(t, UpdateOrgRole(role))
+	loaded, err := GetOrgRoleByID(3, role.ID)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "analysts", loaded.LowerName)
+	assert.EqualValues(t, AccessModeWrite, loaded.UnitAccessMode("reports"))
+
+	_, err = GetOrgRoleByID(6, role.ID)
+	assert.True(t, IsErrOrgRoleNotExist(err))
+
+	team := AssertExistsAndLoadBean(t, &Team{ID: 2}).(*Team)
+	team.RoleID = role.ID
+	assert.NoError(t, UpdateTeam(team, false, false))
+
+	roles, err := GetOrgRoles(3)
+	assert.NoError(t, err)
+	assert.Len(t, roles, 1)
+	assert.EqualValues(t, 1, roles[0].NumTeams)
+
+	assert.True(t, IsErrOrgRoleInUse(DeleteOrgRole(role)))
+	team.RoleID = 0
+	assert.NoError(t, UpdateTeam(team, false, false))
+	assert.NoError(t, DeleteOrgRole(role))
+	AssertNotExistsBean(t, &OrgRole{ID: role.ID})
+}
@@ -29,6 +29,16 @@ type Team struct {
 	Authorize   AccessMode
 	Members     []*User `xorm:"-"`
 	NumMembers  int
+	// CanManageOAuth2Apps allows the members to manage the oauth2 applications of the organization
+	CanManageOAuth2Apps bool `xorm:"can_manage_oauth2_apps NOT NULL DEFAULT false"`
+	// RoleID is the role granting the access to the permission units, if it is not
+	// set the access is given by Units
+	RoleID int64       `xorm:"INDEX NOT NULL DEFAULT 0"`
+	Role   *OrgRole    `xorm:"-"`
+	Units  []*TeamUnit `xorm:"-"`
+	// ParentID is the team this team is a sub-team of, the members of a team are
+	// implicitly members of its ancestors
+	ParentID int64 `xorm:"INDEX NOT NULL DEFAULT 0"`
 }
 
 // SearchTeamOptions holds the search options
@@ -189,6 +199,14 @@ func NewTeam(t *Team) (err error) {
 	if has {
 		return ErrTeamAlreadyExist{t.OrgID, t.LowerName}
 	}
+	if t.RoleID != 0 {
+		if _, err = getOrgRoleByID(x, t.OrgID, t.RoleID); err != nil {
+			return err
+		}
+	}
+	if err = checkTeamParent(x, t); err != nil {
+		return err
+	}
 
 	sess := x.NewSession()
 	defer sess.Close()
@@ -204,6 +222,14 @@ func NewTeam(t *Team) (err error) {
 		return err
 	}
 
+	if err = replaceTeamUnits(sess, t); err != nil {
+		errRollback := sess.Rollback()
+		if errRollback != nil {
+			log.Error("NewTeam sess.Rollback: %v", errRollback)
+		}
+		return err
+	}
+
 	// Update organization number of teams.
 	if _, err = sess.Exec("UPDATE `user` SET num_teams=num_teams+1 WHERE id = ?", t.OrgID); err != nil {
 		errRollback := sess.Rollback()
@@ -272,6 +298,12 @@ func GetTeamByID(teamID int64) (*Team, error) {
 	return getTeamByID(x, teamID)
 }
 
+// GetAllTeams returns the teams of all organizations
+func GetAllTeams() ([]*Team, error) {
+	teams := make([]*Team, 0)
+	return teams, x.OrderBy("id").Find(&teams)
+}
+
 // GetTeamNamesByID returns team's lower name from a list of team ids.
 func GetTeamNamesByID(teamIDs []int64) ([]string, error) {
 	if len(teamIDs) == 0 {
@@ -288,7 +320,8 @@ func GetTeamNamesByID(teamIDs []int64) ([]string, error) {
 	return teamNames, err
 }
 
-// UpdateTeam updates information of team.
+// UpdateTeam updates information of team. The access modes to the permission units are
+// only replaced if the units of the team have been set or loaded.
 func UpdateTeam(t *Team, authChanged, includeAllChanged bool) (err error) {
 	if len(t.Name) == 0 {
 		return errors.New("empty team name")
@@ -315,12 +348,26 @@ func UpdateTeam(t *Team, authChanged, includeAllChanged bool) (err error) {
 	} else if has {
 		return ErrTeamAlreadyExist{t.OrgID, t.LowerName}
 	}
+	if t.RoleID != 0 {
+		if _, err = getOrgRoleByID(sess, t.OrgID, t.RoleID); err != nil {
+			return err
+		}
+	}
+	if err = checkTeamParent(sess, t); err != nil {
+		return err
+	}
 
 	if _, err = sess.ID(t.ID).Cols("name", "lower_name", "description",
-		"can_create_org_repo", "authorize", "includes_all_repositories").Update(t); err != nil {
+		"can_create_org_repo", "authorize", "includes_all_repositories", "can_manage_oauth2_apps", "role_id", "parent_id").Update(t); err != nil {
 		return fmt.Errorf("update: %v", err)
 	}
 
+	if t.Units != nil {
+		if err = replaceTeamUnits(sess, t); err != nil {
+			return fmt.Errorf("replaceTeamUnits: %v", err)
+		}
+	}
+
 	return sess.Commit()
 }
 
@@ -349,6 +396,19 @@ func DeleteTeam(t *Team) error {
 	if _, err := sess.ID(t.ID).Delete(new(Team)); err != nil {
 		return err
 	}
+	if _, err := sess.Where("team_id = ?", t.ID).Delete(new(TeamUnit)); err != nil {
+		return err
+	}
+	if _, err := sess.Where("team_id = ?", t.ID).Delete(new(OrgInvite)); err != nil {
+		return err
+	}
+	// Move the sub-teams to the parent of the team.
+	if _, err := sess.Exec("UPDATE `team` SET parent_id = ? WHERE parent_id = ?", t.ParentID, t.ID); err != nil {
+		return err
+	}
+	if err := deleteSCIMResource(sess, SCIMResourceGroup, t.ID); err != nil {
+		return err
+	}
 	// Update organization number of teams.
 	if _, err := sess.Exec("UPDATE `user` SET num_teams=num_teams-1 WHERE id=?", t.OrgID); err != nil {
 		return err
@@ -481,6 +541,8 @@ func AddTeamMember(team *Team, userID int64) error {
 		return err
 	} else if _, err := sess.Incr("num_members").ID(team.ID).Update(new(Team)); err != nil {
 		return err
+	} else if _, err := sess.Delete(&OrgJoinRequest{OrgID: team.OrgID, UserID: userID}); err != nil {
+		return err
 	}
 
 	team.NumMembers++
@@ -0,0 +1,223 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"sort"
+	"strings"
+
+	"go.wandrs.dev/framework/modules/util"
+
+	"xorm.io/builder"
+)
+
+// teamParents maps the teams of an organization to their parent team
+type teamParents map[int64]int64
+
+func getTeamParents(e Engine, orgID int64) (teamParents, error) {
+	teams := make([]*Team, 0, 10)
+	if err := e.Where("org_id = ?", orgID).Find(&teams); err != nil {
+		return nil, err
+	}
+	parents := make(teamParents, len(teams))
+	for _, t := range teams {
+		parents[t.ID] = t.ParentID
+	}
+	return parents, nil
+}
+
+// ancestors returns the IDs of the ancestors of the team from its parent up to the root
+func (p teamParents) ancestors(teamID int64) []int64 {
+	var ids []int64
+	seen := map[int64]bool{teamID: true}
+	for id := p[teamID]; id != 0 && !seen[id]; id = p[id] {
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// descendants returns the IDs of all sub-teams of the team, at any depth
+func (p teamParents) descendants(teamID int64) []int64 {
+	var ids []int64
+	for id := range p {
+		if util.IsInt64InSlice(teamID, p.ancestors(id)) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// checkTeamParent checks that the parent of the team is a team of the same organization
+// other than the owners team, and that it is not the team itself or one of its sub-teams
+func checkTeamParent(e Engine, t *Team) error {
+	if t.ParentID == 0 {
+		return nil
+	}
+
+	parent, err := getTeamByID(e, t.ParentID)
+	if err != nil {
+		if IsErrTeamNotExist(err) {
+			return ErrTeamNotExist{t.OrgID, t.ParentID, ""}
+		}
+		return err
+	} else if parent.OrgID != t.OrgID {
+		return ErrTeamNotExist{t.OrgID, t.ParentID, ""}
+	} else if parent.IsOwnerTeam() {
+		return ErrInvalidTeamParent{t.ID, t.ParentID}
+	}
+
+	if t.ID == 0 {
+		return nil
+	}
+	parents, err := getTeamParents(e, t.OrgID)
+	if err != nil {
+		return err
+	}
+	if t.ParentID == t.ID || util.IsInt64InSlice(t.ID, parents.ancestors(t.ParentID)) {
+		return ErrInvalidTeamParent{t.ID, t.ParentID}
+	}
+	return nil
+}
+
+// GetParentCandidates returns the teams of the organization which may become the parent
+// of the team, excluding the owners team, the team itself and its sub-teams
+func (t *Team) GetParentCandidates() ([]*Team, error) {
+	parents, err := getTeamParents(x, t.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	excluded := append(parents.descendants(t.ID), t.ID)
+
+	teams := make([]*Team, 0, len(parents))
+	return teams, x.Where("org_id = ?", t.OrgID).
+		And("lower_name != ?", strings.ToLower(ownerTeamName)).
+		And(builder.NotIn("id", excluded)).
+		OrderBy("lower_name").
+		Find(&teams)
+}
+
+// GetSubTeams returns the direct sub-teams of the team
+func (t *Team) GetSubTeams() ([]*Team, error) {
+	teams := make([]*Team, 0, 5)
+	return teams, x.Where("org_id = ?", t.OrgID).
+		And("parent_id = ?", t.ID).
+		OrderBy("lower_name").
+		Find(&teams)
+}
+
+func getTeamEffectiveIDs(e Engine, t *Team) ([]int64, error) {
+	parents, err := getTeamParents(e, t.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	return append(parents.descendants(t.ID), t.ID), nil
+}
+
+func isTeamEffectiveMember(e Engine, orgID, teamID, userID int64) (bool, error) {
+	parents, err := getTeamParents(e, orgID)
+	if err != nil {
+		return false, err
+	}
+	return isUserInTeams(e, userID, append(parents.descendants(teamID), teamID))
+}
+
+// IsTeamEffectiveMember returns true if given user is a member of the team or of one of its
+// sub-teams
+func IsTeamEffectiveMember(orgID, teamID, userID int64) (bool, error) {
+	return isTeamEffectiveMember(x, orgID, teamID, userID)
+}
+
+// GetEffectiveMembers returns the paginated members of the team and of its sub-teams,
+// ordered by name
+func (t *Team) GetEffectiveMembers(opts *SearchMembersOptions) ([]*User, error) {
+	ids, err := getTeamEffectiveIDs(x, t)
+	if err != nil {
+		return nil, err
+	}
+
+	sess := x.NewSession()
+	defer sess.Close()
+	if opts.Page != 0 {
+		sess = opts.setSessionPagination(sess)
+	}
+	members := make([]*User, 0, t.NumMembers)
+	return members, sess.
+		Where(builder.In("id", builder.Select("uid").From("team_user").Where(builder.In("team_id", ids)))).
+		OrderBy("name").
+		Find(&members)
+}
+
+func getUserOrgEffectiveTeams(e Engine, orgID, userID int64) ([]*Team, error) {
+	teams, err := getUserOrgTeams(e, orgID, userID)
+	if err != nil || len(teams) == 0 {
+		return teams, err
+	}
+	parents, err := getTeamParents(e, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(teams))
+	for _, t := range teams {
+		ids = append(ids, t.ID)
+	}
+	var missing []int64
+	for _, t := range teams {
+		for _, id := range parents.ancestors(t.ID) {
+			if !util.IsInt64InSlice(id, ids) {
+				ids = append(ids, id)
+				missing = append(missing, id)
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return teams, nil
+	}
+	return teams, e.In("id", missing).Find(&teams)
+}
+
+// GetUserOrgEffectiveTeams returns the teams of the organization the user is a member of,
+// including the ancestors of these teams
+func GetUserOrgEffectiveTeams(orgID, userID int64) ([]*Team, error) {
+	return getUserOrgEffectiveTeams(x, orgID, userID)
+}
+
+// TeamTreeNode is a team in the tree of the teams of an organization
+type TeamTreeNode struct {
+	Team  *Team
+	Depth int
+}
+
+// TeamTree orders the teams depth first, sub-teams following their parent. Teams whose
+// parent is not in the list are roots.
+func TeamTree(teams []*Team) []*TeamTreeNode {
+	inList := make(map[int64]bool, len(teams))
+	for _, t := range teams {
+		inList[t.ID] = true
+	}
+	children := make(map[int64][]*Team)
+	for _, t := range teams {
+		parentID := t.ParentID
+		if !inList[parentID] {
+			parentID = 0
+		}
+		children[parentID] = append(children[parentID], t)
+	}
+
+	nodes := make([]*TeamTreeNode, 0, len(teams))
+	var walk func(parentID int64, depth int)
+	walk = func(parentID int64, depth int) {
+		sort.SliceStable(children[parentID], func(i, j int) bool {
+			return children[parentID][i].LowerName < children[parentID][j].LowerName
+		})
+		for _, t := range children[parentID] {
+			nodes = append(nodes, &TeamTreeNode{Team: t, Depth: depth})
+			walk(t.ID, depth+1)
+		}
+	}
+	walk(0, 0)
+	return nodes
+}
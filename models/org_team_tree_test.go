@@ -0,0 +1,129 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setTeamParent(t *testing.T, teamID, parentID int64) error {
+	team := AssertExistsAndLoadBean(t, &Team{ID: teamID}).(*Team)
+	team.ParentID = parentID
+	return UpdateTeam(team, false, false)
+}
+
+func TestTeamParent(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	assert.NoError(t, setTeamParent(t, 7, 2))
+	assert.NoError(t, setTeamParent(t, 12, 7))
+	AssertExistsAndLoadBean(t, &Team{ID: 7, ParentID: 2})
+
+	// cycles
+	err := setTeamParent(t, 2, 12)
+	assert.True(t, IsErrInvalidTeamParent(err))
+	err = setTeamParent(t, 2, 2)
+	assert.True(t, IsErrInvalidTeamParent(err))
+	// the owners team cannot have sub-teams
+	err = setTeamParent(t, 2, 1)
+	assert.True(t, IsErrInvalidTeamParent(err))
+	// teams of other organizations
+	err = setTeamParent(t, 2, 3)
+	assert.True(t, IsErrTeamNotExist(err))
+	err = NewTeam(&Team{OrgID: 3, Name: "sub", ParentID: 3})
+	assert.True(t, IsErrTeamNotExist(err))
+
+	team := AssertExistsAndLoadBean(t, &Team{ID: 7}).(*Team)
+	candidates, err := team.GetParentCandidates()
+	assert.NoError(t, err)
+	if assert.Len(t, candidates, 1) {
+		assert.EqualValues(t, 2, candidates[0].ID)
+	}
+	subTeams, err := team.GetSubTeams()
+	assert.NoError(t, err)
+	if assert.Len(t, subTeams, 1) {
+		assert.EqualValues(t, 12, subTeams[0].ID)
+	}
+
+	// deleting a team moves its sub-teams to its parent
+	assert.NoError(t, DeleteTeam(team))
+	AssertExistsAndLoadBean(t, &Team{ID: 12, ParentID: 2})
+}
+
+func TestTeamEffectiveMembers(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	assert.NoError(t, setTeamParent(t, 7, 2))
+	assert.NoError(t, setTeamParent(t, 12, 7))
+
+	isMember, err := IsTeamMember(3, 2, 15)
+	assert.NoError(t, err)
+	assert.False(t, isMember)
+	for _, uid := range []int64{2, 4, 15, 28} {
+		isMember, err = IsTeamEffectiveMember(3, 2, uid)
+		assert.NoError(t, err)
+		assert.True(t, isMember)
+	}
+	isMember, err = IsTeamEffectiveMember(3, 12, 15)
+	assert.NoError(t, err)
+	assert.False(t, isMember)
+
+	team := AssertExistsAndLoadBean(t, &Team{ID: 2}).(*Team)
+	members, err := team.GetEffectiveMembers(&SearchMembersOptions{})
+	assert.NoError(t, err)
+	ids := make([]int64, 0, len(members))
+	for _, m := range members {
+		ids = append(ids, m.ID)
+	}
+	assert.ElementsMatch(t, []int64{2, 4, 15, 28}, ids)
+
+	members, err = team.GetEffectiveMembers(&SearchMembersOptions{ListOptions{Page: 1, PageSize: 2}})
+	assert.NoError(t, err)
+	assert.Len(t, members, 2)
+
+	teams, err := GetUserOrgEffectiveTeams(3, 28)
+	assert.NoError(t, err)
+	ids = ids[:0]
+	for _, team := range teams {
+		ids = append(ids, team.ID)
+	}
+	assert.ElementsMatch(t, []int64{12, 7, 2}, ids)
+}
+
+func TestHasUnitAccess_SubTeam(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	defer registerTestUnits()()
+	assert.NoError(t, setTeamParent(t, 7, 2))
+
+	team := AssertExistsAndLoadBean(t, &Team{ID: 2}).(*Team)
+	team.SetUnitAccessModes(map[string]AccessMode{"billing": AccessModeWrite})
+	assert.NoError(t, UpdateTeam(team, false, false))
+
+	org := AssertExistsAndLoadBean(t, &User{ID: 3}).(*User)
+	user := AssertExistsAndLoadBean(t, &User{ID: 15}).(*User)
+	has, err := HasUnitAccess(user, org, "billing", AccessModeWrite)
+	assert.NoError(t, err)
+	assert.True(t, has)
+}
+
+func TestTeamTree(t *testing.T) {
+	teams := []*Team{
+		{ID: 1, LowerName: "owners"},
+		{ID: 2, LowerName: "b", ParentID: 3},
+		{ID: 3, LowerName: "a"},
+		{ID: 4, LowerName: "c", ParentID: 2},
+		{ID: 5, LowerName: "d", ParentID: 99},
+	}
+	nodes := TeamTree(teams)
+	ids := make([]int64, 0, len(nodes))
+	depths := make([]int, 0, len(nodes))
+	for _, n := range nodes {
+		ids = append(ids, n.Team.ID)
+		depths = append(depths, n.Depth)
+	}
+	assert.EqualValues(t, []int64{3, 2, 4, 5, 1}, ids)
+	assert.EqualValues(t, []int{0, 1, 2, 0, 0}, depths)
+}
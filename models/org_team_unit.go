@@ -0,0 +1,185 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import "fmt"
+
+// TeamUnit is the access mode of a team to a permission unit
+type TeamUnit struct {
+	ID         int64      `xorm:"pk autoincr"`
+	OrgID      int64      `xorm:"INDEX"`
+	TeamID     int64      `xorm:"UNIQUE(s)"`
+	Unit       string     `xorm:"VARCHAR(50) UNIQUE(s) NOT NULL"`
+	AccessMode AccessMode `xorm:"NOT NULL DEFAULT 0"`
+}
+
+func getTeamUnits(e Engine, teamID int64) ([]*TeamUnit, error) {
+	units := make([]*TeamUnit, 0, 5)
+	return units, e.Where("team_id = ?", teamID).Find(&units)
+}
+
+func (t *Team) getUnits(e Engine) (err error) {
+	if t.Units != nil {
+		return nil
+	}
+	t.Units, err = getTeamUnits(e, t.ID)
+	return err
+}
+
+// GetUnits loads the access modes of the team to the permission units
+func (t *Team) GetUnits() error {
+	return t.getUnits(x)
+}
+
+func (t *Team) loadRole(e Engine) (err error) {
+	if t.RoleID == 0 || t.Role != nil {
+		return nil
+	}
+	t.Role, err = getOrgRoleByID(e, t.OrgID, t.RoleID)
+	return err
+}
+
+// LoadRole loads the role of the team, if it has one
+func (t *Team) LoadRole() error {
+	return t.loadRole(x)
+}
+
+func (t *Team) loadUnitAccess(e Engine) error {
+	if err := t.getUnits(e); err != nil {
+		return err
+	}
+	return t.loadRole(e)
+}
+
+// LoadUnitAccess loads the units and the role of the team which UnitAccessMode depends on
+func (t *Team) LoadUnitAccess() error {
+	return t.loadUnitAccess(x)
+}
+
+// UnitAccessMode returns the access mode of the team to the permission unit. The owners
+// team has owner access and teams with admin access have admin access to all units.
+// Other teams are granted the access of their role, or their own access modes if they
+// have no role. LoadUnitAccess must have been called before.
+func (t *Team) UnitAccessMode(unit string) AccessMode {
+	if t.IsOwnerTeam() {
+		return AccessModeOwner
+	}
+	if t.Authorize >= AccessModeAdmin {
+		return t.Authorize
+	}
+	if t.Role != nil {
+		return t.Role.UnitAccessMode(unit)
+	}
+	for _, u := range t.Units {
+		if u.Unit == unit {
+			return u.AccessMode
+		}
+	}
+	return AccessModeNone
+}
+
+// SetUnitAccessModes replaces the access modes of the team to the permission units, they
+// are saved by NewTeam and UpdateTeam. Units without access are left out.
+func (t *Team) SetUnitAccessModes(modes map[string]AccessMode) {
+	t.Units = make([]*TeamUnit, 0, len(modes))
+	for _, u := range Units() {
+		if mode := modes[u.Name]; mode > AccessModeNone {
+			t.Units = append(t.Units, &TeamUnit{OrgID: t.OrgID, TeamID: t.ID, Unit: u.Name, AccessMode: mode})
+		}
+	}
+}
+
+// UnitAccessModes returns the access modes of the team to the registered units
+func (t *Team) UnitAccessModes() map[string]AccessMode {
+	modes := make(map[string]AccessMode)
+	for _, u := range Units() {
+		modes[u.Name] = t.UnitAccessMode(u.Name)
+	}
+	return modes
+}
+
+// ParseUnitAccessModes parses the access modes "none", "read", "write" and "admin" of the units
+func ParseUnitAccessModes(modes map[string]string) (map[string]AccessMode, error) {
+	parsed := make(map[string]AccessMode, len(modes))
+	for unit, mode := range modes {
+		if _, ok := GetUnit(unit); !ok {
+			return nil, ErrUnitNotExist{unit}
+		}
+		switch mode {
+		case "", "none":
+			parsed[unit] = AccessModeNone
+		case "read", "write", "admin":
+			parsed[unit] = ParseAccessMode(mode)
+		default:
+			return nil, fmt.Errorf("invalid access mode %q of unit %s", mode, unit)
+		}
+	}
+	return parsed, nil
+}
+
+func replaceTeamUnits(e Engine, t *Team) error {
+	if _, err := e.Where("team_id = ?", t.ID).Delete(new(TeamUnit)); err != nil {
+		return err
+	}
+	for _, u := range t.Units {
+		u.ID = 0
+		u.OrgID = t.OrgID
+		u.TeamID = t.ID
+	}
+	if len(t.Units) == 0 {
+		return nil
+	}
+	_, err := e.Insert(&t.Units)
+	return err
+}
+
+// GetUnitAccessModes returns the highest access modes of the user to the registered units
+// of the organization over all teams of the user, including the ancestors of the teams.
+// Site administrators have owner access.
+func GetUnitAccessModes(u, org *User) (map[string]AccessMode, error) {
+	return getUnitAccessModes(x, u, org)
+}
+
+func getUnitAccessModes(e Engine, u, org *User) (map[string]AccessMode, error) {
+	modes := make(map[string]AccessMode)
+	if u == nil {
+		return modes, nil
+	}
+	if u.IsAdmin {
+		for _, unit := range Units() {
+			modes[unit.Name] = AccessModeOwner
+		}
+		return modes, nil
+	}
+
+	teams, err := getUserOrgEffectiveTeams(e, org.ID, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range teams {
+		if err := t.loadUnitAccess(e); err != nil {
+			return nil, err
+		}
+		for unit, mode := range t.UnitAccessModes() {
+			if mode > modes[unit] {
+				modes[unit] = mode
+			}
+		}
+	}
+	return modes, nil
+}
+
+// HasUnitAccess returns true if the user has at least the access mode to the permission
+// unit of the organization
+func HasUnitAccess(u, org *User, unit string, mode AccessMode) (bool, error) {
+	if _, ok := GetUnit(unit); !ok {
+		return false, ErrUnitNotExist{unit}
+	}
+	modes, err := GetUnitAccessModes(u, org)
+	if err != nil {
+		return false, err
+	}
+	return modes[unit] >= mode, nil
+}
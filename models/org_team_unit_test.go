@@ -0,0 +1,106 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// registerTestUnits registers the units used by the tests and returns a function removing them
+func registerTestUnits() func() {
+	RegisterUnit(Unit{Name: "billing", NameKey: "billing", DescKey: "billing_desc"})
+	RegisterUnit(Unit{Name: "reports", NameKey: "reports", DescKey: "reports_desc"})
+	return func() {
+		unitsLock.Lock()
+		defer unitsLock.Unlock()
+		units = nil
+		unitsMap = make(map[string]*Unit)
+	}
+}
+
+func TestRegisterUnit(t *testing.T) {
+	defer registerTestUnits()()
+
+	assert.Len(t, Units(), 2)
+	assert.EqualValues(t, "billing", Units()[0].Name)
+	u, ok := GetUnit("reports")
+	assert.True(t, ok)
+	assert.EqualValues(t, "reports_desc", u.DescKey)
+	_, ok = GetUnit("unknown")
+	assert.False(t, ok)
+
+	assert.Panics(t, func() { RegisterUnit(Unit{Name: "billing"}) })
+	assert.Panics(t, func() { RegisterUnit(Unit{}) })
+}
+
+func TestTeam_UnitAccessMode(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	defer registerTestUnits()()
+
+	team := AssertExistsAndLoadBean(t, &Team{ID: 2}).(*Team)
+	team.SetUnitAccessModes(map[string]AccessMode{"billing": AccessModeWrite, "unknown": AccessModeRead})
+	assert.NoError(t, UpdateTeam(team, false, false))
+	AssertExistsAndLoadBean(t, &TeamUnit{OrgID: 3, TeamID: 2, Unit: "billing", AccessMode: AccessModeWrite})
+	AssertNotExistsBean(t, &TeamUnit{Unit: "unknown"})
+
+	team = AssertExistsAndLoadBean(t, &Team{ID: 2}).(*Team)
+	assert.NoError(t, team.LoadUnitAccess())
+	assert.EqualValues(t, AccessModeWrite, team.UnitAccessMode("billing"))
+	assert.EqualValues(t, AccessModeNone, team.UnitAccessMode("reports"))
+
+	// the owners team has owner access to all units
+	owners := AssertExistsAndLoadBean(t, &Team{ID: 1}).(*Team)
+	assert.NoError(t, owners.LoadUnitAccess())
+	assert.EqualValues(t, AccessModeOwner, owners.UnitAccessMode("reports"))
+
+	// a role replaces the access modes of the team
+	role := &OrgRole{OrgID: 3, Name: "Accountant"}
+	role.SetUnitAccessModes(map[string]AccessMode{"reports": AccessModeRead})
+	assert.NoError(t, NewOrgRole(role))
+	team.RoleID = role.ID
+	team.Units = nil
+	assert.NoError(t, UpdateTeam(team, false, false))
+	team = AssertExistsAndLoadBean(t, &Team{ID: 2}).(*Team)
+	assert.NoError(t, team.LoadUnitAccess())
+	assert.EqualValues(t, AccessModeNone, team.UnitAccessMode("billing"))
+	assert.EqualValues(t, AccessModeRead, team.UnitAccessMode("reports"))
+
+	// roles of other organizations cannot be used
+	other := &OrgRole{OrgID: 6, Name: "Accountant"}
+	assert.NoError(t, NewOrgRole(other))
+	team.RoleID = other.ID
+	assert.True(t, IsErrOrgRoleNotExist(UpdateTeam(team, false, false)))
+}
+
+func TestHasUnitAccess(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	defer registerTestUnits()()
+
+	org := AssertExistsAndLoadBean(t, &User{ID: 3}).(*User)
+	team := AssertExistsAndLoadBean(t, &Team{ID: 2}).(*Team)
+	team.SetUnitAccessModes(map[string]AccessMode{"billing": AccessModeRead})
+	assert.NoError(t, UpdateTeam(team, false, false))
+
+	test := func(uid int64, unit string, mode AccessMode, expected bool) {
+		u := AssertExistsAndLoadBean(t, &User{ID: uid}).(*User)
+		has, err := HasUnitAccess(u, org, unit, mode)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, has, "user %d, unit %s, mode %s", uid, unit, mode)
+	}
+	test(1, "reports", AccessModeOwner, true) // site administrator
+	test(2, "reports", AccessModeOwner, true) // owner
+	test(4, "billing", AccessModeRead, true)
+	test(4, "billing", AccessModeWrite, false)
+	test(4, "reports", AccessModeRead, false)
+	test(5, "billing", AccessModeRead, false) // not a member
+
+	_, err := HasUnitAccess(nil, org, "unknown", AccessModeRead)
+	assert.True(t, IsErrUnitNotExist(err))
+	has, err := HasUnitAccess(nil, org, "billing", AccessModeRead)
+	assert.NoError(t, err)
+	assert.False(t, has)
+}
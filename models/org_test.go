@@ -55,6 +55,26 @@ func TestUser_IsOrgMember(t *testing.T) {
 	}
 }
 
+func TestUser_CanManageOAuth2ApplicationsBy(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	org := AssertExistsAndLoadBean(t, &User{ID: 3}).(*User)
+
+	test := func(userID int64, expected bool) {
+		canManage, err := org.CanManageOAuth2ApplicationsBy(userID)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, canManage)
+	}
+	test(2, true) // owner
+	test(4, false)
+	test(5, false)
+
+	team := AssertExistsAndLoadBean(t, &Team{ID: 2}).(*Team)
+	team.CanManageOAuth2Apps = true
+	assert.NoError(t, UpdateTeam(team, false, false))
+	test(4, true) // member of team2
+	test(5, false)
+}
+
 func TestUser_GetTeam(t *testing.T) {
 	assert.NoError(t, PrepareTestDatabase())
 	org := AssertExistsAndLoadBean(t, &User{ID: 3}).(*User)
@@ -0,0 +1,112 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.wandrs.dev/framework/modules/log"
+	"go.wandrs.dev/framework/modules/timeutil"
+
+	"xorm.io/builder"
+)
+
+// IsTwoFactorEnrolled returns true if the user has enrolled a TOTP two-factor authentication
+// or registered a WebAuthn credential
+func IsTwoFactorEnrolled(uid int64) (bool, error) {
+	has, err := x.Where("uid = ?", uid).Exist(new(TwoFactor))
+	if err != nil || has {
+		return has, err
+	}
+	return ExistsWebAuthnCredentialsForUID(uid)
+}
+
+// IsTwoFactorCompliant returns true if the organization does not require two-factor
+// authentication or if the user has enrolled it
+func (org *User) IsTwoFactorCompliant(uid int64) (bool, error) {
+	if !org.RequireTwoFactor {
+		return true, nil
+	}
+	return IsTwoFactorEnrolled(uid)
+}
+
+func twoFactorNonCompliantCond() builder.Cond {
+	return builder.NotIn("`user`.id", builder.Select("uid").From("two_factor")).
+		And(builder.NotIn("`user`.id", builder.Select("user_id").From("webauthn_credential")))
+}
+
+// GetTwoFactorNonCompliantMembers returns the members of the organization who have not
+// enrolled two-factor authentication, ordered by name
+func (org *User) GetTwoFactorNonCompliantMembers() ([]*User, error) {
+	users := make([]*User, 0, 10)
+	return users, x.
+		Join("INNER", "org_user", "`org_user`.uid = `user`.id").
+		Where("`org_user`.org_id = ?", org.ID).
+		And(twoFactorNonCompliantCond()).
+		OrderBy("`user`.name").
+		Find(&users)
+}
+
+// SetOrgTwoFactorPolicy updates the two-factor authentication policy of the organization.
+// The doer must have enrolled two-factor authentication to require it.
+func SetOrgTwoFactorPolicy(org, doer *User, require, removeNonCompliant bool) error {
+	if require && !org.RequireTwoFactor {
+		enrolled, err := IsTwoFactorEnrolled(doer.ID)
+		if err != nil {
+			return err
+		} else if !enrolled {
+			return ErrTwoFactorNotEnrolled{doer.ID}
+		}
+		org.TwoFactorRequiredUnix = timeutil.TimeStampNow()
+	}
+	org.RequireTwoFactor = require
+	org.RemoveTwoFactorNonCompliant = removeNonCompliant
+	if !require {
+		org.TwoFactorRequiredUnix = 0
+	}
+	return UpdateUserCols(org, "require_two_factor", "remove_two_factor_non_compliant", "two_factor_required_unix")
+}
+
+// RemoveTwoFactorNonCompliantMembers removes the members who have not enrolled two-factor
+// authentication from the organizations which require it for longer than the grace period
+// and chose to remove them. Owners are never removed.
+func RemoveTwoFactorNonCompliantMembers(ctx context.Context, gracePeriod time.Duration) error {
+	orgs := make([]*User, 0, 10)
+	if err := x.
+		Where("type = ?", UserTypeOrganization).
+		And("require_two_factor = ?", true).
+		And("remove_two_factor_non_compliant = ?", true).
+		And("two_factor_required_unix <= ?", timeutil.TimeStampNow().AddDuration(-gracePeriod)).
+		Find(&orgs); err != nil {
+		return err
+	}
+
+	for _, org := range orgs {
+		members, err := org.GetTwoFactorNonCompliantMembers()
+		if err != nil {
+			return err
+		}
+		for _, u := range members {
+			select {
+			case <-ctx.Done():
+				return ErrCancelledf("Before remove %s from %s", u.Name, org.Name)
+			default:
+			}
+			isOwner, err := IsOrganizationOwner(org.ID, u.ID)
+			if err != nil {
+				return err
+			} else if isOwner {
+				continue
+			}
+			if err := RemoveOrgUser(org.ID, u.ID); err != nil {
+				return err
+			}
+			RecordAuditEvent(OrgMemberRemoveAuditEvent(org, u))
+			log.Trace("Member without two-factor authentication removed from %s: %s", org.Name, u.Name)
+		}
+	}
+	return nil
+}
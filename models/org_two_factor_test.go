@@ -0,0 +1,97 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTwoFactorEnrolled(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	enrolled, err := IsTwoFactorEnrolled(24)
+	assert.NoError(t, err)
+	assert.True(t, enrolled)
+	enrolled, err = IsTwoFactorEnrolled(2)
+	assert.NoError(t, err)
+	assert.False(t, enrolled)
+
+	// a WebAuthn credential is enough
+	_, err = x.Delete(&TwoFactor{UID: 24})
+	assert.NoError(t, err)
+	enrolled, err = IsTwoFactorEnrolled(24)
+	assert.NoError(t, err)
+	assert.True(t, enrolled)
+}
+
+func TestSetOrgTwoFactorPolicy(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	org := AssertExistsAndLoadBean(t, &User{ID: 3}).(*User)
+	owner := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	enrolled := AssertExistsAndLoadBean(t, &User{ID: 24}).(*User)
+
+	compliant, err := org.IsTwoFactorCompliant(owner.ID)
+	assert.NoError(t, err)
+	assert.True(t, compliant)
+
+	err = SetOrgTwoFactorPolicy(org, owner, true, false)
+	assert.True(t, IsErrTwoFactorNotEnrolled(err))
+	assert.False(t, org.RequireTwoFactor)
+
+	assert.NoError(t, SetOrgTwoFactorPolicy(org, enrolled, true, true))
+	org = AssertExistsAndLoadBean(t, &User{ID: 3, RequireTwoFactor: true, RemoveTwoFactorNonCompliant: true}).(*User)
+	assert.NotZero(t, org.TwoFactorRequiredUnix)
+
+	compliant, err = org.IsTwoFactorCompliant(owner.ID)
+	assert.NoError(t, err)
+	assert.False(t, compliant)
+	compliant, err = org.IsTwoFactorCompliant(enrolled.ID)
+	assert.NoError(t, err)
+	assert.True(t, compliant)
+
+	// disabling the policy does not require two-factor authentication
+	assert.NoError(t, SetOrgTwoFactorPolicy(org, owner, false, false))
+	AssertExistsAndLoadBean(t, &User{ID: 3, TwoFactorRequiredUnix: 0})
+}
+
+func TestGetTwoFactorNonCompliantMembers(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	org := AssertExistsAndLoadBean(t, &User{ID: 3}).(*User)
+	assert.NoError(t, AddOrgUser(3, 24))
+
+	members, err := org.GetTwoFactorNonCompliantMembers()
+	assert.NoError(t, err)
+	ids := make([]int64, 0, len(members))
+	for _, u := range members {
+		ids = append(ids, u.ID)
+	}
+	assert.Contains(t, ids, int64(2))
+	assert.Contains(t, ids, int64(4))
+	assert.NotContains(t, ids, int64(24))
+}
+
+func TestRemoveTwoFactorNonCompliantMembers(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	org := AssertExistsAndLoadBean(t, &User{ID: 3}).(*User)
+	enrolled := AssertExistsAndLoadBean(t, &User{ID: 24}).(*User)
+	assert.NoError(t, AddOrgUser(3, 24))
+	assert.NoError(t, SetOrgTwoFactorPolicy(org, enrolled, true, true))
+
+	// within the grace period
+	assert.NoError(t, RemoveTwoFactorNonCompliantMembers(context.Background(), time.Hour))
+	AssertExistsAndLoadBean(t, &OrgUser{OrgID: 3, UID: 4})
+
+	assert.NoError(t, RemoveTwoFactorNonCompliantMembers(context.Background(), 0))
+	AssertNotExistsBean(t, &OrgUser{OrgID: 3, UID: 4})
+	AssertNotExistsBean(t, &TeamUser{OrgID: 3, UID: 4})
+	// owners are never removed
+	AssertExistsAndLoadBean(t, &OrgUser{OrgID: 3, UID: 2})
+	AssertExistsAndLoadBean(t, &OrgUser{OrgID: 3, UID: 24})
+	AssertExistsAndLoadBean(t, &AuditEvent{Action: AuditOrgMemberRemove, OrgID: 3})
+}
@@ -0,0 +1,66 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"go.wandrs.dev/framework/modules/timeutil"
+)
+
+// SCIMResourceType is the type of a resource provisioned through SCIM
+type SCIMResourceType int
+
+// SCIM resource types
+const (
+	SCIMResourceUser  SCIMResourceType = iota + 1 // 1
+	SCIMResourceGroup                             // 2
+)
+
+// SCIMResource holds the SCIM metadata of a user or team which has been provisioned through SCIM
+type SCIMResource struct {
+	ID           int64              `xorm:"pk autoincr"`
+	ResourceType SCIMResourceType   `xorm:"UNIQUE(s) NOT NULL"`
+	ResourceID   int64              `xorm:"UNIQUE(s) NOT NULL"`
+	ExternalID   string             `xorm:"INDEX"`
+	CreatedUnix  timeutil.TimeStamp `xorm:"created"`
+	UpdatedUnix  timeutil.TimeStamp `xorm:"updated"`
+}
+
+// GetSCIMResource returns the SCIM metadata of the user or team, which is empty
+// if it has never been provisioned through SCIM
+func GetSCIMResource(resourceType SCIMResourceType, resourceID int64) (*SCIMResource, error) {
+	res := &SCIMResource{ResourceType: resourceType, ResourceID: resourceID}
+	if _, err := x.Get(res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetSCIMResources returns the SCIM metadata of all users or teams by their ID
+func GetSCIMResources(resourceType SCIMResourceType) (map[int64]*SCIMResource, error) {
+	list := make([]*SCIMResource, 0)
+	if err := x.Where("resource_type = ?", resourceType).Find(&list); err != nil {
+		return nil, err
+	}
+	resources := make(map[int64]*SCIMResource, len(list))
+	for _, res := range list {
+		resources[res.ResourceID] = res
+	}
+	return resources, nil
+}
+
+// SaveSCIMResource records that the user or team has been provisioned through SCIM
+func SaveSCIMResource(res *SCIMResource) error {
+	if res.ID == 0 {
+		_, err := x.Insert(res)
+		return err
+	}
+	_, err := x.ID(res.ID).Cols("external_id").Update(res)
+	return err
+}
+
+func deleteSCIMResource(e Engine, resourceType SCIMResourceType, resourceID int64) error {
+	_, err := e.Delete(&SCIMResource{ResourceType: resourceType, ResourceID: resourceID})
+	return err
+}
@@ -0,0 +1,35 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveSCIMResource(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	res, err := GetSCIMResource(SCIMResourceGroup, 2)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, res.ID)
+
+	res.ExternalID = "developers"
+	assert.NoError(t, SaveSCIMResource(res))
+	AssertExistsAndLoadBean(t, &SCIMResource{ResourceType: SCIMResourceGroup, ResourceID: 2, ExternalID: "developers"})
+
+	res.ExternalID = "engineering"
+	assert.NoError(t, SaveSCIMResource(res))
+	resources, err := GetSCIMResources(SCIMResourceGroup)
+	assert.NoError(t, err)
+	if assert.Len(t, resources, 1) {
+		assert.Equal(t, "engineering", resources[2].ExternalID)
+	}
+
+	// the metadata is deleted along with the team
+	assert.NoError(t, DeleteTeam(AssertExistsAndLoadBean(t, &Team{ID: 2}).(*Team)))
+	AssertNotExistsBean(t, &SCIMResource{ResourceType: SCIMResourceGroup, ResourceID: 2})
+}
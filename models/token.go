@@ -7,13 +7,16 @@ package models
 
 import (
 	"crypto/subtle"
+	"strings"
 	"time"
 
 	"go.wandrs.dev/framework/modules/base"
+	"go.wandrs.dev/framework/modules/setting"
 	"go.wandrs.dev/framework/modules/timeutil"
 	"go.wandrs.dev/framework/modules/util"
 
 	gouuid "github.com/google/uuid"
+	"xorm.io/builder"
 )
 
 // AccessToken represents a personal access token.
@@ -25,9 +28,12 @@ type AccessToken struct {
 	TokenHash      string `xorm:"UNIQUE"` // sha256 of token
 	TokenSalt      string
 	TokenLastEight string `xorm:"token_last_eight"`
+	Scope          string `xorm:"TEXT"`
 
 	CreatedUnix       timeutil.TimeStamp `xorm:"INDEX created"`
 	UpdatedUnix       timeutil.TimeStamp `xorm:"INDEX updated"`
+	ExpiredUnix       timeutil.TimeStamp `xorm:"INDEX NOT NULL DEFAULT 0"` // 0 means the token never expires
+	ExpiryNotified    bool               `xorm:"NOT NULL DEFAULT false"`
 	HasRecentActivity bool               `xorm:"-"`
 	HasUsed           bool               `xorm:"-"`
 }
@@ -38,8 +44,64 @@ func (t *AccessToken) AfterLoad() {
 	t.HasRecentActivity = t.UpdatedUnix.AddDuration(7*24*time.Hour) > timeutil.TimeStampNow()
 }
 
+// ExpiresUnix returns when the token expires, or 0 if it never does. Tokens
+// created without an expiry date before a maximum lifetime was configured
+// expire once they reach it.
+func (t *AccessToken) ExpiresUnix() timeutil.TimeStamp {
+	if t.ExpiredUnix == 0 && setting.AccessTokenMaxLifetime > 0 {
+		return t.CreatedUnix.AddDuration(setting.AccessTokenMaxLifetime)
+	}
+	return t.ExpiredUnix
+}
+
+// IsExpired returns true if the token has an expiry date which has passed.
+func (t *AccessToken) IsExpired() bool {
+	expires := t.ExpiresUnix()
+	return expires > 0 && expires <= timeutil.TimeStampNow()
+}
+
+// HasScope returns true if the token grants the given scope.
+func (t *AccessToken) HasScope(scope AccessTokenScope) bool {
+	return AccessTokenScopeContains(t.Scope, scope)
+}
+
+// Scopes returns the scopes granted to the token.
+func (t *AccessToken) Scopes() []AccessTokenScope {
+	if t.Scope == "" {
+		return []AccessTokenScope{AccessTokenScopeAll}
+	}
+	parts := strings.Split(t.Scope, ",")
+	scopes := make([]AccessTokenScope, 0, len(parts))
+	for _, part := range parts {
+		scopes = append(scopes, AccessTokenScope(part))
+	}
+	return scopes
+}
+
+// checkAccessTokenExpiry validates the expiry of a new token against the
+// configured maximum lifetime, defaulting to the maximum if none is given.
+func checkAccessTokenExpiry(t *AccessToken) error {
+	now := timeutil.TimeStampNow()
+	if t.ExpiredUnix != 0 && t.ExpiredUnix <= now {
+		return ErrAccessTokenInvalidExpiry{Expiry: t.ExpiredUnix, MaxLifetime: setting.AccessTokenMaxLifetime}
+	}
+	if setting.AccessTokenMaxLifetime <= 0 {
+		return nil
+	}
+	maxExpiry := now.AddDuration(setting.AccessTokenMaxLifetime)
+	if t.ExpiredUnix == 0 {
+		t.ExpiredUnix = maxExpiry
+	} else if t.ExpiredUnix > maxExpiry {
+		return ErrAccessTokenInvalidExpiry{Expiry: t.ExpiredUnix, MaxLifetime: setting.AccessTokenMaxLifetime}
+	}
+	return nil
+}
+
 // NewAccessToken creates new access token.
 func NewAccessToken(t *AccessToken) error {
+	if err := checkAccessTokenExpiry(t); err != nil {
+		return err
+	}
 	salt, err := util.RandomString(10)
 	if err != nil {
 		return err
@@ -71,6 +133,9 @@ func GetAccessTokenBySHA(token string) (*AccessToken, error) {
 	for _, t := range tokens {
 		tempHash := hashToken(token, t.TokenSalt)
 		if subtle.ConstantTimeCompare([]byte(t.TokenHash), []byte(tempHash)) == 1 {
+			if t.IsExpired() {
+				return nil, ErrAccessTokenExpired{ID: t.ID, UID: t.UID}
+			}
 			return &t, nil
 		}
 	}
@@ -128,3 +193,38 @@ func DeleteAccessTokenByID(id, userID int64) error {
 	}
 	return nil
 }
+
+// accessTokenExpiresCond matches the tokens which expire after the first and
+// no later than the second time, see AccessToken.ExpiresUnix.
+func accessTokenExpiresCond(after, before timeutil.TimeStamp) builder.Cond {
+	cond := builder.Gt{"expired_unix": after}.And(builder.Lte{"expired_unix": before})
+	if setting.AccessTokenMaxLifetime > 0 {
+		cond = cond.Or(builder.Eq{"expired_unix": 0}.And(
+			builder.Gt{"created_unix": after.AddDuration(-setting.AccessTokenMaxLifetime)},
+			builder.Lte{"created_unix": before.AddDuration(-setting.AccessTokenMaxLifetime)},
+		))
+	}
+	return cond
+}
+
+// GetAccessTokensExpiringBefore returns all tokens which expire before the given
+// time and whose owners have not been notified yet.
+func GetAccessTokensExpiringBefore(before timeutil.TimeStamp) ([]*AccessToken, error) {
+	tokens := make([]*AccessToken, 0, 10)
+	return tokens, x.
+		Where(accessTokenExpiresCond(timeutil.TimeStampNow(), before)).
+		And("expiry_notified = ?", false).
+		Find(&tokens)
+}
+
+// SetAccessTokenExpiryNotified marks that the owner of the token has been told about its expiry.
+func SetAccessTokenExpiryNotified(t *AccessToken) error {
+	t.ExpiryNotified = true
+	_, err := x.ID(t.ID).Cols("expiry_notified").NoAutoTime().Update(t)
+	return err
+}
+
+// DeleteExpiredAccessTokens deletes all access tokens which have expired.
+func DeleteExpiredAccessTokens() (int64, error) {
+	return x.Where(accessTokenExpiresCond(0, timeutil.TimeStampNow())).Delete(new(AccessToken))
+}
@@ -0,0 +1,150 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"net/http"
+	"strings"
+)
+
+// AccessTokenScope represents a permission granted to an access token.
+type AccessTokenScope string
+
+// Access token scopes
+const (
+	AccessTokenScopeAll AccessTokenScope = "all"
+
+	AccessTokenScopeReadUser  AccessTokenScope = "read:user"
+	AccessTokenScopeWriteUser AccessTokenScope = "write:user"
+
+	AccessTokenScopeReadOrg  AccessTokenScope = "read:org"
+	AccessTokenScopeWriteOrg AccessTokenScope = "write:org"
+
+	AccessTokenScopeAdminUsers  AccessTokenScope = "admin:users"
+	AccessTokenScopeAdminOrgs   AccessTokenScope = "admin:orgs"
+	AccessTokenScopeAdminSystem AccessTokenScope = "admin:system"
+	AccessTokenScopeAdminSCIM   AccessTokenScope = "admin:scim"
+)
+
+// AllAccessTokenScopes contains all valid access token scopes in display order.
+var AllAccessTokenScopes = []AccessTokenScope{
+	AccessTokenScopeAll,
+	AccessTokenScopeReadUser,
+	AccessTokenScopeWriteUser,
+	AccessTokenScopeReadOrg,
+	AccessTokenScopeWriteOrg,
+	AccessTokenScopeAdminUsers,
+	AccessTokenScopeAdminOrgs,
+	AccessTokenScopeAdminSystem,
+	AccessTokenScopeAdminSCIM,
+}
+
+// accessTokenScopeImplies lists the scopes which are implicitly granted by another scope.
+var accessTokenScopeImplies = map[AccessTokenScope][]AccessTokenScope{
+	AccessTokenScopeWriteUser: {AccessTokenScopeReadUser},
+	AccessTokenScopeWriteOrg:  {AccessTokenScopeReadOrg},
+}
+
+// explicitAccessTokenScopes are the scopes which are not granted by all and must be listed.
+var explicitAccessTokenScopes = map[AccessTokenScope]bool{
+	AccessTokenScopeAdminSCIM: true,
+}
+
+// IsValid returns true if the scope is known.
+func (s AccessTokenScope) IsValid() bool {
+	for _, scope := range AllAccessTokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// DescKey returns the locale key describing the scope.
+func (s AccessTokenScope) DescKey() string {
+	return "settings.token_scope_desc." + strings.ReplaceAll(string(s), ":", "_")
+}
+
+// AccessTokenScopeCategory pairs the scopes that guard one area of the API:
+// safe requests need Read and all other requests need Write.
+type AccessTokenScopeCategory struct {
+	Read  AccessTokenScope
+	Write AccessTokenScope
+}
+
+// Access token scope categories used by the API routes
+var (
+	AccessTokenScopeCategoryUser        = AccessTokenScopeCategory{AccessTokenScopeReadUser, AccessTokenScopeWriteUser}
+	AccessTokenScopeCategoryOrg         = AccessTokenScopeCategory{AccessTokenScopeReadOrg, AccessTokenScopeWriteOrg}
+	AccessTokenScopeCategoryAdminUsers  = AccessTokenScopeCategory{AccessTokenScopeAdminUsers, AccessTokenScopeAdminUsers}
+	AccessTokenScopeCategoryAdminOrgs   = AccessTokenScopeCategory{AccessTokenScopeAdminOrgs, AccessTokenScopeAdminOrgs}
+	AccessTokenScopeCategoryAdminSystem = AccessTokenScopeCategory{AccessTokenScopeAdminSystem, AccessTokenScopeAdminSystem}
+)
+
+// ScopeForMethod returns the scope required for a request with the given HTTP method.
+func (c AccessTokenScopeCategory) ScopeForMethod(method string) AccessTokenScope {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return c.Read
+	default:
+		return c.Write
+	}
+}
+
+// ParseAccessTokenScopes validates the given scopes and returns them in their
+// normalized, comma separated form suitable for AccessToken.Scope.
+// An empty list grants all scopes.
+func ParseAccessTokenScopes(scopes []string) (string, error) {
+	if len(scopes) == 0 {
+		return string(AccessTokenScopeAll), nil
+	}
+
+	seen := make(map[AccessTokenScope]bool, len(scopes))
+	for _, raw := range scopes {
+		for _, part := range strings.Split(raw, ",") {
+			scope := AccessTokenScope(strings.TrimSpace(part))
+			if scope == "" {
+				continue
+			}
+			if !scope.IsValid() {
+				return "", ErrAccessTokenInvalidScope{Scope: string(scope)}
+			}
+			seen[scope] = true
+		}
+	}
+	if len(seen) == 0 {
+		return string(AccessTokenScopeAll), nil
+	}
+
+	normalized := make([]string, 0, len(seen))
+	for _, scope := range AllAccessTokenScopes {
+		// all covers every scope but the explicit ones
+		if seen[scope] && (!seen[AccessTokenScopeAll] || scope == AccessTokenScopeAll || explicitAccessTokenScopes[scope]) {
+			normalized = append(normalized, string(scope))
+		}
+	}
+	return strings.Join(normalized, ","), nil
+}
+
+// AccessTokenScopeContains returns true if the comma separated scope list grants
+// the required scope. Tokens created before scopes existed have an empty list
+// and are treated as having all scopes. Explicit scopes are only granted if listed.
+func AccessTokenScopeContains(scopeList string, required AccessTokenScope) bool {
+	if scopeList == "" {
+		return !explicitAccessTokenScopes[required]
+	}
+	for _, part := range strings.Split(scopeList, ",") {
+		scope := AccessTokenScope(strings.TrimSpace(part))
+		if scope == required || (scope == AccessTokenScopeAll && !explicitAccessTokenScopes[required]) {
+			return true
+		}
+		for _, implied := range accessTokenScopeImplies[scope] {
+			if implied == required {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAccessTokenScopes(t *testing.T) {
+	scope, err := ParseAccessTokenScopes(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "all", scope)
+
+	scope, err = ParseAccessTokenScopes([]string{"write:org", "read:user", "write:org"})
+	assert.NoError(t, err)
+	assert.Equal(t, "read:user,write:org", scope)
+
+	scope, err = ParseAccessTokenScopes([]string{"read:user,all"})
+	assert.NoError(t, err)
+	assert.Equal(t, "all", scope)
+
+	scope, err = ParseAccessTokenScopes([]string{"admin:scim", "all", "read:user"})
+	assert.NoError(t, err)
+	assert.Equal(t, "all,admin:scim", scope)
+
+	_, err = ParseAccessTokenScopes([]string{"read:user", "write:everything"})
+	assert.True(t, IsErrAccessTokenInvalidScope(err))
+}
+
+func TestAccessTokenScopeContains(t *testing.T) {
+	assert.True(t, AccessTokenScopeContains("", AccessTokenScopeAdminUsers))
+	assert.True(t, AccessTokenScopeContains("all", AccessTokenScopeWriteOrg))
+	assert.True(t, AccessTokenScopeContains("write:org", AccessTokenScopeReadOrg))
+	assert.False(t, AccessTokenScopeContains("read:org", AccessTokenScopeWriteOrg))
+	assert.False(t, AccessTokenScopeContains("read:user,write:org", AccessTokenScopeAdminUsers))
+	assert.False(t, AccessTokenScopeContains("", AccessTokenScopeAdminSCIM))
+	assert.False(t, AccessTokenScopeContains("all", AccessTokenScopeAdminSCIM))
+	assert.True(t, AccessTokenScopeContains("all,admin:scim", AccessTokenScopeAdminSCIM))
+
+	assert.Equal(t, AccessTokenScopeReadUser, AccessTokenScopeCategoryUser.ScopeForMethod(http.MethodGet))
+	assert.Equal(t, AccessTokenScopeWriteUser, AccessTokenScopeCategoryUser.ScopeForMethod(http.MethodPatch))
+}
@@ -6,6 +6,10 @@ package models
 
 import (
 	"testing"
+	"time"
+
+	"go.wandrs.dev/framework/modules/setting"
+	"go.wandrs.dev/framework/modules/timeutil"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -127,3 +131,60 @@ func TestDeleteAccessTokenByID(t *testing.T) {
 	assert.Error(t, err)
 	assert.True(t, IsErrAccessTokenNotExist(err))
 }
+
+func TestAccessTokenExpiry(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	token := &AccessToken{
+		UID:         3,
+		Name:        "Token Expired",
+		ExpiredUnix: timeutil.TimeStampNow().Add(-60),
+	}
+	err := NewAccessToken(token)
+	assert.True(t, IsErrAccessTokenInvalidExpiry(err))
+
+	token.ExpiredUnix = timeutil.TimeStampNow().Add(3600)
+	assert.NoError(t, NewAccessToken(token))
+
+	// expire the token behind the back of NewAccessToken
+	token.ExpiredUnix = timeutil.TimeStampNow().Add(-60)
+	assert.NoError(t, UpdateAccessToken(token))
+
+	_, err = GetAccessTokenBySHA(token.Token)
+	assert.True(t, IsErrAccessTokenExpired(err))
+
+	deleted, err := DeleteExpiredAccessTokens()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, deleted)
+	AssertNotExistsBean(t, &AccessToken{ID: token.ID})
+}
+
+func TestAccessTokenMaxLifetime(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	defer func(maxLifetime time.Duration) {
+		setting.AccessTokenMaxLifetime = maxLifetime
+	}(setting.AccessTokenMaxLifetime)
+	setting.AccessTokenMaxLifetime = 24 * time.Hour
+
+	token := &AccessToken{UID: 3, Name: "Token Default Expiry"}
+	assert.NoError(t, NewAccessToken(token))
+	assert.NotZero(t, token.ExpiredUnix)
+
+	token = &AccessToken{
+		UID:         3,
+		Name:        "Token Too Long",
+		ExpiredUnix: timeutil.TimeStampNow().AddDuration(48 * time.Hour),
+	}
+	assert.True(t, IsErrAccessTokenInvalidExpiry(NewAccessToken(token)))
+
+	// tokens created without an expiry date before the limit was set expire at the limit
+	_, err := GetAccessTokenBySHA("d2c6c1ba3890b309189a8e618c72a162e4efbf36")
+	assert.True(t, IsErrAccessTokenExpired(err))
+
+	deleted, err := DeleteExpiredAccessTokens()
+	assert.NoError(t, err)
+	assert.NotZero(t, deleted)
+	AssertNotExistsBean(t, &AccessToken{ID: 1})
+	AssertExistsAndLoadBean(t, &AccessToken{Name: "Token Default Expiry"})
+}
@@ -0,0 +1,58 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Unit is a named permission unit of the organizations. Applications built on the
+// framework register their units, e.g. "billing" or "reports", and teams are granted
+// an access mode per unit.
+type Unit struct {
+	// Name identifies the unit, it is stored in the database and must not change
+	Name string
+	// NameKey and DescKey are the locale keys of the display name and the description
+	NameKey string
+	DescKey string
+}
+
+var (
+	unitsLock sync.RWMutex
+	units     []*Unit
+	unitsMap  = make(map[string]*Unit)
+)
+
+// RegisterUnit registers a permission unit. Units are expected to be registered at
+// start up, e.g. in an init function of the application.
+func RegisterUnit(u Unit) {
+	unitsLock.Lock()
+	defer unitsLock.Unlock()
+
+	if len(u.Name) == 0 || len(u.Name) > 50 {
+		panic(fmt.Sprintf("invalid unit name %q", u.Name))
+	}
+	if _, ok := unitsMap[u.Name]; ok {
+		panic(fmt.Sprintf("unit %q registered twice", u.Name))
+	}
+	units = append(units, &u)
+	unitsMap[u.Name] = &u
+}
+
+// Units returns the registered units in the order of registration
+func Units() []*Unit {
+	unitsLock.RLock()
+	defer unitsLock.RUnlock()
+	return append([]*Unit(nil), units...)
+}
+
+// GetUnit returns the registered unit with the given name
+func GetUnit(name string) (*Unit, bool) {
+	unitsLock.RLock()
+	defer unitsLock.RUnlock()
+	u, ok := unitsMap[name]
+	return u, ok
+}
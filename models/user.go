@@ -146,13 +146,17 @@ type User struct {
 	NumRepos     int
 
 	// For organization
-	NumTeams                  int
-	NumMembers                int
-	Teams                     []*Team             `xorm:"-"`
-	Members                   UserList            `xorm:"-"`
-	MembersIsPublic           map[int64]bool      `xorm:"-"`
-	Visibility                structs.VisibleType `xorm:"NOT NULL DEFAULT 0"`
-	RepoAdminChangeTeamAccess bool                `xorm:"NOT NULL DEFAULT false"`
+	NumTeams                    int
+	NumMembers                  int
+	Teams                       []*Team             `xorm:"-"`
+	Members                     UserList            `xorm:"-"`
+	MembersIsPublic             map[int64]bool      `xorm:"-"`
+	Visibility                  structs.VisibleType `xorm:"NOT NULL DEFAULT 0"`
+	RepoAdminChangeTeamAccess   bool                `xorm:"NOT NULL DEFAULT false"`
+	AllowJoinRequests           bool                `xorm:"NOT NULL DEFAULT false"`
+	RequireTwoFactor            bool                `xorm:"NOT NULL DEFAULT false"`
+	RemoveTwoFactorNonCompliant bool                `xorm:"NOT NULL DEFAULT false"`
+	TwoFactorRequiredUnix       timeutil.TimeStamp  `xorm:"NOT NULL DEFAULT 0"`
 
 	// Preferences
 	DiffViewStyle       string `xorm:"NOT NULL DEFAULT ''"`
@@ -238,6 +242,11 @@ func (u *User) IsOAuth2() bool {
 	return u.LoginType == LoginOAuth2
 }
 
+// IsSAML returns true if user login type is LoginSAML.
+func (u *User) IsSAML() bool {
+	return u.LoginType == LoginSAML
+}
+
 // CanCreateOrganization returns true if user can create organisation.
 func (u *User) CanCreateOrganization() bool {
 	return u.IsAdmin || (u.AllowCreateOrganization && !setting.Admin.DisableRegularOrgCreation)
@@ -958,10 +967,20 @@ func deleteUser(e Engine, u *User) error {
 		&EmailAddress{UID: u.ID},
 		&UserOpenID{UID: u.ID},
 		&TeamUser{UID: u.ID},
+		&OrgJoinRequest{UserID: u.ID},
+		&OAuth2Grant{UserID: u.ID},
+		&WebAuthnCredential{UserID: u.ID},
+		&SCIMResource{ResourceType: SCIMResourceUser, ResourceID: u.ID},
+		&UserSession{UID: u.ID},
 	); err != nil {
 		return fmt.Errorf("deleteBeans: %v", err)
 	}
 
+	// Applications owned by an organization are kept, only the personal ones are removed.
+	if err = deleteOAuth2ApplicationsByUserID(e, u.ID); err != nil {
+		return fmt.Errorf("deleteOAuth2ApplicationsByUserID: %v", err)
+	}
+
 	// ***** START: ExternalLoginUser *****
 	if err = removeAllAccountLinks(e, u); err != nil {
 		return fmt.Errorf("ExternalLoginUser: %v", err)
@@ -1429,6 +1448,7 @@ func SyncExternalUsers(ctx context.Context, updateExisting bool) error {
 
 					if err != nil {
 						log.Error("SyncExternalUsers[%s]: Error creating user %s: %v", s.Name, su.Username, err)
+						continue
 					}
 				} else if updateExisting {
 					existingUsers = append(existingUsers, usr.ID)
@@ -1459,6 +1479,13 @@ func SyncExternalUsers(ctx context.Context, updateExisting bool) error {
 							log.Error("SyncExternalUsers[%s]: Error updating user %s: %v", s.Name, usr.Name, err)
 						}
 					}
+				} else {
+					continue
+				}
+
+				// Synchronize team memberships of mapped LDAP groups
+				if err = syncLDAPGroupTeams(s, usr, su.Groups); err != nil {
+					log.Error("SyncExternalUsers[%s]: Error synchronizing teams of user %s: %v", s.Name, usr.Name, err)
 				}
 			}
 
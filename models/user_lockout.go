@@ -0,0 +1,37 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"go.wandrs.dev/framework/modules/auth/lockout"
+	"go.wandrs.dev/framework/modules/log"
+	"go.wandrs.dev/framework/modules/setting"
+)
+
+// RecordFailedSignIn records a failed password or two-factor attempt of the user
+// and creates a notice if the account is locked because of it.
+func RecordFailedSignIn(u *User) {
+	if !lockout.RecordAccountFailure(u.ID) {
+		return
+	}
+	log.Warn("Account %s locked for %v after %d failed sign in attempts", u.Name, setting.LoginLockoutDuration, setting.LoginMaxFailedAttempts)
+	if err := CreateNotice(NoticeSecurity, "Account %s has been locked for %v after %d failed sign in attempts",
+		u.Name, setting.LoginLockoutDuration, setting.LoginMaxFailedAttempts); err != nil {
+		log.Error("CreateNotice: %v", err)
+	}
+}
+
+// RecordFailedSignInFromIP records a failed sign in attempt from the IP address
+// and creates a notice if the address is blocked because of it.
+func RecordFailedSignInFromIP(ip string) {
+	if !lockout.RecordIPFailure(ip) {
+		return
+	}
+	log.Warn("Sign in from %s blocked for %v after %d failed attempts", ip, setting.LoginLockoutDuration, setting.LoginMaxFailedAttemptsPerIP)
+	if err := CreateNotice(NoticeSecurity, "Sign in from %s has been blocked for %v after %d failed attempts",
+		ip, setting.LoginLockoutDuration, setting.LoginMaxFailedAttemptsPerIP); err != nil {
+		log.Error("CreateNotice: %v", err)
+	}
+}
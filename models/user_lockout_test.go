@@ -0,0 +1,40 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+	"time"
+
+	"go.wandrs.dev/framework/modules/auth/lockout"
+	"go.wandrs.dev/framework/modules/setting"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserSignIn_LockedOut(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	oldMax, oldWindow, oldDuration := setting.LoginMaxFailedAttempts, setting.LoginFailedAttemptsWindow, setting.LoginLockoutDuration
+	defer func() {
+		setting.LoginMaxFailedAttempts, setting.LoginFailedAttemptsWindow, setting.LoginLockoutDuration = oldMax, oldWindow, oldDuration
+	}()
+	setting.LoginMaxFailedAttempts, setting.LoginFailedAttemptsWindow, setting.LoginLockoutDuration = 2, time.Minute, time.Minute
+	defer lockout.ResetAccount(2)
+
+	_, err := UserSignIn("user2", "wrong")
+	assert.True(t, IsErrUserNotExist(err))
+	_, err = UserSignIn("user2", "wrong")
+	assert.True(t, IsErrUserNotExist(err))
+	AssertExistsAndLoadBean(t, &Notice{Type: NoticeSecurity})
+
+	// the correct password is rejected while the account is locked
+	_, err = UserSignIn("user2", "password")
+	assert.True(t, IsErrUserLockedOut(err))
+
+	lockout.ResetAccount(2)
+	u, err := UserSignIn("user2", "password")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, u.ID)
+}
@@ -0,0 +1,93 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"time"
+
+	"go.wandrs.dev/framework/modules/timeutil"
+)
+
+// UserSessionActivityInterval is how often the last activity of a session is updated
+const UserSessionActivityInterval = time.Minute
+
+// UserSession tracks a signed in session of a user, independently of the session provider.
+// The session refers to it by its ID, so that deleting it signs the session out.
+type UserSession struct {
+	ID          int64              `xorm:"pk autoincr"`
+	UID         int64              `xorm:"INDEX NOT NULL"`
+	UserAgent   string             `xorm:"TEXT"`
+	IPAddress   string             `xorm:"VARCHAR(64)"`
+	CreatedUnix timeutil.TimeStamp `xorm:"created"`
+	UpdatedUnix timeutil.TimeStamp `xorm:"INDEX"`
+}
+
+// CreateUserSession tracks a new session of a user
+func CreateUserSession(s *UserSession) error {
+	s.UpdatedUnix = timeutil.TimeStampNow()
+	_, err := x.Insert(s)
+	return err
+}
+
+// GetUserSessionByID returns the tracked session with the given ID
+func GetUserSessionByID(id int64) (*UserSession, error) {
+	s := new(UserSession)
+	has, err := x.ID(id).Get(s)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrUserSessionNotExist{ID: id}
+	}
+	return s, nil
+}
+
+// UpdateActivity updates the last activity and the IP address of the session,
+// at most once per UserSessionActivityInterval unless the address changed
+func (s *UserSession) UpdateActivity(ip string) error {
+	now := timeutil.TimeStampNow()
+	if s.IPAddress == ip && now-s.UpdatedUnix < timeutil.TimeStamp(UserSessionActivityInterval.Seconds()) {
+		return nil
+	}
+	s.IPAddress = ip
+	s.UpdatedUnix = now
+	_, err := x.ID(s.ID).Cols("ip_address", "updated_unix").Update(s)
+	return err
+}
+
+// ListUserSessions returns the tracked sessions of the user, most recently active first
+func ListUserSessions(uid int64) ([]*UserSession, error) {
+	sessions := make([]*UserSession, 0, 5)
+	return sessions, x.Where("uid = ?", uid).Desc("updated_unix").Find(&sessions)
+}
+
+// DeleteUserSession deletes a tracked session of the user, which signs it out
+func DeleteUserSession(uid, id int64) error {
+	cnt, err := x.Delete(&UserSession{ID: id, UID: uid})
+	if err != nil {
+		return err
+	} else if cnt != 1 {
+		return ErrUserSessionNotExist{ID: id, UID: uid}
+	}
+	return nil
+}
+
+// DeleteUserSessions deletes all tracked sessions of the user, which signs them out
+func DeleteUserSessions(uid int64) error {
+	return deleteUserSessions(x, uid, 0)
+}
+
+// DeleteOtherUserSessions deletes the tracked sessions of the user except the given one
+func DeleteOtherUserSessions(uid, keepID int64) error {
+	return deleteUserSessions(x, uid, keepID)
+}
+
+func deleteUserSessions(e Engine, uid, keepID int64) error {
+	sess := e.Where("uid = ?", uid)
+	if keepID != 0 {
+		sess.And("id != ?", keepID)
+	}
+	_, err := sess.Delete(new(UserSession))
+	return err
+}
@@ -0,0 +1,59 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+
+	"go.wandrs.dev/framework/modules/timeutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserSessions(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	first := &UserSession{UID: 2, UserAgent: "Firefox", IPAddress: "10.0.0.1"}
+	assert.NoError(t, CreateUserSession(first))
+	second := &UserSession{UID: 2, UserAgent: "Chrome", IPAddress: "10.0.0.2"}
+	assert.NoError(t, CreateUserSession(second))
+	other := &UserSession{UID: 4, UserAgent: "Safari", IPAddress: "10.0.0.3"}
+	assert.NoError(t, CreateUserSession(other))
+
+	sessions, err := ListUserSessions(2)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+
+	s, err := GetUserSessionByID(first.ID)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "Firefox", s.UserAgent)
+
+	// activity is only recorded once per interval unless the address changes
+	s.UpdatedUnix -= 10
+	assert.NoError(t, s.UpdateActivity("10.0.0.1"))
+	AssertExistsAndLoadBean(t, &UserSession{ID: first.ID, UpdatedUnix: first.UpdatedUnix})
+	s.UpdatedUnix -= timeutil.TimeStamp(UserSessionActivityInterval.Seconds())
+	assert.NoError(t, s.UpdateActivity("10.0.0.1"))
+	assert.NoError(t, s.UpdateActivity("10.0.0.4"))
+	updated := AssertExistsAndLoadBean(t, &UserSession{ID: first.ID}).(*UserSession)
+	assert.EqualValues(t, "10.0.0.4", updated.IPAddress)
+	assert.True(t, updated.UpdatedUnix >= timeutil.TimeStampNow()-1)
+
+	// sessions of other users cannot be deleted
+	err = DeleteUserSession(2, other.ID)
+	assert.True(t, IsErrUserSessionNotExist(err))
+
+	assert.NoError(t, DeleteOtherUserSessions(2, second.ID))
+	AssertNotExistsBean(t, &UserSession{ID: first.ID})
+	AssertExistsAndLoadBean(t, &UserSession{ID: second.ID})
+	AssertExistsAndLoadBean(t, &UserSession{ID: other.ID})
+
+	assert.NoError(t, DeleteUserSession(2, second.ID))
+	_, err = GetUserSessionByID(second.ID)
+	assert.True(t, IsErrUserSessionNotExist(err))
+
+	assert.NoError(t, DeleteUserSessions(4))
+	AssertNotExistsBean(t, &UserSession{UID: 4})
+}
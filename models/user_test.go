@@ -367,3 +367,18 @@ func TestGetMaileableUsersByIDs(t *testing.T) {
 		assert.Equal(t, results[1].ID, 4)
 	}
 }
+
+func TestDeleteUserKeepsOrgOAuth2Applications(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	user := AssertExistsAndLoadBean(t, &User{ID: 4}).(*User)
+
+	personal, err := CreateOAuth2Application(CreateOAuth2ApplicationOptions{Name: "personal", UserID: user.ID})
+	assert.NoError(t, err)
+	owned, err := CreateOAuth2Application(CreateOAuth2ApplicationOptions{Name: "owned by org", UserID: 3})
+	assert.NoError(t, err)
+
+	assert.NoError(t, RemoveOrgUser(3, user.ID))
+	assert.NoError(t, DeleteUser(user))
+	AssertNotExistsBean(t, &OAuth2Application{ID: personal.ID})
+	AssertExistsAndLoadBean(t, &OAuth2Application{ID: owned.ID, UID: 3})
+}
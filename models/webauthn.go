@@ -0,0 +1,149 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+
+	"go.wandrs.dev/framework/modules/auth/webauthn"
+	"go.wandrs.dev/framework/modules/timeutil"
+)
+
+// WebAuthnUserHandle returns the opaque user handle the credentials of the user are bound to
+func WebAuthnUserHandle(uid int64) []byte {
+	handle := make([]byte, 8)
+	binary.BigEndian.PutUint64(handle, uint64(uid))
+	return handle
+}
+
+// WebAuthnUserIDFromHandle returns the id of the user the user handle was created for
+func WebAuthnUserIDFromHandle(handle []byte) (int64, bool) {
+	if len(handle) != 8 {
+		return 0, false
+	}
+	return int64(binary.BigEndian.Uint64(handle)), true
+}
+
+// WebAuthnCredential represents a security key or platform authenticator registered by a user
+type WebAuthnCredential struct {
+	ID     int64 `xorm:"pk autoincr"`
+	Name   string
+	UserID int64 `xorm:"INDEX"`
+	// CredentialID is the unpadded base64url encoded id of the credential
+	CredentialID    string `xorm:"INDEX VARCHAR(410)"`
+	PublicKey       []byte
+	AttestationType string
+	AAGUID          []byte
+	SignCount       uint32   `xorm:"BIGINT"`
+	Transports      []string `xorm:"TEXT JSON"`
+	// Legacy marks credentials registered with the FIDO U2F API, they are scoped to the U2F application id
+	Legacy      bool               `xorm:"NOT NULL DEFAULT false"`
+	CreatedUnix timeutil.TimeStamp `xorm:"INDEX created"`
+	UpdatedUnix timeutil.TimeStamp `xorm:"INDEX updated"`
+}
+
+// TableName returns a better table name for WebAuthnCredential
+func (cred WebAuthnCredential) TableName() string {
+	return "webauthn_credential"
+}
+
+// RawCredentialID returns the decoded id of the credential
+func (cred *WebAuthnCredential) RawCredentialID() []byte {
+	id, _ := base64.RawURLEncoding.DecodeString(cred.CredentialID)
+	return id
+}
+
+// UpdateSignCount will update the database value of the sign counter
+func (cred *WebAuthnCredential) UpdateSignCount() error {
+	_, err := x.ID(cred.ID).Cols("sign_count").Update(cred)
+	return err
+}
+
+// WebAuthnCredentialList is a list of *WebAuthnCredential
+type WebAuthnCredentialList []*WebAuthnCredential
+
+// ToCredentialDescriptors returns the descriptors of the credentials sent to the browser
+func (list WebAuthnCredentialList) ToCredentialDescriptors() []webauthn.CredentialDescriptor {
+	descs := make([]webauthn.CredentialDescriptor, 0, len(list))
+	for _, cred := range list {
+		descs = append(descs, webauthn.NewCredentialDescriptor(cred.RawCredentialID(), cred.Transports))
+	}
+	return descs
+}
+
+// HasLegacy returns true if one of the credentials was registered with the FIDO U2F API
+func (list WebAuthnCredentialList) HasLegacy() bool {
+	for _, cred := range list {
+		if cred.Legacy {
+			return true
+		}
+	}
+	return false
+}
+
+// GetWebAuthnCredentialsByUID returns all WebAuthn credentials of the given user
+func GetWebAuthnCredentialsByUID(uid int64) (WebAuthnCredentialList, error) {
+	return getWebAuthnCredentialsByUID(x, uid)
+}
+
+func getWebAuthnCredentialsByUID(e Engine, uid int64) (WebAuthnCredentialList, error) {
+	creds := make(WebAuthnCredentialList, 0)
+	return creds, e.Where("user_id = ?", uid).Find(&creds)
+}
+
+// ExistsWebAuthnCredentialsForUID returns true if the given user has registered WebAuthn credentials
+func ExistsWebAuthnCredentialsForUID(uid int64) (bool, error) {
+	return x.Where("user_id = ?", uid).Exist(&WebAuthnCredential{})
+}
+
+// GetWebAuthnCredentialByID returns the WebAuthn credential with the given id
+func GetWebAuthnCredentialByID(id int64) (*WebAuthnCredential, error) {
+	cred := new(WebAuthnCredential)
+	if found, err := x.ID(id).Get(cred); err != nil {
+		return nil, err
+	} else if !found {
+		return nil, ErrWebAuthnCredentialNotExist{ID: id}
+	}
+	return cred, nil
+}
+
+// GetWebAuthnCredentialByCredID returns the WebAuthn credential of the user with the given raw credential id
+func GetWebAuthnCredentialByCredID(uid int64, credID []byte) (*WebAuthnCredential, error) {
+	cred := &WebAuthnCredential{
+		UserID:       uid,
+		CredentialID: base64.RawURLEncoding.EncodeToString(credID),
+	}
+	if found, err := x.Get(cred); err != nil {
+		return nil, err
+	} else if !found {
+		return nil, ErrWebAuthnCredentialNotExist{CredentialID: cred.CredentialID}
+	}
+	return cred, nil
+}
+
+// CreateCredential will create a new WebAuthnCredential from the given verified credential
+func CreateCredential(uid int64, name string, cred *webauthn.Credential) (*WebAuthnCredential, error) {
+	c := &WebAuthnCredential{
+		UserID:          uid,
+		Name:            name,
+		CredentialID:    base64.RawURLEncoding.EncodeToString(cred.ID),
+		PublicKey:       cred.PublicKey,
+		AttestationType: cred.AttestationType,
+		AAGUID:          cred.AAGUID,
+		SignCount:       cred.SignCount,
+		Transports:      cred.Transports,
+	}
+	if _, err := x.InsertOne(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// DeleteCredential will delete the WebAuthn credential with the given id of the user
+func DeleteCredential(id, uid int64) (bool, error) {
+	deleted, err := x.Delete(&WebAuthnCredential{ID: id, UserID: uid})
+	return deleted > 0, err
+}
@@ -0,0 +1,103 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+
+	"go.wandrs.dev/framework/modules/auth/webauthn"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetWebAuthnCredentialByID(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	res, err := GetWebAuthnCredentialByID(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "WebAuthn credential", res.Name)
+	assert.Equal(t, []byte("TestCredential"), res.RawCredentialID())
+
+	_, err = GetWebAuthnCredentialByID(342432)
+	assert.Error(t, err)
+	assert.True(t, IsErrWebAuthnCredentialNotExist(err))
+}
+
+func TestGetWebAuthnCredentialByCredID(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	res, err := GetWebAuthnCredentialByCredID(24, []byte("TestCredential"))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, res.ID)
+
+	// credentials are looked up per user
+	_, err = GetWebAuthnCredentialByCredID(1, []byte("TestCredential"))
+	assert.True(t, IsErrWebAuthnCredentialNotExist(err))
+}
+
+func TestGetWebAuthnCredentialsByUID(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	res, err := GetWebAuthnCredentialsByUID(24)
+	assert.NoError(t, err)
+	assert.Len(t, res, 1)
+	assert.Equal(t, "WebAuthn credential", res[0].Name)
+	assert.False(t, res.HasLegacy())
+	assert.Len(t, res.ToCredentialDescriptors(), 1)
+
+	exists, err := ExistsWebAuthnCredentialsForUID(24)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = ExistsWebAuthnCredentialsForUID(1)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestWebAuthnUserHandle(t *testing.T) {
+	uid, ok := WebAuthnUserIDFromHandle(WebAuthnUserHandle(24))
+	assert.True(t, ok)
+	assert.EqualValues(t, 24, uid)
+
+	_, ok = WebAuthnUserIDFromHandle([]byte{24})
+	assert.False(t, ok)
+}
+
+func TestWebAuthnCredential_TableName(t *testing.T) {
+	assert.Equal(t, "webauthn_credential", WebAuthnCredential{}.TableName())
+}
+
+func TestWebAuthnCredential_UpdateLargeCounter(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	cred := AssertExistsAndLoadBean(t, &WebAuthnCredential{ID: 1}).(*WebAuthnCredential)
+	cred.SignCount = 0xffffffff
+	assert.NoError(t, cred.UpdateSignCount())
+	AssertExistsIf(t, true, &WebAuthnCredential{ID: 1, SignCount: 0xffffffff})
+}
+
+func TestCreateCredential(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	res, err := CreateCredential(1, "WebAuthn Created Credential", &webauthn.Credential{ID: []byte("Test"), Transports: []string{"usb", "nfc"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "WebAuthn Created Credential", res.Name)
+	assert.Equal(t, "VGVzdA", res.CredentialID)
+
+	cred := AssertExistsAndLoadBean(t, &WebAuthnCredential{ID: res.ID}).(*WebAuthnCredential)
+	assert.Equal(t, []string{"usb", "nfc"}, cred.Transports)
+}
+
+func TestDeleteCredential(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	deleted, err := DeleteCredential(1, 1)
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+	AssertExistsIf(t, true, &WebAuthnCredential{ID: 1})
+
+	deleted, err = DeleteCredential(1, 24)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	AssertExistsIf(t, false, &WebAuthnCredential{ID: 1})
+}
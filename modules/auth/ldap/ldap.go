@@ -15,6 +15,7 @@ import (
 	"go.wandrs.dev/framework/modules/log"
 
 	"github.com/go-ldap/ldap/v3"
+	jsoniter "github.com/json-iterator/go"
 )
 
 // SecurityProtocol protocol type
@@ -56,6 +57,8 @@ type Source struct {
 	GroupFilter           string // Group Name Filter
 	GroupMemberUID        string // Group Attribute containing array of UserUID
 	UserUID               string // User Attribute listed in Group
+	GroupTeamMap          string // JSON mapping of group DNs to organization teams
+	GroupTeamMapRemoval   bool   // Remove user from mapped teams of groups it is no longer a member of
 }
 
 // SearchResult : user data
@@ -67,6 +70,7 @@ type SearchResult struct {
 	SSHPublicKey []string // SSH Public Key
 	IsAdmin      bool     // if user is administrator
 	IsRestricted bool     // if user is restricted
+	Groups       []string // DNs of the groups the user is a member of, if group checking is enabled
 }
 
 func (ls *Source) sanitizedUserQuery(username string) (string, bool) {
@@ -113,6 +117,63 @@ func (ls *Source) sanitizedGroupDN(groupDn string) (string, bool) {
 	return groupDn, true
 }
 
+// searchGroups returns the groups matching the group filter with their member attribute
+func (ls *Source) searchGroups(l *ldap.Conn) ([]*ldap.Entry, error) {
+	groupFilter, ok := ls.sanitizedGroupFilter(ls.GroupFilter)
+	if !ok {
+		return nil, fmt.Errorf("invalid group filter: %s", ls.GroupFilter)
+	}
+	groupDN, ok := ls.sanitizedGroupDN(ls.GroupDN)
+	if !ok {
+		return nil, fmt.Errorf("invalid group search base: %s", ls.GroupDN)
+	}
+
+	log.Trace("Fetching groups '%v' with filter '%s' and base '%s'", ls.GroupMemberUID, groupFilter, groupDN)
+	groupSearch := ldap.NewSearchRequest(
+		groupDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false, groupFilter,
+		[]string{ls.GroupMemberUID},
+		nil)
+
+	var srg *ldap.SearchResult
+	var err error
+	if ls.UsePagedSearch() {
+		srg, err = l.SearchWithPaging(groupSearch, ls.SearchPageSize)
+	} else {
+		srg, err = l.Search(groupSearch)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return srg.Entries, nil
+}
+
+// groupsOf returns the DNs of the groups listing the user with the given DN and UserUID attribute value
+func (ls *Source) groupsOf(groups []*ldap.Entry, userDN, uid string) []string {
+	var dns []string
+	for _, group := range groups {
+		for _, member := range group.GetAttributeValues(ls.GroupMemberUID) {
+			if (ls.UserUID == "dn" && member == userDN) || (uid != "" && member == uid) {
+				dns = append(dns, group.DN)
+				break
+			}
+		}
+	}
+	return dns
+}
+
+// ParseGroupTeamMap returns the teams mapped to each group DN, keyed by organization name
+func (ls *Source) ParseGroupTeamMap() (map[string]map[string][]string, error) {
+	groupTeamMap := make(map[string]map[string][]string)
+	if strings.TrimSpace(ls.GroupTeamMap) == "" {
+		return groupTeamMap, nil
+	}
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	if err := json.Unmarshal([]byte(ls.GroupTeamMap), &groupTeamMap); err != nil {
+		return nil, err
+	}
+	return groupTeamMap, nil
+}
+
 func (ls *Source) findUserDN(l *ldap.Conn, name string) (string, bool) {
 	log.Trace("Search for LDAP user: %s", name)
 
@@ -343,43 +404,19 @@ func (ls *Source) SearchEntry(name, passwd string, directBind bool) *SearchResul
 	uid := sr.Entries[0].GetAttributeValue(ls.UserUID)
 
 	// Check group membership
+	var groups []string
 	if ls.GroupsEnabled {
-		groupFilter, ok := ls.sanitizedGroupFilter(ls.GroupFilter)
-		if !ok {
-			return nil
-		}
-		groupDN, ok := ls.sanitizedGroupDN(ls.GroupDN)
-		if !ok {
-			return nil
-		}
-
-		log.Trace("Fetching groups '%v' with filter '%s' and base '%s'", ls.GroupMemberUID, groupFilter, groupDN)
-		groupSearch := ldap.NewSearchRequest(
-			groupDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false, groupFilter,
-			[]string{ls.GroupMemberUID},
-			nil)
-
-		srg, err := l.Search(groupSearch)
+		groupEntries, err := ls.searchGroups(l)
 		if err != nil {
 			log.Error("LDAP group search failed: %v", err)
 			return nil
-		} else if len(srg.Entries) < 1 {
+		} else if len(groupEntries) < 1 {
 			log.Error("LDAP group search failed: 0 entries")
 			return nil
 		}
 
-		isMember := false
-	Entries:
-		for _, group := range srg.Entries {
-			for _, member := range group.GetAttributeValues(ls.GroupMemberUID) {
-				if (ls.UserUID == "dn" && member == sr.Entries[0].DN) || member == uid {
-					isMember = true
-					break Entries
-				}
-			}
-		}
-
-		if !isMember {
+		groups = ls.groupsOf(groupEntries, sr.Entries[0].DN, uid)
+		if len(groups) == 0 {
 			log.Error("LDAP group membership test failed")
 			return nil
 		}
@@ -410,6 +447,7 @@ func (ls *Source) SearchEntry(name, passwd string, directBind bool) *SearchResul
 		SSHPublicKey: sshPublicKey,
 		IsAdmin:      isAdmin,
 		IsRestricted: isRestricted,
+		Groups:       groups,
 	}
 }
 
@@ -444,6 +482,9 @@ func (ls *Source) SearchEntries() ([]*SearchResult, error) {
 	isAttributeSSHPublicKeySet := len(strings.TrimSpace(ls.AttributeSSHPublicKey)) > 0
 
 	attribs := []string{ls.AttributeUsername, ls.AttributeName, ls.AttributeSurname, ls.AttributeMail}
+	if ls.GroupsEnabled && len(strings.TrimSpace(ls.UserUID)) > 0 {
+		attribs = append(attribs, ls.UserUID)
+	}
 	if isAttributeSSHPublicKeySet {
 		attribs = append(attribs, ls.AttributeSSHPublicKey)
 	}
@@ -464,6 +505,15 @@ func (ls *Source) SearchEntries() ([]*SearchResult, error) {
 		return nil, err
 	}
 
+	var groupEntries []*ldap.Entry
+	if ls.GroupsEnabled {
+		groupEntries, err = ls.searchGroups(l)
+		if err != nil {
+			log.Error("LDAP group search failed: %v", err)
+			return nil, err
+		}
+	}
+
 	result := make([]*SearchResult, len(sr.Entries))
 
 	for i, v := range sr.Entries {
@@ -480,6 +530,9 @@ func (ls *Source) SearchEntries() ([]*SearchResult, error) {
 		if isAttributeSSHPublicKeySet {
 			result[i].SSHPublicKey = v.GetAttributeValues(ls.AttributeSSHPublicKey)
 		}
+		if ls.GroupsEnabled {
+			result[i].Groups = ls.groupsOf(groupEntries, v.DN, v.GetAttributeValue(ls.UserUID))
+		}
 	}
 
 	return result, nil
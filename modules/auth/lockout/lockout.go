@@ -0,0 +1,153 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package lockout counts failed sign in attempts per account and per IP address
+// and locks them out temporarily once a threshold is exceeded. The counters are
+// kept in the configured cache so that instances sharing it share the counters.
+package lockout
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	mc "go.wandrs.dev/cache"
+	"go.wandrs.dev/framework/modules/cache"
+	"go.wandrs.dev/framework/modules/log"
+	"go.wandrs.dev/framework/modules/setting"
+)
+
+var (
+	fallback     mc.Cache
+	fallbackOnce sync.Once
+	failuresLock sync.Mutex
+)
+
+// store returns the configured cache, or a memory cache of this instance if caching is disabled
+func store() mc.Cache {
+	if c := cache.GetCache(); c != nil {
+		return c
+	}
+	fallbackOnce.Do(func() {
+		var err error
+		if fallback, err = mc.NewCacher(mc.Options{Adapter: "memory", Interval: 60}); err != nil {
+			log.Fatal("Unable to create the lockout cache: %v", err)
+		}
+	})
+	return fallback
+}
+
+func accountKey(uid int64) string {
+	return fmt.Sprintf("lockout_account_%d", uid)
+}
+
+// ipKey returns the key of the address, which may be a remote address including the port
+func ipKey(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return "lockout_ip_" + addr
+}
+
+func toInt64(v interface{}) int64 {
+	if v == nil {
+		return 0
+	}
+	n, _ := strconv.ParseInt(fmt.Sprint(v), 10, 64)
+	return n
+}
+
+func lockedUntil(key string) (time.Time, bool) {
+	until := toInt64(store().Get(key + "_until"))
+	if until <= time.Now().Unix() {
+		return time.Time{}, false
+	}
+	return time.Unix(until, 0), true
+}
+
+// incrFailures increases the failed attempts of the key and returns their number.
+// Caches that cannot do so atomically are local to this instance, so a lock is enough.
+func incrFailures(c mc.Cache, key string) (int64, error) {
+	window := int64(setting.LoginFailedAttemptsWindow.Seconds())
+	if counter, ok := c.(cache.Counter); ok {
+		return counter.IncrWithTimeout(key, window)
+	}
+
+	failuresLock.Lock()
+	defer failuresLock.Unlock()
+	if !c.IsExist(key) || c.Incr(key) != nil {
+		if err := c.Put(key, 1, window); err != nil {
+			return 0, err
+		}
+	}
+	return toInt64(c.Get(key)), nil
+}
+
+// recordFailure counts a failed attempt and returns true if the key has been locked because of it
+func recordFailure(key string, max int) bool {
+	if max <= 0 {
+		return false
+	}
+	c := store()
+	failures := key + "_failures"
+	n, err := incrFailures(c, failures)
+	if err != nil {
+		log.Error("Unable to record failed attempt of %s: %v", key, err)
+		return false
+	}
+	if n < int64(max) {
+		return false
+	}
+
+	until := time.Now().Add(setting.LoginLockoutDuration)
+	if err := c.Put(key+"_until", until.Unix(), int64(setting.LoginLockoutDuration.Seconds())); err != nil {
+		log.Error("Unable to lock %s: %v", key, err)
+		return false
+	}
+	_ = c.Delete(failures)
+	return true
+}
+
+func reset(key string) {
+	c := store()
+	_ = c.Delete(key + "_failures")
+	_ = c.Delete(key + "_until")
+}
+
+// AccountLockedUntil returns when the lockout of the account ends, if it is locked
+func AccountLockedUntil(uid int64) (time.Time, bool) {
+	return lockedUntil(accountKey(uid))
+}
+
+// IsAccountLocked returns true if the account is locked
+func IsAccountLocked(uid int64) bool {
+	_, locked := AccountLockedUntil(uid)
+	return locked
+}
+
+// RecordAccountFailure records a failed attempt to sign in to the account and
+// returns true if the account has been locked because of it
+func RecordAccountFailure(uid int64) bool {
+	return recordFailure(accountKey(uid), setting.LoginMaxFailedAttempts)
+}
+
+// ResetAccount clears the failed attempts and the lockout of the account
+func ResetAccount(uid int64) {
+	reset(accountKey(uid))
+}
+
+// IsIPBlocked returns true if sign in from the IP address is blocked. The address
+// may include a port, which is ignored.
+func IsIPBlocked(ip string) bool {
+	_, blocked := lockedUntil(ipKey(ip))
+	return blocked
+}
+
+// RecordIPFailure records a failed attempt to sign in from the IP address and
+// returns true if the address has been blocked because of it
+func RecordIPFailure(ip string) bool {
+	return recordFailure(ipKey(ip), setting.LoginMaxFailedAttemptsPerIP)
+}
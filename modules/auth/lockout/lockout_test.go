@@ -0,0 +1,84 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package lockout
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"go.wandrs.dev/framework/modules/setting"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setLimits(t *testing.T, account, ip int) {
+	oldAccount, oldIP := setting.LoginMaxFailedAttempts, setting.LoginMaxFailedAttemptsPerIP
+	oldWindow, oldDuration := setting.LoginFailedAttemptsWindow, setting.LoginLockoutDuration
+	setting.LoginMaxFailedAttempts, setting.LoginMaxFailedAttemptsPerIP = account, ip
+	setting.LoginFailedAttemptsWindow, setting.LoginLockoutDuration = time.Minute, time.Minute
+	t.Cleanup(func() {
+		setting.LoginMaxFailedAttempts, setting.LoginMaxFailedAttemptsPerIP = oldAccount, oldIP
+		setting.LoginFailedAttemptsWindow, setting.LoginLockoutDuration = oldWindow, oldDuration
+	})
+}
+
+func TestAccountLockout(t *testing.T) {
+	setLimits(t, 3, 0)
+	defer ResetAccount(1)
+
+	assert.False(t, RecordAccountFailure(1))
+	assert.False(t, RecordAccountFailure(1))
+	assert.False(t, IsAccountLocked(1))
+	assert.True(t, RecordAccountFailure(1))
+	assert.True(t, IsAccountLocked(1))
+	assert.False(t, IsAccountLocked(2))
+
+	until, locked := AccountLockedUntil(1)
+	assert.True(t, locked)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), until, 2*time.Second)
+
+	ResetAccount(1)
+	assert.False(t, IsAccountLocked(1))
+	assert.False(t, RecordAccountFailure(1))
+}
+
+func TestAccountLockoutDisabled(t *testing.T) {
+	setLimits(t, 0, 0)
+	defer ResetAccount(1)
+
+	for i := 0; i < 10; i++ {
+		assert.False(t, RecordAccountFailure(1))
+	}
+	assert.False(t, IsAccountLocked(1))
+}
+
+func TestIPBlocking(t *testing.T) {
+	setLimits(t, 0, 2)
+	defer reset(ipKey("10.0.0.1"))
+
+	assert.False(t, RecordIPFailure("10.0.0.1:1234"))
+	assert.True(t, RecordIPFailure("10.0.0.1:5678"))
+	assert.True(t, IsIPBlocked("10.0.0.1"))
+	assert.True(t, IsIPBlocked("10.0.0.1:4321"))
+	assert.False(t, IsIPBlocked("10.0.0.2:1234"))
+}
+
+func TestConcurrentFailures(t *testing.T) {
+	setLimits(t, 100, 0)
+	defer ResetAccount(1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 99; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			RecordAccountFailure(1)
+		}()
+	}
+	wg.Wait()
+	assert.False(t, IsAccountLocked(1))
+	assert.True(t, RecordAccountFailure(1))
+}
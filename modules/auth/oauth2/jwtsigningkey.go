@@ -0,0 +1,582 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oauth2
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.wandrs.dev/framework/modules/log"
+	"go.wandrs.dev/framework/modules/setting"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// ErrInvalidAlgorithmType represents an invalid algorithm error.
+type ErrInvalidAlgorithmType struct {
+	Algorithm string
+}
+
+func (err ErrInvalidAlgorithmType) Error() string {
+	return fmt.Sprintf("JWT signing algorithm is not supported: %s", err.Algorithm)
+}
+
+// IsErrInvalidAlgorithmType checks if an error is a ErrInvalidAlgorithmType.
+func IsErrInvalidAlgorithmType(err error) bool {
+	_, ok := err.(ErrInvalidAlgorithmType)
+	return ok
+}
+
+// JWTSigningKey represents a algorithm/key pair to sign JWTs
+type JWTSigningKey interface {
+	IsSymmetric() bool
+	SigningMethod() jwt.SigningMethod
+	SignKey() interface{}
+	VerifyKey() interface{}
+	ToJWK() (map[string]string, error)
+	PreProcessToken(*jwt.Token)
+}
+
+type hmacSigningKey struct {
+	signingMethod jwt.SigningMethod
+	secret        []byte
+}
+
+func (key hmacSigningKey) IsSymmetric() bool {
+	return true
+}
+
+func (key hmacSigningKey) SigningMethod() jwt.SigningMethod {
+	return key.signingMethod
+}
+
+func (key hmacSigningKey) SignKey() interface{} {
+	return key.secret
+}
+
+func (key hmacSigningKey) VerifyKey() interface{} {
+	return key.secret
+}
+
+func (key hmacSigningKey) ToJWK() (map[string]string, error) {
+	return map[string]string{
+		"kty": "oct",
+		"alg": key.SigningMethod().Alg(),
+	}, nil
+}
+
+func (key hmacSigningKey) PreProcessToken(*jwt.Token) {}
+
+// identifiableSigningKey is a signing key which is published with a key id
+type identifiableSigningKey interface {
+	kid() string
+}
+
+type rsaSigningKey struct {
+	signingMethod jwt.SigningMethod
+	key           *rsa.PrivateKey
+	id            string
+}
+
+func newRSASigningKey(signingMethod jwt.SigningMethod, key *rsa.PrivateKey) (rsaSigningKey, error) {
+	kid, err := createPublicKeyThumbprint(&key.PublicKey)
+	if err != nil {
+		return rsaSigningKey{}, err
+	}
+
+	return rsaSigningKey{
+		signingMethod,
+		key,
+		base64.RawURLEncoding.EncodeToString(kid),
+	}, nil
+}
+
+func (key rsaSigningKey) IsSymmetric() bool {
+	return false
+}
+
+func (key rsaSigningKey) SigningMethod() jwt.SigningMethod {
+	return key.signingMethod
+}
+
+func (key rsaSigningKey) SignKey() interface{} {
+	return key.key
+}
+
+func (key rsaSigningKey) VerifyKey() interface{} {
+	return key.key.Public()
+}
+
+func (key rsaSigningKey) ToJWK() (map[string]string, error) {
+	pubKey := key.key.Public().(*rsa.PublicKey)
+
+	return map[string]string{
+		"kty": "RSA",
+		"alg": key.SigningMethod().Alg(),
+		"use": "sig",
+		"kid": key.id,
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pubKey.E)).Bytes()),
+		"n":   base64.RawURLEncoding.EncodeToString(pubKey.N.Bytes()),
+	}, nil
+}
+
+func (key rsaSigningKey) PreProcessToken(token *jwt.Token) {
+	token.Header["kid"] = key.id
+}
+
+func (key rsaSigningKey) kid() string {
+	return key.id
+}
+
+type ecdsaSigningKey struct {
+	signingMethod jwt.SigningMethod
+	key           *ecdsa.PrivateKey
+	id            string
+}
+
+func newECDSASigningKey(signingMethod jwt.SigningMethod, key *ecdsa.PrivateKey) (ecdsaSigningKey, error) {
+	kid, err := createPublicKeyThumbprint(&key.PublicKey)
+	if err != nil {
+		return ecdsaSigningKey{}, err
+	}
+
+	return ecdsaSigningKey{
+		signingMethod,
+		key,
+		base64.RawURLEncoding.EncodeToString(kid),
+	}, nil
+}
+
+func (key ecdsaSigningKey) IsSymmetric() bool {
+	return false
+}
+
+func (key ecdsaSigningKey) SigningMethod() jwt.SigningMethod {
+	return key.signingMethod
+}
+
+func (key ecdsaSigningKey) SignKey() interface{} {
+	return key.key
+}
+
+func (key ecdsaSigningKey) VerifyKey() interface{} {
+	return key.key.Public()
+}
+
+func (key ecdsaSigningKey) ToJWK() (map[string]string, error) {
+	pubKey := key.key.Public().(*ecdsa.PublicKey)
+	size := (pubKey.Curve.Params().BitSize + 7) / 8
+
+	return map[string]string{
+		"kty": "EC",
+		"alg": key.SigningMethod().Alg(),
+		"use": "sig",
+		"kid": key.id,
+		"crv": pubKey.Params().Name,
+		"x":   base64.RawURLEncoding.EncodeToString(padBytes(pubKey.X.Bytes(), size)),
+		"y":   base64.RawURLEncoding.EncodeToString(padBytes(pubKey.Y.Bytes(), size)),
+	}, nil
+}
+
+func (key ecdsaSigningKey) PreProcessToken(token *jwt.Token) {
+	token.Header["kid"] = key.id
+}
+
+func (key ecdsaSigningKey) kid() string {
+	return key.id
+}
+
+// padBytes left pads b with zeros to the given size as required for JWK coordinates
+func padBytes(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	padded := make([]byte, size)
+	copy(padded[size-len(b):], b)
+	return padded
+}
+
+// createPublicKeyThumbprint creates a JWK thumbprint of the public key as described in RFC 7638
+func createPublicKeyThumbprint(publicKey interface{}) ([]byte, error) {
+	var canonical string
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		canonical = fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`,
+			base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		)
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		canonical = fmt.Sprintf(`{"crv":"%s","kty":"EC","x":"%s","y":"%s"}`,
+			key.Params().Name,
+			base64.RawURLEncoding.EncodeToString(padBytes(key.X.Bytes(), size)),
+			base64.RawURLEncoding.EncodeToString(padBytes(key.Y.Bytes(), size)),
+		)
+	default:
+		return nil, fmt.Errorf("unsupported public key type: %T", publicKey)
+	}
+
+	hash := sha256.Sum256([]byte(canonical))
+	return hash[:], nil
+}
+
+// CreateJWTSigningKey creates a signing key from an algorithm / key pair.
+func CreateJWTSigningKey(algorithm string, key interface{}) (JWTSigningKey, error) {
+	var signingMethod jwt.SigningMethod
+	switch algorithm {
+	case "HS256":
+		signingMethod = jwt.SigningMethodHS256
+	case "HS384":
+		signingMethod = jwt.SigningMethodHS384
+	case "HS512":
+		signingMethod = jwt.SigningMethodHS512
+
+	case "RS256":
+		signingMethod = jwt.SigningMethodRS256
+	case "RS384":
+		signingMethod = jwt.SigningMethodRS384
+	case "RS512":
+		signingMethod = jwt.SigningMethodRS512
+
+	case "ES256":
+		signingMethod = jwt.SigningMethodES256
+	case "ES384":
+		signingMethod = jwt.SigningMethodES384
+	case "ES512":
+		signingMethod = jwt.SigningMethodES512
+	default:
+		return nil, ErrInvalidAlgorithmType{algorithm}
+	}
+
+	switch signingMethod.(type) {
+	case *jwt.SigningMethodECDSA:
+		privateKey, ok := key.(*ecdsa.PrivateKey)
+		if !ok || privateKey.Curve.Params().BitSize != signingMethod.(*jwt.SigningMethodECDSA).CurveBits {
+			return nil, jwt.ErrInvalidKeyType
+		}
+		return newECDSASigningKey(signingMethod, privateKey)
+	case *jwt.SigningMethodRSA:
+		privateKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, jwt.ErrInvalidKeyType
+		}
+		return newRSASigningKey(signingMethod, privateKey)
+	default:
+		secret, ok := key.([]byte)
+		if !ok {
+			return nil, jwt.ErrInvalidKeyType
+		}
+		return hmacSigningKey{signingMethod, secret}, nil
+	}
+}
+
+// retiredSigningKey is a previous signing key which is still accepted for verification
+type retiredSigningKey struct {
+	key       JWTSigningKey
+	retiredAt time.Time
+}
+
+var (
+	signingKeysLock    sync.RWMutex
+	defaultSigningKey  JWTSigningKey
+	retiredSigningKeys []retiredSigningKey
+	lastSigningKeyLoad time.Time
+)
+
+// signingKeyReloadInterval limits how often an unknown kid causes the keys to be reloaded from disk
+const signingKeyReloadInterval = time.Minute
+
+// DefaultSigningKey returns the key used to sign new tokens
+func DefaultSigningKey() JWTSigningKey {
+	signingKeysLock.RLock()
+	defer signingKeysLock.RUnlock()
+	return defaultSigningKey
+}
+
+// InitSigningKey loads the configured signing key, generating a new key
+// on first start if none exists yet.
+func InitSigningKey() error {
+	signingKeysLock.Lock()
+	defer signingKeysLock.Unlock()
+	return loadSigningKeys()
+}
+
+// loadSigningKeys (re)loads the current and retired keys, the caller must hold signingKeysLock
+func loadSigningKeys() error {
+	lastSigningKeyLoad = time.Now()
+
+	switch setting.OAuth2.JWTSigningAlgorithm {
+	case "HS256", "HS384", "HS512":
+		key, err := CreateJWTSigningKey(setting.OAuth2.JWTSigningAlgorithm, setting.OAuth2.JWTSecretBytes)
+		if err != nil {
+			return err
+		}
+		defaultSigningKey = key
+		retiredSigningKeys = nil
+		return nil
+	}
+
+	keyPath := setting.OAuth2.JWTSigningPrivateKeyFile
+	key, err := loadOrCreateAsymmetricKey(keyPath)
+	if err != nil {
+		return fmt.Errorf("error loading JWT signing key from %s: %v", keyPath, err)
+	}
+
+	signingKey, err := CreateJWTSigningKey(setting.OAuth2.JWTSigningAlgorithm, key)
+	if jwt.ErrInvalidKeyType == err {
+		// the algorithm has been changed to a different key type: retire the old key
+		log.Info("JWT signing key %s does not match algorithm %s, generating a new key", keyPath, setting.OAuth2.JWTSigningAlgorithm)
+		if err = retireSigningKeyFile(keyPath); err != nil {
+			return err
+		}
+		if key, err = loadOrCreateAsymmetricKey(keyPath); err != nil {
+			return err
+		}
+		signingKey, err = CreateJWTSigningKey(setting.OAuth2.JWTSigningAlgorithm, key)
+	}
+	if err != nil {
+		return err
+	}
+	defaultSigningKey = signingKey
+
+	retiredSigningKeys, err = loadRetiredSigningKeys(keyPath)
+	return err
+}
+
+// RotateSigningKey retires the current signing key and replaces it with a newly generated one.
+// Tokens signed by the retired key stay valid for JWT_SIGNING_KEY_GRACE_PERIOD.
+func RotateSigningKey() error {
+	signingKeysLock.Lock()
+	defer signingKeysLock.Unlock()
+
+	if defaultSigningKey == nil {
+		return fmt.Errorf("JWT signing key is not initialized")
+	}
+	if defaultSigningKey.IsSymmetric() {
+		return fmt.Errorf("symmetric JWT signing keys cannot be rotated, change JWT_SECRET instead")
+	}
+
+	keyPath := setting.OAuth2.JWTSigningPrivateKeyFile
+	if err := retireSigningKeyFile(keyPath); err != nil {
+		return err
+	}
+	if err := loadSigningKeys(); err != nil {
+		return err
+	}
+	log.Info("JWT signing key rotated, new key id: %s", defaultSigningKey.(identifiableSigningKey).kid())
+	return nil
+}
+
+// GetSigningKeyByID returns the current or a retired key within the grace period with the given kid.
+// Unknown kids reload the keys from disk as another instance may have rotated them.
+func GetSigningKeyByID(kid string) JWTSigningKey {
+	if key := findSigningKeyByID(kid); key != nil {
+		return key
+	}
+
+	signingKeysLock.Lock()
+	defer signingKeysLock.Unlock()
+	if defaultSigningKey == nil || defaultSigningKey.IsSymmetric() || time.Since(lastSigningKeyLoad) < signingKeyReloadInterval {
+		return nil
+	}
+	if err := loadSigningKeys(); err != nil {
+		log.Error("Unable to reload JWT signing keys: %v", err)
+		return nil
+	}
+	return findSigningKeyByIDLocked(kid)
+}
+
+func findSigningKeyByID(kid string) JWTSigningKey {
+	signingKeysLock.RLock()
+	defer signingKeysLock.RUnlock()
+	return findSigningKeyByIDLocked(kid)
+}
+
+func findSigningKeyByIDLocked(kid string) JWTSigningKey {
+	for _, key := range verificationKeysLocked() {
+		if k, ok := key.(identifiableSigningKey); ok && k.kid() == kid {
+			return key
+		}
+	}
+	return nil
+}
+
+// VerificationKeys returns the current signing key followed by all retired keys
+// which are still within their grace period.
+func VerificationKeys() []JWTSigningKey {
+	signingKeysLock.RLock()
+	defer signingKeysLock.RUnlock()
+	return verificationKeysLocked()
+}
+
+func verificationKeysLocked() []JWTSigningKey {
+	if defaultSigningKey == nil {
+		return nil
+	}
+	keys := []JWTSigningKey{defaultSigningKey}
+	for _, retired := range retiredSigningKeys {
+		if time.Since(retired.retiredAt) < setting.OAuth2.JWTSigningKeyGracePeriod {
+			keys = append(keys, retired.key)
+		}
+	}
+	return keys
+}
+
+// loadOrCreateAsymmetricKey loads the private key from the PEM file at keyPath,
+// generating a new key of the configured type if the file does not exist.
+func loadOrCreateAsymmetricKey(keyPath string) (interface{}, error) {
+	if _, err := os.Stat(keyPath); os.IsNotExist(err) {
+		key, err := generateAsymmetricKey(setting.OAuth2.JWTSigningAlgorithm)
+		if err != nil {
+			return nil, err
+		}
+		if err := saveAsymmetricKey(keyPath, key); err != nil {
+			return nil, err
+		}
+		log.Info("Generated new JWT signing key %s", keyPath)
+		return key, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return readAsymmetricKey(keyPath)
+}
+
+func generateAsymmetricKey(algorithm string) (interface{}, error) {
+	switch algorithm {
+	case "RS256", "RS384", "RS512":
+		return rsa.GenerateKey(rand.Reader, 4096)
+	case "ES256":
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "ES384":
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case "ES512":
+		return ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	default:
+		return nil, ErrInvalidAlgorithmType{algorithm}
+	}
+}
+
+func saveAsymmetricKey(keyPath string, key interface{}) error {
+	bytes, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(keyPath), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(keyPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err = f.Close(); err != nil {
+			log.Error("Close: %v", err)
+		}
+	}()
+
+	return pem.Encode(f, &pem.Block{Type: "PRIVATE KEY", Bytes: bytes})
+}
+
+func readAsymmetricKey(keyPath string) (interface{}, error) {
+	bytes, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(bytes)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("no valid PEM data found in %s", keyPath)
+	}
+
+	return x509.ParsePKCS8PrivateKey(block.Bytes)
+}
+
+// retireSigningKeyFile moves the key file to <keyPath>.<unix timestamp>
+func retireSigningKeyFile(keyPath string) error {
+	return os.Rename(keyPath, keyPath+"."+strconv.FormatInt(time.Now().Unix(), 10))
+}
+
+// loadRetiredSigningKeys loads all retired keys within their grace period and
+// removes the ones which have expired.
+func loadRetiredSigningKeys(keyPath string) ([]retiredSigningKey, error) {
+	matches, err := filepath.Glob(keyPath + ".*")
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]retiredSigningKey, 0, len(matches))
+	for _, match := range matches {
+		retiredUnix, err := strconv.ParseInt(strings.TrimPrefix(match, keyPath+"."), 10, 64)
+		if err != nil {
+			continue
+		}
+		retiredAt := time.Unix(retiredUnix, 0)
+		if time.Since(retiredAt) >= setting.OAuth2.JWTSigningKeyGracePeriod {
+			if err := os.Remove(match); err != nil {
+				log.Error("Unable to remove expired JWT signing key %s: %v", match, err)
+			}
+			continue
+		}
+
+		key, err := readAsymmetricKey(match)
+		if err != nil {
+			log.Error("Unable to read retired JWT signing key %s: %v", match, err)
+			continue
+		}
+		signingKey, err := CreateJWTSigningKey(retiredKeyAlgorithm(key), key)
+		if err != nil {
+			log.Error("Unable to load retired JWT signing key %s: %v", match, err)
+			continue
+		}
+		keys = append(keys, retiredSigningKey{signingKey, retiredAt})
+	}
+
+	// newest first
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].retiredAt.After(keys[j].retiredAt)
+	})
+	return keys, nil
+}
+
+// retiredKeyAlgorithm returns the configured algorithm if it fits the key type,
+// otherwise the default algorithm for the key.
+func retiredKeyAlgorithm(key interface{}) string {
+	algorithm := setting.OAuth2.JWTSigningAlgorithm
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if strings.HasPrefix(algorithm, "RS") {
+			return algorithm
+		}
+		return "RS256"
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P384():
+			return "ES384"
+		case elliptic.P521():
+			return "ES512"
+		default:
+			return "ES256"
+		}
+	}
+	return algorithm
+}
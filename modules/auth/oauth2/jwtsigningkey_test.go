@@ -0,0 +1,106 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package oauth2
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.wandrs.dev/framework/modules/setting"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSigningKeyRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwt")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	oldSetting := setting.OAuth2
+	defer func() {
+		setting.OAuth2 = oldSetting
+	}()
+	setting.OAuth2.JWTSigningAlgorithm = "ES256"
+	setting.OAuth2.JWTSigningPrivateKeyFile = filepath.Join(dir, "private.pem")
+	setting.OAuth2.JWTSigningKeyGracePeriod = time.Hour
+
+	assert.NoError(t, InitSigningKey())
+	first := DefaultSigningKey()
+	assert.False(t, first.IsSymmetric())
+	assert.FileExists(t, setting.OAuth2.JWTSigningPrivateKeyFile)
+
+	jwk, err := first.ToJWK()
+	assert.NoError(t, err)
+	assert.Equal(t, "EC", jwk["kty"])
+	assert.Equal(t, "P-256", jwk["crv"])
+	assert.Equal(t, "ES256", jwk["alg"])
+
+	token := jwt.NewWithClaims(first.SigningMethod(), jwt.StandardClaims{Subject: "1"})
+	first.PreProcessToken(token)
+	signed, err := token.SignedString(first.SignKey())
+	assert.NoError(t, err)
+
+	// a restart loads the same key
+	assert.NoError(t, InitSigningKey())
+	assert.Equal(t, jwk["kid"], DefaultSigningKey().(identifiableSigningKey).kid())
+
+	assert.NoError(t, RotateSigningKey())
+	second := DefaultSigningKey()
+	assert.NotEqual(t, jwk["kid"], second.(identifiableSigningKey).kid())
+	assert.Len(t, VerificationKeys(), 2)
+
+	// tokens signed by the retired key can still be verified
+	retired := GetSigningKeyByID(jwk["kid"])
+	if assert.NotNil(t, retired) {
+		_, err = jwt.Parse(signed, func(*jwt.Token) (interface{}, error) {
+			return retired.VerifyKey(), nil
+		})
+		assert.NoError(t, err)
+	}
+
+	// retired keys are dropped after the grace period
+	setting.OAuth2.JWTSigningKeyGracePeriod = 0
+	assert.NoError(t, InitSigningKey())
+	assert.Len(t, VerificationKeys(), 1)
+	matches, err := filepath.Glob(setting.OAuth2.JWTSigningPrivateKeyFile + ".*")
+	assert.NoError(t, err)
+	assert.Empty(t, matches)
+}
+
+func TestSigningKeyAlgorithmChange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwt")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	oldSetting := setting.OAuth2
+	defer func() {
+		setting.OAuth2 = oldSetting
+	}()
+	setting.OAuth2.JWTSigningAlgorithm = "ES256"
+	setting.OAuth2.JWTSigningPrivateKeyFile = filepath.Join(dir, "private.pem")
+	setting.OAuth2.JWTSigningKeyGracePeriod = time.Hour
+
+	assert.NoError(t, InitSigningKey())
+
+	setting.OAuth2.JWTSigningAlgorithm = "ES384"
+	assert.NoError(t, InitSigningKey())
+	assert.Equal(t, "ES384", DefaultSigningKey().SigningMethod().Alg())
+	if keys := VerificationKeys(); assert.Len(t, keys, 2) {
+		assert.Equal(t, "ES256", keys[1].SigningMethod().Alg())
+	}
+
+	setting.OAuth2.JWTSigningAlgorithm = "HS256"
+	setting.OAuth2.JWTSecretBytes = []byte("secret")
+	assert.NoError(t, InitSigningKey())
+	assert.True(t, DefaultSigningKey().IsSymmetric())
+	assert.Error(t, RotateSigningKey())
+
+	_, err = CreateJWTSigningKey("none", nil)
+	assert.True(t, IsErrInvalidAlgorithmType(err))
+}
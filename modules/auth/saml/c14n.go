@@ -0,0 +1,156 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package saml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"sort"
+	"strings"
+)
+
+// Canonicalization algorithms supported for signed content
+const (
+	AlgorithmExcC14N             = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	AlgorithmExcC14NWithComments = "http://www.w3.org/2001/10/xml-exc-c14n#WithComments"
+)
+
+var (
+	attrValueEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+	textEscaper      = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+)
+
+// canonicalizer serializes an element following Exclusive XML Canonicalization 1.0
+type canonicalizer struct {
+	buf               bytes.Buffer
+	exclude           *element
+	inclusivePrefixes []string
+	withComments      bool
+}
+
+// canonicalize returns the exclusive canonical form of the element, leaving out the excluded
+// descendant. The prefixes of inclusivePrefixes are rendered as in inclusive canonicalization,
+// "#default" denotes the default namespace.
+func canonicalize(el, exclude *element, inclusivePrefixes []string, withComments bool) []byte {
+	c := &canonicalizer{
+		exclude:      exclude,
+		withComments: withComments,
+	}
+	for _, prefix := range inclusivePrefixes {
+		if prefix == "#default" {
+			prefix = ""
+		}
+		c.inclusivePrefixes = append(c.inclusivePrefixes, prefix)
+	}
+	c.writeElement(el, map[string]string{})
+	return c.buf.Bytes()
+}
+
+func qualifiedName(prefix, local string) string {
+	if prefix == "" {
+		return local
+	}
+	return prefix + ":" + local
+}
+
+func isNamespaceDeclaration(attr xml.Attr) bool {
+	return attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns")
+}
+
+func (c *canonicalizer) writeElement(el *element, rendered map[string]string) {
+	// namespaces visibly utilized by the element and its attributes
+	utilized := map[string]bool{el.Prefix: true}
+	for _, attr := range el.Attrs {
+		if !isNamespaceDeclaration(attr) && attr.Name.Space != "" {
+			utilized[attr.Name.Space] = true
+		}
+	}
+	for _, prefix := range c.inclusivePrefixes {
+		if prefix == "" || el.lookupNamespace(prefix) != "" {
+			utilized[prefix] = true
+		}
+	}
+
+	type namespace struct {
+		prefix, uri string
+	}
+	var namespaces []namespace
+	for prefix := range utilized {
+		if prefix == "xml" {
+			continue
+		}
+		uri := el.lookupNamespace(prefix)
+		if previous, ok := rendered[prefix]; ok && previous == uri {
+			continue
+		} else if !ok && prefix == "" && uri == "" {
+			continue
+		}
+		namespaces = append(namespaces, namespace{prefix, uri})
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].prefix < namespaces[j].prefix
+	})
+
+	type attribute struct {
+		namespace, local, name, value string
+	}
+	var attrs []attribute
+	for _, attr := range el.Attrs {
+		if isNamespaceDeclaration(attr) {
+			continue
+		}
+		attrs = append(attrs, attribute{
+			namespace: el.lookupNamespace(attr.Name.Space),
+			local:     attr.Name.Local,
+			name:      qualifiedName(attr.Name.Space, attr.Name.Local),
+			value:     attr.Value,
+		})
+	}
+	sort.Slice(attrs, func(i, j int) bool {
+		if attrs[i].namespace != attrs[j].namespace {
+			return attrs[i].namespace < attrs[j].namespace
+		}
+		return attrs[i].local < attrs[j].local
+	})
+
+	name := qualifiedName(el.Prefix, el.Local)
+	c.buf.WriteString("<" + name)
+	if len(namespaces) > 0 {
+		scope := make(map[string]string, len(rendered)+len(namespaces))
+		for prefix, uri := range rendered {
+			scope[prefix] = uri
+		}
+		for _, ns := range namespaces {
+			if ns.prefix == "" {
+				c.buf.WriteString(` xmlns="`)
+			} else {
+				c.buf.WriteString(` xmlns:` + ns.prefix + `="`)
+			}
+			c.buf.WriteString(attrValueEscaper.Replace(ns.uri) + `"`)
+			scope[ns.prefix] = ns.uri
+		}
+		rendered = scope
+	}
+	for _, attr := range attrs {
+		c.buf.WriteString(" " + attr.name + `="` + attrValueEscaper.Replace(attr.value) + `"`)
+	}
+	c.buf.WriteString(">")
+
+	for _, child := range el.Children {
+		switch t := child.(type) {
+		case *element:
+			if t != c.exclude {
+				c.writeElement(t, rendered)
+			}
+		case xml.CharData:
+			c.buf.WriteString(textEscaper.Replace(string(t)))
+		case xml.Comment:
+			if c.withComments {
+				c.buf.WriteString("<!--" + string(t) + "-->")
+			}
+		}
+	}
+	c.buf.WriteString("</" + name + ">")
+}
@@ -0,0 +1,197 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package saml
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	// register hash functions used by signatures
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+// XML signature namespaces and algorithms supported for signed content
+const (
+	NamespaceDSig   = "http://www.w3.org/2000/09/xmldsig#"
+	namespaceExcC14 = "http://www.w3.org/2001/10/xml-exc-c14n#"
+
+	AlgorithmEnvelopedSignature = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+
+	AlgorithmRSASHA1     = "http://www.w3.org/2000/09/xmldsig#rsa-sha1"
+	AlgorithmRSASHA256   = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	AlgorithmRSASHA512   = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha512"
+	AlgorithmECDSASHA256 = "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256"
+	AlgorithmECDSASHA512 = "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha512"
+
+	AlgorithmSHA1   = "http://www.w3.org/2000/09/xmldsig#sha1"
+	AlgorithmSHA256 = "http://www.w3.org/2001/04/xmlenc#sha256"
+	AlgorithmSHA512 = "http://www.w3.org/2001/04/xmlenc#sha512"
+)
+
+var (
+	digestAlgorithms = map[string]crypto.Hash{
+		AlgorithmSHA1:   crypto.SHA1,
+		AlgorithmSHA256: crypto.SHA256,
+		AlgorithmSHA512: crypto.SHA512,
+	}
+	signatureAlgorithms = map[string]crypto.Hash{
+		AlgorithmRSASHA1:     crypto.SHA1,
+		AlgorithmRSASHA256:   crypto.SHA256,
+		AlgorithmRSASHA512:   crypto.SHA512,
+		AlgorithmECDSASHA256: crypto.SHA256,
+		AlgorithmECDSASHA512: crypto.SHA512,
+	}
+)
+
+// errNotSigned is returned if the element does not contain a signature
+var errNotSigned = errors.New("element is not signed")
+
+func decodeBase64(value string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ""))
+}
+
+// canonicalizationOf returns the canonicalization parameters of a CanonicalizationMethod or Transform element
+func canonicalizationOf(el *element) (prefixes []string, withComments bool, err error) {
+	switch algorithm := el.Attr("Algorithm"); algorithm {
+	case AlgorithmExcC14N, AlgorithmExcC14NWithComments:
+		if inclusive := el.ChildElement(namespaceExcC14, "InclusiveNamespaces"); inclusive != nil {
+			prefixes = strings.Fields(inclusive.Attr("PrefixList"))
+		}
+		return prefixes, algorithm == AlgorithmExcC14NWithComments, nil
+	default:
+		return nil, false, fmt.Errorf("unsupported canonicalization algorithm %q", algorithm)
+	}
+}
+
+// verifySignature verifies the enveloped signature of the element against the certificate.
+// Only a signature which is a direct child of the element and references the element itself
+// is accepted, so the verified content is exactly the element the caller goes on to read.
+func verifySignature(el *element, cert *x509.Certificate) error {
+	signatures := el.ChildElements(NamespaceDSig, "Signature")
+	if len(signatures) == 0 {
+		return errNotSigned
+	} else if len(signatures) > 1 {
+		return errors.New("multiple signatures")
+	}
+	signature := signatures[0]
+
+	signedInfo := signature.ChildElement(NamespaceDSig, "SignedInfo")
+	if signedInfo == nil {
+		return errors.New("missing SignedInfo")
+	}
+
+	references := signedInfo.ChildElements(NamespaceDSig, "Reference")
+	if len(references) != 1 {
+		return errors.New("exactly one signature reference expected")
+	}
+	reference := references[0]
+	if id := el.Attr("ID"); id == "" || reference.Attr("URI") != "#"+id {
+		return errors.New("signature does not reference the signed element")
+	}
+
+	var prefixes []string
+	var withComments, enveloped, canonicalized bool
+	if transforms := reference.ChildElement(NamespaceDSig, "Transforms"); transforms != nil {
+		for _, transform := range transforms.ChildElements(NamespaceDSig, "Transform") {
+			if transform.Attr("Algorithm") == AlgorithmEnvelopedSignature {
+				enveloped = true
+				continue
+			}
+			var err error
+			if prefixes, withComments, err = canonicalizationOf(transform); err != nil {
+				return err
+			}
+			canonicalized = true
+		}
+	}
+	if !enveloped || !canonicalized {
+		return errors.New("signature must be enveloped and canonicalized")
+	}
+
+	digestMethod := reference.ChildElement(NamespaceDSig, "DigestMethod")
+	if digestMethod == nil {
+		return errors.New("missing DigestMethod")
+	}
+	digestHash, ok := digestAlgorithms[digestMethod.Attr("Algorithm")]
+	if !ok {
+		return fmt.Errorf("unsupported digest algorithm %q", digestMethod.Attr("Algorithm"))
+	}
+	digestValue := reference.ChildElement(NamespaceDSig, "DigestValue")
+	if digestValue == nil {
+		return errors.New("missing DigestValue")
+	}
+	expectedDigest, err := decodeBase64(digestValue.Text())
+	if err != nil {
+		return fmt.Errorf("invalid DigestValue: %v", err)
+	}
+	h := digestHash.New()
+	h.Write(canonicalize(el, signature, prefixes, withComments))
+	if subtle.ConstantTimeCompare(h.Sum(nil), expectedDigest) != 1 {
+		return errors.New("digest mismatch")
+	}
+
+	canonicalizationMethod := signedInfo.ChildElement(NamespaceDSig, "CanonicalizationMethod")
+	if canonicalizationMethod == nil {
+		return errors.New("missing CanonicalizationMethod")
+	}
+	if prefixes, withComments, err = canonicalizationOf(canonicalizationMethod); err != nil {
+		return err
+	}
+	signatureMethod := signedInfo.ChildElement(NamespaceDSig, "SignatureMethod")
+	if signatureMethod == nil {
+		return errors.New("missing SignatureMethod")
+	}
+	signatureHash, ok := signatureAlgorithms[signatureMethod.Attr("Algorithm")]
+	if !ok {
+		return fmt.Errorf("unsupported signature algorithm %q", signatureMethod.Attr("Algorithm"))
+	}
+	signatureValue := signature.ChildElement(NamespaceDSig, "SignatureValue")
+	if signatureValue == nil {
+		return errors.New("missing SignatureValue")
+	}
+	sig, err := decodeBase64(signatureValue.Text())
+	if err != nil {
+		return fmt.Errorf("invalid SignatureValue: %v", err)
+	}
+	h = signatureHash.New()
+	h.Write(canonicalize(signedInfo, nil, prefixes, withComments))
+	hashed := h.Sum(nil)
+
+	switch pub := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if !strings.Contains(signatureMethod.Attr("Algorithm"), "rsa-") {
+			return errors.New("signature algorithm does not match the certificate key")
+		}
+		if err := rsa.VerifyPKCS1v15(pub, signatureHash, hashed, sig); err != nil {
+			return errors.New("invalid signature")
+		}
+	case *ecdsa.PublicKey:
+		if !strings.Contains(signatureMethod.Attr("Algorithm"), "ecdsa-") {
+			return errors.New("signature algorithm does not match the certificate key")
+		}
+		// XML signatures encode ECDSA signatures as the concatenation of r and s
+		if len(sig) == 0 || len(sig)%2 != 0 {
+			return errors.New("invalid signature")
+		}
+		r := new(big.Int).SetBytes(sig[:len(sig)/2])
+		s := new(big.Int).SetBytes(sig[len(sig)/2:])
+		if !ecdsa.Verify(pub, hashed, r, s) {
+			return errors.New("invalid signature")
+		}
+	default:
+		return errors.New("unsupported certificate key type")
+	}
+	return nil
+}
@@ -0,0 +1,418 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SAML 2.0 namespaces and identifiers
+const (
+	NamespaceProtocol  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	NamespaceAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+	NamespaceMetadata  = "urn:oasis:names:tc:SAML:2.0:metadata"
+
+	BindingHTTPPost     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+	BindingHTTPRedirect = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+
+	NameIDFormatUnspecified  = "urn:oasis:names:tc:SAML:1.1:nameid-format:unspecified"
+	NameIDFormatEmailAddress = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
+	NameIDFormatPersistent   = "urn:oasis:names:tc:SAML:2.0:nameid-format:persistent"
+
+	StatusSuccess = "urn:oasis:names:tc:SAML:2.0:status:Success"
+
+	confirmationMethodBearer = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+)
+
+// DefaultClockSkew is the default tolerated difference between the clocks of the identity and service provider
+const DefaultClockSkew = 3 * time.Minute
+
+// ServiceProvider represents this instance as SAML 2.0 service provider towards one identity provider
+type ServiceProvider struct {
+	// EntityID is the entity id of the service provider, it is the audience of accepted assertions
+	EntityID string
+	// ACSURL is the URL of the assertion consumer service receiving responses through the HTTP-POST binding
+	ACSURL string
+	// NameIDFormat is the name identifier format requested from the identity provider
+	NameIDFormat string
+
+	// IdPEntityID is the entity id of the identity provider, it is the expected issuer of assertions
+	IdPEntityID string
+	// IdPSSOURL is the single sign-on service URL of the identity provider using the HTTP-Redirect binding
+	IdPSSOURL string
+	// IdPCertificate is the certificate the identity provider signs responses or assertions with
+	IdPCertificate *x509.Certificate
+
+	// AllowIdPInitiated allows unsolicited responses, i.e. sign-ins started at the identity provider
+	AllowIdPInitiated bool
+	// ClockSkew is the tolerated clock difference, DefaultClockSkew if zero
+	ClockSkew time.Duration
+}
+
+// Assertion holds the verified statements about the authenticated subject
+type Assertion struct {
+	ID           string
+	Issuer       string
+	NameID       string
+	NameIDFormat string
+	SessionIndex string
+	// NotOnOrAfter is the time the assertion must not be accepted anymore
+	NotOnOrAfter time.Time
+	// Attributes maps the names and friendly names of the attributes to their values
+	Attributes map[string][]string
+}
+
+// Attribute returns the first value of the attribute with the given name or friendly name
+func (a *Assertion) Attribute(name string) string {
+	if values := a.Attributes[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// ParseCertificate parses a PEM encoded certificate or the bare base64 encoded DER form
+// found in the metadata of identity providers
+func ParseCertificate(data string) (*x509.Certificate, error) {
+	if block, _ := pem.Decode([]byte(data)); block != nil {
+		return x509.ParseCertificate(block.Bytes)
+	}
+	der, err := decodeBase64(data)
+	if err != nil {
+		return nil, fmt.Errorf("certificate is neither PEM nor base64 encoded: %v", err)
+	}
+	return x509.ParseCertificate(der)
+}
+
+func newID() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	// IDs are of type xs:ID and must not start with a digit
+	return "_" + hex.EncodeToString(buf), nil
+}
+
+type issuer struct {
+	XMLName xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+	Value   string   `xml:",chardata"`
+}
+
+type nameIDPolicy struct {
+	XMLName     xml.Name `xml:"NameIDPolicy"`
+	Format      string   `xml:"Format,attr,omitempty"`
+	AllowCreate bool     `xml:"AllowCreate,attr"`
+}
+
+type authnRequest struct {
+	XMLName                     xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol AuthnRequest"`
+	ID                          string   `xml:"ID,attr"`
+	Version                     string   `xml:"Version,attr"`
+	IssueInstant                string   `xml:"IssueInstant,attr"`
+	Destination                 string   `xml:"Destination,attr"`
+	AssertionConsumerServiceURL string   `xml:"AssertionConsumerServiceURL,attr"`
+	ProtocolBinding             string   `xml:"ProtocolBinding,attr"`
+	Issuer                      issuer
+	NameIDPolicy                nameIDPolicy
+}
+
+// AuthnRequestURL returns the URL to redirect the user agent to for signing in at the identity provider
+// using the HTTP-Redirect binding, and the id of the request the response has to refer to.
+func (sp *ServiceProvider) AuthnRequestURL(relayState string) (string, string, error) {
+	id, err := newID()
+	if err != nil {
+		return "", "", err
+	}
+	data, err := xml.Marshal(authnRequest{
+		ID:                          id,
+		Version:                     "2.0",
+		IssueInstant:                time.Now().UTC().Format(time.RFC3339),
+		Destination:                 sp.IdPSSOURL,
+		AssertionConsumerServiceURL: sp.ACSURL,
+		ProtocolBinding:             BindingHTTPPost,
+		Issuer:                      issuer{Value: sp.EntityID},
+		NameIDPolicy: nameIDPolicy{
+			Format:      sp.NameIDFormat,
+			AllowCreate: true,
+		},
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := w.Write(data); err != nil {
+		return "", "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", "", err
+	}
+
+	u, err := url.Parse(sp.IdPSSOURL)
+	if err != nil {
+		return "", "", err
+	}
+	query := u.Query()
+	query.Set("SAMLRequest", base64.StdEncoding.EncodeToString(buf.Bytes()))
+	if relayState != "" {
+		query.Set("RelayState", relayState)
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), id, nil
+}
+
+type assertionConsumerService struct {
+	Binding   string `xml:"Binding,attr"`
+	Location  string `xml:"Location,attr"`
+	Index     int    `xml:"index,attr"`
+	IsDefault bool   `xml:"isDefault,attr"`
+}
+
+type spSSODescriptor struct {
+	AuthnRequestsSigned        bool                     `xml:"AuthnRequestsSigned,attr"`
+	WantAssertionsSigned       bool                     `xml:"WantAssertionsSigned,attr"`
+	ProtocolSupportEnumeration string                   `xml:"protocolSupportEnumeration,attr"`
+	NameIDFormat               string                   `xml:"NameIDFormat,omitempty"`
+	AssertionConsumerService   assertionConsumerService `xml:"AssertionConsumerService"`
+}
+
+type entityDescriptor struct {
+	XMLName         xml.Name        `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+	EntityID        string          `xml:"entityID,attr"`
+	SPSSODescriptor spSSODescriptor `xml:"SPSSODescriptor"`
+}
+
+// Metadata returns the metadata document describing the service provider to the identity provider
+func (sp *ServiceProvider) Metadata() ([]byte, error) {
+	data, err := xml.MarshalIndent(entityDescriptor{
+		EntityID: sp.EntityID,
+		SPSSODescriptor: spSSODescriptor{
+			WantAssertionsSigned:       true,
+			ProtocolSupportEnumeration: NamespaceProtocol,
+			NameIDFormat:               sp.NameIDFormat,
+			AssertionConsumerService: assertionConsumerService{
+				Binding:   BindingHTTPPost,
+				Location:  sp.ACSURL,
+				Index:     1,
+				IsDefault: true,
+			},
+		},
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+func (sp *ServiceProvider) clockSkew() time.Duration {
+	if sp.ClockSkew == 0 {
+		return DefaultClockSkew
+	}
+	return sp.ClockSkew
+}
+
+func parseTime(value string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+// checkInResponseTo checks the response refers to the request sent by us, or is an unsolicited
+// response if these are allowed
+func (sp *ServiceProvider) checkInResponseTo(inResponseTo, requestID string) error {
+	switch {
+	case inResponseTo == "":
+		if !sp.AllowIdPInitiated {
+			return errors.New("unsolicited responses are not allowed")
+		}
+	case inResponseTo != requestID:
+		return errors.New("response does not refer to the pending request")
+	}
+	return nil
+}
+
+// ParseResponse verifies the base64 encoded response received through the HTTP-POST binding and
+// returns its assertion. requestID is the id of the pending request sent to the identity provider,
+// empty if there is none.
+func (sp *ServiceProvider) ParseResponse(encoded, requestID string) (*Assertion, error) {
+	if sp.IdPCertificate == nil {
+		return nil, errors.New("no identity provider certificate configured")
+	}
+	data, err := decodeBase64(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response encoding: %v", err)
+	}
+	response, err := parseXML(data)
+	if err != nil {
+		return nil, err
+	}
+	if !response.Is(NamespaceProtocol, "Response") {
+		return nil, errors.New("not a SAML response")
+	}
+	if response.Attr("Version") != "2.0" {
+		return nil, fmt.Errorf("unsupported SAML version %q", response.Attr("Version"))
+	}
+	if destination := response.Attr("Destination"); destination != "" && destination != sp.ACSURL {
+		return nil, fmt.Errorf("response is destined for %q", destination)
+	}
+	if err := sp.checkInResponseTo(response.Attr("InResponseTo"), requestID); err != nil {
+		return nil, err
+	}
+	if iss := response.ChildElement(NamespaceAssertion, "Issuer"); iss != nil && iss.Text() != sp.IdPEntityID {
+		return nil, fmt.Errorf("response issued by unknown identity provider %q", iss.Text())
+	}
+
+	var statusCode string
+	if status := response.ChildElement(NamespaceProtocol, "Status"); status != nil {
+		if code := status.ChildElement(NamespaceProtocol, "StatusCode"); code != nil {
+			statusCode = code.Attr("Value")
+		}
+	}
+	if statusCode != StatusSuccess {
+		return nil, fmt.Errorf("identity provider returned status %q", statusCode)
+	}
+
+	responseSigned := false
+	switch err := verifySignature(response, sp.IdPCertificate); err {
+	case nil:
+		responseSigned = true
+	case errNotSigned:
+	default:
+		return nil, fmt.Errorf("response signature: %v", err)
+	}
+
+	if len(response.ChildElements(NamespaceAssertion, "EncryptedAssertion")) > 0 {
+		return nil, errors.New("encrypted assertions are not supported")
+	}
+	assertions := response.ChildElements(NamespaceAssertion, "Assertion")
+	if len(assertions) != 1 {
+		return nil, errors.New("exactly one assertion expected")
+	}
+	switch err := verifySignature(assertions[0], sp.IdPCertificate); err {
+	case nil:
+	case errNotSigned:
+		if !responseSigned {
+			return nil, errors.New("neither response nor assertion are signed")
+		}
+	default:
+		return nil, fmt.Errorf("assertion signature: %v", err)
+	}
+
+	return sp.parseAssertion(assertions[0], requestID, time.Now())
+}
+
+func (sp *ServiceProvider) parseAssertion(el *element, requestID string, now time.Time) (*Assertion, error) {
+	skew := sp.clockSkew()
+	assertion := &Assertion{
+		ID:         el.Attr("ID"),
+		Attributes: make(map[string][]string),
+	}
+
+	if iss := el.ChildElement(NamespaceAssertion, "Issuer"); iss == nil || iss.Text() != sp.IdPEntityID {
+		return nil, errors.New("assertion is not issued by the identity provider")
+	}
+	assertion.Issuer = sp.IdPEntityID
+
+	subject := el.ChildElement(NamespaceAssertion, "Subject")
+	if subject == nil {
+		return nil, errors.New("assertion has no subject")
+	}
+	nameID := subject.ChildElement(NamespaceAssertion, "NameID")
+	if nameID == nil || nameID.Text() == "" {
+		return nil, errors.New("assertion has no NameID")
+	}
+	assertion.NameID = nameID.Text()
+	assertion.NameIDFormat = nameID.Attr("Format")
+
+	// at least one bearer confirmation must hold for this service provider
+	confirmed := false
+	for _, confirmation := range subject.ChildElements(NamespaceAssertion, "SubjectConfirmation") {
+		if confirmation.Attr("Method") != confirmationMethodBearer {
+			continue
+		}
+		data := confirmation.ChildElement(NamespaceAssertion, "SubjectConfirmationData")
+		if data == nil || data.Attr("Recipient") != sp.ACSURL {
+			continue
+		}
+		notOnOrAfter, err := parseTime(data.Attr("NotOnOrAfter"))
+		if err != nil || !now.Before(notOnOrAfter.Add(skew)) {
+			continue
+		}
+		if sp.checkInResponseTo(data.Attr("InResponseTo"), requestID) != nil {
+			continue
+		}
+		confirmed = true
+		assertion.NotOnOrAfter = notOnOrAfter
+		break
+	}
+	if !confirmed {
+		return nil, errors.New("assertion has no valid bearer subject confirmation")
+	}
+
+	if conditions := el.ChildElement(NamespaceAssertion, "Conditions"); conditions != nil {
+		if value := conditions.Attr("NotBefore"); value != "" {
+			notBefore, err := parseTime(value)
+			if err != nil || now.Add(skew).Before(notBefore) {
+				return nil, errors.New("assertion is not yet valid")
+			}
+		}
+		if value := conditions.Attr("NotOnOrAfter"); value != "" {
+			notOnOrAfter, err := parseTime(value)
+			if err != nil || !now.Before(notOnOrAfter.Add(skew)) {
+				return nil, errors.New("assertion has expired")
+			}
+			if notOnOrAfter.Before(assertion.NotOnOrAfter) {
+				assertion.NotOnOrAfter = notOnOrAfter
+			}
+		}
+		for _, restriction := range conditions.ChildElements(NamespaceAssertion, "AudienceRestriction") {
+			found := false
+			for _, audience := range restriction.ChildElements(NamespaceAssertion, "Audience") {
+				if audience.Text() == sp.EntityID {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, errors.New("assertion is not intended for this service provider")
+			}
+		}
+	}
+
+	if authn := el.ChildElement(NamespaceAssertion, "AuthnStatement"); authn != nil {
+		assertion.SessionIndex = authn.Attr("SessionIndex")
+	}
+
+	for _, statement := range el.ChildElements(NamespaceAssertion, "AttributeStatement") {
+		for _, attribute := range statement.ChildElements(NamespaceAssertion, "Attribute") {
+			var values []string
+			for _, value := range attribute.ChildElements(NamespaceAssertion, "AttributeValue") {
+				values = append(values, value.Text())
+			}
+			name := strings.TrimSpace(attribute.Attr("Name"))
+			friendlyName := strings.TrimSpace(attribute.Attr("FriendlyName"))
+			if name != "" {
+				assertion.Attributes[name] = append(assertion.Attributes[name], values...)
+			}
+			if friendlyName != "" && friendlyName != name {
+				assertion.Attributes[friendlyName] = append(assertion.Attributes[friendlyName], values...)
+			}
+		}
+	}
+	return assertion, nil
+}
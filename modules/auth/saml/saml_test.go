@@ -0,0 +1,348 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testIdP is a stand-in identity provider signing responses with a self-signed certificate
+type testIdP struct {
+	entityID string
+	key      *rsa.PrivateKey
+	cert     *x509.Certificate
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "idp.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return &testIdP{entityID: "https://idp.example.com", key: key, cert: cert}
+}
+
+func (idp *testIdP) serviceProvider() *ServiceProvider {
+	return &ServiceProvider{
+		EntityID:       "https://sp.example.com/metadata",
+		ACSURL:         "https://sp.example.com/acs",
+		NameIDFormat:   NameIDFormatPersistent,
+		IdPEntityID:    idp.entityID,
+		IdPSSOURL:      "https://idp.example.com/sso?tenant=1",
+		IdPCertificate: idp.cert,
+	}
+}
+
+type testAssertion struct {
+	ID           string
+	Issuer       string
+	NameID       string
+	Audience     string
+	Recipient    string
+	InResponseTo string
+	NotBefore    time.Time
+	NotOnOrAfter time.Time
+}
+
+func (idp *testIdP) assertion(sp *ServiceProvider, requestID string) *testAssertion {
+	now := time.Now().UTC()
+	return &testAssertion{
+		ID:           "_assertion1",
+		Issuer:       idp.entityID,
+		NameID:       "jdoe",
+		Audience:     sp.EntityID,
+		Recipient:    sp.ACSURL,
+		InResponseTo: requestID,
+		NotBefore:    now.Add(-time.Minute),
+		NotOnOrAfter: now.Add(5 * time.Minute),
+	}
+}
+
+func (a *testAssertion) xml() string {
+	return fmt.Sprintf(`<saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" xmlns:xs="http://www.w3.org/2001/XMLSchema" ID="%s" IssueInstant="%s" Version="2.0">`+
+		`<saml:Issuer>%s</saml:Issuer>`+
+		`<saml:Subject><saml:NameID Format="urn:oasis:names:tc:SAML:2.0:nameid-format:persistent">%s</saml:NameID>`+
+		`<saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer">`+
+		`<saml:SubjectConfirmationData InResponseTo="%s" NotOnOrAfter="%s" Recipient="%s"/>`+
+		`</saml:SubjectConfirmation></saml:Subject>`+
+		`<saml:Conditions NotBefore="%s" NotOnOrAfter="%s"><saml:AudienceRestriction><saml:Audience>%s</saml:Audience></saml:AudienceRestriction></saml:Conditions>`+
+		`<saml:AuthnStatement AuthnInstant="%s" SessionIndex="_session1"/>`+
+		`<saml:AttributeStatement>`+
+		`<saml:Attribute Name="urn:oid:0.9.2342.19200300.100.1.3" FriendlyName="mail"><saml:AttributeValue xsi:type="xs:string" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">jdoe@example.com</saml:AttributeValue></saml:Attribute>`+
+		`<saml:Attribute Name="groups"><saml:AttributeValue>users</saml:AttributeValue><saml:AttributeValue>admins</saml:AttributeValue></saml:Attribute>`+
+		`</saml:AttributeStatement></saml:Assertion>`,
+		a.ID, a.NotBefore.Format(time.RFC3339), a.Issuer, a.NameID,
+		a.InResponseTo, a.NotOnOrAfter.Format(time.RFC3339), a.Recipient,
+		a.NotBefore.Format(time.RFC3339), a.NotOnOrAfter.Format(time.RFC3339), a.Audience,
+		a.NotBefore.Format(time.RFC3339))
+}
+
+// sign inserts an enveloped signature over the element with the given id after the first
+// occurrence of insertAfter
+func (idp *testIdP) sign(t *testing.T, doc, id, insertAfter string) string {
+	root, err := parseXML([]byte(doc))
+	assert.NoError(t, err)
+	var signed *element
+	var find func(*element)
+	find = func(el *element) {
+		if el.Attr("ID") == id {
+			signed = el
+		}
+		for _, child := range el.Children {
+			if c, ok := child.(*element); ok {
+				find(c)
+			}
+		}
+	}
+	find(root)
+	assert.NotNil(t, signed)
+
+	digest := sha256.Sum256(canonicalize(signed, nil, nil, false))
+	signedInfo := `<ds:SignedInfo xmlns:ds="http://www.w3.org/2000/09/xmldsig#">` +
+		`<ds:CanonicalizationMethod Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"/>` +
+		`<ds:SignatureMethod Algorithm="http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"/>` +
+		`<ds:Reference URI="#` + id + `"><ds:Transforms>` +
+		`<ds:Transform Algorithm="http://www.w3.org/2000/09/xmldsig#enveloped-signature"/>` +
+		`<ds:Transform Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"/>` +
+		`</ds:Transforms><ds:DigestMethod Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"/>` +
+		`<ds:DigestValue>` + base64.StdEncoding.EncodeToString(digest[:]) + `</ds:DigestValue>` +
+		`</ds:Reference></ds:SignedInfo>`
+	signedInfoEl, err := parseXML([]byte(signedInfo))
+	assert.NoError(t, err)
+	hashed := sha256.Sum256(canonicalize(signedInfoEl, nil, nil, false))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, idp.key, crypto.SHA256, hashed[:])
+	assert.NoError(t, err)
+
+	signature := `<ds:Signature xmlns:ds="http://www.w3.org/2000/09/xmldsig#">` +
+		strings.Replace(signedInfo, ` xmlns:ds="http://www.w3.org/2000/09/xmldsig#"`, "", 1) +
+		`<ds:SignatureValue>` + base64.StdEncoding.EncodeToString(sig) + `</ds:SignatureValue></ds:Signature>`
+	pos := strings.Index(doc, insertAfter) + len(insertAfter)
+	return doc[:pos] + signature + doc[pos:]
+}
+
+func (idp *testIdP) response(sp *ServiceProvider, requestID, assertion string) string {
+	inResponseTo := ""
+	if requestID != "" {
+		inResponseTo = ` InResponseTo="` + requestID + `"`
+	}
+	return `<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="_response1" Version="2.0"` +
+		` IssueInstant="` + time.Now().UTC().Format(time.RFC3339) + `" Destination="` + sp.ACSURL + `"` + inResponseTo + `>` +
+		`<saml:Issuer>` + idp.entityID + `</saml:Issuer>` +
+		`<samlp:Status><samlp:StatusCode Value="urn:oasis:names:tc:SAML:2.0:status:Success"/></samlp:Status>` +
+		assertion + `</samlp:Response>`
+}
+
+func encode(doc string) string {
+	return base64.StdEncoding.EncodeToString([]byte(doc))
+}
+
+func TestCanonicalize(t *testing.T) {
+	root, err := parseXML([]byte(`<a:root xmlns:a="urn:a" xmlns:b="urn:b" xmlns:unused="urn:u"><a:child b:attr="1" z="2" a="&quot;"/><plain xmlns="urn:d">t&amp;&gt;<!-- comment --></plain></a:root>`))
+	assert.NoError(t, err)
+	assert.Equal(t,
+		`<a:root xmlns:a="urn:a"><a:child xmlns:b="urn:b" a="&quot;" z="2" b:attr="1"></a:child><plain xmlns="urn:d">t&amp;&gt;</plain></a:root>`,
+		string(canonicalize(root, nil, nil, false)))
+
+	child := root.Children[0].(*element)
+	assert.Equal(t,
+		`<a:child xmlns:a="urn:a" xmlns:b="urn:b" a="&quot;" z="2" b:attr="1"></a:child>`,
+		string(canonicalize(child, nil, nil, false)))
+	assert.Equal(t,
+		`<a:child xmlns:a="urn:a" xmlns:b="urn:b" xmlns:unused="urn:u" a="&quot;" z="2" b:attr="1"></a:child>`,
+		string(canonicalize(child, nil, []string{"unused"}, false)))
+
+	_, err = parseXML([]byte(`<!DOCTYPE r [<!ENTITY e "x">]><r>&e;</r>`))
+	assert.Error(t, err)
+}
+
+func TestParseResponse(t *testing.T) {
+	idp := newTestIdP(t)
+	sp := idp.serviceProvider()
+	const requestID = "_request1"
+
+	t.Run("SignedAssertion", func(t *testing.T) {
+		assertion := idp.sign(t, idp.assertion(sp, requestID).xml(), "_assertion1", "</saml:Issuer>")
+		a, err := sp.ParseResponse(encode(idp.response(sp, requestID, assertion)), requestID)
+		assert.NoError(t, err)
+		assert.Equal(t, "_assertion1", a.ID)
+		assert.Equal(t, "jdoe", a.NameID)
+		assert.Equal(t, NameIDFormatPersistent, a.NameIDFormat)
+		assert.Equal(t, "_session1", a.SessionIndex)
+		assert.Equal(t, "jdoe@example.com", a.Attribute("mail"))
+		assert.Equal(t, "jdoe@example.com", a.Attribute("urn:oid:0.9.2342.19200300.100.1.3"))
+		assert.Equal(t, []string{"users", "admins"}, a.Attributes["groups"])
+	})
+
+	t.Run("SignedResponse", func(t *testing.T) {
+		response := idp.sign(t, idp.response(sp, requestID, idp.assertion(sp, requestID).xml()), "_response1", "</saml:Issuer>")
+		a, err := sp.ParseResponse(encode(response), requestID)
+		assert.NoError(t, err)
+		assert.Equal(t, "jdoe", a.NameID)
+	})
+
+	t.Run("Unsigned", func(t *testing.T) {
+		_, err := sp.ParseResponse(encode(idp.response(sp, requestID, idp.assertion(sp, requestID).xml())), requestID)
+		assert.Error(t, err)
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		assertion := idp.sign(t, idp.assertion(sp, requestID).xml(), "_assertion1", "</saml:Issuer>")
+		assertion = strings.Replace(assertion, ">jdoe<", ">admin<", 1)
+		_, err := sp.ParseResponse(encode(idp.response(sp, requestID, assertion)), requestID)
+		assert.Error(t, err)
+	})
+
+	t.Run("CommentInNameID", func(t *testing.T) {
+		a := idp.assertion(sp, requestID)
+		a.NameID = "jdoe@example.com.evil.com"
+		assertion := idp.sign(t, a.xml(), "_assertion1", "</saml:Issuer>")
+		assertion = strings.Replace(assertion, "jdoe@example.com.evil.com", "jdoe@example.com<!---->.evil.com", 1)
+		parsed, err := sp.ParseResponse(encode(idp.response(sp, requestID, assertion)), requestID)
+		assert.NoError(t, err)
+		assert.Equal(t, "jdoe@example.com.evil.com", parsed.NameID)
+	})
+
+	t.Run("Wrapped", func(t *testing.T) {
+		// a signed assertion is kept in an extension while a forged one is presented
+		signed := idp.sign(t, idp.assertion(sp, requestID).xml(), "_assertion1", "</saml:Issuer>")
+		forged := idp.assertion(sp, requestID)
+		forged.ID = "_forged"
+		forged.NameID = "admin"
+		response := idp.response(sp, requestID, forged.xml())
+		response = strings.Replace(response, "</saml:Issuer>", "</saml:Issuer><samlp:Extensions>"+signed+"</samlp:Extensions>", 1)
+		_, err := sp.ParseResponse(encode(response), requestID)
+		assert.Error(t, err)
+
+		// the signature of the forged assertion refers to the signed one
+		forgedXML := forged.xml()
+		signature := signed[strings.Index(signed, "<ds:Signature"):strings.Index(signed, "</ds:Signature>")] + "</ds:Signature>"
+		forgedXML = strings.Replace(forgedXML, "</saml:Issuer>", "</saml:Issuer>"+signature, 1)
+		_, err = sp.ParseResponse(encode(idp.response(sp, requestID, forgedXML)), requestID)
+		assert.Error(t, err)
+	})
+
+	t.Run("OtherIdP", func(t *testing.T) {
+		other := newTestIdP(t)
+		assertion := other.sign(t, idp.assertion(sp, requestID).xml(), "_assertion1", "</saml:Issuer>")
+		_, err := sp.ParseResponse(encode(idp.response(sp, requestID, assertion)), requestID)
+		assert.Error(t, err)
+	})
+
+	for name, modify := range map[string]func(*testAssertion){
+		"WrongIssuer":    func(a *testAssertion) { a.Issuer = "https://evil.example.com" },
+		"WrongAudience":  func(a *testAssertion) { a.Audience = "https://other.example.com" },
+		"WrongRecipient": func(a *testAssertion) { a.Recipient = "https://other.example.com/acs" },
+		"WrongRequest":   func(a *testAssertion) { a.InResponseTo = "_other" },
+		"Expired": func(a *testAssertion) {
+			a.NotBefore = time.Now().Add(-time.Hour)
+			a.NotOnOrAfter = time.Now().Add(-30 * time.Minute)
+		},
+		"NotYetValid": func(a *testAssertion) { a.NotBefore = time.Now().Add(30 * time.Minute) },
+	} {
+		modify := modify
+		t.Run(name, func(t *testing.T) {
+			a := idp.assertion(sp, requestID)
+			modify(a)
+			assertion := idp.sign(t, a.xml(), "_assertion1", "</saml:Issuer>")
+			_, err := sp.ParseResponse(encode(idp.response(sp, requestID, assertion)), requestID)
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("IdPInitiated", func(t *testing.T) {
+		assertion := idp.sign(t, idp.assertion(sp, "").xml(), "_assertion1", "</saml:Issuer>")
+		response := encode(idp.response(sp, "", assertion))
+
+		_, err := sp.ParseResponse(response, "")
+		assert.Error(t, err)
+
+		unsolicited := *sp
+		unsolicited.AllowIdPInitiated = true
+		a, err := unsolicited.ParseResponse(response, "")
+		assert.NoError(t, err)
+		assert.Equal(t, "jdoe", a.NameID)
+
+		// a response to a request of another session is never accepted
+		assertion = idp.sign(t, idp.assertion(sp, requestID).xml(), "_assertion1", "</saml:Issuer>")
+		_, err = unsolicited.ParseResponse(encode(idp.response(sp, requestID, assertion)), "")
+		assert.Error(t, err)
+	})
+}
+
+func TestAuthnRequestURL(t *testing.T) {
+	sp := newTestIdP(t).serviceProvider()
+	redirect, id, err := sp.AuthnRequestURL("state")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(id, "_"))
+
+	u, err := url.Parse(redirect)
+	assert.NoError(t, err)
+	assert.Equal(t, "idp.example.com", u.Host)
+	assert.Equal(t, "1", u.Query().Get("tenant"))
+	assert.Equal(t, "state", u.Query().Get("RelayState"))
+
+	compressed, err := base64.StdEncoding.DecodeString(u.Query().Get("SAMLRequest"))
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(flate.NewReader(bytes.NewReader(compressed)))
+	assert.NoError(t, err)
+	request, err := parseXML(data)
+	assert.NoError(t, err)
+	assert.True(t, request.Is(NamespaceProtocol, "AuthnRequest"))
+	assert.Equal(t, id, request.Attr("ID"))
+	assert.Equal(t, sp.ACSURL, request.Attr("AssertionConsumerServiceURL"))
+	assert.Equal(t, sp.EntityID, request.ChildElement(NamespaceAssertion, "Issuer").Text())
+}
+
+func TestMetadata(t *testing.T) {
+	sp := newTestIdP(t).serviceProvider()
+	data, err := sp.Metadata()
+	assert.NoError(t, err)
+	metadata, err := parseXML(data)
+	assert.NoError(t, err)
+	assert.True(t, metadata.Is(NamespaceMetadata, "EntityDescriptor"))
+	assert.Equal(t, sp.EntityID, metadata.Attr("entityID"))
+	acs := metadata.ChildElement(NamespaceMetadata, "SPSSODescriptor").ChildElement(NamespaceMetadata, "AssertionConsumerService")
+	assert.Equal(t, sp.ACSURL, acs.Attr("Location"))
+	assert.Equal(t, BindingHTTPPost, acs.Attr("Binding"))
+}
+
+func TestParseCertificate(t *testing.T) {
+	idp := newTestIdP(t)
+	fromPEM, err := ParseCertificate(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: idp.cert.Raw})))
+	assert.NoError(t, err)
+	assert.Equal(t, idp.cert.Raw, fromPEM.Raw)
+
+	fromBase64, err := ParseCertificate(base64.StdEncoding.EncodeToString(idp.cert.Raw))
+	assert.NoError(t, err)
+	assert.Equal(t, idp.cert.Raw, fromBase64.Raw)
+
+	_, err = ParseCertificate("not a certificate")
+	assert.Error(t, err)
+}
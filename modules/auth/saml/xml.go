@@ -0,0 +1,154 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package saml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const namespaceXML = "http://www.w3.org/XML/1998/namespace"
+
+// element is a node of a parsed XML document which keeps the namespace prefixes
+// and declarations as written, which is required to canonicalize signed content
+type element struct {
+	Prefix   string
+	Local    string
+	Attrs    []xml.Attr
+	Children []interface{} // *element, xml.CharData or xml.Comment
+	Parent   *element
+}
+
+// parseXML parses a document into a tree of elements and returns its root element
+func parseXML(data []byte) (*element, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var root, current *element
+	for {
+		tok, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			el := &element{
+				Prefix: t.Name.Space,
+				Local:  t.Name.Local,
+				Attrs:  append([]xml.Attr(nil), t.Attr...),
+				Parent: current,
+			}
+			if current == nil {
+				if root != nil {
+					return nil, errors.New("xml: multiple root elements")
+				}
+				root = el
+			} else {
+				current.Children = append(current.Children, el)
+			}
+			current = el
+		case xml.EndElement:
+			if current == nil || current.Prefix != t.Name.Space || current.Local != t.Name.Local {
+				return nil, fmt.Errorf("xml: unexpected end element </%s>", t.Name.Local)
+			}
+			current = current.Parent
+		case xml.CharData:
+			if current != nil {
+				current.Children = append(current.Children, t.Copy())
+			}
+		case xml.Comment:
+			if current != nil {
+				current.Children = append(current.Children, t.Copy())
+			}
+		case xml.Directive:
+			// DTDs have no place in protocol messages and would allow entity tricks
+			return nil, errors.New("xml: directives are not allowed")
+		}
+	}
+	if root == nil || current != nil {
+		return nil, errors.New("xml: incomplete document")
+	}
+	return root, nil
+}
+
+// lookupNamespace returns the namespace URI bound to the prefix in the scope of the element
+func (el *element) lookupNamespace(prefix string) string {
+	if prefix == "xml" {
+		return namespaceXML
+	}
+	for e := el; e != nil; e = e.Parent {
+		for _, attr := range e.Attrs {
+			if (prefix == "" && attr.Name.Space == "" && attr.Name.Local == "xmlns") ||
+				(prefix != "" && attr.Name.Space == "xmlns" && attr.Name.Local == prefix) {
+				return attr.Value
+			}
+		}
+	}
+	return ""
+}
+
+// Namespace returns the namespace URI of the element
+func (el *element) Namespace() string {
+	return el.lookupNamespace(el.Prefix)
+}
+
+// Is returns true if the element has the given namespace URI and local name
+func (el *element) Is(namespace, local string) bool {
+	return el.Local == local && el.Namespace() == namespace
+}
+
+// Attr returns the value of the unqualified attribute with the given name
+func (el *element) Attr(name string) string {
+	for _, attr := range el.Attrs {
+		if attr.Name.Space == "" && attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// ChildElements returns the child elements with the given namespace URI and local name
+func (el *element) ChildElements(namespace, local string) []*element {
+	var children []*element
+	for _, child := range el.Children {
+		if c, ok := child.(*element); ok && c.Is(namespace, local) {
+			children = append(children, c)
+		}
+	}
+	return children
+}
+
+// ChildElement returns the first child element with the given namespace URI and local name
+func (el *element) ChildElement(namespace, local string) *element {
+	for _, child := range el.Children {
+		if c, ok := child.(*element); ok && c.Is(namespace, local) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Text returns the concatenated character data of the element and its descendants,
+// comments are skipped as they are not covered by signatures
+func (el *element) Text() string {
+	var sb strings.Builder
+	var walk func(*element)
+	walk = func(e *element) {
+		for _, child := range e.Children {
+			switch c := child.(type) {
+			case xml.CharData:
+				sb.Write(c)
+			case *element:
+				walk(c)
+			}
+		}
+	}
+	walk(el)
+	return strings.TrimSpace(sb.String())
+}
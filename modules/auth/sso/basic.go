@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/auth/lockout"
 	"go.wandrs.dev/framework/modules/base"
 	"go.wandrs.dev/framework/modules/log"
 	"go.wandrs.dev/framework/modules/setting"
@@ -88,6 +89,7 @@ func (b *Basic) VerifyAuthData(req *http.Request, w http.ResponseWriter, store D
 		}
 
 		store.GetData()["IsApiToken"] = true
+		store.GetData()["ApiTokenScope"] = string(models.AccessTokenScopeAll)
 		return u
 	}
 
@@ -106,8 +108,9 @@ func (b *Basic) VerifyAuthData(req *http.Request, w http.ResponseWriter, store D
 		}
 
 		store.GetData()["IsApiToken"] = true
+		store.GetData()["ApiTokenScope"] = token.Scope
 		return u
-	} else if !models.IsErrAccessTokenNotExist(err) && !models.IsErrAccessTokenEmpty(err) {
+	} else if !models.IsErrAccessTokenNotExist(err) && !models.IsErrAccessTokenEmpty(err) && !models.IsErrAccessTokenExpired(err) {
 		log.Error("GetAccessTokenBySha: %v", err)
 	}
 
@@ -115,10 +118,17 @@ func (b *Basic) VerifyAuthData(req *http.Request, w http.ResponseWriter, store D
 		return nil
 	}
 
+	if lockout.IsIPBlocked(req.RemoteAddr) {
+		log.Info("Basic Authorization: Sign in from %s is blocked", req.RemoteAddr)
+		return nil
+	}
+
 	log.Trace("Basic Authorization: Attempting SignIn for %s", uname)
 	u, err := models.UserSignIn(uname, passwd)
 	if err != nil {
-		if !models.IsErrUserNotExist(err) {
+		if models.IsErrUserNotExist(err) {
+			models.RecordFailedSignInFromIP(req.RemoteAddr)
+		} else if !models.IsErrUserLockedOut(err) {
 			log.Error("UserSignIn: %v", err)
 		}
 		return nil
@@ -23,26 +23,35 @@ var (
 
 // CheckOAuthAccessToken returns uid of user from oauth token
 func CheckOAuthAccessToken(accessToken string) int64 {
+	grant := GetOAuthAccessTokenGrant(accessToken)
+	if grant == nil {
+		return 0
+	}
+	return grant.UserID
+}
+
+// GetOAuthAccessTokenGrant returns the grant of a valid oauth access token or nil
+func GetOAuthAccessTokenGrant(accessToken string) *models.OAuth2Grant {
 	// JWT tokens require a "."
 	if !strings.Contains(accessToken, ".") {
-		return 0
+		return nil
 	}
 	token, err := models.ParseOAuth2Token(accessToken)
 	if err != nil {
 		log.Trace("ParseOAuth2Token: %v", err)
-		return 0
+		return nil
 	}
 	var grant *models.OAuth2Grant
 	if grant, err = models.GetOAuth2GrantByID(token.GrantID); err != nil || grant == nil {
-		return 0
+		return nil
 	}
 	if token.Type != models.TypeAccessToken {
-		return 0
+		return nil
 	}
 	if token.ExpiresAt < time.Now().Unix() || token.IssuedAt > time.Now().Unix() {
-		return 0
+		return nil
 	}
-	return grant.UserID
+	return grant
 }
 
 // OAuth2 implements the SingleSignOn interface and authenticates requests
@@ -88,12 +97,13 @@ func (o *OAuth2) userIDFromToken(req *http.Request, store DataStore) int64 {
 		uid := CheckOAuthAccessToken(tokenSHA)
 		if uid != 0 {
 			store.GetData()["IsApiToken"] = true
+			store.GetData()["ApiTokenScope"] = string(models.AccessTokenScopeAll)
 		}
 		return uid
 	}
 	t, err := models.GetAccessTokenBySHA(tokenSHA)
 	if err != nil {
-		if !models.IsErrAccessTokenNotExist(err) && !models.IsErrAccessTokenEmpty(err) {
+		if !models.IsErrAccessTokenNotExist(err) && !models.IsErrAccessTokenEmpty(err) && !models.IsErrAccessTokenExpired(err) {
 			log.Error("GetAccessTokenBySHA: %v", err)
 		}
 		return 0
@@ -103,6 +113,7 @@ func (o *OAuth2) userIDFromToken(req *http.Request, store DataStore) int64 {
 		log.Error("UpdateAccessToken: %v", err)
 	}
 	store.GetData()["IsApiToken"] = true
+	store.GetData()["ApiTokenScope"] = t.Scope
 	return t.UID
 }
 
@@ -5,9 +5,21 @@
 package sso
 
 import (
+	"net"
 	"net/http"
 
 	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/log"
+	"go.wandrs.dev/framework/modules/setting"
+	"go.wandrs.dev/framework/modules/web/middleware"
+)
+
+const (
+	// UserSessionKey is the session variable holding the ID of the tracked session of the user
+	UserSessionKey = "user_session_id"
+	// ImpersonatorKey is the session variable holding the ID of the administrator
+	// impersonating the user of the session
+	ImpersonatorKey = "impersonator_uid"
 )
 
 // Ensure the struct implements the interface.
@@ -37,11 +49,77 @@ func (s *Session) IsEnabled() bool {
 
 // VerifyAuthData checks if there is a user uid stored in the session and returns the user
 // object for that uid.
-// Returns nil if there is no user uid stored in the session.
+// Returns nil if there is no user uid stored in the session or if the session has been signed out remotely.
 func (s *Session) VerifyAuthData(req *http.Request, w http.ResponseWriter, store DataStore, sess SessionStore) *models.User {
 	user := SessionUser(sess)
-	if user != nil {
+	if user != nil && trackUserSession(req, w, sess, user) {
 		return user
 	}
 	return nil
 }
+
+// trackUserSession updates the tracked session of the signed in user, or starts tracking
+// the session if it is not tracked yet. It returns false if the tracked session has been
+// deleted, in which case the session is signed out. While an administrator impersonates
+// the user, the tracked session remains the one of the administrator.
+func trackUserSession(req *http.Request, w http.ResponseWriter, sess SessionStore, user *models.User) bool {
+	uid := user.ID
+	if impersonator, ok := sess.Get(ImpersonatorKey).(int64); ok {
+		uid = impersonator
+	}
+
+	ip := req.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+
+	if id, ok := sess.Get(UserSessionKey).(int64); ok {
+		us, err := models.GetUserSessionByID(id)
+		if err != nil {
+			if !models.IsErrUserSessionNotExist(err) {
+				log.Error("GetUserSessionByID: %v", err)
+				return false
+			}
+			log.Trace("Session Authorization: session %d of user %-v has been signed out", id, user)
+			signOutSession(w, sess)
+			return false
+		}
+		if us.UID == uid {
+			if err := us.UpdateActivity(ip); err != nil {
+				log.Error("UpdateActivity: %v", err)
+			}
+			return true
+		}
+	}
+
+	us := &models.UserSession{
+		UID:       uid,
+		UserAgent: req.UserAgent(),
+		IPAddress: ip,
+	}
+	if err := models.CreateUserSession(us); err != nil {
+		log.Error("CreateUserSession: %v", err)
+		return false
+	}
+	if err := sess.Set(UserSessionKey, us.ID); err != nil {
+		log.Error("Error setting session: %v", err)
+	}
+	return true
+}
+
+// signOutSession removes the user from the session and deletes the auto-login cookies
+func signOutSession(w http.ResponseWriter, sess SessionStore) {
+	_ = sess.Delete("uid")
+	_ = sess.Delete("uname")
+	_ = sess.Delete(UserSessionKey)
+	_ = sess.Delete(ImpersonatorKey)
+	for _, name := range []string{setting.CookieUserName, setting.CookieRememberName} {
+		middleware.SetCookie(w, name, "",
+			-1,
+			setting.AppSubURL,
+			setting.SessionConfig.Domain,
+			setting.SessionConfig.Secure,
+			true,
+			middleware.SameSite(setting.SessionConfig.SameSite))
+	}
+}
@@ -115,7 +115,8 @@ func handleSignIn(resp http.ResponseWriter, req *http.Request, sess SessionStore
 	_ = sess.Delete("openid_determined_username")
 	_ = sess.Delete("twofaUid")
 	_ = sess.Delete("twofaRemember")
-	_ = sess.Delete("u2fChallenge")
+	_ = sess.Delete("webauthnAssertion")
+	_ = sess.Delete("webauthnPasskeyAssertion")
 	_ = sess.Delete("linkAccount")
 	err := sess.Set("uid", user.ID)
 	if err != nil {
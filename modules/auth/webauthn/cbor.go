@@ -0,0 +1,248 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Only the subset of CBOR (RFC 7049) used by authenticators is supported:
+// definite length items, integer and text map keys and simple values.
+
+const (
+	cborUnsigned   = 0
+	cborNegative   = 1
+	cborByteString = 2
+	cborTextString = 3
+	cborArray      = 4
+	cborMap        = 5
+	cborTag        = 6
+	cborSimple     = 7
+
+	// maxCBORItems bounds the length of arrays and maps to protect against malicious input
+	maxCBORItems = 1024
+	// maxCBORDepth bounds the nesting of arrays and maps
+	maxCBORDepth = 16
+)
+
+var errCBORTruncated = errors.New("cbor: unexpected end of data")
+
+// decodeCBOR decodes the first CBOR item of data and returns it with the remaining bytes.
+// Integers are returned as int64, byte strings as []byte, text strings as string,
+// arrays as []interface{} and maps as map[interface{}]interface{}.
+func decodeCBOR(data []byte) (interface{}, []byte, error) {
+	d := &cborDecoder{data: data}
+	v, err := d.decode(0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return v, d.data[d.pos:], nil
+}
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *cborDecoder) read(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, errCBORTruncated
+	}
+	b := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+// head reads the initial byte and argument of an item
+func (d *cborDecoder) head() (major byte, info byte, arg uint64, err error) {
+	b, err := d.read(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, info = b[0]>>5, b[0]&0x1f
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info == 24:
+		b, err = d.read(1)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		return major, info, uint64(b[0]), nil
+	case info == 25:
+		b, err = d.read(2)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		return major, info, uint64(binary.BigEndian.Uint16(b)), nil
+	case info == 26:
+		b, err = d.read(4)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		return major, info, uint64(binary.BigEndian.Uint32(b)), nil
+	case info == 27:
+		b, err = d.read(8)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		return major, info, binary.BigEndian.Uint64(b), nil
+	}
+	return 0, 0, 0, fmt.Errorf("cbor: unsupported additional information %d", info)
+}
+
+func (d *cborDecoder) decode(depth int) (interface{}, error) {
+	if depth > maxCBORDepth {
+		return nil, errors.New("cbor: nesting too deep")
+	}
+	major, info, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case cborUnsigned:
+		if arg > math.MaxInt64 {
+			return nil, errors.New("cbor: integer overflow")
+		}
+		return int64(arg), nil
+	case cborNegative:
+		if arg > math.MaxInt64 {
+			return nil, errors.New("cbor: integer overflow")
+		}
+		return -1 - int64(arg), nil
+	case cborByteString:
+		b, err := d.read(arg)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case cborTextString:
+		b, err := d.read(arg)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case cborArray:
+		if arg > maxCBORItems {
+			return nil, errors.New("cbor: array too long")
+		}
+		arr := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			v, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	case cborMap:
+		if arg > maxCBORItems {
+			return nil, errors.New("cbor: map too long")
+		}
+		m := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			k, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch k.(type) {
+			case int64, string:
+			default:
+				return nil, fmt.Errorf("cbor: unsupported map key type %T", k)
+			}
+			v, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = v
+		}
+		return m, nil
+	case cborTag:
+		// tags carry no meaning for the structures used here, return the tagged item
+		return d.decode(depth + 1)
+	case cborSimple:
+		switch {
+		case info == 20:
+			return false, nil
+		case info == 21:
+			return true, nil
+		case info == 22, info == 23:
+			return nil, nil
+		case info == 25:
+			return float64(halfToFloat32(uint16(arg))), nil
+		case info == 26:
+			return float64(math.Float32frombits(uint32(arg))), nil
+		case info == 27:
+			return math.Float64frombits(arg), nil
+		}
+	}
+	return nil, fmt.Errorf("cbor: unsupported item (major type %d, info %d)", major, info)
+}
+
+func halfToFloat32(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exp := uint32(h>>10) & 0x1f
+	frac := uint32(h) & 0x3ff
+	switch exp {
+	case 0:
+		f := float32(frac) / (1 << 24)
+		if sign != 0 {
+			f = -f
+		}
+		return f
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | frac<<13)
+	}
+	return math.Float32frombits(sign | (exp+112)<<23 | frac<<13)
+}
+
+// cborEncoder writes the CBOR items needed to build COSE keys and attestation objects
+type cborEncoder struct {
+	buf []byte
+}
+
+func (e *cborEncoder) head(major byte, arg uint64) {
+	switch {
+	case arg < 24:
+		e.buf = append(e.buf, major<<5|byte(arg))
+	case arg <= math.MaxUint8:
+		e.buf = append(e.buf, major<<5|24, byte(arg))
+	case arg <= math.MaxUint16:
+		e.buf = append(e.buf, major<<5|25, byte(arg>>8), byte(arg))
+	case arg <= math.MaxUint32:
+		e.buf = append(e.buf, major<<5|26)
+		e.buf = append(e.buf, make([]byte, 4)...)
+		binary.BigEndian.PutUint32(e.buf[len(e.buf)-4:], uint32(arg))
+	default:
+		e.buf = append(e.buf, major<<5|27)
+		e.buf = append(e.buf, make([]byte, 8)...)
+		binary.BigEndian.PutUint64(e.buf[len(e.buf)-8:], arg)
+	}
+}
+
+func (e *cborEncoder) writeInt(v int64) {
+	if v < 0 {
+		e.head(cborNegative, uint64(-1-v))
+		return
+	}
+	e.head(cborUnsigned, uint64(v))
+}
+
+func (e *cborEncoder) writeBytes(b []byte) {
+	e.head(cborByteString, uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *cborEncoder) writeString(s string) {
+	e.head(cborTextString, uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *cborEncoder) writeMapHeader(n int) {
+	e.head(cborMap, uint64(n))
+}
@@ -0,0 +1,140 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithm identifiers (RFC 8152) supported for credentials
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+	AlgRS256 int64 = -257
+)
+
+// COSE key parameters
+const (
+	coseKeyType      int64 = 1
+	coseKeyAlgorithm int64 = 3
+	coseKeyCurve     int64 = -1 // n for RSA keys
+	coseKeyX         int64 = -2 // e for RSA keys
+	coseKeyY         int64 = -3
+
+	coseKeyTypeOKP int64 = 1
+	coseKeyTypeEC2 int64 = 2
+	coseKeyTypeRSA int64 = 3
+
+	coseCurveP256    int64 = 1
+	coseCurveEd25519 int64 = 6
+)
+
+// PublicKey is the public key of a credential together with its signature algorithm
+type PublicKey struct {
+	Algorithm int64
+	Key       crypto.PublicKey
+}
+
+// ParsePublicKey parses a COSE encoded public key as found in the authenticator data
+func ParsePublicKey(data []byte) (*PublicKey, error) {
+	v, rest, err := decodeCBOR(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("webauthn: trailing data after public key")
+	}
+	return parsePublicKey(v)
+}
+
+func parsePublicKey(v interface{}) (*PublicKey, error) {
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("webauthn: public key is not a map")
+	}
+	kty, _ := m[coseKeyType].(int64)
+	alg, _ := m[coseKeyAlgorithm].(int64)
+	switch {
+	case kty == coseKeyTypeEC2 && alg == AlgES256:
+		crv, _ := m[coseKeyCurve].(int64)
+		x, _ := m[coseKeyX].([]byte)
+		y, _ := m[coseKeyY].([]byte)
+		if crv != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("webauthn: invalid EC2 public key")
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("webauthn: EC2 public key is not on the curve")
+		}
+		return &PublicKey{Algorithm: alg, Key: key}, nil
+	case kty == coseKeyTypeRSA && alg == AlgRS256:
+		n, _ := m[coseKeyCurve].([]byte)
+		e, _ := m[coseKeyX].([]byte)
+		if len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("webauthn: invalid RSA public key")
+		}
+		exp := int(new(big.Int).SetBytes(e).Int64())
+		return &PublicKey{Algorithm: alg, Key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exp}}, nil
+	case kty == coseKeyTypeOKP && alg == AlgEdDSA:
+		crv, _ := m[coseKeyCurve].(int64)
+		x, _ := m[coseKeyX].([]byte)
+		if crv != coseCurveEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("webauthn: invalid OKP public key")
+		}
+		return &PublicKey{Algorithm: alg, Key: ed25519.PublicKey(x)}, nil
+	}
+	return nil, fmt.Errorf("webauthn: unsupported public key (kty %d, alg %d)", kty, alg)
+}
+
+// Verify checks the signature of the data made by the private key
+func (key *PublicKey) Verify(data, sig []byte) error {
+	switch k := key.Key.(type) {
+	case *ecdsa.PublicKey:
+		hash := sha256.Sum256(data)
+		if ecdsa.VerifyASN1(k, hash[:], sig) {
+			return nil
+		}
+	case *rsa.PublicKey:
+		hash := sha256.Sum256(data)
+		if rsa.VerifyPKCS1v15(k, crypto.SHA256, hash[:], sig) == nil {
+			return nil
+		}
+	case ed25519.PublicKey:
+		if ed25519.Verify(k, data, sig) {
+			return nil
+		}
+	}
+	return errors.New("webauthn: invalid signature")
+}
+
+// MarshalECDSAPublicKey encodes a P-256 public key, as used by FIDO U2F security keys, in the COSE format
+func MarshalECDSAPublicKey(key *ecdsa.PublicKey) []byte {
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	key.X.FillBytes(x)
+	key.Y.FillBytes(y)
+
+	e := &cborEncoder{}
+	e.writeMapHeader(5)
+	e.writeInt(coseKeyType)
+	e.writeInt(coseKeyTypeEC2)
+	e.writeInt(coseKeyAlgorithm)
+	e.writeInt(AlgES256)
+	e.writeInt(coseKeyCurve)
+	e.writeInt(coseCurveP256)
+	e.writeInt(coseKeyX)
+	e.writeBytes(x)
+	e.writeInt(coseKeyY)
+	e.writeBytes(y)
+	return e.buf
+}
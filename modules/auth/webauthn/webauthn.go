@@ -0,0 +1,482 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go.wandrs.dev/framework/modules/setting"
+)
+
+// Timeout is the time in milliseconds the browser waits for the authenticator
+const Timeout = 60000
+
+// challengeLength is the number of random bytes of a challenge
+const challengeLength = 32
+
+// Authenticator data flags
+const (
+	flagUserPresent            byte = 0x01
+	flagUserVerified           byte = 0x04
+	flagAttestedCredentialData byte = 0x40
+)
+
+// Config describes the relying party, that is this instance
+type Config struct {
+	// RPID is the domain the credentials are scoped to
+	RPID string
+	// RPOrigin is the origin the browser must report in the client data
+	RPOrigin string
+	// RPDisplayName is shown to the user by the browser
+	RPDisplayName string
+	// AppID is the FIDO U2F application id credentials registered with the legacy U2F API are scoped to
+	AppID string
+}
+
+// NewConfig returns the relying party configuration of this instance
+func NewConfig() *Config {
+	return &Config{
+		RPID:          setting.WebAuthn.RPID,
+		RPOrigin:      setting.WebAuthn.RPOrigin,
+		RPDisplayName: setting.AppName,
+		AppID:         setting.U2F.AppID,
+	}
+}
+
+// URLEncodedBase64 is binary data transmitted as unpadded base64url, as done by the browsers
+type URLEncodedBase64 []byte
+
+// MarshalJSON encodes the data as an unpadded base64url string
+func (b URLEncodedBase64) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+// UnmarshalJSON decodes a base64url string, padded or not
+func (b *URLEncodedBase64) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*b = nil
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(string(bytes.TrimRight([]byte(s), "=")))
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+// CredentialDescriptor identifies a credential registered to the user
+type CredentialDescriptor struct {
+	Type       string           `json:"type"`
+	ID         URLEncodedBase64 `json:"id"`
+	Transports []string         `json:"transports,omitempty"`
+}
+
+// NewCredentialDescriptor returns the descriptor of a public key credential
+func NewCredentialDescriptor(id []byte, transports []string) CredentialDescriptor {
+	return CredentialDescriptor{Type: "public-key", ID: id, Transports: transports}
+}
+
+// SessionData is kept in the session of the user between the two steps of a ceremony
+type SessionData struct {
+	Challenge            []byte
+	UserID               int64
+	AllowedCredentialIDs [][]byte
+	// UserVerificationRequired is set when the assertion replaces the password of the user
+	UserVerificationRequired bool
+}
+
+func newChallenge() ([]byte, error) {
+	challenge := make([]byte, challengeLength)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// UserEntity describes the account a credential is created for
+type UserEntity struct {
+	ID          URLEncodedBase64 `json:"id"`
+	Name        string           `json:"name"`
+	DisplayName string           `json:"displayName"`
+}
+
+type relyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type credentialParameter struct {
+	Type      string `json:"type"`
+	Algorithm int64  `json:"alg"`
+}
+
+type authenticatorSelection struct {
+	ResidentKey      string `json:"residentKey,omitempty"`
+	UserVerification string `json:"userVerification,omitempty"`
+}
+
+// PublicKeyCredentialCreationOptions are passed to navigator.credentials.create()
+type PublicKeyCredentialCreationOptions struct {
+	Challenge              URLEncodedBase64       `json:"challenge"`
+	RelyingParty           relyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	Parameters             []credentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int                    `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection authenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// CredentialCreation is the response to the registration request
+type CredentialCreation struct {
+	PublicKey PublicKeyCredentialCreationOptions `json:"publicKey"`
+}
+
+// BeginRegistration starts the registration ceremony of a new credential for the user,
+// the already registered credentials are excluded
+func (c *Config) BeginRegistration(user UserEntity, userID int64, exclude []CredentialDescriptor) (*CredentialCreation, *SessionData, error) {
+	challenge, err := newChallenge()
+	if err != nil {
+		return nil, nil, err
+	}
+	if exclude == nil {
+		exclude = []CredentialDescriptor{}
+	}
+	return &CredentialCreation{
+		PublicKey: PublicKeyCredentialCreationOptions{
+			Challenge:    challenge,
+			RelyingParty: relyingPartyEntity{ID: c.RPID, Name: c.RPDisplayName},
+			User:         user,
+			Parameters: []credentialParameter{
+				{Type: "public-key", Algorithm: AlgES256},
+				{Type: "public-key", Algorithm: AlgEdDSA},
+				{Type: "public-key", Algorithm: AlgRS256},
+			},
+			Timeout:            Timeout,
+			ExcludeCredentials: exclude,
+			// discoverable credentials can also be used as passkeys to sign in without a password
+			AuthenticatorSelection: authenticatorSelection{
+				ResidentKey:      "preferred",
+				UserVerification: "preferred",
+			},
+			Attestation: "none",
+		},
+	}, &SessionData{
+		Challenge: challenge,
+		UserID:    userID,
+	}, nil
+}
+
+type assertionExtensions struct {
+	AppID string `json:"appid,omitempty"`
+}
+
+// PublicKeyCredentialRequestOptions are passed to navigator.credentials.get()
+type PublicKeyCredentialRequestOptions struct {
+	Challenge        URLEncodedBase64       `json:"challenge"`
+	Timeout          int                    `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+	Extensions       *assertionExtensions   `json:"extensions,omitempty"`
+}
+
+// CredentialAssertion is the response to the authentication request
+type CredentialAssertion struct {
+	PublicKey PublicKeyCredentialRequestOptions `json:"publicKey"`
+}
+
+// BeginLogin starts the authentication ceremony of the user with one of the allowed credentials,
+// legacy selects whether credentials registered with the FIDO U2F API may be used
+func (c *Config) BeginLogin(userID int64, allow []CredentialDescriptor, legacy bool) (*CredentialAssertion, *SessionData, error) {
+	challenge, err := newChallenge()
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := make([][]byte, 0, len(allow))
+	for _, desc := range allow {
+		ids = append(ids, desc.ID)
+	}
+	assertion := &CredentialAssertion{
+		PublicKey: PublicKeyCredentialRequestOptions{
+			Challenge:        challenge,
+			Timeout:          Timeout,
+			RPID:             c.RPID,
+			AllowCredentials: allow,
+			UserVerification: "discouraged",
+		},
+	}
+	if legacy && c.AppID != "" {
+		assertion.PublicKey.Extensions = &assertionExtensions{AppID: c.AppID}
+	}
+	return assertion, &SessionData{
+		Challenge:            challenge,
+		UserID:               userID,
+		AllowedCredentialIDs: ids,
+	}, nil
+}
+
+// BeginDiscoverableLogin starts the authentication ceremony of a user that is not known yet.
+// The browser lets the user choose one of the discoverable credentials, the user is identified
+// by the user handle of the assertion. User verification is required as no password is used.
+func (c *Config) BeginDiscoverableLogin() (*CredentialAssertion, *SessionData, error) {
+	challenge, err := newChallenge()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &CredentialAssertion{
+		PublicKey: PublicKeyCredentialRequestOptions{
+			Challenge:        challenge,
+			Timeout:          Timeout,
+			RPID:             c.RPID,
+			AllowCredentials: []CredentialDescriptor{},
+			UserVerification: "required",
+		},
+	}, &SessionData{
+		Challenge:                challenge,
+		UserVerificationRequired: true,
+	}, nil
+}
+
+type collectedClientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+func (c *Config) verifyClientData(clientDataJSON []byte, ceremony string, session *SessionData) error {
+	var clientData collectedClientData
+	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
+		return fmt.Errorf("webauthn: invalid client data: %v", err)
+	}
+	if clientData.Type != ceremony {
+		return fmt.Errorf("webauthn: unexpected client data type %q", clientData.Type)
+	}
+	challenge, err := base64.RawURLEncoding.DecodeString(clientData.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(challenge, session.Challenge) != 1 {
+		return errors.New("webauthn: challenge mismatch")
+	}
+	if clientData.Origin != c.RPOrigin {
+		return fmt.Errorf("webauthn: unexpected origin %q", clientData.Origin)
+	}
+	return nil
+}
+
+// authenticatorData is the parsed data signed by the authenticator
+type authenticatorData struct {
+	RPIDHash     []byte
+	Flags        byte
+	SignCount    uint32
+	AAGUID       []byte
+	CredentialID []byte
+	PublicKey    []byte
+}
+
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, errors.New("webauthn: authenticator data too short")
+	}
+	authData := &authenticatorData{
+		RPIDHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	if authData.Flags&flagAttestedCredentialData == 0 {
+		return authData, nil
+	}
+	rest := data[37:]
+	if len(rest) < 18 {
+		return nil, errors.New("webauthn: attested credential data too short")
+	}
+	authData.AAGUID = rest[:16]
+	idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if len(rest) < idLength {
+		return nil, errors.New("webauthn: credential id truncated")
+	}
+	authData.CredentialID = rest[:idLength]
+	rest = rest[idLength:]
+	// the public key is followed by the extensions, if any
+	_, extensions, err := decodeCBOR(rest)
+	if err != nil {
+		return nil, fmt.Errorf("webauthn: invalid credential public key: %v", err)
+	}
+	authData.PublicKey = rest[:len(rest)-len(extensions)]
+	return authData, nil
+}
+
+func rpIDHashMatches(hash []byte, id string) bool {
+	expected := sha256.Sum256([]byte(id))
+	return subtle.ConstantTimeCompare(hash, expected[:]) == 1
+}
+
+// AuthenticatorAttestationResponse holds the data returned by the authenticator on registration
+type AuthenticatorAttestationResponse struct {
+	ClientDataJSON    URLEncodedBase64 `json:"clientDataJSON"`
+	AttestationObject URLEncodedBase64 `json:"attestationObject"`
+	Transports        []string         `json:"transports"`
+}
+
+// CredentialCreationResponse is the credential created by navigator.credentials.create()
+type CredentialCreationResponse struct {
+	ID       string                           `json:"id"`
+	RawID    URLEncodedBase64                 `json:"rawId"`
+	Type     string                           `json:"type"`
+	Response AuthenticatorAttestationResponse `json:"response"`
+}
+
+// Credential is a verified newly registered credential
+type Credential struct {
+	ID              []byte
+	PublicKey       []byte
+	AttestationType string
+	AAGUID          []byte
+	SignCount       uint32
+	Transports      []string
+}
+
+// FinishRegistration verifies the created credential against the registration session.
+// As no attestation is requested the attestation statement is not verified.
+func (c *Config) FinishRegistration(session *SessionData, resp *CredentialCreationResponse) (*Credential, error) {
+	if resp.Type != "public-key" {
+		return nil, fmt.Errorf("webauthn: unexpected credential type %q", resp.Type)
+	}
+	if err := c.verifyClientData(resp.Response.ClientDataJSON, "webauthn.create", session); err != nil {
+		return nil, err
+	}
+
+	v, _, err := decodeCBOR(resp.Response.AttestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("webauthn: invalid attestation object: %v", err)
+	}
+	attestation, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("webauthn: invalid attestation object")
+	}
+	format, _ := attestation["fmt"].(string)
+	rawAuthData, _ := attestation["authData"].([]byte)
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if !rpIDHashMatches(authData.RPIDHash, c.RPID) {
+		return nil, errors.New("webauthn: relying party id mismatch")
+	}
+	if authData.Flags&flagUserPresent == 0 {
+		return nil, errors.New("webauthn: user not present")
+	}
+	if authData.CredentialID == nil {
+		return nil, errors.New("webauthn: no attested credential data")
+	}
+	if !bytes.Equal(authData.CredentialID, resp.RawID) {
+		return nil, errors.New("webauthn: credential id mismatch")
+	}
+	if _, err := ParsePublicKey(authData.PublicKey); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:              authData.CredentialID,
+		PublicKey:       authData.PublicKey,
+		AttestationType: format,
+		AAGUID:          authData.AAGUID,
+		SignCount:       authData.SignCount,
+		Transports:      resp.Response.Transports,
+	}, nil
+}
+
+// AuthenticatorAssertionResponse holds the data returned by the authenticator on authentication
+type AuthenticatorAssertionResponse struct {
+	ClientDataJSON    URLEncodedBase64 `json:"clientDataJSON"`
+	AuthenticatorData URLEncodedBase64 `json:"authenticatorData"`
+	Signature         URLEncodedBase64 `json:"signature"`
+	UserHandle        URLEncodedBase64 `json:"userHandle"`
+}
+
+type clientExtensionResults struct {
+	AppID bool `json:"appid"`
+}
+
+// CredentialAssertionResponse is the assertion made by navigator.credentials.get()
+type CredentialAssertionResponse struct {
+	ID                     string                         `json:"id"`
+	RawID                  URLEncodedBase64               `json:"rawId"`
+	Type                   string                         `json:"type"`
+	Response               AuthenticatorAssertionResponse `json:"response"`
+	ClientExtensionResults clientExtensionResults         `json:"clientExtensionResults"`
+}
+
+// ErrClonedAuthenticator is returned when the signature counter of the authenticator did not increase,
+// which indicates the credential may have been cloned
+var ErrClonedAuthenticator = errors.New("webauthn: signature counter did not increase")
+
+// FinishLogin verifies the assertion against the authentication session with the stored public key
+// and sign counter of the credential and returns the new sign counter
+func (c *Config) FinishLogin(session *SessionData, resp *CredentialAssertionResponse, publicKey []byte, signCount uint32) (uint32, error) {
+	if resp.Type != "public-key" {
+		return 0, fmt.Errorf("webauthn: unexpected credential type %q", resp.Type)
+	}
+	if len(session.AllowedCredentialIDs) > 0 {
+		allowed := false
+		for _, id := range session.AllowedCredentialIDs {
+			if bytes.Equal(id, resp.RawID) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return 0, errors.New("webauthn: credential not allowed")
+		}
+	}
+	if err := c.verifyClientData(resp.Response.ClientDataJSON, "webauthn.get", session); err != nil {
+		return 0, err
+	}
+
+	authData, err := parseAuthenticatorData(resp.Response.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+	rpID := c.RPID
+	if resp.ClientExtensionResults.AppID && c.AppID != "" {
+		rpID = c.AppID
+	}
+	if !rpIDHashMatches(authData.RPIDHash, rpID) {
+		return 0, errors.New("webauthn: relying party id mismatch")
+	}
+	if authData.Flags&flagUserPresent == 0 {
+		return 0, errors.New("webauthn: user not present")
+	}
+	if session.UserVerificationRequired && authData.Flags&flagUserVerified == 0 {
+		return 0, errors.New("webauthn: user not verified")
+	}
+
+	key, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(resp.Response.ClientDataJSON)
+	signed := append(append([]byte{}, resp.Response.AuthenticatorData...), clientDataHash[:]...)
+	if err := key.Verify(signed, resp.Response.Signature); err != nil {
+		return 0, err
+	}
+
+	if (authData.SignCount != 0 || signCount != 0) && authData.SignCount <= signCount {
+		return 0, ErrClonedAuthenticator
+	}
+	return authData.SignCount, nil
+}
@@ -0,0 +1,211 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testConfig = &Config{
+	RPID:          "example.com",
+	RPOrigin:      "https://example.com",
+	RPDisplayName: "Example",
+	AppID:         "https://example.com",
+}
+
+// testAuthenticator is a software authenticator holding a single credential
+type testAuthenticator struct {
+	credentialID []byte
+	key          *ecdsa.PrivateKey
+	signCount    uint32
+	userVerified bool
+	userHandle   []byte
+}
+
+func newTestAuthenticator(t *testing.T) *testAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	return &testAuthenticator{credentialID: []byte("credential-id"), key: key}
+}
+
+func clientDataJSON(ceremony string, challenge []byte, origin string) []byte {
+	data, _ := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    origin,
+	})
+	return data
+}
+
+func (a *testAuthenticator) authData(rpID string, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append([]byte{}, rpIDHash[:]...)
+	flags := flagUserPresent
+	if a.userVerified {
+		flags |= flagUserVerified
+	}
+	if attested {
+		flags |= flagAttestedCredentialData
+	}
+	data = append(data, flags, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[33:], a.signCount)
+	if attested {
+		data = append(data, make([]byte, 16)...) // aaguid
+		data = append(data, byte(len(a.credentialID)>>8), byte(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+		data = append(data, MarshalECDSAPublicKey(&a.key.PublicKey)...)
+	}
+	return data
+}
+
+func (a *testAuthenticator) create(session *SessionData) *CredentialCreationResponse {
+	e := &cborEncoder{}
+	e.writeMapHeader(3)
+	e.writeString("fmt")
+	e.writeString("none")
+	e.writeString("attStmt")
+	e.writeMapHeader(0)
+	e.writeString("authData")
+	e.writeBytes(a.authData(testConfig.RPID, true))
+
+	return &CredentialCreationResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(a.credentialID),
+		RawID: a.credentialID,
+		Type:  "public-key",
+		Response: AuthenticatorAttestationResponse{
+			ClientDataJSON:    clientDataJSON("webauthn.create", session.Challenge, testConfig.RPOrigin),
+			AttestationObject: e.buf,
+			Transports:        []string{"usb"},
+		},
+	}
+}
+
+func (a *testAuthenticator) get(t *testing.T, session *SessionData, rpID string) *CredentialAssertionResponse {
+	a.signCount++
+	authData := a.authData(rpID, false)
+	clientData := clientDataJSON("webauthn.get", session.Challenge, testConfig.RPOrigin)
+	clientDataHash := sha256.Sum256(clientData)
+	hash := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, hash[:])
+	assert.NoError(t, err)
+
+	return &CredentialAssertionResponse{
+		ID:    base64.RawURLEncoding.EncodeToString(a.credentialID),
+		RawID: a.credentialID,
+		Type:  "public-key",
+		Response: AuthenticatorAssertionResponse{
+			ClientDataJSON:    clientData,
+			AuthenticatorData: authData,
+			Signature:         sig,
+			UserHandle:        a.userHandle,
+		},
+		ClientExtensionResults: clientExtensionResults{AppID: rpID == testConfig.AppID},
+	}
+}
+
+func TestRegistrationAndLogin(t *testing.T) {
+	authenticator := newTestAuthenticator(t)
+
+	creation, session, err := testConfig.BeginRegistration(UserEntity{ID: []byte{1}, Name: "user1", DisplayName: "User One"}, 1, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "example.com", creation.PublicKey.RelyingParty.ID)
+	assert.Equal(t, session.Challenge, []byte(creation.PublicKey.Challenge))
+
+	cred, err := testConfig.FinishRegistration(session, authenticator.create(session))
+	assert.NoError(t, err)
+	assert.Equal(t, authenticator.credentialID, cred.ID)
+	assert.Equal(t, "none", cred.AttestationType)
+	assert.Equal(t, []string{"usb"}, cred.Transports)
+
+	// a response to another challenge is rejected
+	_, otherSession, err := testConfig.BeginRegistration(UserEntity{ID: []byte{1}, Name: "user1"}, 1, nil)
+	assert.NoError(t, err)
+	_, err = testConfig.FinishRegistration(otherSession, authenticator.create(session))
+	assert.Error(t, err)
+
+	assertion, session, err := testConfig.BeginLogin(1, []CredentialDescriptor{NewCredentialDescriptor(cred.ID, cred.Transports)}, false)
+	assert.NoError(t, err)
+	assert.Nil(t, assertion.PublicKey.Extensions)
+
+	signCount, err := testConfig.FinishLogin(session, authenticator.get(t, session, testConfig.RPID), cred.PublicKey, cred.SignCount)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, signCount)
+
+	// a replayed or cloned authenticator does not increase the counter
+	authenticator.signCount = 0
+	_, err = testConfig.FinishLogin(session, authenticator.get(t, session, testConfig.RPID), cred.PublicKey, signCount)
+	assert.Equal(t, ErrClonedAuthenticator, err)
+
+	// the signature must be made by the registered key
+	_, err = testConfig.FinishLogin(session, newTestAuthenticator(t).get(t, session, testConfig.RPID), cred.PublicKey, 0)
+	assert.Error(t, err)
+}
+
+func TestLegacyU2FLogin(t *testing.T) {
+	authenticator := newTestAuthenticator(t)
+	publicKey := MarshalECDSAPublicKey(&authenticator.key.PublicKey)
+
+	assertion, session, err := testConfig.BeginLogin(1, []CredentialDescriptor{NewCredentialDescriptor(authenticator.credentialID, nil)}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, testConfig.AppID, assertion.PublicKey.Extensions.AppID)
+
+	// U2F credentials are scoped to the application id instead of the relying party id
+	_, err = testConfig.FinishLogin(session, authenticator.get(t, session, testConfig.AppID), publicKey, 0)
+	assert.NoError(t, err)
+
+	// credentials which were not allowed are rejected
+	_, session, err = testConfig.BeginLogin(1, []CredentialDescriptor{NewCredentialDescriptor([]byte("other"), nil)}, true)
+	assert.NoError(t, err)
+	_, err = testConfig.FinishLogin(session, authenticator.get(t, session, testConfig.AppID), publicKey, 0)
+	assert.Error(t, err)
+}
+
+func TestDiscoverableLogin(t *testing.T) {
+	authenticator := newTestAuthenticator(t)
+	authenticator.userHandle = []byte{1}
+	publicKey := MarshalECDSAPublicKey(&authenticator.key.PublicKey)
+
+	assertion, session, err := testConfig.BeginDiscoverableLogin()
+	assert.NoError(t, err)
+	assert.Empty(t, assertion.PublicKey.AllowCredentials)
+	assert.Equal(t, "required", assertion.PublicKey.UserVerification)
+
+	// the user must be verified when no password is used
+	_, err = testConfig.FinishLogin(session, authenticator.get(t, session, testConfig.RPID), publicKey, 0)
+	assert.Error(t, err)
+
+	authenticator.userVerified = true
+	resp := authenticator.get(t, session, testConfig.RPID)
+	assert.Equal(t, []byte{1}, []byte(resp.Response.UserHandle))
+	signCount, err := testConfig.FinishLogin(session, resp, publicKey, 1)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, signCount)
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	e := &cborEncoder{}
+	e.writeMapHeader(2)
+	e.writeInt(-300)
+	e.writeBytes([]byte{1, 2, 3})
+	e.writeString("key")
+	e.writeInt(70000)
+
+	v, rest, err := decodeCBOR(append(e.buf, 0xff))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xff}, rest)
+	assert.Equal(t, map[interface{}]interface{}{int64(-300): []byte{1, 2, 3}, "key": int64(70000)}, v)
+
+	_, _, err = decodeCBOR(e.buf[:len(e.buf)-1])
+	assert.Error(t, err)
+}
@@ -14,6 +14,12 @@ import (
 
 var conn mc.Cache
 
+// Counter is implemented by caches that can increase a counter and set its
+// expire time in one atomic step
+type Counter interface {
+	IncrWithTimeout(key string, expire int64) (int64, error)
+}
+
 func newCache(cacheConfig setting.Cache) (mc.Cache, error) {
 	return mc.NewCacher(mc.Options{
 		Adapter:       cacheConfig.Adapter,
@@ -78,6 +78,30 @@ func (c *RedisCacher) Incr(key string) error {
 	return c.c.Incr(graceful.GetManager().HammerContext(), c.prefix+key).Err()
 }
 
+// incrWithTimeout increases the counter and sets the expiry when it creates it,
+// so that concurrent callers on other instances cannot lose an increment
+var incrWithTimeout = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if n == 1 then
+	redis.call("EXPIRE", KEYS[1], ARGV[1])
+end
+return n
+`)
+
+// IncrWithTimeout increases the counter of the key and returns its new value.
+// A counter that does not exist is created with the given expire time.
+func (c *RedisCacher) IncrWithTimeout(key string, expire int64) (int64, error) {
+	key = c.prefix + key
+	n, err := incrWithTimeout.Run(graceful.GetManager().HammerContext(), c.c, []string{key}, expire).Int64()
+	if err != nil {
+		return 0, err
+	}
+	if n == 1 && !c.occupyMode {
+		return n, c.c.HSet(graceful.GetManager().HammerContext(), c.hsetName, key, "0").Err()
+	}
+	return n, nil
+}
+
 // Decr decreases cached int-type value by given key as a counter.
 func (c *RedisCacher) Decr(key string) error {
 	if !c.IsExist(key) {
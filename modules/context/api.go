@@ -208,6 +208,8 @@ func (ctx *APIContext) CheckForOTP() {
 		return
 	}
 	if !ok {
+		models.RecordFailedSignIn(ctx.Context.User)
+		models.RecordFailedSignInFromIP(ctx.RemoteAddr())
 		ctx.Context.Error(401)
 		return
 	}
@@ -244,6 +246,11 @@ func APIContexter() func(http.Handler) http.Handler {
 
 			// Get user from session if logged in.
 			ctx.User, ctx.IsBasicAuth = sso.SignedInUser(ctx.Req, ctx.Resp, &ctx, ctx.Session)
+			// the session may be used by an administrator impersonating the user
+			isAPIToken, _ := ctx.Data["IsApiToken"].(bool)
+			if ctx.User != nil && !ctx.IsBasicAuth && !isAPIToken {
+				ctx.loadImpersonator()
+			}
 			if ctx.User != nil {
 				ctx.IsSigned = true
 				ctx.Data["IsSigned"] = ctx.IsSigned
@@ -0,0 +1,28 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package context
+
+import (
+	"net"
+
+	"go.wandrs.dev/framework/models"
+)
+
+// Audit writes an event to the audit log, made by the signed in user from the address of the request.
+// Changes made while impersonating a user are made by the impersonating administrator.
+func (ctx *Context) Audit(evt *models.AuditEvent) {
+	if ctx.Impersonator != nil {
+		evt.ActorID = ctx.Impersonator.ID
+		evt.ActorName = ctx.Impersonator.Name
+	} else if ctx.User != nil {
+		evt.ActorID = ctx.User.ID
+		evt.ActorName = ctx.User.Name
+	}
+	evt.IPAddress = ctx.RemoteAddr()
+	if host, _, err := net.SplitHostPort(evt.IPAddress); err == nil {
+		evt.IPAddress = host
+	}
+	models.RecordAuditEvent(evt)
+}
@@ -166,6 +166,8 @@ func ToggleAPI(options *ToggleOptions) func(ctx *APIContext) {
 					return
 				}
 				if !ok {
+					models.RecordFailedSignIn(ctx.User)
+					models.RecordFailedSignInFromIP(ctx.RemoteAddr())
 					ctx.JSON(http.StatusForbidden, map[string]string{
 						"message": "Only signed in user is allowed to call APIs.",
 					})
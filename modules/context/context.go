@@ -64,6 +64,8 @@ type Context struct {
 	User        *models.User
 	IsSigned    bool
 	IsBasicAuth bool
+	// Impersonator is the administrator impersonating User, if any
+	Impersonator *models.User
 
 	Org *Organization
 }
@@ -698,6 +700,9 @@ func Contexter() func(next http.Handler) http.Handler {
 
 			// Get user from session if logged in.
 			ctx.User, ctx.IsBasicAuth = sso.SignedInUser(ctx.Req, ctx.Resp, &ctx, ctx.Session)
+			if ctx.User != nil && !ctx.IsBasicAuth {
+				ctx.loadImpersonator()
+			}
 
 			if ctx.User != nil {
 				ctx.IsSigned = true
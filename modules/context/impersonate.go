@@ -0,0 +1,67 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package context
+
+import (
+	"net/http"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/auth/sso"
+	"go.wandrs.dev/framework/modules/log"
+)
+
+// loadImpersonator loads the administrator impersonating the signed in user. If the
+// administrator has been deleted or is no longer allowed to impersonate, the session
+// is signed out.
+func (ctx *Context) loadImpersonator() {
+	id, ok := ctx.Session.Get(sso.ImpersonatorKey).(int64)
+	if !ok {
+		return
+	}
+
+	impersonator, err := models.GetUserByID(id)
+	if err != nil && !models.IsErrUserNotExist(err) {
+		log.Error("GetUserByID: %v", err)
+	}
+	if err != nil || !impersonator.IsAdmin || impersonator.ProhibitLogin {
+		log.Info("Impersonation of %s by user %d ended as the user may no longer impersonate", ctx.User.Name, id)
+		_ = ctx.Session.Delete(sso.ImpersonatorKey)
+		_ = ctx.Session.Delete("uname")
+		ctx.User = nil
+		return
+	}
+
+	ctx.Impersonator = impersonator
+	ctx.Data["Impersonator"] = impersonator
+}
+
+// Impersonate switches the session of the signed in administrator to the user
+func (ctx *Context) Impersonate(u *models.User) error {
+	if err := ctx.Session.Set(sso.ImpersonatorKey, ctx.User.ID); err != nil {
+		return err
+	}
+	if err := ctx.Session.Set("uid", u.ID); err != nil {
+		return err
+	}
+	return ctx.Session.Set("uname", u.Name)
+}
+
+// StopImpersonating switches the session back to the impersonating administrator
+func (ctx *Context) StopImpersonating() error {
+	if err := ctx.Session.Set("uid", ctx.Impersonator.ID); err != nil {
+		return err
+	}
+	if err := ctx.Session.Set("uname", ctx.Impersonator.Name); err != nil {
+		return err
+	}
+	return ctx.Session.Delete(sso.ImpersonatorKey)
+}
+
+// NotImpersonated forbids the route while an administrator impersonates the signed in user
+func NotImpersonated(ctx *Context) {
+	if ctx.Impersonator != nil {
+		ctx.Error(http.StatusForbidden, ctx.Tr("impersonation.forbidden"))
+	}
+}
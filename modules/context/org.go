@@ -6,11 +6,16 @@
 package context
 
 import (
+	"net/http"
 	"strings"
 
 	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/base"
+	"go.wandrs.dev/framework/modules/setting"
 )
 
+const tplOrgTwoFactorRequired base.TplName = "org/two_factor_required"
+
 // Organization contains organization context
 type Organization struct {
 	IsOwner      bool
@@ -19,10 +24,17 @@ type Organization struct {
 	IsTeamAdmin  bool // In owner team or team that has admin permission level.
 	Organization *models.User
 	OrgLink      string
+	// UnitAccessModes are the access modes of the signed in user to the permission units
+	UnitAccessModes map[string]models.AccessMode
 
 	Team *models.Team
 }
 
+// HasUnitAccess returns true if the signed in user has at least the access mode to the permission unit
+func (org *Organization) HasUnitAccess(unit string, mode models.AccessMode) bool {
+	return org.UnitAccessModes[unit] >= mode
+}
+
 // HandleOrgAssignment handles organization assignment
 func HandleOrgAssignment(ctx *Context, args ...bool) {
 	var (
@@ -108,8 +120,37 @@ func HandleOrgAssignment(ctx *Context, args ...bool) {
 	ctx.Data["IsOrganizationOwner"] = ctx.Org.IsOwner
 	ctx.Data["IsOrganizationMember"] = ctx.Org.IsMember
 
+	ctx.Org.UnitAccessModes = make(map[string]models.AccessMode)
+	if ctx.Org.IsOwner {
+		for _, unit := range models.Units() {
+			ctx.Org.UnitAccessModes[unit.Name] = models.AccessModeOwner
+		}
+	} else if ctx.Org.IsMember {
+		ctx.Org.UnitAccessModes, err = models.GetUnitAccessModes(ctx.User, org)
+		if err != nil {
+			ctx.ServerError("GetUnitAccessModes", err)
+			return
+		}
+	}
+	ctx.Data["OrgUnitAccessModes"] = ctx.Org.UnitAccessModes
+
 	ctx.Org.OrgLink = org.OrganisationLink()
 	ctx.Data["OrgLink"] = ctx.Org.OrgLink
+	ctx.Data["EnableOAuth2"] = setting.OAuth2.Enable
+
+	// Members without two-factor authentication are asked to enroll it, except site admins
+	// who have super access.
+	if ctx.Org.IsMember && !ctx.User.IsAdmin {
+		compliant, err := org.IsTwoFactorCompliant(ctx.User.ID)
+		if err != nil {
+			ctx.ServerError("IsTwoFactorCompliant", err)
+			return
+		} else if !compliant {
+			ctx.Data["Title"] = org.DisplayName()
+			ctx.HTML(http.StatusForbidden, tplOrgTwoFactorRequired)
+			return
+		}
+	}
 
 	// Team.
 	if ctx.Org.IsMember {
@@ -119,9 +160,9 @@ func HandleOrgAssignment(ctx *Context, args ...bool) {
 				return
 			}
 		} else {
-			org.Teams, err = org.GetUserTeams(ctx.User.ID)
+			org.Teams, err = models.GetUserOrgEffectiveTeams(org.ID, ctx.User.ID)
 			if err != nil {
-				ctx.ServerError("GetUserTeams", err)
+				ctx.ServerError("GetUserOrgEffectiveTeams", err)
 				return
 			}
 		}
@@ -166,3 +207,16 @@ func OrgAssignment(args ...bool) func(ctx *Context) {
 		HandleOrgAssignment(ctx, args...)
 	}
 }
+
+// RequireOrgUnitAccess returns a middleware requiring the signed in user to have at least the
+// access mode to the permission unit of the organization, it must follow OrgAssignment
+func RequireOrgUnitAccess(unit string, mode models.AccessMode) func(ctx *Context) {
+	if _, ok := models.GetUnit(unit); !ok {
+		panic(models.ErrUnitNotExist{Name: unit})
+	}
+	return func(ctx *Context) {
+		if !ctx.Org.HasUnitAccess(unit, mode) {
+			ctx.NotFound("RequireOrgUnitAccess", nil)
+		}
+	}
+}
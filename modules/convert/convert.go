@@ -7,6 +7,7 @@ package convert
 
 import (
 	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/log"
 	api "go.wandrs.dev/framework/modules/structs"
 )
 
@@ -31,6 +32,8 @@ func ToOrganization(org *models.User) *api.Organization {
 		Location:                  org.Location,
 		Visibility:                org.Visibility.String(),
 		RepoAdminChangeTeamAccess: org.RepoAdminChangeTeamAccess,
+		AllowJoinRequests:         org.AllowJoinRequests,
+		RequireTwoFactor:          org.RequireTwoFactor,
 	}
 }
 
@@ -40,22 +43,86 @@ func ToTeam(team *models.Team) *api.Team {
 		return nil
 	}
 
-	return &api.Team{
-		ID:          team.ID,
-		Name:        team.Name,
-		Description: team.Description,
-		Permission:  team.Authorize.String(),
+	apiTeam := &api.Team{
+		ID:                  team.ID,
+		Name:                team.Name,
+		Description:         team.Description,
+		Permission:          team.Authorize.String(),
+		CanManageOAuth2Apps: team.CanManageOAuth2Apps,
+		RoleID:              team.RoleID,
+		ParentID:            team.ParentID,
+	}
+	if err := team.LoadUnitAccess(); err != nil {
+		log.Error("LoadUnitAccess[%d]: %v", team.ID, err)
+		return apiTeam
+	}
+	apiTeam.Units = make(map[string]string)
+	for unit, mode := range team.UnitAccessModes() {
+		apiTeam.Units[unit] = mode.String()
+	}
+	return apiTeam
+}
+
+// ToOrgInvite convert models.OrgInvite to api.OrgInvite, the attributes are expected to be loaded
+func ToOrgInvite(inv *models.OrgInvite) *api.OrgInvite {
+	return &api.OrgInvite{
+		ID:       inv.ID,
+		Email:    inv.Email,
+		TeamID:   inv.TeamID,
+		TeamName: inv.Team.Name,
+		Inviter:  inv.Inviter.Name,
+		Created:  inv.CreatedUnix.AsTime(),
+		Expires:  inv.ExpiresUnix.AsTime(),
+	}
+}
+
+// ToOrgJoinRequest convert models.OrgJoinRequest to api.OrgJoinRequest, the attributes are expected to be loaded
+func ToOrgJoinRequest(r *models.OrgJoinRequest, doer *models.User) *api.OrgJoinRequest {
+	return &api.OrgJoinRequest{
+		ID:      r.ID,
+		User:    ToUser(r.User, doer),
+		Message: r.Message,
+		Created: r.CreatedUnix.AsTime(),
 	}
 }
 
 // ToOAuth2Application convert from models.OAuth2Application to api.OAuth2Application
 func ToOAuth2Application(app *models.OAuth2Application) *api.OAuth2Application {
 	return &api.OAuth2Application{
-		ID:           app.ID,
-		Name:         app.Name,
-		ClientID:     app.ClientID,
-		ClientSecret: app.ClientSecret,
-		RedirectURIs: app.RedirectURIs,
-		Created:      app.CreatedUnix.AsTime(),
+		ID:                    app.ID,
+		Name:                  app.Name,
+		ClientID:              app.ClientID,
+		ClientSecret:          app.ClientSecret,
+		RedirectURIs:          app.RedirectURIs,
+		FrontchannelLogoutURI: app.FrontchannelLogoutURI,
+		Trusted:               app.Trusted,
+		Created:               app.CreatedUnix.AsTime(),
+	}
+}
+
+// ToAdminOAuth2Application converts an oauth2 application with its owner loaded for site administrators
+func ToAdminOAuth2Application(app *models.OAuth2Application) *api.OAuth2Application {
+	apiApp := ToOAuth2Application(app)
+	if app.User != nil {
+		apiApp.Owner = app.User.Name
+	}
+	return apiApp
+}
+
+// ToAuditEvent converts an event of the audit log to API format
+func ToAuditEvent(evt *models.AuditEvent) *api.AuditEvent {
+	return &api.AuditEvent{
+		ID:         evt.ID,
+		Action:     string(evt.Action),
+		ActorID:    evt.ActorID,
+		ActorName:  evt.ActorName,
+		OrgID:      evt.OrgID,
+		TargetType: evt.Action.TargetType(),
+		TargetID:   evt.TargetID,
+		TargetName: evt.TargetName,
+		IPAddress:  evt.IPAddress,
+		Before:     evt.Before,
+		After:      evt.After,
+		Created:    evt.CreatedUnix.AsTime(),
 	}
 }
@@ -41,6 +41,12 @@ type UpdateExistingConfig struct {
 	UpdateExisting bool
 }
 
+// NotifyBeforeConfig represents a cron task with NotifyBefore setting
+type NotifyBeforeConfig struct {
+	BaseConfig
+	NotifyBefore time.Duration
+}
+
 // CleanupHookTaskConfig represents a cron task with settings to cleanup hook_task
 type CleanupHookTaskConfig struct {
 	BaseConfig
@@ -6,8 +6,12 @@ package cron
 
 import (
 	"context"
+	"time"
 
 	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/log"
+	"go.wandrs.dev/framework/modules/timeutil"
+	"go.wandrs.dev/framework/services/mailer"
 )
 
 func registerSyncExternalUsers() {
@@ -24,6 +28,78 @@ func registerSyncExternalUsers() {
 	})
 }
 
+func registerDeleteExpiredAccessTokens() {
+	RegisterTaskFatal("delete_expired_access_tokens", &NotifyBeforeConfig{
+		BaseConfig: BaseConfig{
+			Enabled:    true,
+			RunAtStart: false,
+			Schedule:   "@every 24h",
+		},
+		NotifyBefore: 72 * time.Hour,
+	}, func(ctx context.Context, _ *models.User, config Config) error {
+		realConfig := config.(*NotifyBeforeConfig)
+		if realConfig.NotifyBefore > 0 {
+			tokens, err := models.GetAccessTokensExpiringBefore(timeutil.TimeStampNow().AddDuration(realConfig.NotifyBefore))
+			if err != nil {
+				return err
+			}
+			for _, t := range tokens {
+				select {
+				case <-ctx.Done():
+					return models.ErrCancelledf("Before notify owner of access token %d", t.ID)
+				default:
+				}
+				u, err := models.GetUserByID(t.UID)
+				if err != nil {
+					log.Error("GetUserByID[%d]: %v", t.UID, err)
+					continue
+				}
+				mailer.SendAccessTokenExpiryMail(u, t)
+				if err := models.SetAccessTokenExpiryNotified(t); err != nil {
+					return err
+				}
+			}
+		}
+		_, err := models.DeleteExpiredAccessTokens()
+		return err
+	})
+}
+
+func registerDeleteOldAuditEvents() {
+	RegisterTaskFatal("delete_old_audit_events", &OlderThanConfig{
+		BaseConfig: BaseConfig{
+			Enabled:    true,
+			RunAtStart: false,
+			Schedule:   "@every 24h",
+		},
+		OlderThan: 365 * 24 * time.Hour,
+	}, func(_ context.Context, _ *models.User, config Config) error {
+		olderThanConfig := config.(*OlderThanConfig)
+		if olderThanConfig.OlderThan <= 0 {
+			return nil
+		}
+		_, err := models.DeleteAuditEventsOlderThan(olderThanConfig.OlderThan)
+		return err
+	})
+}
+
+func registerRemoveTwoFactorNonCompliantMembers() {
+	RegisterTaskFatal("remove_two_factor_non_compliant_members", &OlderThanConfig{
+		BaseConfig: BaseConfig{
+			Enabled:    true,
+			RunAtStart: false,
+			Schedule:   "@every 24h",
+		},
+		OlderThan: 7 * 24 * time.Hour,
+	}, func(ctx context.Context, _ *models.User, config Config) error {
+		olderThanConfig := config.(*OlderThanConfig)
+		return models.RemoveTwoFactorNonCompliantMembers(ctx, olderThanConfig.OlderThan)
+	})
+}
+
 func initBasicTasks() {
 	registerSyncExternalUsers()
+	registerDeleteExpiredAccessTokens()
+	registerDeleteOldAuditEvents()
+	registerRemoveTwoFactorNonCompliantMembers()
 }
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/auth/oauth2"
 )
 
 func registerDeleteInactiveUsers() {
@@ -25,6 +26,17 @@ func registerDeleteInactiveUsers() {
 	})
 }
 
+func registerRotateOAuth2SigningKey() {
+	RegisterTaskFatal("rotate_oauth2_signing_key", &BaseConfig{
+		Enabled:    false,
+		RunAtStart: false,
+		Schedule:   "@every 2160h",
+	}, func(_ context.Context, _ *models.User, _ Config) error {
+		return oauth2.RotateSigningKey()
+	})
+}
+
 func initExtendedTasks() {
 	registerDeleteInactiveUsers()
+	registerRotateOAuth2SigningKey()
 }
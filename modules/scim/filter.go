@@ -0,0 +1,523 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package scim
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// caseExactAttributes are the attributes whose string values are compared case-sensitively
+var caseExactAttributes = map[string]bool{
+	"id":         true,
+	"externalid": true,
+}
+
+// Resource is the generic JSON representation of a resource filters and patch operations work on
+type Resource map[string]interface{}
+
+// ToResource returns the generic representation of a User or Group
+func ToResource(v interface{}) (Resource, error) {
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	resource := make(Resource)
+	if err := json.Unmarshal(bs, &resource); err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
+
+// Decode fills the User or Group from the generic representation
+func (r Resource) Decode(v interface{}) error {
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	bs, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(bs, v); err != nil {
+		return NewError(http.StatusBadRequest, ErrorTypeInvalidValue, "%v", err)
+	}
+	return nil
+}
+
+// key returns the key of the attribute, attribute names are case-insensitive
+func (r Resource) key(name string) (string, bool) {
+	if _, ok := r[name]; ok {
+		return name, true
+	}
+	for key := range r {
+		if strings.EqualFold(key, name) {
+			return key, true
+		}
+	}
+	return name, false
+}
+
+// get returns the value of the attribute
+func (r Resource) get(name string) (interface{}, bool) {
+	key, ok := r.key(name)
+	if !ok {
+		return nil, false
+	}
+	return r[key], true
+}
+
+// AttrPath is an attribute reference like userName, name.givenName or emails.value
+type AttrPath struct {
+	Name    string
+	SubAttr string
+}
+
+// parseAttrPath parses an attribute path, an optional schema URI prefix is left out
+func parseAttrPath(s string) (AttrPath, bool) {
+	if strings.HasPrefix(strings.ToLower(s), "urn:") {
+		idx := strings.LastIndex(s, ":")
+		s = s[idx+1:]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 2 || parts[0] == "" || (len(parts) == 2 && parts[1] == "") {
+		return AttrPath{}, false
+	}
+	path := AttrPath{Name: parts[0]}
+	if len(parts) == 2 {
+		path.SubAttr = parts[1]
+	}
+	return path, true
+}
+
+// values returns all values the attribute path refers to, multi-valued attributes are flattened
+func (p AttrPath) values(r Resource) []interface{} {
+	value, ok := r.get(p.Name)
+	if !ok || value == nil {
+		return nil
+	}
+	elements, isMulti := value.([]interface{})
+	if !isMulti {
+		elements = []interface{}{value}
+	}
+	if p.SubAttr == "" {
+		if isMulti {
+			// the value of a multi-valued complex attribute is its "value" sub-attribute
+			values := make([]interface{}, 0, len(elements))
+			for _, element := range elements {
+				if complexValue, ok := element.(map[string]interface{}); ok {
+					if v, ok := Resource(complexValue).get("value"); ok {
+						values = append(values, v)
+					}
+					continue
+				}
+				values = append(values, element)
+			}
+			return values
+		}
+		return elements
+	}
+	values := make([]interface{}, 0, len(elements))
+	for _, element := range elements {
+		if complexValue, ok := element.(map[string]interface{}); ok {
+			if v, ok := Resource(complexValue).get(p.SubAttr); ok && v != nil {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+func (p AttrPath) caseExact() bool {
+	return caseExactAttributes[strings.ToLower(p.Name)] && p.SubAttr == ""
+}
+
+// Filter is a parsed filter expression
+type Filter interface {
+	// Matches returns true if the resource matches the filter
+	Matches(r Resource) bool
+}
+
+type logicalFilter struct {
+	and         bool
+	left, right Filter
+}
+
+func (f *logicalFilter) Matches(r Resource) bool {
+	if f.and {
+		return f.left.Matches(r) && f.right.Matches(r)
+	}
+	return f.left.Matches(r) || f.right.Matches(r)
+}
+
+type notFilter struct {
+	filter Filter
+}
+
+func (f *notFilter) Matches(r Resource) bool {
+	return !f.filter.Matches(r)
+}
+
+type compareFilter struct {
+	path  AttrPath
+	op    string
+	value interface{}
+}
+
+func (f *compareFilter) Matches(r Resource) bool {
+	values := f.path.values(r)
+	if f.op == "pr" {
+		for _, v := range values {
+			if s, ok := v.(string); !ok || s != "" {
+				return true
+			}
+		}
+		return false
+	}
+	if len(values) == 0 {
+		// a missing attribute only equals null
+		return (f.op == "eq" && f.value == nil) || (f.op == "ne" && f.value != nil)
+	}
+	for _, v := range values {
+		if f.compare(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *compareFilter) compare(actual interface{}) bool {
+	switch expected := f.value.(type) {
+	case string:
+		s, ok := actual.(string)
+		if !ok {
+			return f.op == "ne"
+		}
+		if !f.path.caseExact() {
+			s, expected = strings.ToLower(s), strings.ToLower(expected)
+		}
+		switch f.op {
+		case "eq":
+			return s == expected
+		case "ne":
+			return s != expected
+		case "co":
+			return strings.Contains(s, expected)
+		case "sw":
+			return strings.HasPrefix(s, expected)
+		case "ew":
+			return strings.HasSuffix(s, expected)
+		case "gt":
+			return s > expected
+		case "ge":
+			return s >= expected
+		case "lt":
+			return s < expected
+		case "le":
+			return s <= expected
+		}
+	case float64:
+		n, ok := actual.(float64)
+		if !ok {
+			return f.op == "ne"
+		}
+		switch f.op {
+		case "eq":
+			return n == expected
+		case "ne":
+			return n != expected
+		case "gt":
+			return n > expected
+		case "ge":
+			return n >= expected
+		case "lt":
+			return n < expected
+		case "le":
+			return n <= expected
+		}
+	case bool:
+		b, ok := actual.(bool)
+		switch f.op {
+		case "eq":
+			return ok && b == expected
+		case "ne":
+			return !ok || b != expected
+		}
+	case nil:
+		switch f.op {
+		case "eq":
+			return actual == nil
+		case "ne":
+			return actual != nil
+		}
+	}
+	return false
+}
+
+type valuePathFilter struct {
+	path   AttrPath
+	filter Filter
+}
+
+func (f *valuePathFilter) Matches(r Resource) bool {
+	value, _ := r.get(f.path.Name)
+	elements, _ := value.([]interface{})
+	for _, element := range elements {
+		if complexValue, ok := element.(map[string]interface{}); ok && f.filter.Matches(complexValue) {
+			return true
+		}
+	}
+	return false
+}
+
+// token kinds of filter expressions
+const (
+	tokenWord = iota
+	tokenString
+	tokenOpen
+	tokenClose
+	tokenOpenBracket
+	tokenCloseBracket
+)
+
+type token struct {
+	kind  int
+	value string
+}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenOpen})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenClose})
+			i++
+		case c == '[':
+			tokens = append(tokens, token{kind: tokenOpenBracket})
+			i++
+		case c == ']':
+			tokens = append(tokens, token{kind: tokenCloseBracket})
+			i++
+		case c == '"':
+			end := i + 1
+			for ; end < len(s) && s[end] != '"'; end++ {
+				if s[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(s) {
+				return nil, NewError(http.StatusBadRequest, ErrorTypeInvalidFilter, "unterminated string in filter")
+			}
+			var value string
+			if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(s[i:end+1]), &value); err != nil {
+				return nil, NewError(http.StatusBadRequest, ErrorTypeInvalidFilter, "invalid string in filter: %v", err)
+			}
+			tokens = append(tokens, token{kind: tokenString, value: value})
+			i = end + 1
+		default:
+			end := i
+			for end < len(s) && !strings.ContainsRune(" \t()[]\"", rune(s[end])) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenWord, value: s[i:end]})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *filterParser) peek() *token {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *filterParser) next() *token {
+	t := p.peek()
+	if t != nil {
+		p.pos++
+	}
+	return t
+}
+
+func (p *filterParser) isKeyword(keyword string) bool {
+	t := p.peek()
+	return t != nil && t.kind == tokenWord && strings.EqualFold(t.value, keyword)
+}
+
+func invalidFilter(format string, args ...interface{}) error {
+	return NewError(http.StatusBadRequest, ErrorTypeInvalidFilter, format, args...)
+}
+
+// parseOr parses FILTER "or" FILTER, which has the lowest precedence
+func (p *filterParser) parseOr() (Filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalFilter{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (Filter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalFilter{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (Filter, error) {
+	if p.isKeyword("not") {
+		p.next()
+		if t := p.next(); t == nil || t.kind != tokenOpen {
+			return nil, invalidFilter("expected ( after not")
+		}
+		filter, err := p.parseGroup()
+		if err != nil {
+			return nil, err
+		}
+		return &notFilter{filter: filter}, nil
+	}
+
+	t := p.next()
+	if t == nil {
+		return nil, invalidFilter("unexpected end of filter")
+	}
+	if t.kind == tokenOpen {
+		return p.parseGroup()
+	}
+	if t.kind != tokenWord {
+		return nil, invalidFilter("expected attribute path")
+	}
+	path, ok := parseAttrPath(t.value)
+	if !ok {
+		return nil, invalidFilter("invalid attribute path %q", t.value)
+	}
+
+	if next := p.peek(); next != nil && next.kind == tokenOpenBracket {
+		p.next()
+		if path.SubAttr != "" {
+			return nil, invalidFilter("invalid attribute path %q", t.value)
+		}
+		filter, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t == nil || t.kind != tokenCloseBracket {
+			return nil, invalidFilter("expected ]")
+		}
+		return &valuePathFilter{path: path, filter: filter}, nil
+	}
+
+	opToken := p.next()
+	if opToken == nil || opToken.kind != tokenWord {
+		return nil, invalidFilter("expected operator after %q", t.value)
+	}
+	op := strings.ToLower(opToken.value)
+	switch op {
+	case "pr":
+		return &compareFilter{path: path, op: op}, nil
+	case "eq", "ne", "co", "sw", "ew", "gt", "ge", "lt", "le":
+	default:
+		return nil, invalidFilter("unknown operator %q", opToken.value)
+	}
+
+	valueToken := p.next()
+	if valueToken == nil {
+		return nil, invalidFilter("expected value after %q", opToken.value)
+	}
+	var value interface{}
+	switch {
+	case valueToken.kind == tokenString:
+		value = valueToken.value
+	case valueToken.kind != tokenWord:
+		return nil, invalidFilter("expected value after %q", opToken.value)
+	case strings.EqualFold(valueToken.value, "true"):
+		value = true
+	case strings.EqualFold(valueToken.value, "false"):
+		value = false
+	case strings.EqualFold(valueToken.value, "null"):
+		value = nil
+	default:
+		n, err := strconv.ParseFloat(valueToken.value, 64)
+		if err != nil {
+			return nil, invalidFilter("invalid value %q", valueToken.value)
+		}
+		value = n
+	}
+	switch value.(type) {
+	case bool, nil:
+		if op != "eq" && op != "ne" {
+			return nil, invalidFilter("operator %q cannot compare %s", op, valueToken.value)
+		}
+	case float64:
+		if op == "co" || op == "sw" || op == "ew" {
+			return nil, invalidFilter("operator %q cannot compare %s", op, valueToken.value)
+		}
+	}
+	return &compareFilter{path: path, op: op, value: value}, nil
+}
+
+// parseGroup parses the rest of a parenthesized filter
+func (p *filterParser) parseGroup() (Filter, error) {
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.next(); t == nil || t.kind != tokenClose {
+		return nil, invalidFilter("expected )")
+	}
+	return filter, nil
+}
+
+// ParseFilter parses a filter expression like userName eq "alice" or emails[type eq "work"]
+func ParseFilter(s string) (Filter, error) {
+	if strings.TrimFunc(s, unicode.IsSpace) == "" {
+		return nil, invalidFilter("empty filter")
+	}
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != nil {
+		return nil, invalidFilter("unexpected input at the end of the filter")
+	}
+	return filter, nil
+}
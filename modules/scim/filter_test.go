@@ -0,0 +1,90 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package scim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilter(t *testing.T) {
+	active := Boolean(true)
+	resource, err := ToResource(&User{
+		Schemas:     []string{SchemaUser},
+		ID:          "42",
+		ExternalID:  "AbC",
+		UserName:    "Alice",
+		Name:        &Name{GivenName: "Alice", FamilyName: "Liddell"},
+		DisplayName: "Alice Liddell",
+		Emails: []MultiValue{
+			{Value: "alice@example.com", Type: "work", Primary: true},
+			{Value: "alice@example.org", Type: "home"},
+		},
+		Active: &active,
+	})
+	assert.NoError(t, err)
+
+	kases := []struct {
+		filter  string
+		matches bool
+	}{
+		{`userName eq "alice"`, true},
+		{`USERNAME Eq "ALICE"`, true},
+		{`urn:ietf:params:scim:schemas:core:2.0:User:userName eq "alice"`, true},
+		{`userName ne "alice"`, false},
+		{`userName sw "al"`, true},
+		{`userName ew "ce"`, true},
+		{`userName co "lic"`, true},
+		{`userName gt "Aa"`, true},
+		{`userName lt "Aa"`, false},
+		{`id eq "42"`, true},
+		{`externalId eq "AbC"`, true},
+		{`externalId eq "abc"`, false},
+		{`name.familyName eq "liddell"`, true},
+		{`emails.value eq "alice@example.org"`, true},
+		{`emails eq "alice@example.org"`, true},
+		{`emails[type eq "work" and primary eq true]`, true},
+		{`emails[type eq "home" and primary eq true]`, false},
+		{`emails[value ew ".org"]`, true},
+		{`active eq true`, true},
+		{`active eq false`, false},
+		{`title pr`, false},
+		{`title eq null`, true},
+		{`displayName pr`, true},
+		{`userName eq "bob" or userName eq "alice"`, true},
+		{`userName eq "bob" or userName eq "alice" and active eq false`, false},
+		{`(userName eq "bob" or userName eq "alice") and active eq true`, true},
+		{`not (userName eq "bob")`, true},
+		{`not(userName eq "alice")`, false},
+		{`displayName eq "Alice \"Liddell\""`, false},
+	}
+	for _, kase := range kases {
+		filter, err := ParseFilter(kase.filter)
+		if assert.NoError(t, err, kase.filter) {
+			assert.Equal(t, kase.matches, filter.Matches(resource), kase.filter)
+		}
+	}
+
+	for _, invalid := range []string{
+		``,
+		`userName`,
+		`userName eq`,
+		`userName is "alice"`,
+		`userName eq alice`,
+		`userName eq "alice`,
+		`userName eq "alice" and`,
+		`(userName eq "alice"`,
+		`emails[type eq "work"`,
+		`active co true`,
+		`a.b.c eq "x"`,
+		`userName eq "alice" userName`,
+	} {
+		_, err := ParseFilter(invalid)
+		if assert.Error(t, err, invalid) {
+			assert.Equal(t, ErrorTypeInvalidFilter, err.(*Error).ScimType, invalid)
+		}
+	}
+}
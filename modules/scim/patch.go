@@ -0,0 +1,294 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package scim
+
+import (
+	"net/http"
+	"strings"
+)
+
+// PatchRequest is a request to modify a resource
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// PatchOperation is an operation of a patch request
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// patchPath is the target of a patch operation like members, name.givenName
+// or emails[type eq "work"].value
+type patchPath struct {
+	AttrPath
+	filter Filter
+}
+
+func parsePatchPath(s string) (*patchPath, error) {
+	invalidPath := NewError(http.StatusBadRequest, ErrorTypeInvalidPath, "invalid path %q", s)
+
+	open := strings.Index(s, "[")
+	if open < 0 {
+		path, ok := parseAttrPath(s)
+		if !ok {
+			return nil, invalidPath
+		}
+		return &patchPath{AttrPath: path}, nil
+	}
+
+	closing := strings.LastIndex(s, "]")
+	if closing < open {
+		return nil, invalidPath
+	}
+	path, ok := parseAttrPath(s[:open])
+	if !ok || path.SubAttr != "" {
+		return nil, invalidPath
+	}
+	if rest := s[closing+1:]; rest != "" {
+		if !strings.HasPrefix(rest, ".") || len(rest) == 1 || strings.Contains(rest[1:], ".") {
+			return nil, invalidPath
+		}
+		path.SubAttr = rest[1:]
+	}
+	filter, err := ParseFilter(s[open+1 : closing])
+	if err != nil {
+		return nil, invalidPath
+	}
+	return &patchPath{AttrPath: path, filter: filter}, nil
+}
+
+// Apply applies the operations of the request to the resource in order
+func (r *PatchRequest) Apply(resource Resource) error {
+	if len(r.Operations) == 0 {
+		return NewError(http.StatusBadRequest, ErrorTypeInvalidSyntax, "no operations")
+	}
+	for _, op := range r.Operations {
+		if err := op.apply(resource); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (op *PatchOperation) apply(resource Resource) error {
+	kind := strings.ToLower(op.Op)
+	switch kind {
+	case "add", "replace", "remove":
+	default:
+		return NewError(http.StatusBadRequest, ErrorTypeInvalidSyntax, "unknown operation %q", op.Op)
+	}
+
+	if op.Path == "" {
+		if kind == "remove" {
+			return NewError(http.StatusBadRequest, ErrorTypeNoTarget, "remove operations require a path")
+		}
+		values, ok := op.Value.(map[string]interface{})
+		if !ok {
+			return NewError(http.StatusBadRequest, ErrorTypeInvalidValue, "operations without a path require an object value")
+		}
+		for name, value := range values {
+			// attributes of the core schema may be qualified by its URI
+			path, ok := parseAttrPath(name)
+			if !ok {
+				continue
+			}
+			if path.SubAttr != "" {
+				setSubAttr(resource, path, value)
+			} else {
+				setAttr(resource, kind, path.Name, value)
+			}
+		}
+		return nil
+	}
+
+	path, err := parsePatchPath(op.Path)
+	if err != nil {
+		return err
+	}
+	if path.filter != nil {
+		return op.applyFiltered(resource, kind, path)
+	}
+
+	if kind == "remove" {
+		key, ok := resource.key(path.Name)
+		if !ok {
+			return nil
+		}
+		if path.SubAttr != "" {
+			removeSubAttr(resource[key], path.SubAttr)
+			return nil
+		}
+		// some clients identify the values of a multi-valued attribute to remove by the value
+		if removed, ok := op.Value.([]interface{}); ok {
+			if elements, ok := resource[key].([]interface{}); ok {
+				resource[key] = removeValues(elements, removed)
+				return nil
+			}
+		}
+		delete(resource, key)
+		return nil
+	}
+
+	if path.SubAttr != "" {
+		setSubAttr(resource, path.AttrPath, op.Value)
+		return nil
+	}
+	setAttr(resource, kind, path.Name, op.Value)
+	return nil
+}
+
+// applyFiltered applies the operation to the values of a multi-valued attribute matching the filter
+func (op *PatchOperation) applyFiltered(resource Resource, kind string, path *patchPath) error {
+	key, _ := resource.key(path.Name)
+	elements, _ := resource[key].([]interface{})
+
+	matched := false
+	result := make([]interface{}, 0, len(elements))
+	for _, element := range elements {
+		complexValue, ok := element.(map[string]interface{})
+		if !ok || !path.filter.Matches(complexValue) {
+			result = append(result, element)
+			continue
+		}
+		matched = true
+
+		switch {
+		case kind == "remove" && path.SubAttr == "":
+			continue
+		case kind == "remove":
+			if subKey, ok := Resource(complexValue).key(path.SubAttr); ok {
+				delete(complexValue, subKey)
+			}
+		case path.SubAttr != "":
+			subKey, _ := Resource(complexValue).key(path.SubAttr)
+			complexValue[subKey] = op.Value
+		case kind == "replace":
+			value, ok := op.Value.(map[string]interface{})
+			if !ok {
+				return NewError(http.StatusBadRequest, ErrorTypeInvalidValue, "a value of %s must be an object", path.Name)
+			}
+			element = value
+		default:
+			value, ok := op.Value.(map[string]interface{})
+			if !ok {
+				return NewError(http.StatusBadRequest, ErrorTypeInvalidValue, "a value of %s must be an object", path.Name)
+			}
+			for name, v := range value {
+				subKey, _ := Resource(complexValue).key(name)
+				complexValue[subKey] = v
+			}
+		}
+		result = append(result, element)
+	}
+
+	if !matched && kind != "remove" {
+		return NewError(http.StatusBadRequest, ErrorTypeNoTarget, "no value of %s matches the filter", path.Name)
+	}
+	resource[key] = result
+	return nil
+}
+
+// setAttr adds or replaces the attribute, values added to a multi-valued attribute are appended
+func setAttr(resource Resource, kind, name string, value interface{}) {
+	key, _ := resource.key(name)
+	if kind == "add" {
+		existing, isMulti := resource[key].([]interface{})
+		if isMulti {
+			added, ok := value.([]interface{})
+			if !ok {
+				added = []interface{}{value}
+			}
+			resource[key] = append(existing, newValues(existing, added)...)
+			return
+		}
+		if existingValue, ok := resource[key].(map[string]interface{}); ok {
+			if addedValue, ok := value.(map[string]interface{}); ok {
+				for name, v := range addedValue {
+					subKey, _ := Resource(existingValue).key(name)
+					existingValue[subKey] = v
+				}
+				return
+			}
+		}
+	}
+	resource[key] = value
+}
+
+// setSubAttr sets the sub-attribute of a complex attribute or of all values of a multi-valued one
+func setSubAttr(resource Resource, path AttrPath, value interface{}) {
+	key, _ := resource.key(path.Name)
+	switch parent := resource[key].(type) {
+	case map[string]interface{}:
+		subKey, _ := Resource(parent).key(path.SubAttr)
+		parent[subKey] = value
+	case []interface{}:
+		for _, element := range parent {
+			if complexValue, ok := element.(map[string]interface{}); ok {
+				subKey, _ := Resource(complexValue).key(path.SubAttr)
+				complexValue[subKey] = value
+			}
+		}
+	default:
+		resource[key] = map[string]interface{}{path.SubAttr: value}
+	}
+}
+
+func removeSubAttr(parent interface{}, subAttr string) {
+	switch parent := parent.(type) {
+	case map[string]interface{}:
+		if subKey, ok := Resource(parent).key(subAttr); ok {
+			delete(parent, subKey)
+		}
+	case []interface{}:
+		for _, element := range parent {
+			removeSubAttr(element, subAttr)
+		}
+	}
+}
+
+// valueOf returns the identifying value of a value of a multi-valued attribute
+func valueOf(element interface{}) interface{} {
+	if complexValue, ok := element.(map[string]interface{}); ok {
+		v, _ := Resource(complexValue).get("value")
+		return v
+	}
+	return element
+}
+
+// newValues returns the added values which are not in the existing ones yet
+func newValues(existing, added []interface{}) []interface{} {
+	values := make([]interface{}, 0, len(added))
+	for _, element := range added {
+		if !containsValue(existing, valueOf(element)) {
+			values = append(values, element)
+		}
+	}
+	return values
+}
+
+func removeValues(elements, removed []interface{}) []interface{} {
+	values := make([]interface{}, 0, len(elements))
+	for _, element := range elements {
+		if !containsValue(removed, valueOf(element)) {
+			values = append(values, element)
+		}
+	}
+	return values
+}
+
+func containsValue(elements []interface{}, value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	for _, element := range elements {
+		if valueOf(element) == value {
+			return true
+		}
+	}
+	return false
+}
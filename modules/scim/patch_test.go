@@ -0,0 +1,98 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package scim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatchRequest_Apply(t *testing.T) {
+	newGroup := func() Resource {
+		resource, err := ToResource(&Group{
+			Schemas:     []string{SchemaGroup},
+			ID:          "1",
+			DisplayName: "Developers",
+			Members:     []MultiValue{{Value: "2"}, {Value: "4"}},
+		})
+		assert.NoError(t, err)
+		return resource
+	}
+	members := func(resource Resource) []string {
+		group := &Group{}
+		assert.NoError(t, resource.Decode(group))
+		values := make([]string, 0, len(group.Members))
+		for _, member := range group.Members {
+			values = append(values, member.Value)
+		}
+		return values
+	}
+
+	resource := newGroup()
+	assert.NoError(t, (&PatchRequest{Operations: []PatchOperation{
+		{Op: "Add", Path: "members", Value: []interface{}{map[string]interface{}{"value": "4"}, map[string]interface{}{"value": "5"}}},
+		{Op: "remove", Path: `members[value eq "2"]`},
+	}}).Apply(resource))
+	assert.Equal(t, []string{"4", "5"}, members(resource))
+
+	// values to remove may be given as the operation value
+	resource = newGroup()
+	assert.NoError(t, (&PatchRequest{Operations: []PatchOperation{
+		{Op: "Remove", Path: "members", Value: []interface{}{map[string]interface{}{"value": "4"}}},
+	}}).Apply(resource))
+	assert.Equal(t, []string{"2"}, members(resource))
+
+	resource = newGroup()
+	assert.NoError(t, (&PatchRequest{Operations: []PatchOperation{
+		{Op: "replace", Value: map[string]interface{}{"displayName": "Testers", "members": []interface{}{}}},
+	}}).Apply(resource))
+	assert.Empty(t, members(resource))
+	assert.Equal(t, "Testers", resource["displayName"])
+
+	resource = newGroup()
+	assert.NoError(t, (&PatchRequest{Operations: []PatchOperation{
+		{Op: "remove", Path: "members"},
+	}}).Apply(resource))
+	assert.Empty(t, members(resource))
+
+	active := Boolean(true)
+	resource, err := ToResource(&User{
+		Schemas:  []string{SchemaUser},
+		UserName: "alice",
+		Name:     &Name{GivenName: "Alice"},
+		Emails:   []MultiValue{{Value: "alice@example.com", Type: "work"}},
+		Active:   &active,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, (&PatchRequest{Operations: []PatchOperation{
+		{Op: "replace", Path: "active", Value: "False"},
+		{Op: "replace", Path: "urn:ietf:params:scim:schemas:core:2.0:User:name.familyName", Value: "Liddell"},
+		{Op: "replace", Path: `emails[type eq "work"].value`, Value: "alice@example.org"},
+		{Op: "add", Value: map[string]interface{}{"displayName": "Alice Liddell", "name.formatted": "Ms. Alice Liddell"}},
+	}}).Apply(resource))
+	user := &User{}
+	assert.NoError(t, resource.Decode(user))
+	assert.False(t, bool(*user.Active))
+	assert.Equal(t, &Name{GivenName: "Alice", FamilyName: "Liddell", Formatted: "Ms. Alice Liddell"}, user.Name)
+	assert.Equal(t, "alice@example.org", user.PrimaryEmail())
+	assert.Equal(t, "Alice Liddell", user.FullName())
+
+	for _, kase := range []struct {
+		op       PatchOperation
+		scimType string
+	}{
+		{PatchOperation{Op: "move", Path: "userName"}, ErrorTypeInvalidSyntax},
+		{PatchOperation{Op: "remove"}, ErrorTypeNoTarget},
+		{PatchOperation{Op: "add", Value: "alice"}, ErrorTypeInvalidValue},
+		{PatchOperation{Op: "replace", Path: `emails[type eq "home"].value`, Value: "x"}, ErrorTypeNoTarget},
+		{PatchOperation{Op: "replace", Path: `emails[type eq "home"`, Value: "x"}, ErrorTypeInvalidPath},
+	} {
+		err := (&PatchRequest{Operations: []PatchOperation{kase.op}}).Apply(resource)
+		if assert.Error(t, err) {
+			assert.Equal(t, kase.scimType, err.(*Error).ScimType)
+		}
+	}
+}
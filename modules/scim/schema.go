@@ -0,0 +1,153 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package scim
+
+// Attribute describes an attribute of a schema
+type Attribute struct {
+	Name          string      `json:"name"`
+	Type          string      `json:"type"`
+	MultiValued   bool        `json:"multiValued"`
+	Required      bool        `json:"required"`
+	CaseExact     bool        `json:"caseExact"`
+	Mutability    string      `json:"mutability"`
+	Returned      string      `json:"returned"`
+	Uniqueness    string      `json:"uniqueness"`
+	SubAttributes []Attribute `json:"subAttributes,omitempty"`
+}
+
+// Schema describes the attributes of a resource
+type Schema struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Attributes  []Attribute `json:"attributes"`
+	Meta        *Meta       `json:"meta,omitempty"`
+}
+
+func stringAttribute(name string, required bool) Attribute {
+	return Attribute{Name: name, Type: "string", Required: required, Mutability: "readWrite", Returned: "default", Uniqueness: "none"}
+}
+
+func multiValuedAttribute(name, mutability string) Attribute {
+	value := stringAttribute("value", false)
+	value.Mutability = "immutable"
+	display := stringAttribute("display", false)
+	display.Mutability = "readOnly"
+	return Attribute{
+		Name:          name,
+		Type:          "complex",
+		MultiValued:   true,
+		Mutability:    mutability,
+		Returned:      "default",
+		Uniqueness:    "none",
+		SubAttributes: []Attribute{value, display},
+	}
+}
+
+// UserSchema describes the supported attributes of users
+func UserSchema() *Schema {
+	userName := stringAttribute("userName", true)
+	userName.Uniqueness = "server"
+	password := stringAttribute("password", false)
+	password.Mutability = "writeOnly"
+	password.Returned = "never"
+	emails := multiValuedAttribute("emails", "readWrite")
+	emails.SubAttributes = []Attribute{
+		stringAttribute("value", false),
+		stringAttribute("type", false),
+		{Name: "primary", Type: "boolean", Mutability: "readWrite", Returned: "default", Uniqueness: "none"},
+	}
+
+	return &Schema{
+		Schemas:     []string{SchemaSchema},
+		ID:          SchemaUser,
+		Name:        "User",
+		Description: "User Account",
+		Attributes: []Attribute{
+			userName,
+			{
+				Name:       "name",
+				Type:       "complex",
+				Mutability: "readWrite",
+				Returned:   "default",
+				Uniqueness: "none",
+				SubAttributes: []Attribute{
+					stringAttribute("formatted", false),
+					stringAttribute("familyName", false),
+					stringAttribute("givenName", false),
+				},
+			},
+			stringAttribute("displayName", false),
+			emails,
+			{Name: "active", Type: "boolean", Mutability: "readWrite", Returned: "default", Uniqueness: "none"},
+			password,
+		},
+	}
+}
+
+// GroupSchema describes the supported attributes of groups
+func GroupSchema() *Schema {
+	return &Schema{
+		Schemas:     []string{SchemaSchema},
+		ID:          SchemaGroup,
+		Name:        "Group",
+		Description: "Group",
+		Attributes: []Attribute{
+			stringAttribute("displayName", true),
+			multiValuedAttribute("members", "readWrite"),
+		},
+	}
+}
+
+// ResourceType describes an endpoint of resources
+type ResourceType struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Endpoint    string   `json:"endpoint"`
+	Description string   `json:"description"`
+	Schema      string   `json:"schema"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+// Supported describes whether an optional feature is supported
+type Supported struct {
+	Supported bool `json:"supported"`
+}
+
+// FilterSupported describes the support of filters
+type FilterSupported struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+// BulkSupported describes the support of bulk operations
+type BulkSupported struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+// AuthenticationScheme describes how clients authenticate
+type AuthenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Primary     bool   `json:"primary"`
+}
+
+// ServiceProviderConfig describes the features of the service provider
+type ServiceProviderConfig struct {
+	Schemas               []string               `json:"schemas"`
+	Patch                 Supported              `json:"patch"`
+	Bulk                  BulkSupported          `json:"bulk"`
+	Filter                FilterSupported        `json:"filter"`
+	ChangePassword        Supported              `json:"changePassword"`
+	Sort                  Supported              `json:"sort"`
+	ETag                  Supported              `json:"etag"`
+	AuthenticationSchemes []AuthenticationScheme `json:"authenticationSchemes"`
+	Meta                  *Meta                  `json:"meta,omitempty"`
+}
@@ -0,0 +1,26 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package setting
+
+import "go.wandrs.dev/framework/modules/log"
+
+// SCIM defines the settings of the SCIM provisioning API
+var SCIM = struct {
+	Enabled      bool
+	Organization string
+}{
+	Enabled: false,
+}
+
+func newSCIMService() {
+	sec := Cfg.Section("scim")
+	if err := sec.MapTo(&SCIM); err != nil {
+		log.Fatal("Failed to map scim settings: %v", err)
+	}
+
+	if SCIM.Enabled {
+		log.Info("SCIM Provisioning Enabled")
+	}
+}
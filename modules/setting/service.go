@@ -18,6 +18,7 @@ var Service struct {
 	DefaultOrgVisibilityMode            structs.VisibleType
 	ActiveCodeLives                     int
 	ResetPwdCodeLives                   int
+	OrgInviteCodeLives                  int
 	RegisterEmailConfirm                bool
 	RegisterManualConfirm               bool
 	EmailDomainWhitelist                []string
@@ -65,6 +66,7 @@ func newService() {
 	sec := Cfg.Section("service")
 	Service.ActiveCodeLives = sec.Key("ACTIVE_CODE_LIVE_MINUTES").MustInt(180)
 	Service.ResetPwdCodeLives = sec.Key("RESET_PASSWD_CODE_LIVE_MINUTES").MustInt(180)
+	Service.OrgInviteCodeLives = sec.Key("ORG_INVITE_CODE_LIVE_MINUTES").MustInt(10080)
 	Service.DisableRegistration = sec.Key("DISABLE_REGISTRATION").MustBool()
 	Service.AllowOnlyInternalRegistration = sec.Key("ALLOW_ONLY_INTERNAL_REGISTRATION").MustBool()
 	Service.AllowOnlyExternalRegistration = sec.Key("ALLOW_ONLY_EXTERNAL_REGISTRATION").MustBool()
@@ -27,7 +27,6 @@ import (
 	"go.wandrs.dev/framework/modules/util"
 
 	jsoniter "github.com/json-iterator/go"
-	shellquote "github.com/kballard/go-shellquote"
 	"github.com/unknwon/com"
 	ini "gopkg.in/ini.v1"
 )
@@ -136,6 +135,11 @@ var (
 	PasswordComplexity                 []string
 	PasswordHashAlgo                   string
 	PasswordCheckPwn                   bool
+	AccessTokenMaxLifetime             time.Duration
+	LoginMaxFailedAttempts             int
+	LoginMaxFailedAttemptsPerIP        int
+	LoginFailedAttemptsWindow          time.Duration
+	LoginLockoutDuration               time.Duration
 
 	// UI settings
 	UI = struct {
@@ -298,20 +302,36 @@ var (
 		AccessTokenExpirationTime  int64
 		RefreshTokenExpirationTime int64
 		InvalidateRefreshTokens    bool
-		JWTSecretBytes             []byte `ini:"-"`
-		JWTSecretBase64            string `ini:"JWT_SECRET"`
+		JWTSecretBytes             []byte        `ini:"-"`
+		JWTSecretBase64            string        `ini:"JWT_SECRET"`
+		JWTSigningAlgorithm        string        `ini:"JWT_SIGNING_ALGORITHM"`
+		JWTSigningPrivateKeyFile   string        `ini:"JWT_SIGNING_PRIVATE_KEY_FILE"`
+		JWTSigningKeyGracePeriod   time.Duration `ini:"JWT_SIGNING_KEY_GRACE_PERIOD"`
+		DeviceCodeExpirationTime   int64
+		DeviceCodePollInterval     int64
+		IntrospectionClientIDs     []string `ini:"INTROSPECTION_CLIENT_IDS"`
 		MaxTokenLength             int
 	}{
 		Enable:                     true,
 		AccessTokenExpirationTime:  3600,
 		RefreshTokenExpirationTime: 730,
 		InvalidateRefreshTokens:    false,
+		JWTSigningAlgorithm:        "RS256",
+		JWTSigningPrivateKeyFile:   "jwt/private.pem",
+		DeviceCodeExpirationTime:   600,
+		DeviceCodePollInterval:     5,
 		MaxTokenLength:             math.MaxInt16,
 	}
 
+	// U2F settings, only used by the security keys registered with the legacy FIDO U2F API
 	U2F = struct {
-		AppID         string
-		TrustedFacets []string
+		AppID string
+	}{}
+
+	// WebAuthn settings
+	WebAuthn = struct {
+		RPID     string
+		RPOrigin string
 	}{}
 
 	// Metrics settings
@@ -629,6 +649,14 @@ func NewContext() {
 		return
 	}
 
+	if !filepath.IsAbs(OAuth2.JWTSigningPrivateKeyFile) {
+		OAuth2.JWTSigningPrivateKeyFile = filepath.Join(AppDataPath, OAuth2.JWTSigningPrivateKeyFile)
+	}
+	if OAuth2.JWTSigningKeyGracePeriod <= 0 {
+		// keep retired keys until all refresh tokens signed by them have expired
+		OAuth2.JWTSigningKeyGracePeriod = time.Duration(OAuth2.RefreshTokenExpirationTime) * time.Hour
+	}
+
 	if OAuth2.Enable {
 		OAuth2.JWTSecretBytes = make([]byte, 32)
 		n, err := base64.RawURLEncoding.Decode(OAuth2.JWTSecretBytes, []byte(OAuth2.JWTSecretBase64))
@@ -673,6 +701,11 @@ func NewContext() {
 	PasswordHashAlgo = sec.Key("PASSWORD_HASH_ALGO").MustString("pbkdf2")
 	CSRFCookieHTTPOnly = sec.Key("CSRF_COOKIE_HTTP_ONLY").MustBool(true)
 	PasswordCheckPwn = sec.Key("PASSWORD_CHECK_PWN").MustBool(false)
+	AccessTokenMaxLifetime = sec.Key("ACCESS_TOKEN_MAX_LIFETIME").MustDuration(0)
+	LoginMaxFailedAttempts = sec.Key("LOGIN_MAX_FAILED_ATTEMPTS").MustInt(10)
+	LoginMaxFailedAttemptsPerIP = sec.Key("LOGIN_MAX_FAILED_ATTEMPTS_PER_IP").MustInt(0)
+	LoginFailedAttemptsWindow = sec.Key("LOGIN_FAILED_ATTEMPTS_WINDOW").MustDuration(15 * time.Minute)
+	LoginLockoutDuration = sec.Key("LOGIN_LOCKOUT_DURATION").MustDuration(15 * time.Minute)
 
 	InternalToken = loadInternalToken(sec)
 
@@ -797,9 +830,12 @@ func NewContext() {
 	newMarkup()
 
 	sec = Cfg.Section("U2F")
-	U2F.TrustedFacets, _ = shellquote.Split(sec.Key("TRUSTED_FACETS").MustString(strings.TrimSuffix(AppURL, AppSubURL+"/")))
 	U2F.AppID = sec.Key("APP_ID").MustString(strings.TrimSuffix(AppURL, "/"))
 
+	sec = Cfg.Section("webauthn")
+	WebAuthn.RPID = sec.Key("RP_ID").MustString(appURL.Hostname())
+	WebAuthn.RPOrigin = sec.Key("RP_ORIGIN").MustString(appURL.Scheme + "://" + appURL.Host)
+
 	UI.ReactionsMap = make(map[string]bool)
 	for _, reaction := range UI.Reactions {
 		UI.ReactionsMap[reaction] = true
@@ -990,6 +1026,7 @@ func NewServices() {
 	newCacheService()
 	newSessionService()
 	newCORSService()
+	newSCIMService()
 	newMailService()
 	newRegisterMailService()
 	newNotifyMailService()
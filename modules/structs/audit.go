@@ -0,0 +1,27 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package structs
+
+import "time"
+
+// AuditEvent represents a change recorded in the audit log
+type AuditEvent struct {
+	ID     int64  `json:"id"`
+	Action string `json:"action"`
+	// ID of the user who made the change, 0 if it has been made by the system
+	ActorID    int64  `json:"actor_id"`
+	ActorName  string `json:"actor_name"`
+	OrgID      int64  `json:"org_id"`
+	TargetType string `json:"target_type"`
+	TargetID   int64  `json:"target_id"`
+	TargetName string `json:"target_name"`
+	IPAddress  string `json:"ip_address"`
+	// values before the change
+	Before map[string]string `json:"before"`
+	// values after the change
+	After map[string]string `json:"after"`
+	// swagger:strfmt date-time
+	Created time.Time `json:"created"`
+}
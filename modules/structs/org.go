@@ -15,6 +15,8 @@ type Organization struct {
 	Location                  string `json:"location"`
 	Visibility                string `json:"visibility"`
 	RepoAdminChangeTeamAccess bool   `json:"repo_admin_change_team_access"`
+	AllowJoinRequests         bool   `json:"allow_join_requests"`
+	RequireTwoFactor          bool   `json:"require_two_factor"`
 }
 
 // CreateOrgOption options for creating an organization
@@ -29,6 +31,8 @@ type CreateOrgOption struct {
 	// enum: public,limited,private
 	Visibility                string `json:"visibility" binding:"In(,public,limited,private)"`
 	RepoAdminChangeTeamAccess bool   `json:"repo_admin_change_team_access"`
+	// whether users can request to join the organization
+	AllowJoinRequests bool `json:"allow_join_requests"`
 }
 
 // EditOrgOption options for editing an organization
@@ -41,4 +45,12 @@ type EditOrgOption struct {
 	// enum: public,limited,private
 	Visibility                string `json:"visibility" binding:"In(,public,limited,private)"`
 	RepoAdminChangeTeamAccess bool   `json:"repo_admin_change_team_access"`
+	// whether users can request to join the organization, unchanged if not set
+	AllowJoinRequests *bool `json:"allow_join_requests"`
+	// whether members must enroll two-factor authentication, unchanged if not set. The
+	// authenticated user must have enrolled it to require it.
+	RequireTwoFactor *bool `json:"require_two_factor"`
+	// whether members without two-factor authentication are removed after the grace period,
+	// unchanged if not set
+	RemoveTwoFactorNonCompliant *bool `json:"remove_two_factor_non_compliant"`
 }
@@ -0,0 +1,29 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package structs
+
+import "time"
+
+// OrgInvite represents a pending invitation to join a team of an organization
+type OrgInvite struct {
+	ID       int64  `json:"id"`
+	Email    string `json:"email"`
+	TeamID   int64  `json:"team_id"`
+	TeamName string `json:"team_name"`
+	Inviter  string `json:"inviter"`
+	// swagger:strfmt date-time
+	Created time.Time `json:"created"`
+	// swagger:strfmt date-time
+	Expires time.Time `json:"expires"`
+}
+
+// CreateOrgInviteOption options for inviting a person by email to join a team
+type CreateOrgInviteOption struct {
+	// required: true
+	// swagger:strfmt email
+	Email string `json:"email" binding:"Required;Email;MaxSize(254)"`
+	// required: true
+	TeamID int64 `json:"team_id" binding:"Required"`
+}
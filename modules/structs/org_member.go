@@ -4,7 +4,31 @@
 
 package structs
 
+import "time"
+
 // AddOrgMembershipOption add user to organization options
 type AddOrgMembershipOption struct {
 	Role string `json:"role" binding:"Required"`
 }
+
+// OrgJoinRequest represents a pending request of a user to join an organization
+type OrgJoinRequest struct {
+	ID      int64  `json:"id"`
+	User    *User  `json:"user"`
+	Message string `json:"message"`
+	// swagger:strfmt date-time
+	Created time.Time `json:"created"`
+}
+
+// CreateOrgJoinRequestOption options for requesting to join an organization
+type CreateOrgJoinRequestOption struct {
+	// message to the owners of the organization
+	Message string `json:"message" binding:"MaxSize(1000)"`
+}
+
+// ApproveOrgJoinRequestOption options for approving a request to join an organization
+type ApproveOrgJoinRequestOption struct {
+	// ID of the team to add the user to
+	// required: true
+	TeamID int64 `json:"team_id" binding:"Required"`
+}
@@ -12,7 +12,14 @@ type Team struct {
 	Description  string        `json:"description"`
 	Organization *Organization `json:"organization"`
 	// enum: none,read,write,admin,owner
-	Permission string `json:"permission"`
+	Permission          string `json:"permission"`
+	CanManageOAuth2Apps bool   `json:"can_manage_oauth2_apps"`
+	// ID of the organization role granting the access to the permission units, 0 if the team has no role
+	RoleID int64 `json:"role_id"`
+	// access modes of the team to the permission units, granted by the role or by the team itself
+	Units map[string]string `json:"units"`
+	// ID of the parent team, 0 if the team is not a sub-team
+	ParentID int64 `json:"parent_id"`
 }
 
 // CreateTeamOption options for creating a team
@@ -21,7 +28,14 @@ type CreateTeamOption struct {
 	Name        string `json:"name" binding:"Required;AlphaDashDot;MaxSize(30)"`
 	Description string `json:"description" binding:"MaxSize(255)"`
 	// enum: read,write,admin
-	Permission string `json:"permission"`
+	Permission          string `json:"permission"`
+	CanManageOAuth2Apps bool   `json:"can_manage_oauth2_apps"`
+	// ID of the organization role granting the access to the permission units
+	RoleID int64 `json:"role_id"`
+	// access modes (none, read, write or admin) of the team to the permission units, used if the team has no role
+	Units map[string]string `json:"units"`
+	// ID of the parent team, the members of the team are implicitly members of the parent
+	ParentID int64 `json:"parent_id"`
 }
 
 // EditTeamOption options for editing a team
@@ -30,5 +44,12 @@ type EditTeamOption struct {
 	Name        string  `json:"name" binding:"AlphaDashDot;MaxSize(30)"`
 	Description *string `json:"description" binding:"MaxSize(255)"`
 	// enum: read,write,admin
-	Permission string `json:"permission"`
+	Permission          string `json:"permission"`
+	CanManageOAuth2Apps *bool  `json:"can_manage_oauth2_apps"`
+	// ID of the organization role granting the access to the permission units, 0 removes the role
+	RoleID *int64 `json:"role_id"`
+	// access modes (none, read, write or admin) of the team to the permission units, replacing all of them
+	Units map[string]string `json:"units"`
+	// ID of the parent team, 0 makes the team a top level team
+	ParentID *int64 `json:"parent_id"`
 }
@@ -12,10 +12,13 @@ import (
 // AccessToken represents an API access token.
 // swagger:response AccessToken
 type AccessToken struct {
-	ID             int64  `json:"id"`
-	Name           string `json:"name"`
-	Token          string `json:"sha1"`
-	TokenLastEight string `json:"token_last_eight"`
+	ID             int64    `json:"id"`
+	Name           string   `json:"name"`
+	Token          string   `json:"sha1"`
+	TokenLastEight string   `json:"token_last_eight"`
+	Scopes         []string `json:"scopes"`
+	// swagger:strfmt date-time
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
 // AccessTokenList represents a list of API access token.
@@ -26,23 +29,52 @@ type AccessTokenList []*AccessToken
 // swagger:parameters userCreateToken
 type CreateAccessTokenOption struct {
 	Name string `json:"name" binding:"Required"`
+	// scopes granted to the token, all scopes are granted if empty
+	Scopes []string `json:"scopes"`
+	// expiration date of the token, it never expires if empty unless a maximum lifetime is configured
+	// swagger:strfmt date-time
+	ExpiresAt *time.Time `json:"expires_at"`
 }
 
 // CreateOAuth2ApplicationOptions holds options to create an oauth2 application
 type CreateOAuth2ApplicationOptions struct {
-	Name         string   `json:"name" binding:"Required"`
-	RedirectURIs []string `json:"redirect_uris" binding:"Required"`
+	Name                  string   `json:"name" binding:"Required"`
+	RedirectURIs          []string `json:"redirect_uris" binding:"Required"`
+	FrontchannelLogoutURI string   `json:"frontchannel_logout_uri" binding:"ValidUrl"`
+}
+
+// AdminCreateOAuth2ApplicationOptions holds options for an admin to create an oauth2 application of any user
+type AdminCreateOAuth2ApplicationOptions struct {
+	// username of the user or organization owning the application
+	Owner                 string   `json:"owner" binding:"Required"`
+	Name                  string   `json:"name" binding:"Required"`
+	RedirectURIs          []string `json:"redirect_uris" binding:"Required"`
+	FrontchannelLogoutURI string   `json:"frontchannel_logout_uri" binding:"ValidUrl"`
+	// users are not asked for consent by trusted applications
+	Trusted bool `json:"trusted"`
+}
+
+// AdminEditOAuth2ApplicationOptions holds options for an admin to edit an oauth2 application
+type AdminEditOAuth2ApplicationOptions struct {
+	Name                  string   `json:"name" binding:"Required"`
+	RedirectURIs          []string `json:"redirect_uris" binding:"Required"`
+	FrontchannelLogoutURI string   `json:"frontchannel_logout_uri" binding:"ValidUrl"`
+	// users are not asked for consent by trusted applications, unchanged if omitted
+	Trusted *bool `json:"trusted"`
 }
 
 // OAuth2Application represents an OAuth2 application.
 // swagger:response OAuth2Application
 type OAuth2Application struct {
-	ID           int64     `json:"id"`
-	Name         string    `json:"name"`
-	ClientID     string    `json:"client_id"`
-	ClientSecret string    `json:"client_secret"`
-	RedirectURIs []string  `json:"redirect_uris"`
-	Created      time.Time `json:"created"`
+	ID                    int64     `json:"id"`
+	Name                  string    `json:"name"`
+	ClientID              string    `json:"client_id"`
+	ClientSecret          string    `json:"client_secret"`
+	RedirectURIs          []string  `json:"redirect_uris"`
+	FrontchannelLogoutURI string    `json:"frontchannel_logout_uri"`
+	Trusted               bool      `json:"trusted"`
+	Owner                 string    `json:"owner,omitempty"`
+	Created               time.Time `json:"created"`
 }
 
 // OAuth2ApplicationList represents a list of OAuth2 applications.
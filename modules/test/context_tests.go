@@ -16,6 +16,7 @@ import (
 	"go.wandrs.dev/framework/models"
 	"go.wandrs.dev/framework/modules/context"
 	"go.wandrs.dev/framework/modules/web/middleware"
+	"go.wandrs.dev/session"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
@@ -31,8 +32,9 @@ func MockContext(t *testing.T, path string) *context.Context {
 		Flash: &middleware.Flash{
 			Values: make(url.Values),
 		},
-		Resp:   context.NewResponse(resp),
-		Locale: &mockLocale{},
+		Resp:    context.NewResponse(resp),
+		Locale:  &mockLocale{},
+		Session: &mockSession{values: make(map[interface{}]interface{})},
 	}
 
 	requestURL, err := url.Parse(path)
@@ -89,6 +91,55 @@ func (rw *mockResponseWriter) Push(target string, opts *http.PushOptions) error
 	return nil
 }
 
+type mockSession struct {
+	values map[interface{}]interface{}
+}
+
+func (s *mockSession) Set(key, value interface{}) error {
+	s.values[key] = value
+	return nil
+}
+
+func (s *mockSession) Get(key interface{}) interface{} {
+	return s.values[key]
+}
+
+func (s *mockSession) Delete(key interface{}) error {
+	delete(s.values, key)
+	return nil
+}
+
+func (s *mockSession) ID() string {
+	return "mock"
+}
+
+func (s *mockSession) Release() error {
+	return nil
+}
+
+func (s *mockSession) Flush() error {
+	s.values = make(map[interface{}]interface{})
+	return nil
+}
+
+func (s *mockSession) Read(string) (session.RawStore, error) {
+	return s, nil
+}
+
+func (s *mockSession) Destroy(http.ResponseWriter, *http.Request) error {
+	return s.Flush()
+}
+
+func (s *mockSession) RegenerateID(http.ResponseWriter, *http.Request) (session.RawStore, error) {
+	return s, nil
+}
+
+func (s *mockSession) Count() int {
+	return 1
+}
+
+func (s *mockSession) GC() {}
+
 type mockRender struct{}
 
 func (tr *mockRender) TemplateLookup(tmpl string) *template.Template {
@@ -0,0 +1,211 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"net/http"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/base"
+	"go.wandrs.dev/framework/modules/context"
+	"go.wandrs.dev/framework/modules/log"
+	"go.wandrs.dev/framework/modules/setting"
+	"go.wandrs.dev/framework/modules/util"
+	"go.wandrs.dev/framework/modules/web"
+	"go.wandrs.dev/framework/services/forms"
+)
+
+const (
+	tplApplications    base.TplName = "admin/applications/list"
+	tplApplicationNew  base.TplName = "admin/applications/new"
+	tplApplicationEdit base.TplName = "admin/applications/edit"
+)
+
+// Applications shows the oauth2 applications of all users
+func Applications(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("admin.applications")
+	ctx.Data["PageIsAdmin"] = true
+	ctx.Data["PageIsAdminApplications"] = true
+
+	page := ctx.QueryInt("page")
+	if page <= 1 {
+		page = 1
+	}
+	apps, count, err := models.ListAllOAuth2Applications(models.ListOptions{
+		PageSize: setting.UI.Admin.UserPagingNum,
+		Page:     page,
+	})
+	if err != nil {
+		ctx.ServerError("ListAllOAuth2Applications", err)
+		return
+	}
+	ctx.Data["Applications"] = apps
+	ctx.Data["Total"] = count
+
+	pager := context.NewPagination(int(count), setting.UI.Admin.UserPagingNum, page, 5)
+	pager.SetDefaultParams(ctx)
+	ctx.Data["Page"] = pager
+
+	ctx.HTML(http.StatusOK, tplApplications)
+}
+
+// NewApplication renders the page to create an oauth2 application
+func NewApplication(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("admin.applications.new")
+	ctx.Data["PageIsAdmin"] = true
+	ctx.Data["PageIsAdminApplications"] = true
+	ctx.HTML(http.StatusOK, tplApplicationNew)
+}
+
+// NewApplicationPost creates an oauth2 application for the given owner
+func NewApplicationPost(ctx *context.Context) {
+	form := web.GetForm(ctx).(*forms.AdminEditOAuth2ApplicationForm)
+	ctx.Data["Title"] = ctx.Tr("admin.applications.new")
+	ctx.Data["PageIsAdmin"] = true
+	ctx.Data["PageIsAdminApplications"] = true
+
+	if ctx.HasError() {
+		ctx.HTML(http.StatusOK, tplApplicationNew)
+		return
+	}
+
+	owner, err := models.GetUserByName(form.Owner)
+	if err != nil {
+		if models.IsErrUserNotExist(err) {
+			ctx.Data["Err_Owner"] = true
+			ctx.RenderWithErr(ctx.Tr("form.user_not_exist"), tplApplicationNew, form)
+		} else {
+			ctx.ServerError("GetUserByName", err)
+		}
+		return
+	}
+
+	app, err := models.CreateOAuth2Application(models.CreateOAuth2ApplicationOptions{
+		Name:                  form.Name,
+		UserID:                owner.ID,
+		RedirectURIs:          []string{form.RedirectURI},
+		FrontchannelLogoutURI: form.FrontchannelLogoutURI,
+		Trusted:               form.Trusted,
+	})
+	if err != nil {
+		ctx.ServerError("CreateOAuth2Application", err)
+		return
+	}
+	log.Trace("OAuth2 application created by admin(%s): %s", ctx.User.Name, app.ClientID)
+
+	app.User = owner
+	ctx.Data["App"] = app
+	ctx.Data["ClientSecret"], err = app.GenerateClientSecret()
+	if err != nil {
+		ctx.ServerError("GenerateClientSecret", err)
+		return
+	}
+	ctx.Flash.Success(ctx.Tr("admin.applications.new_success", app.Name))
+	ctx.HTML(http.StatusOK, tplApplicationEdit)
+}
+
+func prepareApplicationEdit(ctx *context.Context) *models.OAuth2Application {
+	ctx.Data["Title"] = ctx.Tr("admin.applications.edit")
+	ctx.Data["PageIsAdmin"] = true
+	ctx.Data["PageIsAdminApplications"] = true
+
+	app, err := models.GetOAuth2ApplicationByID(ctx.ParamsInt64(":id"))
+	if err != nil {
+		if models.IsErrOAuthApplicationNotFound(err) {
+			ctx.NotFound("GetOAuth2ApplicationByID", err)
+		} else {
+			ctx.ServerError("GetOAuth2ApplicationByID", err)
+		}
+		return nil
+	}
+	if err := app.LoadUser(); err != nil {
+		ctx.ServerError("LoadUser", err)
+		return nil
+	}
+	ctx.Data["App"] = app
+	return app
+}
+
+// EditApplication renders the page to edit an oauth2 application
+func EditApplication(ctx *context.Context) {
+	if prepareApplicationEdit(ctx) == nil {
+		return
+	}
+	ctx.HTML(http.StatusOK, tplApplicationEdit)
+}
+
+// EditApplicationPost updates an oauth2 application
+func EditApplicationPost(ctx *context.Context) {
+	form := web.GetForm(ctx).(*forms.AdminEditOAuth2ApplicationForm)
+	app := prepareApplicationEdit(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if ctx.HasError() {
+		ctx.HTML(http.StatusOK, tplApplicationEdit)
+		return
+	}
+
+	updated, err := models.UpdateOAuth2Application(models.UpdateOAuth2ApplicationOptions{
+		ID:                    app.ID,
+		Name:                  form.Name,
+		UserID:                app.UID,
+		RedirectURIs:          []string{form.RedirectURI},
+		FrontchannelLogoutURI: form.FrontchannelLogoutURI,
+		Trusted:               util.OptionalBoolOf(form.Trusted),
+	})
+	if err != nil {
+		ctx.ServerError("UpdateOAuth2Application", err)
+		return
+	}
+	log.Trace("OAuth2 application updated by admin(%s): %s", ctx.User.Name, app.ClientID)
+
+	ctx.Flash.Success(ctx.Tr("admin.applications.update_success", updated.Name))
+	ctx.Redirect(setting.AppSubURL + "/admin/applications/" + ctx.Params(":id"))
+}
+
+// RegenerateApplicationSecret generates a new client secret of an oauth2 application
+func RegenerateApplicationSecret(ctx *context.Context) {
+	app := prepareApplicationEdit(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	var err error
+	ctx.Data["ClientSecret"], err = app.GenerateClientSecret()
+	if err != nil {
+		ctx.ServerError("GenerateClientSecret", err)
+		return
+	}
+	log.Trace("OAuth2 application secret regenerated by admin(%s): %s", ctx.User.Name, app.ClientID)
+
+	ctx.Flash.Success(ctx.Tr("admin.applications.update_success", app.Name))
+	ctx.HTML(http.StatusOK, tplApplicationEdit)
+}
+
+// DeleteApplication deletes an oauth2 application
+func DeleteApplication(ctx *context.Context) {
+	app, err := models.GetOAuth2ApplicationByID(ctx.ParamsInt64(":id"))
+	if err != nil {
+		if models.IsErrOAuthApplicationNotFound(err) {
+			ctx.NotFound("GetOAuth2ApplicationByID", err)
+		} else {
+			ctx.ServerError("GetOAuth2ApplicationByID", err)
+		}
+		return
+	}
+
+	if err = models.DeleteOAuth2Application(app.ID, app.UID); err != nil {
+		ctx.ServerError("DeleteOAuth2Application", err)
+		return
+	}
+	log.Trace("OAuth2 application deleted by admin(%s): %s", ctx.User.Name, app.ClientID)
+
+	ctx.Flash.Success(ctx.Tr("admin.applications.deletion_success"))
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"redirect": setting.AppSubURL + "/admin/applications",
+	})
+}
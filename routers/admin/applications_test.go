@@ -0,0 +1,81 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"net/http"
+	"testing"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/test"
+	"go.wandrs.dev/framework/modules/web"
+	"go.wandrs.dev/framework/services/forms"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewApplicationPost(t *testing.T) {
+	models.PrepareTestEnv(t)
+	ctx := test.MockContext(t, "admin/applications/new")
+	test.LoadUser(t, ctx, 1)
+
+	web.SetForm(ctx, &forms.AdminEditOAuth2ApplicationForm{
+		Owner:       "user2",
+		Name:        "trusted-app",
+		RedirectURI: "https://example.com/callback",
+		Trusted:     true,
+	})
+	NewApplicationPost(ctx)
+	assert.EqualValues(t, http.StatusOK, ctx.Resp.Status())
+	assert.NotEmpty(t, ctx.Data["ClientSecret"])
+
+	app := models.AssertExistsAndLoadBean(t, &models.OAuth2Application{Name: "trusted-app"}).(*models.OAuth2Application)
+	assert.EqualValues(t, 2, app.UID)
+	assert.True(t, app.Trusted)
+	assert.Equal(t, []string{"https://example.com/callback"}, app.RedirectURIs)
+
+	ctx = test.MockContext(t, "admin/applications/new")
+	test.LoadUser(t, ctx, 1)
+	web.SetForm(ctx, &forms.AdminEditOAuth2ApplicationForm{
+		Owner:       "user-not-exist",
+		Name:        "app",
+		RedirectURI: "https://example.com/callback",
+	})
+	NewApplicationPost(ctx)
+	assert.NotEmpty(t, ctx.Flash.ErrorMsg)
+}
+
+func TestEditApplicationPost(t *testing.T) {
+	models.PrepareTestEnv(t)
+	ctx := test.MockContext(t, "admin/applications/1")
+	test.LoadUser(t, ctx, 1)
+	ctx.SetParams(":id", "1")
+
+	web.SetForm(ctx, &forms.AdminEditOAuth2ApplicationForm{
+		Name:        "Renamed",
+		RedirectURI: "https://example.com/callback",
+		Trusted:     true,
+	})
+	EditApplicationPost(ctx)
+	assert.EqualValues(t, http.StatusFound, ctx.Resp.Status())
+
+	models.AssertExistsAndLoadBean(t, &models.OAuth2Application{ID: 1, UID: 1, Name: "Renamed", Trusted: true})
+}
+
+func TestDeleteApplication(t *testing.T) {
+	models.PrepareTestEnv(t)
+	ctx := test.MockContext(t, "admin/applications/1/delete")
+	test.LoadUser(t, ctx, 1)
+	ctx.SetParams(":id", "1")
+	DeleteApplication(ctx)
+	assert.EqualValues(t, http.StatusOK, ctx.Resp.Status())
+	models.AssertNotExistsBean(t, &models.OAuth2Application{ID: 1})
+
+	ctx = test.MockContext(t, "admin/applications/1/delete")
+	test.LoadUser(t, ctx, 1)
+	ctx.SetParams(":id", "1")
+	DeleteApplication(ctx)
+	assert.EqualValues(t, http.StatusNotFound, ctx.Resp.Status())
+}
@@ -0,0 +1,32 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"net/http"
+
+	"go.wandrs.dev/framework/modules/base"
+	"go.wandrs.dev/framework/modules/context"
+	"go.wandrs.dev/framework/routers/utils"
+)
+
+const (
+	tplAudit base.TplName = "admin/audit"
+)
+
+// Audit shows the audit log
+func Audit(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("admin.audit")
+	ctx.Data["PageIsAdmin"] = true
+	ctx.Data["PageIsAdminAudit"] = true
+	ctx.Data["ShowIPAddress"] = true
+
+	utils.ListAuditEvents(ctx, 0)
+	if ctx.Written() {
+		return
+	}
+
+	ctx.HTML(http.StatusOK, tplAudit)
+}
@@ -0,0 +1,67 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"net/http"
+	"testing"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/test"
+	"go.wandrs.dev/framework/modules/web"
+	"go.wandrs.dev/framework/services/forms"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditUserPost_Audit(t *testing.T) {
+	models.PrepareTestEnv(t)
+	ctx := test.MockContext(t, "admin/users/2")
+	test.LoadUser(t, ctx, 1)
+	ctx.SetParams(":userid", "2")
+
+	u := models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User)
+	web.SetForm(ctx, &forms.AdminEditUserForm{
+		LoginType:               "0-0",
+		UserName:                u.Name,
+		LoginName:               u.LoginName,
+		FullName:                u.FullName,
+		Email:                   u.Email,
+		Website:                 u.Website,
+		Location:                u.Location,
+		Active:                  u.IsActive,
+		Admin:                   !u.IsAdmin,
+		Restricted:              u.IsRestricted,
+		AllowGitHook:            u.AllowGitHook,
+		AllowImportLocal:        u.AllowImportLocal,
+		AllowCreateOrganization: u.AllowCreateOrganization,
+		ProhibitLogin:           u.ProhibitLogin,
+	})
+	EditUserPost(ctx)
+	assert.EqualValues(t, http.StatusFound, ctx.Resp.Status())
+
+	evt := models.AssertExistsAndLoadBean(t, &models.AuditEvent{Action: models.AuditUserEdit, TargetID: 2}).(*models.AuditEvent)
+	assert.EqualValues(t, 1, evt.ActorID)
+	assert.Equal(t, "user1", evt.ActorName)
+	assert.Equal(t, "user2", evt.TargetName)
+	assert.Equal(t, map[string]string{"is_admin": "false"}, evt.Before)
+	assert.Equal(t, map[string]string{"is_admin": "true"}, evt.After)
+}
+
+func TestAudit(t *testing.T) {
+	models.PrepareTestEnv(t)
+	assert.NoError(t, models.CreateAuditEvent(&models.AuditEvent{Action: models.AuditUserEdit, ActorID: 1, ActorName: "user1"}))
+	assert.NoError(t, models.CreateAuditEvent(&models.AuditEvent{Action: models.AuditAccessTokenCreate, ActorID: 2, ActorName: "user2"}))
+
+	ctx := test.MockContext(t, "admin/audit")
+	test.LoadUser(t, ctx, 1)
+	ctx.Req.Form.Set("actor", "user2")
+	Audit(ctx)
+	assert.EqualValues(t, http.StatusOK, ctx.Resp.Status())
+	events := ctx.Data["AuditEvents"].([]*models.AuditEvent)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, models.AuditAccessTokenCreate, events[0].Action)
+	}
+}
@@ -5,15 +5,18 @@
 package admin
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"net/http"
 	"regexp"
+	"strings"
 
 	"go.wandrs.dev/framework/models"
 	"go.wandrs.dev/framework/modules/auth/ldap"
 	"go.wandrs.dev/framework/modules/auth/oauth2"
 	"go.wandrs.dev/framework/modules/auth/pam"
+	"go.wandrs.dev/framework/modules/auth/saml"
 	"go.wandrs.dev/framework/modules/base"
 	"go.wandrs.dev/framework/modules/context"
 	"go.wandrs.dev/framework/modules/log"
@@ -66,6 +69,7 @@ var (
 			{models.LoginNames[models.LoginSMTP], models.LoginSMTP},
 			{models.LoginNames[models.LoginOAuth2], models.LoginOAuth2},
 			{models.LoginNames[models.LoginSSPI], models.LoginSSPI},
+			{models.LoginNames[models.LoginSAML], models.LoginSAML},
 		}
 		if pam.Supported {
 			items = append(items, dropdownItem{models.LoginNames[models.LoginPAM], models.LoginPAM})
@@ -113,12 +117,12 @@ func NewAuthSource(ctx *context.Context) {
 	ctx.HTML(http.StatusOK, tplAuthNew)
 }
 
-func parseLDAPConfig(form forms.AuthenticationForm) *models.LDAPConfig {
+func parseLDAPConfig(ctx *context.Context, form forms.AuthenticationForm) (*models.LDAPConfig, error) {
 	var pageSize uint32
 	if form.UsePagedSearch {
 		pageSize = uint32(form.SearchPageSize)
 	}
-	return &models.LDAPConfig{
+	config := &models.LDAPConfig{
 		Source: &ldap.Source{
 			Name:                  form.Name,
 			Host:                  form.Host,
@@ -142,12 +146,19 @@ func parseLDAPConfig(form forms.AuthenticationForm) *models.LDAPConfig {
 			GroupFilter:           form.GroupFilter,
 			GroupMemberUID:        form.GroupMemberUID,
 			UserUID:               form.UserUID,
+			GroupTeamMap:          strings.TrimSpace(form.GroupTeamMap),
+			GroupTeamMapRemoval:   form.GroupTeamMapRemoval,
 			AdminFilter:           form.AdminFilter,
 			RestrictedFilter:      form.RestrictedFilter,
 			AllowDeactivateAll:    form.AllowDeactivateAll,
 			Enabled:               true,
 		},
 	}
+	if _, err := config.ParseGroupTeamMap(); err != nil {
+		ctx.Data["Err_GroupTeamMap"] = true
+		return nil, errors.New(ctx.Tr("admin.auths.invalid_group_team_map", err.Error()))
+	}
+	return config, nil
 }
 
 func parseSMTPConfig(form forms.AuthenticationForm) *models.SMTPConfig {
@@ -161,7 +172,7 @@ func parseSMTPConfig(form forms.AuthenticationForm) *models.SMTPConfig {
 	}
 }
 
-func parseOAuth2Config(form forms.AuthenticationForm) *models.OAuth2Config {
+func parseOAuth2Config(ctx *context.Context, form forms.AuthenticationForm) (*models.OAuth2Config, error) {
 	var customURLMapping *oauth2.CustomURLMapping
 	if form.Oauth2UseCustomURL {
 		customURLMapping = &oauth2.CustomURLMapping{
@@ -173,14 +184,24 @@ func parseOAuth2Config(form forms.AuthenticationForm) *models.OAuth2Config {
 	} else {
 		customURLMapping = nil
 	}
-	return &models.OAuth2Config{
+	config := &models.OAuth2Config{
 		Provider:                      form.Oauth2Provider,
 		ClientID:                      form.Oauth2Key,
 		ClientSecret:                  form.Oauth2Secret,
 		OpenIDConnectAutoDiscoveryURL: form.OpenIDConnectAutoDiscoveryURL,
 		CustomURLMapping:              customURLMapping,
 		IconURL:                       form.Oauth2IconURL,
+		GroupClaimName:                strings.TrimSpace(form.Oauth2GroupClaimName),
+		AdminGroup:                    strings.TrimSpace(form.Oauth2AdminGroup),
+		RestrictedGroup:               strings.TrimSpace(form.Oauth2RestrictedGroup),
+		GroupTeamMap:                  strings.TrimSpace(form.Oauth2GroupTeamMap),
+		GroupTeamMapRemoval:           form.Oauth2GroupTeamMapRemoval,
+	}
+	if _, err := config.ParseGroupTeamMap(); err != nil {
+		ctx.Data["Err_GroupTeamMap"] = true
+		return nil, errors.New(ctx.Tr("admin.auths.invalid_group_team_map", err.Error()))
 	}
+	return config, nil
 }
 
 func parseSSPIConfig(ctx *context.Context, form forms.AuthenticationForm) (*models.SSPIConfig, error) {
@@ -207,6 +228,31 @@ func parseSSPIConfig(ctx *context.Context, form forms.AuthenticationForm) (*mode
 	}, nil
 }
 
+func parseSAMLConfig(ctx *context.Context, form forms.AuthenticationForm) (*models.SAMLConfig, error) {
+	if util.IsEmptyString(form.SAMLIdPEntityID) || util.IsEmptyString(form.SAMLIdPSSOURL) {
+		return nil, errors.New(ctx.Tr("admin.auths.saml_idp_required"))
+	}
+	if _, err := saml.ParseCertificate(form.SAMLIdPCertificate); err != nil {
+		ctx.Data["Err_SAMLIdPCertificate"] = true
+		return nil, errors.New(ctx.Tr("admin.auths.saml_invalid_certificate", err.Error()))
+	}
+
+	return &models.SAMLConfig{
+		IdPEntityID:            strings.TrimSpace(form.SAMLIdPEntityID),
+		IdPSSOURL:              strings.TrimSpace(form.SAMLIdPSSOURL),
+		IdPCertificate:         strings.TrimSpace(form.SAMLIdPCertificate),
+		SPEntityID:             strings.TrimSpace(form.SAMLSPEntityID),
+		NameIDFormat:           strings.TrimSpace(form.SAMLNameIDFormat),
+		AttributeUsername:      strings.TrimSpace(form.SAMLAttributeUsername),
+		AttributeEmail:         strings.TrimSpace(form.SAMLAttributeEmail),
+		AttributeFullName:      strings.TrimSpace(form.SAMLAttributeFullName),
+		AttributeAdmin:         strings.TrimSpace(form.SAMLAttributeAdmin),
+		AdminValue:             form.SAMLAdminValue,
+		AllowIdPInitiated:      form.SAMLAllowIdPInitiated,
+		EnableAutoRegistration: form.SAMLEnableAutoRegistration,
+	}, nil
+}
+
 // NewAuthSourcePost response for adding an auth source
 func NewAuthSourcePost(ctx *context.Context) {
 	form := *web.GetForm(ctx).(*forms.AuthenticationForm)
@@ -232,7 +278,12 @@ func NewAuthSourcePost(ctx *context.Context) {
 	var config convert.Conversion
 	switch models.LoginType(form.Type) {
 	case models.LoginLDAP, models.LoginDLDAP:
-		config = parseLDAPConfig(form)
+		var err error
+		config, err = parseLDAPConfig(ctx, form)
+		if err != nil {
+			ctx.RenderWithErr(err.Error(), tplAuthNew, form)
+			return
+		}
 		hasTLS = ldap.SecurityProtocol(form.SecurityProtocol) > ldap.SecurityProtocolUnencrypted
 	case models.LoginSMTP:
 		config = parseSMTPConfig(form)
@@ -243,7 +294,12 @@ func NewAuthSourcePost(ctx *context.Context) {
 			EmailDomain: form.PAMEmailDomain,
 		}
 	case models.LoginOAuth2:
-		config = parseOAuth2Config(form)
+		var err error
+		config, err = parseOAuth2Config(ctx, form)
+		if err != nil {
+			ctx.RenderWithErr(err.Error(), tplAuthNew, form)
+			return
+		}
 	case models.LoginSSPI:
 		var err error
 		config, err = parseSSPIConfig(ctx, form)
@@ -257,6 +313,13 @@ func NewAuthSourcePost(ctx *context.Context) {
 			ctx.RenderWithErr(ctx.Tr("admin.auths.login_source_of_type_exist"), tplAuthNew, form)
 			return
 		}
+	case models.LoginSAML:
+		var err error
+		config, err = parseSAMLConfig(ctx, form)
+		if err != nil {
+			ctx.RenderWithErr(err.Error(), tplAuthNew, form)
+			return
+		}
 	default:
 		ctx.Error(http.StatusBadRequest)
 		return
@@ -268,13 +331,14 @@ func NewAuthSourcePost(ctx *context.Context) {
 		return
 	}
 
-	if err := models.CreateLoginSource(&models.LoginSource{
+	source := &models.LoginSource{
 		Type:          models.LoginType(form.Type),
 		Name:          form.Name,
 		IsActived:     form.IsActive,
 		IsSyncEnabled: form.IsSyncEnabled,
 		Cfg:           config,
-	}); err != nil {
+	}
+	if err := models.CreateLoginSource(source); err != nil {
 		if models.IsErrLoginSourceAlreadyExist(err) {
 			ctx.Data["Err_Name"] = true
 			ctx.RenderWithErr(ctx.Tr("admin.auths.login_source_exist", err.(models.ErrLoginSourceAlreadyExist).Name), tplAuthNew, form)
@@ -285,6 +349,12 @@ func NewAuthSourcePost(ctx *context.Context) {
 	}
 
 	log.Trace("Authentication created by admin(%s): %s", ctx.User.Name, form.Name)
+	ctx.Audit(&models.AuditEvent{
+		Action:     models.AuditLoginSourceCreate,
+		TargetID:   source.ID,
+		TargetName: source.Name,
+		After:      source.AuditValues(),
+	})
 
 	ctx.Flash.Success(ctx.Tr("admin.auths.new_success", form.Name))
 	ctx.Redirect(setting.AppSubURL + "/admin/auths")
@@ -342,7 +412,11 @@ func EditAuthSourcePost(ctx *context.Context) {
 	var config convert.Conversion
 	switch models.LoginType(form.Type) {
 	case models.LoginLDAP, models.LoginDLDAP:
-		config = parseLDAPConfig(form)
+		config, err = parseLDAPConfig(ctx, form)
+		if err != nil {
+			ctx.RenderWithErr(err.Error(), tplAuthEdit, form)
+			return
+		}
 	case models.LoginSMTP:
 		config = parseSMTPConfig(form)
 	case models.LoginPAM:
@@ -351,18 +425,35 @@ func EditAuthSourcePost(ctx *context.Context) {
 			EmailDomain: form.PAMEmailDomain,
 		}
 	case models.LoginOAuth2:
-		config = parseOAuth2Config(form)
+		config, err = parseOAuth2Config(ctx, form)
+		if err != nil {
+			ctx.RenderWithErr(err.Error(), tplAuthEdit, form)
+			return
+		}
 	case models.LoginSSPI:
 		config, err = parseSSPIConfig(ctx, form)
 		if err != nil {
 			ctx.RenderWithErr(err.Error(), tplAuthEdit, form)
 			return
 		}
+	case models.LoginSAML:
+		config, err = parseSAMLConfig(ctx, form)
+		if err != nil {
+			ctx.RenderWithErr(err.Error(), tplAuthEdit, form)
+			return
+		}
 	default:
 		ctx.Error(http.StatusBadRequest)
 		return
 	}
 
+	before := source.AuditValues()
+	oldConfig, err := source.Cfg.ToDB()
+	if err != nil {
+		ctx.ServerError("ToDB", err)
+		return
+	}
+
 	source.Name = form.Name
 	source.IsActived = form.IsActive
 	source.IsSyncEnabled = form.IsSyncEnabled
@@ -377,6 +468,21 @@ func EditAuthSourcePost(ctx *context.Context) {
 		return
 	}
 	log.Trace("Authentication changed by admin(%s): %d", ctx.User.Name, source.ID)
+	after := source.AuditValues()
+	if newConfig, err := config.ToDB(); err != nil || !bytes.Equal(oldConfig, newConfig) {
+		// the values of the configuration are not recorded as they contain secrets
+		before["config"], after["config"] = "", "changed"
+	}
+	changedBefore, changedAfter := models.AuditChanges(before, after)
+	if len(changedAfter) > 0 {
+		ctx.Audit(&models.AuditEvent{
+			Action:     models.AuditLoginSourceEdit,
+			TargetID:   source.ID,
+			TargetName: source.Name,
+			Before:     changedBefore,
+			After:      changedAfter,
+		})
+	}
 
 	ctx.Flash.Success(ctx.Tr("admin.auths.update_success"))
 	ctx.Redirect(setting.AppSubURL + "/admin/auths/" + fmt.Sprint(form.ID))
@@ -402,6 +508,12 @@ func DeleteAuthSource(ctx *context.Context) {
 		return
 	}
 	log.Trace("Authentication deleted by admin(%s): %d", ctx.User.Name, source.ID)
+	ctx.Audit(&models.AuditEvent{
+		Action:     models.AuditLoginSourceDelete,
+		TargetID:   source.ID,
+		TargetName: source.Name,
+		Before:     source.AuditValues(),
+	})
 
 	ctx.Flash.Success(ctx.Tr("admin.auths.deletion_success"))
 	ctx.JSON(http.StatusOK, map[string]interface{}{
@@ -12,6 +12,7 @@ import (
 	"strings"
 
 	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/auth/lockout"
 	"go.wandrs.dev/framework/modules/base"
 	"go.wandrs.dev/framework/modules/context"
 	"go.wandrs.dev/framework/modules/log"
@@ -197,6 +198,10 @@ func prepareUserInfo(ctx *context.Context) *models.User {
 		ctx.Data["TwoFactorEnabled"] = false
 	}
 
+	if until, locked := lockout.AccountLockedUntil(u.ID); locked {
+		ctx.Data["LockedUntil"] = until
+	}
+
 	return u
 }
 
@@ -232,6 +237,7 @@ func EditUserPost(ctx *context.Context) {
 		return
 	}
 
+	before := u.AuditValues()
 	fields := strings.Split(form.LoginType, "-")
 	if len(fields) == 2 {
 		loginType, _ := strconv.ParseInt(fields[0], 10, 0)
@@ -243,7 +249,7 @@ func EditUserPost(ctx *context.Context) {
 		}
 	}
 
-	if len(form.Password) > 0 && (u.IsLocal() || u.IsOAuth2()) {
+	if len(form.Password) > 0 && (u.IsLocal() || u.IsOAuth2() || u.IsSAML()) {
 		var err error
 		if len(form.Password) < setting.MinPasswordLength {
 			ctx.Data["Err_Password"] = true
@@ -295,6 +301,11 @@ func EditUserPost(ctx *context.Context) {
 			ctx.ServerError("DeleteTwoFactorByID", err)
 			return
 		}
+		ctx.Audit(&models.AuditEvent{
+			Action:     models.AuditTwoFactorDisable,
+			TargetID:   u.ID,
+			TargetName: u.Name,
+		})
 	}
 
 	u.LoginName = form.LoginName
@@ -329,6 +340,26 @@ func EditUserPost(ctx *context.Context) {
 		return
 	}
 	log.Trace("Account profile updated by admin (%s): %s", ctx.User.Name, u.Name)
+	if u.ProhibitLogin {
+		if err := models.DeleteUserSessions(u.ID); err != nil {
+			ctx.ServerError("DeleteUserSessions", err)
+			return
+		}
+	}
+	after := u.AuditValues()
+	if len(form.Password) > 0 && (u.IsLocal() || u.IsOAuth2() || u.IsSAML()) {
+		before["password"], after["password"] = "", "changed"
+	}
+	changedBefore, changedAfter := models.AuditChanges(before, after)
+	if len(changedAfter) > 0 {
+		ctx.Audit(&models.AuditEvent{
+			Action:     models.AuditUserEdit,
+			TargetID:   u.ID,
+			TargetName: u.Name,
+			Before:     changedBefore,
+			After:      changedAfter,
+		})
+	}
 
 	ctx.Flash.Success(ctx.Tr("admin.users.update_profile_success"))
 	ctx.Redirect(setting.AppSubURL + "/admin/users/" + ctx.Params(":userid"))
@@ -361,3 +392,49 @@ func DeleteUser(ctx *context.Context) {
 		"redirect": setting.AppSubURL + "/admin/users",
 	})
 }
+
+// UnlockUser clears the failed sign in attempts and the lockout of a user
+func UnlockUser(ctx *context.Context) {
+	u, err := models.GetUserByID(ctx.ParamsInt64(":userid"))
+	if err != nil {
+		ctx.ServerError("GetUserByID", err)
+		return
+	}
+
+	lockout.ResetAccount(u.ID)
+	log.Trace("Account unlocked by admin (%s): %s", ctx.User.Name, u.Name)
+
+	ctx.Flash.Success(ctx.Tr("admin.users.unlock_success"))
+	ctx.Redirect(setting.AppSubURL + "/admin/users/" + ctx.Params(":userid"))
+}
+
+// ImpersonateUser switches the session of the administrator to the user
+func ImpersonateUser(ctx *context.Context) {
+	u, err := models.GetUserByID(ctx.ParamsInt64(":userid"))
+	if err != nil {
+		if models.IsErrUserNotExist(err) {
+			ctx.NotFound("GetUserByID", err)
+		} else {
+			ctx.ServerError("GetUserByID", err)
+		}
+		return
+	}
+	if u.IsAdmin || u.IsOrganization() {
+		ctx.Flash.Error(ctx.Tr("admin.users.impersonate_admin"))
+		ctx.Redirect(setting.AppSubURL + "/admin/users/" + ctx.Params(":userid"))
+		return
+	}
+
+	if err := ctx.Impersonate(u); err != nil {
+		ctx.ServerError("Impersonate", err)
+		return
+	}
+	log.Trace("User %s impersonated by admin %s", u.Name, ctx.User.Name)
+	ctx.Audit(&models.AuditEvent{
+		Action:     models.AuditImpersonateStart,
+		TargetID:   u.ID,
+		TargetName: u.Name,
+	})
+
+	ctx.Redirect(setting.AppSubURL + "/")
+}
@@ -5,9 +5,11 @@
 package admin
 
 import (
+	"net/http"
 	"testing"
 
 	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/auth/sso"
 	"go.wandrs.dev/framework/modules/test"
 	"go.wandrs.dev/framework/modules/web"
 	"go.wandrs.dev/framework/services/forms"
@@ -118,3 +120,26 @@ func TestNewUserPost_InvalidEmail(t *testing.T) {
 
 	assert.NotEmpty(t, ctx.Flash.ErrorMsg)
 }
+
+func TestImpersonateUser(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	// administrators cannot be impersonated
+	ctx := test.MockContext(t, "admin/users/1/impersonate")
+	test.LoadUser(t, ctx, 1)
+	ctx.SetParams(":userid", "1")
+	ImpersonateUser(ctx)
+	assert.EqualValues(t, http.StatusFound, ctx.Resp.Status())
+	assert.EqualValues(t, "admin.users.impersonate_admin", ctx.Flash.ErrorMsg)
+	assert.Nil(t, ctx.Session.Get(sso.ImpersonatorKey))
+
+	ctx = test.MockContext(t, "admin/users/2/impersonate")
+	test.LoadUser(t, ctx, 1)
+	ctx.SetParams(":userid", "2")
+	ImpersonateUser(ctx)
+	assert.EqualValues(t, http.StatusFound, ctx.Resp.Status())
+	assert.EqualValues(t, 2, ctx.Session.Get("uid"))
+	assert.EqualValues(t, "user2", ctx.Session.Get("uname"))
+	assert.EqualValues(t, 1, ctx.Session.Get(sso.ImpersonatorKey))
+	models.AssertExistsAndLoadBean(t, &models.AuditEvent{Action: models.AuditImpersonateStart, ActorID: 1, TargetID: 2})
+}
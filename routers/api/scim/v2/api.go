@@ -0,0 +1,185 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package v2 implements the SCIM 2.0 provisioning API (RFC 7644) which maps SCIM
+// users to users and SCIM groups to organization teams.
+package v2
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/context"
+	"go.wandrs.dev/framework/modules/log"
+	"go.wandrs.dev/framework/modules/scim"
+	"go.wandrs.dev/framework/modules/setting"
+	"go.wandrs.dev/framework/modules/timeutil"
+	"go.wandrs.dev/framework/modules/web"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// Prefix is the path the API is served at
+const Prefix = "/scim/v2"
+
+// checkToken requires a bearer access token of a site administrator which has
+// been issued with the admin:scim scope explicitly
+func checkToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := context.GetPrivateContext(req)
+
+		fields := strings.Fields(req.Header.Get("Authorization"))
+		if len(fields) != 2 || !strings.EqualFold(fields[0], "bearer") {
+			ctx.Resp.Header().Set("WWW-Authenticate", `Bearer realm="SCIM"`)
+			writeError(ctx, scim.NewError(http.StatusUnauthorized, "", "a bearer token is required"))
+			return
+		}
+
+		token, err := models.GetAccessTokenBySHA(fields[1])
+		if err != nil {
+			if models.IsErrAccessTokenNotExist(err) || models.IsErrAccessTokenEmpty(err) || models.IsErrAccessTokenExpired(err) {
+				ctx.Resp.Header().Set("WWW-Authenticate", `Bearer realm="SCIM", error="invalid_token"`)
+				writeError(ctx, scim.NewError(http.StatusUnauthorized, "", "the bearer token is invalid"))
+				return
+			}
+			writeError(ctx, fmt.Errorf("GetAccessTokenBySHA: %v", err))
+			return
+		}
+		if !models.AccessTokenScopeContains(token.Scope, models.AccessTokenScopeAdminSCIM) {
+			writeError(ctx, scim.NewError(http.StatusForbidden, "", "the token does not have the %s scope", models.AccessTokenScopeAdminSCIM))
+			return
+		}
+
+		u, err := models.GetUserByID(token.UID)
+		if err != nil {
+			writeError(ctx, fmt.Errorf("GetUserByID: %v", err))
+			return
+		}
+		if !u.IsAdmin || !u.IsActive || u.ProhibitLogin {
+			log.Warn("SCIM request from %s with a token of %s who is not an administrator", ctx.RemoteAddr(), u.Name)
+			writeError(ctx, scim.NewError(http.StatusForbidden, "", "the token does not belong to an administrator"))
+			return
+		}
+
+		token.UpdatedUnix = timeutil.TimeStampNow()
+		if err := models.UpdateAccessToken(token); err != nil {
+			log.Error("UpdateAccessToken: %v", err)
+		}
+
+		ctx.User = u
+		ctx.IsSigned = true
+		next.ServeHTTP(w, req)
+	})
+}
+
+// Routes registers the routes of the SCIM API
+func Routes() *web.Route {
+	r := web.NewRoute()
+	r.Use(context.PrivateContexter())
+	r.Use(checkToken)
+
+	r.Get("/ServiceProviderConfig", GetServiceProviderConfig)
+	r.Get("/ResourceTypes", ListResourceTypes)
+	r.Get("/ResourceTypes/{id}", GetResourceType)
+	r.Get("/Schemas", ListSchemas)
+	r.Get("/Schemas/{id}", GetSchema)
+
+	r.Get("/Users", ListUsers)
+	r.Post("/Users", CreateUser)
+	r.Get("/Users/{id}", GetUser)
+	r.Put("/Users/{id}", ReplaceUser)
+	r.Patch("/Users/{id}", PatchUser)
+	r.Delete("/Users/{id}", DeleteUser)
+
+	r.Get("/Groups", ListGroups)
+	r.Post("/Groups", CreateGroup)
+	r.Get("/Groups/{id}", GetGroup)
+	r.Put("/Groups/{id}", ReplaceGroup)
+	r.Patch("/Groups/{id}", PatchGroup)
+	r.Delete("/Groups/{id}", DeleteGroup)
+
+	return r
+}
+
+// location returns the URL of the resource or endpoint
+func location(path string) string {
+	return setting.AppURL + strings.TrimPrefix(Prefix, "/") + path
+}
+
+func writeJSON(ctx *context.PrivateContext, status int, v interface{}) {
+	ctx.Resp.Header().Set("Content-Type", scim.ContentType+";charset=utf-8")
+	ctx.Resp.WriteHeader(status)
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	if err := json.NewEncoder(ctx.Resp).Encode(v); err != nil {
+		log.Error("Unable to write SCIM response: %v", err)
+	}
+}
+
+// writeError responds with the SCIM error, other errors are logged and reported as internal errors
+func writeError(ctx *context.PrivateContext, err error) {
+	scimErr, ok := err.(*scim.Error)
+	if !ok {
+		log.Error("SCIM %s %s: %v", ctx.Req.Method, ctx.Req.URL.Path, err)
+		scimErr = scim.NewError(http.StatusInternalServerError, "", "internal server error")
+	}
+	writeJSON(ctx, scimErr.StatusCode(), scimErr)
+}
+
+// readBody decodes the body of the request
+func readBody(ctx *context.PrivateContext, v interface{}) bool {
+	json := jsoniter.ConfigCompatibleWithStandardLibrary
+	if err := json.NewDecoder(ctx.Req.Body).Decode(v); err != nil {
+		writeError(ctx, scim.NewError(http.StatusBadRequest, scim.ErrorTypeInvalidSyntax, "invalid request body: %v", err))
+		return false
+	}
+	return true
+}
+
+// listResources responds with the page of the resources matching the filter of the query
+func listResources(ctx *context.PrivateContext, resources []interface{}) {
+	var filter scim.Filter
+	if query := ctx.Query("filter"); query != "" {
+		var err error
+		if filter, err = scim.ParseFilter(query); err != nil {
+			writeError(ctx, err)
+			return
+		}
+	}
+
+	matches := make([]interface{}, 0, len(resources))
+	for _, resource := range resources {
+		if filter != nil {
+			generic, err := scim.ToResource(resource)
+			if err != nil {
+				writeError(ctx, err)
+				return
+			}
+			if !filter.Matches(generic) {
+				continue
+			}
+		}
+		matches = append(matches, resource)
+	}
+
+	count := ctx.QueryInt("count", setting.API.MaxResponseItems)
+	if count > setting.API.MaxResponseItems {
+		count = setting.API.MaxResponseItems
+	}
+	writeJSON(ctx, http.StatusOK, scim.NewListResponse(matches, ctx.QueryInt("startIndex", 1), count))
+}
+
+// parseID returns the ID of the resource in the path, 0 if it is not a valid ID
+func parseID(ctx *context.PrivateContext) int64 {
+	id := ctx.ParamsInt64("id")
+	if id <= 0 {
+		return 0
+	}
+	return id
+}
+
+func notFound(resourceType, id string) error {
+	return scim.NewError(http.StatusNotFound, "", "%s %s not found", resourceType, id)
+}
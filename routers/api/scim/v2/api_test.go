@@ -0,0 +1,137 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/scim"
+	"go.wandrs.dev/framework/modules/setting"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func newToken(t *testing.T, uid int64, scope string) string {
+	token := &models.AccessToken{UID: uid, Name: "scim-" + scope, Scope: scope}
+	assert.NoError(t, models.NewAccessToken(token))
+	return token.Token
+}
+
+func request(t *testing.T, token, method, path, body string, v interface{}) int {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp := httptest.NewRecorder()
+	Routes().ServeHTTP(resp, req)
+	if v != nil {
+		assert.Equal(t, scim.ContentType+";charset=utf-8", resp.Header().Get("Content-Type"))
+		assert.NoError(t, jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(resp.Body.Bytes(), v))
+	}
+	return resp.Code
+}
+
+func TestAuthentication(t *testing.T) {
+	assert.NoError(t, models.PrepareTestDatabase())
+
+	assert.Equal(t, http.StatusUnauthorized, request(t, "", "GET", "/ServiceProviderConfig", "", &scim.Error{}))
+	assert.Equal(t, http.StatusUnauthorized, request(t, "invalid-token", "GET", "/ServiceProviderConfig", "", &scim.Error{}))
+	// the scope is not granted by all
+	assert.Equal(t, http.StatusForbidden, request(t, newToken(t, 1, "all"), "GET", "/ServiceProviderConfig", "", &scim.Error{}))
+	// only tokens of administrators are accepted
+	assert.Equal(t, http.StatusForbidden, request(t, newToken(t, 2, "admin:scim"), "GET", "/ServiceProviderConfig", "", &scim.Error{}))
+
+	config := &scim.ServiceProviderConfig{}
+	assert.Equal(t, http.StatusOK, request(t, newToken(t, 1, "admin:scim"), "GET", "/ServiceProviderConfig", "", config))
+	assert.True(t, config.Patch.Supported)
+}
+
+func TestUsers(t *testing.T) {
+	assert.NoError(t, models.PrepareTestDatabase())
+	token := newToken(t, 1, "admin:scim")
+
+	user := &scim.User{}
+	assert.Equal(t, http.StatusCreated, request(t, token, "POST", "/Users", `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+		"externalId": "42",
+		"userName": "alice",
+		"name": {"givenName": "Alice", "familyName": "Liddell"},
+		"emails": [{"value": "alice@example.com", "primary": true}]
+	}`, user))
+	u := models.AssertExistsAndLoadBean(t, &models.User{Name: "alice"}).(*models.User)
+	assert.Equal(t, "Alice Liddell", u.FullName)
+	assert.Equal(t, "alice@example.com", u.Email)
+	assert.False(t, u.ProhibitLogin)
+	assert.Equal(t, "42", user.ExternalID)
+
+	assert.Equal(t, http.StatusConflict, request(t, token, "POST", "/Users", `{"userName": "Alice", "emails": [{"value": "alice@example.org"}]}`, &scim.Error{}))
+	assert.Equal(t, http.StatusBadRequest, request(t, token, "POST", "/Users", `{"userName": "al@ice", "emails": [{"value": "alice@example.org"}]}`, &scim.Error{}))
+
+	list := &scim.ListResponse{}
+	assert.Equal(t, http.StatusOK, request(t, token, "GET", `/Users?filter=externalId+eq+"42"`, "", list))
+	assert.Equal(t, 1, list.TotalResults)
+	assert.Equal(t, http.StatusBadRequest, request(t, token, "GET", `/Users?filter=userName+is+"alice"`, "", &scim.Error{}))
+
+	assert.Equal(t, http.StatusOK, request(t, token, "PATCH", "/Users/"+user.ID, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [{"op": "replace", "value": {"userName": "alice2", "active": false}}]
+	}`, user))
+	u = models.AssertExistsAndLoadBean(t, &models.User{ID: u.ID}).(*models.User)
+	assert.Equal(t, "alice2", u.Name)
+	assert.True(t, u.ProhibitLogin)
+
+	// deprovisioned users are prohibited from signing in rather than deleted
+	assert.Equal(t, http.StatusNoContent, request(t, token, "DELETE", "/Users/2", "", nil))
+	assert.True(t, models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User).ProhibitLogin)
+
+	assert.Equal(t, http.StatusNotFound, request(t, token, "GET", "/Users/3", "", &scim.Error{}))
+}
+
+func TestGroups(t *testing.T) {
+	assert.NoError(t, models.PrepareTestDatabase())
+	token := newToken(t, 1, "admin:scim")
+	setting.SCIM.Organization = "user3"
+	defer func() {
+		setting.SCIM.Organization = ""
+	}()
+
+	group := &scim.Group{}
+	assert.Equal(t, http.StatusCreated, request(t, token, "POST", "/Groups", `{
+		"displayName": "developers",
+		"members": [{"value": "4"}]
+	}`, group))
+	team := models.AssertExistsAndLoadBean(t, &models.Team{OrgID: 3, LowerName: "developers"}).(*models.Team)
+	models.AssertExistsAndLoadBean(t, &models.TeamUser{TeamID: team.ID, UID: 4})
+
+	assert.Equal(t, http.StatusConflict, request(t, token, "POST", "/Groups", `{"displayName": "user3/Developers"}`, &scim.Error{}))
+	assert.Equal(t, http.StatusBadRequest, request(t, token, "POST", "/Groups", `{"displayName": "unknown/developers"}`, &scim.Error{}))
+	assert.Equal(t, http.StatusBadRequest, request(t, token, "POST", "/Groups", `{"displayName": "testers", "members": [{"value": "3"}]}`, &scim.Error{}))
+
+	assert.Equal(t, http.StatusOK, request(t, token, "PATCH", "/Groups/"+group.ID, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [
+			{"op": "add", "path": "members", "value": [{"value": "5"}]},
+			{"op": "remove", "path": "members[value eq \"4\"]"}
+		]
+	}`, group))
+	models.AssertNotExistsBean(t, &models.TeamUser{TeamID: team.ID, UID: 4})
+	models.AssertExistsAndLoadBean(t, &models.TeamUser{TeamID: team.ID, UID: 5})
+
+	list := &scim.ListResponse{}
+	assert.Equal(t, http.StatusOK, request(t, token, "GET", `/Groups?filter=displayName+eq+"developers"`, "", list))
+	assert.Equal(t, 1, list.TotalResults)
+
+	// the last owner is not removed from the owners team
+	assert.Equal(t, http.StatusBadRequest, request(t, token, "PUT", "/Groups/1", `{"displayName": "Owners"}`, &scim.Error{}))
+	models.AssertExistsAndLoadBean(t, &models.TeamUser{TeamID: 1, UID: 2})
+
+	assert.Equal(t, http.StatusNoContent, request(t, token, "DELETE", "/Groups/"+group.ID, "", nil))
+	models.AssertNotExistsBean(t, &models.Team{ID: team.ID})
+}
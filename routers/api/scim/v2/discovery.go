@@ -0,0 +1,106 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v2
+
+import (
+	"net/http"
+
+	"go.wandrs.dev/framework/modules/context"
+	"go.wandrs.dev/framework/modules/scim"
+	"go.wandrs.dev/framework/modules/setting"
+)
+
+// GetServiceProviderConfig responds with the features of the API
+func GetServiceProviderConfig(ctx *context.PrivateContext) {
+	writeJSON(ctx, http.StatusOK, &scim.ServiceProviderConfig{
+		Schemas:        []string{scim.SchemaServiceProviderConfig},
+		Patch:          scim.Supported{Supported: true},
+		Filter:         scim.FilterSupported{Supported: true, MaxResults: setting.API.MaxResponseItems},
+		ChangePassword: scim.Supported{Supported: true},
+		AuthenticationSchemes: []scim.AuthenticationScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "OAuth Bearer Token",
+			Description: "Authentication with an access token of an administrator with the admin:scim scope",
+			Primary:     true,
+		}},
+		Meta: &scim.Meta{
+			ResourceType: "ServiceProviderConfig",
+			Location:     location("/ServiceProviderConfig"),
+		},
+	})
+}
+
+func resourceTypes() []*scim.ResourceType {
+	return []*scim.ResourceType{
+		{
+			Schemas:     []string{scim.SchemaResourceType},
+			ID:          "User",
+			Name:        "User",
+			Endpoint:    "/Users",
+			Description: "User Account",
+			Schema:      scim.SchemaUser,
+			Meta:        &scim.Meta{ResourceType: "ResourceType", Location: location("/ResourceTypes/User")},
+		},
+		{
+			Schemas:     []string{scim.SchemaResourceType},
+			ID:          "Group",
+			Name:        "Group",
+			Endpoint:    "/Groups",
+			Description: "Organization Team",
+			Schema:      scim.SchemaGroup,
+			Meta:        &scim.Meta{ResourceType: "ResourceType", Location: location("/ResourceTypes/Group")},
+		},
+	}
+}
+
+// ListResourceTypes responds with the types of resources
+func ListResourceTypes(ctx *context.PrivateContext) {
+	types := resourceTypes()
+	resources := make([]interface{}, 0, len(types))
+	for _, t := range types {
+		resources = append(resources, t)
+	}
+	writeJSON(ctx, http.StatusOK, scim.NewListResponse(resources, 1, len(resources)))
+}
+
+// GetResourceType responds with the type of resources
+func GetResourceType(ctx *context.PrivateContext) {
+	for _, t := range resourceTypes() {
+		if t.ID == ctx.Params("id") {
+			writeJSON(ctx, http.StatusOK, t)
+			return
+		}
+	}
+	writeError(ctx, notFound("ResourceType", ctx.Params("id")))
+}
+
+func schemas() []*scim.Schema {
+	user := scim.UserSchema()
+	user.Meta = &scim.Meta{ResourceType: "Schema", Location: location("/Schemas/" + scim.SchemaUser)}
+	group := scim.GroupSchema()
+	group.Meta = &scim.Meta{ResourceType: "Schema", Location: location("/Schemas/" + scim.SchemaGroup)}
+	return []*scim.Schema{user, group}
+}
+
+// ListSchemas responds with the schemas of the resources
+func ListSchemas(ctx *context.PrivateContext) {
+	all := schemas()
+	resources := make([]interface{}, 0, len(all))
+	for _, schema := range all {
+		resources = append(resources, schema)
+	}
+	writeJSON(ctx, http.StatusOK, scim.NewListResponse(resources, 1, len(resources)))
+}
+
+// GetSchema responds with the schema of the resource
+func GetSchema(ctx *context.PrivateContext) {
+	for _, schema := range schemas() {
+		if schema.ID == ctx.Params("id") {
+			writeJSON(ctx, http.StatusOK, schema)
+			return
+		}
+	}
+	writeError(ctx, notFound("Schema", ctx.Params("id")))
+}
@@ -0,0 +1,397 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v2
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/context"
+	"go.wandrs.dev/framework/modules/log"
+	"go.wandrs.dev/framework/modules/scim"
+	"go.wandrs.dev/framework/modules/setting"
+)
+
+// groupName returns the display name of the team, which is qualified by the
+// organization unless it is the one groups are provisioned in by default
+func groupName(org *models.User, t *models.Team) string {
+	if strings.EqualFold(org.Name, setting.SCIM.Organization) {
+		return t.Name
+	}
+	return org.Name + "/" + t.Name
+}
+
+// parseGroupName returns the organization and the name of the team a display name refers to
+func parseGroupName(displayName string) (*models.User, string, error) {
+	orgName, teamName := setting.SCIM.Organization, displayName
+	if idx := strings.Index(displayName, "/"); idx >= 0 {
+		orgName, teamName = displayName[:idx], displayName[idx+1:]
+	}
+	if teamName == "" {
+		return nil, "", scim.NewError(http.StatusBadRequest, scim.ErrorTypeInvalidValue, "displayName is required")
+	}
+	if orgName == "" {
+		return nil, "", scim.NewError(http.StatusBadRequest, scim.ErrorTypeInvalidValue, "displayName must be qualified by an organization as no default organization is configured")
+	}
+
+	org, err := models.GetOrgByName(orgName)
+	if err != nil {
+		if models.IsErrOrgNotExist(err) {
+			return nil, "", scim.NewError(http.StatusBadRequest, scim.ErrorTypeInvalidValue, "organization %s does not exist", orgName)
+		}
+		return nil, "", err
+	}
+	return org, teamName, nil
+}
+
+// toSCIMGroup returns the SCIM representation of the team, its members are left out if withMembers is false
+func toSCIMGroup(org *models.User, t *models.Team, res *models.SCIMResource, withMembers bool) (*scim.Group, error) {
+	id := strconv.FormatInt(t.ID, 10)
+	group := &scim.Group{
+		Schemas:     []string{scim.SchemaGroup},
+		ID:          id,
+		ExternalID:  res.ExternalID,
+		DisplayName: groupName(org, t),
+		Meta: &scim.Meta{
+			ResourceType: "Group",
+			Location:     location("/Groups/" + id),
+		},
+	}
+	if res.ID > 0 {
+		group.Meta.Created = res.CreatedUnix.AsTimePtr()
+		group.Meta.LastModified = res.UpdatedUnix.AsTimePtr()
+	}
+
+	if withMembers {
+		if err := t.GetMembers(&models.SearchMembersOptions{}); err != nil {
+			return nil, err
+		}
+		group.Members = make([]scim.MultiValue, 0, len(t.Members))
+		for _, member := range t.Members {
+			memberID := strconv.FormatInt(member.ID, 10)
+			group.Members = append(group.Members, scim.MultiValue{
+				Value:   memberID,
+				Display: member.Name,
+				Ref:     location("/Users/" + memberID),
+			})
+		}
+	}
+	return group, nil
+}
+
+// getGroup returns the team of the path and its organization
+func getGroup(ctx *context.PrivateContext) (*models.User, *models.Team, *models.SCIMResource) {
+	t, err := models.GetTeamByID(parseID(ctx))
+	if err != nil {
+		if models.IsErrTeamNotExist(err) {
+			err = notFound("Group", ctx.Params("id"))
+		}
+		writeError(ctx, err)
+		return nil, nil, nil
+	}
+	org, err := models.GetUserByID(t.OrgID)
+	if err != nil {
+		writeError(ctx, err)
+		return nil, nil, nil
+	}
+	res, err := models.GetSCIMResource(models.SCIMResourceGroup, t.ID)
+	if err != nil {
+		writeError(ctx, err)
+		return nil, nil, nil
+	}
+	return org, t, res
+}
+
+// excludesMembers returns true if the client asked to leave out the members, which is expensive for large teams
+func excludesMembers(ctx *context.PrivateContext) bool {
+	for _, attr := range strings.Split(ctx.Query("excludedAttributes"), ",") {
+		if strings.EqualFold(strings.TrimSpace(attr), "members") {
+			return true
+		}
+	}
+	return false
+}
+
+// ListGroups responds with the teams of all organizations matching the filter
+func ListGroups(ctx *context.PrivateContext) {
+	teams, err := models.GetAllTeams()
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	resources, err := models.GetSCIMResources(models.SCIMResourceGroup)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	withMembers := !excludesMembers(ctx)
+	orgs := make(map[int64]*models.User)
+	results := make([]interface{}, 0, len(teams))
+	for _, t := range teams {
+		org, ok := orgs[t.OrgID]
+		if !ok {
+			if org, err = models.GetUserByID(t.OrgID); err != nil {
+				writeError(ctx, err)
+				return
+			}
+			orgs[t.OrgID] = org
+		}
+		res, ok := resources[t.ID]
+		if !ok {
+			res = &models.SCIMResource{}
+		}
+		group, err := toSCIMGroup(org, t, res, withMembers)
+		if err != nil {
+			writeError(ctx, err)
+			return
+		}
+		results = append(results, group)
+	}
+	listResources(ctx, results)
+}
+
+// GetGroup responds with the team
+func GetGroup(ctx *context.PrivateContext) {
+	org, t, res := getGroup(ctx)
+	if ctx.Written() {
+		return
+	}
+	group, err := toSCIMGroup(org, t, res, !excludesMembers(ctx))
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	writeJSON(ctx, http.StatusOK, group)
+}
+
+// groupMembers returns the users referenced by the members of the group by their IDs
+func groupMembers(group *scim.Group) (map[int64]*models.User, error) {
+	users := make(map[int64]*models.User, len(group.Members))
+	for _, member := range group.Members {
+		id, err := strconv.ParseInt(member.Value, 10, 64)
+		if err != nil {
+			return nil, scim.NewError(http.StatusBadRequest, scim.ErrorTypeInvalidValue, "member %q is not a user", member.Value)
+		}
+		u, err := models.GetUserByID(id)
+		if err != nil {
+			if models.IsErrUserNotExist(err) {
+				return nil, scim.NewError(http.StatusBadRequest, scim.ErrorTypeInvalidValue, "member %q is not a user", member.Value)
+			}
+			return nil, err
+		}
+		if u.IsOrganization() {
+			return nil, scim.NewError(http.StatusBadRequest, scim.ErrorTypeInvalidValue, "member %q is not a user", member.Value)
+		}
+		users[id] = u
+	}
+	return users, nil
+}
+
+// syncMembers adds and removes the members of the team so that they are the members of the group
+func syncMembers(ctx *context.PrivateContext, t *models.Team, group *scim.Group) error {
+	users, err := groupMembers(group)
+	if err != nil {
+		return err
+	}
+	if err := t.GetMembers(&models.SearchMembersOptions{}); err != nil {
+		return err
+	}
+
+	for _, member := range t.Members {
+		if users[member.ID] != nil {
+			delete(users, member.ID)
+			continue
+		}
+		if err := models.RemoveTeamMember(t, member.ID); err != nil {
+			if models.IsErrLastOrgOwner(err) {
+				return scim.NewError(http.StatusBadRequest, scim.ErrorTypeMutability, "%s is the last owner of the organization", member.Name)
+			}
+			return err
+		}
+		ctx.Audit(models.TeamMemberAuditEvent(models.AuditTeamMemberRemove, t, member))
+	}
+	for id, u := range users {
+		if err := models.AddTeamMember(t, id); err != nil {
+			return err
+		}
+		ctx.Audit(models.TeamMemberAuditEvent(models.AuditTeamMemberAdd, t, u))
+	}
+	return nil
+}
+
+// CreateGroup provisions a team with the members of the group
+func CreateGroup(ctx *context.PrivateContext) {
+	group := &scim.Group{}
+	if !readBody(ctx, group) {
+		return
+	}
+	org, teamName, err := parseGroupName(group.DisplayName)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	// validate the members before the team is created
+	if _, err := groupMembers(group); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	t := &models.Team{
+		OrgID:     org.ID,
+		Name:      teamName,
+		Authorize: models.AccessModeRead,
+	}
+	if err := models.NewTeam(t); err != nil {
+		writeError(ctx, teamError(err))
+		return
+	}
+	log.Trace("SCIM provisioned team %s of %s by %s", t.Name, org.Name, ctx.User.Name)
+
+	if err := syncMembers(ctx, t, group); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	res := &models.SCIMResource{ResourceType: models.SCIMResourceGroup, ResourceID: t.ID, ExternalID: group.ExternalID}
+	if err := models.SaveSCIMResource(res); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	result, err := toSCIMGroup(org, t, res, true)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.Resp.Header().Set("Location", result.Meta.Location)
+	writeJSON(ctx, http.StatusCreated, result)
+}
+
+// teamError maps the errors of validating a team to SCIM errors
+func teamError(err error) error {
+	switch {
+	case models.IsErrTeamAlreadyExist(err):
+		return scim.NewError(http.StatusConflict, scim.ErrorTypeUniqueness, "%v", err)
+	case models.IsErrNameReserved(err), models.IsErrNamePatternNotAllowed(err), models.IsErrNameCharsNotAllowed(err):
+		return scim.NewError(http.StatusBadRequest, scim.ErrorTypeInvalidValue, "%v", err)
+	}
+	return err
+}
+
+// updateGroup replaces the name and the members of the team with the ones of the group and responds with the result
+func updateGroup(ctx *context.PrivateContext, org *models.User, t *models.Team, res *models.SCIMResource, group *scim.Group) {
+	newOrg, teamName, err := parseGroupName(group.DisplayName)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if newOrg.ID != org.ID {
+		writeError(ctx, scim.NewError(http.StatusBadRequest, scim.ErrorTypeMutability, "a team cannot be moved to another organization"))
+		return
+	}
+
+	if teamName != t.Name {
+		if t.IsOwnerTeam() {
+			writeError(ctx, scim.NewError(http.StatusBadRequest, scim.ErrorTypeMutability, "the owners team cannot be renamed"))
+			return
+		}
+		if err := models.IsUsableTeamName(teamName); err != nil {
+			writeError(ctx, teamError(err))
+			return
+		}
+		t.Name = teamName
+		if err := models.UpdateTeam(t, false, false); err != nil {
+			writeError(ctx, teamError(err))
+			return
+		}
+	}
+	if err := syncMembers(ctx, t, group); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	res.ResourceType = models.SCIMResourceGroup
+	res.ResourceID = t.ID
+	res.ExternalID = group.ExternalID
+	if err := models.SaveSCIMResource(res); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	log.Trace("SCIM updated team %s of %s by %s", t.Name, org.Name, ctx.User.Name)
+
+	result, err := toSCIMGroup(org, t, res, true)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	writeJSON(ctx, http.StatusOK, result)
+}
+
+// ReplaceGroup replaces the name and the members of the team
+func ReplaceGroup(ctx *context.PrivateContext) {
+	org, t, res := getGroup(ctx)
+	if ctx.Written() {
+		return
+	}
+	group := &scim.Group{}
+	if !readBody(ctx, group) {
+		return
+	}
+	updateGroup(ctx, org, t, res, group)
+}
+
+// PatchGroup modifies the name and the members of the team
+func PatchGroup(ctx *context.PrivateContext) {
+	org, t, res := getGroup(ctx)
+	if ctx.Written() {
+		return
+	}
+	patch := &scim.PatchRequest{}
+	if !readBody(ctx, patch) {
+		return
+	}
+
+	current, err := toSCIMGroup(org, t, res, true)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	resource, err := scim.ToResource(current)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if err := patch.Apply(resource); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	group := &scim.Group{}
+	if err := resource.Decode(group); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	updateGroup(ctx, org, t, res, group)
+}
+
+// DeleteGroup deletes the team
+func DeleteGroup(ctx *context.PrivateContext) {
+	org, t, _ := getGroup(ctx)
+	if ctx.Written() {
+		return
+	}
+	if t.IsOwnerTeam() {
+		writeError(ctx, scim.NewError(http.StatusBadRequest, scim.ErrorTypeMutability, "the owners team cannot be deleted"))
+		return
+	}
+	if err := models.DeleteTeam(t); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	log.Trace("SCIM deleted team %s of %s by %s", t.Name, org.Name, ctx.User.Name)
+
+	ctx.Resp.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,270 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v2
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/context"
+	"go.wandrs.dev/framework/modules/log"
+	"go.wandrs.dev/framework/modules/scim"
+)
+
+// toSCIMUser returns the SCIM representation of the user
+func toSCIMUser(u *models.User, res *models.SCIMResource) *scim.User {
+	active := scim.Boolean(!u.ProhibitLogin)
+	id := strconv.FormatInt(u.ID, 10)
+	user := &scim.User{
+		Schemas:     []string{scim.SchemaUser},
+		ID:          id,
+		ExternalID:  res.ExternalID,
+		UserName:    u.Name,
+		DisplayName: u.FullName,
+		Active:      &active,
+		Meta: &scim.Meta{
+			ResourceType: "User",
+			Created:      u.CreatedUnix.AsTimePtr(),
+			LastModified: u.UpdatedUnix.AsTimePtr(),
+			Location:     location("/Users/" + id),
+		},
+	}
+	if u.FullName != "" {
+		user.Name = &scim.Name{Formatted: u.FullName}
+	}
+	if u.Email != "" {
+		user.Emails = []scim.MultiValue{{Value: u.Email, Primary: true}}
+	}
+	return user
+}
+
+// userError maps the errors of validating a user to SCIM errors
+func userError(err error) error {
+	switch {
+	case models.IsErrUserAlreadyExist(err), models.IsErrEmailAlreadyUsed(err):
+		return scim.NewError(http.StatusConflict, scim.ErrorTypeUniqueness, "%v", err)
+	case models.IsErrNameReserved(err), models.IsErrNamePatternNotAllowed(err), models.IsErrNameCharsNotAllowed(err),
+		models.IsErrEmailInvalid(err):
+		return scim.NewError(http.StatusBadRequest, scim.ErrorTypeInvalidValue, "%v", err)
+	}
+	return err
+}
+
+// getUser returns the individual user of the path
+func getUser(ctx *context.PrivateContext) (*models.User, *models.SCIMResource) {
+	u, err := models.GetUserByID(parseID(ctx))
+	if err != nil {
+		if models.IsErrUserNotExist(err) {
+			err = notFound("User", ctx.Params("id"))
+		}
+		writeError(ctx, err)
+		return nil, nil
+	}
+	if u.IsOrganization() {
+		writeError(ctx, notFound("User", ctx.Params("id")))
+		return nil, nil
+	}
+	res, err := models.GetSCIMResource(models.SCIMResourceUser, u.ID)
+	if err != nil {
+		writeError(ctx, err)
+		return nil, nil
+	}
+	return u, res
+}
+
+// ListUsers responds with the users matching the filter
+func ListUsers(ctx *context.PrivateContext) {
+	users, err := models.GetAllUsers()
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	resources, err := models.GetSCIMResources(models.SCIMResourceUser)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	results := make([]interface{}, 0, len(users))
+	for _, u := range users {
+		res, ok := resources[u.ID]
+		if !ok {
+			res = &models.SCIMResource{}
+		}
+		results = append(results, toSCIMUser(u, res))
+	}
+	listResources(ctx, results)
+}
+
+// GetUser responds with the user
+func GetUser(ctx *context.PrivateContext) {
+	u, res := getUser(ctx)
+	if ctx.Written() {
+		return
+	}
+	writeJSON(ctx, http.StatusOK, toSCIMUser(u, res))
+}
+
+// CreateUser provisions a user which signs in with the password of the request, if any
+func CreateUser(ctx *context.PrivateContext) {
+	user := &scim.User{}
+	if !readBody(ctx, user) {
+		return
+	}
+	if user.UserName == "" {
+		writeError(ctx, scim.NewError(http.StatusBadRequest, scim.ErrorTypeInvalidValue, "userName is required"))
+		return
+	}
+	if user.PrimaryEmail() == "" {
+		writeError(ctx, scim.NewError(http.StatusBadRequest, scim.ErrorTypeInvalidValue, "an email address is required"))
+		return
+	}
+
+	u := &models.User{
+		Name:      user.UserName,
+		FullName:  user.FullName(),
+		Email:     user.PrimaryEmail(),
+		Passwd:    user.Password,
+		IsActive:  true,
+		LoginType: models.LoginPlain,
+	}
+	if user.Active != nil {
+		u.ProhibitLogin = !bool(*user.Active)
+	}
+	if err := models.CreateUser(u); err != nil {
+		writeError(ctx, userError(err))
+		return
+	}
+	log.Trace("SCIM provisioned user %s by %s", u.Name, ctx.User.Name)
+
+	res := &models.SCIMResource{ResourceType: models.SCIMResourceUser, ResourceID: u.ID, ExternalID: user.ExternalID}
+	if err := models.SaveSCIMResource(res); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	result := toSCIMUser(u, res)
+	ctx.Resp.Header().Set("Location", result.Meta.Location)
+	writeJSON(ctx, http.StatusCreated, result)
+}
+
+// updateUser replaces the attributes of the user with the ones of the SCIM user and responds with the result
+func updateUser(ctx *context.PrivateContext, u *models.User, res *models.SCIMResource, user *scim.User) {
+	if user.UserName == "" {
+		writeError(ctx, scim.NewError(http.StatusBadRequest, scim.ErrorTypeInvalidValue, "userName is required"))
+		return
+	}
+
+	if user.UserName != u.Name {
+		// only the case of the name changes if it still refers to the same user
+		if !strings.EqualFold(user.UserName, u.Name) {
+			if err := models.ChangeUserName(u, user.UserName); err != nil {
+				writeError(ctx, userError(err))
+				return
+			}
+		}
+		u.Name = user.UserName
+		u.LowerName = strings.ToLower(user.UserName)
+	}
+	u.FullName = user.FullName()
+	if email := user.PrimaryEmail(); email != "" {
+		u.Email = email
+	}
+	if user.Active != nil {
+		u.ProhibitLogin = !bool(*user.Active)
+	}
+	if user.Password != "" {
+		if err := u.SetPassword(user.Password); err != nil {
+			writeError(ctx, err)
+			return
+		}
+	}
+	if err := models.UpdateUserSetting(u); err != nil {
+		writeError(ctx, userError(err))
+		return
+	}
+	if u.ProhibitLogin {
+		if err := models.DeleteUserSessions(u.ID); err != nil {
+			writeError(ctx, err)
+			return
+		}
+	}
+
+	if user.ExternalID != res.ExternalID {
+		res.ResourceType = models.SCIMResourceUser
+		res.ResourceID = u.ID
+		res.ExternalID = user.ExternalID
+		if err := models.SaveSCIMResource(res); err != nil {
+			writeError(ctx, err)
+			return
+		}
+	}
+	log.Trace("SCIM updated user %s by %s", u.Name, ctx.User.Name)
+
+	writeJSON(ctx, http.StatusOK, toSCIMUser(u, res))
+}
+
+// ReplaceUser replaces the attributes of the user
+func ReplaceUser(ctx *context.PrivateContext) {
+	u, res := getUser(ctx)
+	if ctx.Written() {
+		return
+	}
+	user := &scim.User{}
+	if !readBody(ctx, user) {
+		return
+	}
+	updateUser(ctx, u, res, user)
+}
+
+// PatchUser modifies the attributes of the user
+func PatchUser(ctx *context.PrivateContext) {
+	u, res := getUser(ctx)
+	if ctx.Written() {
+		return
+	}
+	patch := &scim.PatchRequest{}
+	if !readBody(ctx, patch) {
+		return
+	}
+
+	resource, err := scim.ToResource(toSCIMUser(u, res))
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if err := patch.Apply(resource); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	user := &scim.User{}
+	if err := resource.Decode(user); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	updateUser(ctx, u, res, user)
+}
+
+// DeleteUser deprovisions the user, which is prohibited from signing in rather than deleted
+func DeleteUser(ctx *context.PrivateContext) {
+	u, _ := getUser(ctx)
+	if ctx.Written() {
+		return
+	}
+	u.ProhibitLogin = true
+	if err := models.UpdateUserCols(u, "prohibit_login"); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if err := models.DeleteUserSessions(u.ID); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	log.Trace("SCIM deprovisioned user %s by %s", u.Name, ctx.User.Name)
+
+	ctx.Resp.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,118 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/context"
+	"go.wandrs.dev/framework/modules/convert"
+	api "go.wandrs.dev/framework/modules/structs"
+	"go.wandrs.dev/framework/routers/api/v1/utils"
+)
+
+// ListAuditEvents API for listing the events of the audit log
+func ListAuditEvents(ctx *context.APIContext) {
+	// swagger:operation GET /admin/audit admin adminListAuditEvents
+	// ---
+	// summary: List the events of the audit log, newest first
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: action
+	//   in: query
+	//   description: only events of this action, e.g. team.member.add
+	//   type: string
+	// - name: actor
+	//   in: query
+	//   description: only events made by the user with this name
+	//   type: string
+	// - name: org
+	//   in: query
+	//   description: only events of the organization with this name
+	//   type: string
+	// - name: target_type
+	//   in: query
+	//   description: only events of targets of this type, e.g. team
+	//   type: string
+	// - name: target_id
+	//   in: query
+	//   description: only events of the target with this id
+	//   type: integer
+	//   format: int64
+	// - name: since
+	//   in: query
+	//   description: only events made at or after this time
+	//   type: string
+	//   format: date-time
+	// - name: before
+	//   in: query
+	//   description: only events made before this time
+	//   type: string
+	//   format: date-time
+	// - name: page
+	//   in: query
+	//   description: page number of results to return (1-based)
+	//   type: integer
+	// - name: limit
+	//   in: query
+	//   description: page size of results
+	//   type: integer
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/AuditEventList"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	before, since, err := utils.GetQueryBeforeSince(ctx)
+	if err != nil {
+		ctx.Error(http.StatusUnprocessableEntity, "GetQueryBeforeSince", err)
+		return
+	}
+
+	opts := &models.SearchAuditEventsOptions{
+		ListOptions: utils.GetListOptions(ctx),
+		Action:      models.AuditAction(ctx.Query("action")),
+		ActorName:   ctx.Query("actor"),
+		TargetType:  ctx.Query("target_type"),
+		TargetID:    ctx.QueryInt64("target_id"),
+		Since:       since,
+		Before:      before,
+	}
+	if orgName := ctx.Query("org"); orgName != "" {
+		org, err := models.GetOrgByName(orgName)
+		if err != nil {
+			if models.IsErrOrgNotExist(err) {
+				ctx.NotFound()
+			} else {
+				ctx.Error(http.StatusInternalServerError, "GetOrgByName", err)
+			}
+			return
+		}
+		opts.OrgID = org.ID
+	}
+
+	events, count, err := models.SearchAuditEvents(opts)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "SearchAuditEvents", err)
+		return
+	}
+
+	apiEvents := make([]*api.AuditEvent, len(events))
+	for i := range events {
+		apiEvents[i] = convert.ToAuditEvent(events[i])
+	}
+
+	ctx.SetLinkHeader(int(count), opts.PageSize)
+	ctx.Header().Set("X-Total-Count", fmt.Sprintf("%d", count))
+	ctx.Header().Set("Access-Control-Expose-Headers", "X-Total-Count, Link")
+	ctx.JSON(http.StatusOK, &apiEvents)
+}
@@ -0,0 +1,260 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/context"
+	"go.wandrs.dev/framework/modules/convert"
+	"go.wandrs.dev/framework/modules/log"
+	api "go.wandrs.dev/framework/modules/structs"
+	"go.wandrs.dev/framework/modules/util"
+	"go.wandrs.dev/framework/modules/web"
+	"go.wandrs.dev/framework/routers/api/v1/utils"
+)
+
+// ListOAuth2Applications API for listing the oauth2 applications of all users
+func ListOAuth2Applications(ctx *context.APIContext) {
+	// swagger:operation GET /admin/oauth2 admin adminListOAuth2Applications
+	// ---
+	// summary: List the OAuth2 applications of all users
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: page
+	//   in: query
+	//   description: page number of results to return (1-based)
+	//   type: integer
+	// - name: limit
+	//   in: query
+	//   description: page size of results
+	//   type: integer
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/OAuth2ApplicationList"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+
+	listOptions := utils.GetListOptions(ctx)
+
+	apps, count, err := models.ListAllOAuth2Applications(listOptions)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "ListAllOAuth2Applications", err)
+		return
+	}
+
+	apiApps := make([]*api.OAuth2Application, len(apps))
+	for i := range apps {
+		apiApps[i] = convert.ToAdminOAuth2Application(apps[i])
+		apiApps[i].ClientSecret = "" // Hide secret on application list
+	}
+
+	ctx.SetLinkHeader(int(count), listOptions.PageSize)
+	ctx.Header().Set("X-Total-Count", fmt.Sprintf("%d", count))
+	ctx.Header().Set("Access-Control-Expose-Headers", "X-Total-Count, Link")
+	ctx.JSON(http.StatusOK, &apiApps)
+}
+
+// CreateOAuth2Application API for creating an oauth2 application of any user
+func CreateOAuth2Application(ctx *context.APIContext) {
+	// swagger:operation POST /admin/oauth2 admin adminCreateOAuth2Application
+	// ---
+	// summary: Create an OAuth2 application for a user or organization
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: body
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/AdminCreateOAuth2ApplicationOptions"
+	// responses:
+	//   "201":
+	//     "$ref": "#/responses/OAuth2Application"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	form := web.GetForm(ctx).(*api.AdminCreateOAuth2ApplicationOptions)
+
+	owner, err := models.GetUserByName(form.Owner)
+	if err != nil {
+		if models.IsErrUserNotExist(err) {
+			ctx.Error(http.StatusUnprocessableEntity, "", err)
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetUserByName", err)
+		}
+		return
+	}
+
+	app, err := models.CreateOAuth2Application(models.CreateOAuth2ApplicationOptions{
+		Name:                  form.Name,
+		UserID:                owner.ID,
+		RedirectURIs:          form.RedirectURIs,
+		FrontchannelLogoutURI: form.FrontchannelLogoutURI,
+		Trusted:               form.Trusted,
+	})
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "CreateOAuth2Application", err)
+		return
+	}
+	app.User = owner
+	if app.ClientSecret, err = app.GenerateClientSecret(); err != nil {
+		ctx.Error(http.StatusInternalServerError, "GenerateClientSecret", err)
+		return
+	}
+	log.Trace("OAuth2 application created by admin(%s): %s", ctx.User.Name, app.ClientID)
+
+	ctx.JSON(http.StatusCreated, convert.ToAdminOAuth2Application(app))
+}
+
+func getOAuth2ApplicationByParams(ctx *context.APIContext) *models.OAuth2Application {
+	app, err := models.GetOAuth2ApplicationByID(ctx.ParamsInt64(":id"))
+	if err != nil {
+		if models.IsErrOAuthApplicationNotFound(err) {
+			ctx.NotFound()
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetOAuth2ApplicationByID", err)
+		}
+		return nil
+	}
+	if err := app.LoadUser(); err != nil {
+		ctx.Error(http.StatusInternalServerError, "LoadUser", err)
+		return nil
+	}
+	return app
+}
+
+// GetOAuth2Application API for getting an oauth2 application of any user
+func GetOAuth2Application(ctx *context.APIContext) {
+	// swagger:operation GET /admin/oauth2/{id} admin adminGetOAuth2Application
+	// ---
+	// summary: Get an OAuth2 application
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of the application
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/OAuth2Application"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	app := getOAuth2ApplicationByParams(ctx)
+	if ctx.Written() {
+		return
+	}
+	app.ClientSecret = ""
+
+	ctx.JSON(http.StatusOK, convert.ToAdminOAuth2Application(app))
+}
+
+// EditOAuth2Application API for editing an oauth2 application of any user
+func EditOAuth2Application(ctx *context.APIContext) {
+	// swagger:operation PATCH /admin/oauth2/{id} admin adminEditOAuth2Application
+	// ---
+	// summary: Edit an OAuth2 application, the client secret is kept
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of the application to edit
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// - name: body
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/AdminEditOAuth2ApplicationOptions"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/OAuth2Application"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	form := web.GetForm(ctx).(*api.AdminEditOAuth2ApplicationOptions)
+	app := getOAuth2ApplicationByParams(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	opts := models.UpdateOAuth2ApplicationOptions{
+		ID:                    app.ID,
+		Name:                  form.Name,
+		UserID:                app.UID,
+		RedirectURIs:          form.RedirectURIs,
+		FrontchannelLogoutURI: form.FrontchannelLogoutURI,
+		Trusted:               util.OptionalBoolOf(app.Trusted),
+	}
+	if form.Trusted != nil {
+		opts.Trusted = util.OptionalBoolOf(*form.Trusted)
+	}
+	updated, err := models.UpdateOAuth2Application(opts)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "UpdateOAuth2Application", err)
+		return
+	}
+	updated.User = app.User
+	log.Trace("OAuth2 application updated by admin(%s): %s", ctx.User.Name, app.ClientID)
+
+	ctx.JSON(http.StatusOK, convert.ToAdminOAuth2Application(updated))
+}
+
+// DeleteOAuth2Application API for deleting an oauth2 application of any user
+func DeleteOAuth2Application(ctx *context.APIContext) {
+	// swagger:operation DELETE /admin/oauth2/{id} admin adminDeleteOAuth2Application
+	// ---
+	// summary: Delete an OAuth2 application
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of the application to delete
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	app := getOAuth2ApplicationByParams(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if err := models.DeleteOAuth2Application(app.ID, app.UID); err != nil {
+		ctx.Error(http.StatusInternalServerError, "DeleteOAuth2Application", err)
+		return
+	}
+	log.Trace("OAuth2 application deleted by admin(%s): %s", ctx.User.Name, app.ClientID)
+
+	ctx.Status(http.StatusNoContent)
+}
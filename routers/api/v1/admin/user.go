@@ -150,6 +150,7 @@ func EditUser(ctx *context.APIContext) {
 	if ctx.Written() {
 		return
 	}
+	before := u.AuditValues()
 
 	parseLoginSource(ctx, u, form.SourceID, form.LoginName)
 	if ctx.Written() {
@@ -237,6 +238,26 @@ func EditUser(ctx *context.APIContext) {
 		return
 	}
 	log.Trace("Account profile updated by admin (%s): %s", ctx.User.Name, u.Name)
+	if u.ProhibitLogin {
+		if err := models.DeleteUserSessions(u.ID); err != nil {
+			ctx.Error(http.StatusInternalServerError, "DeleteUserSessions", err)
+			return
+		}
+	}
+	after := u.AuditValues()
+	if len(form.Password) != 0 {
+		before["password"], after["password"] = "", "changed"
+	}
+	changedBefore, changedAfter := models.AuditChanges(before, after)
+	if len(changedAfter) > 0 {
+		ctx.Audit(&models.AuditEvent{
+			Action:     models.AuditUserEdit,
+			TargetID:   u.ID,
+			TargetName: u.Name,
+			Before:     changedBefore,
+			After:      changedAfter,
+		})
+	}
 
 	ctx.JSON(http.StatusOK, convert.ToUser(u, ctx.User))
 }
@@ -65,6 +65,7 @@
 package v1
 
 import (
+	"fmt"
 	"net/http"
 	"reflect"
 
@@ -95,6 +96,11 @@ func sudo() func(ctx *context.APIContext) {
 
 		if len(sudo) > 0 {
 			if ctx.IsSigned && ctx.User.IsAdmin {
+				// tokens must be allowed to administrate users to act as one
+				if scope := models.AccessTokenScopeCategoryAdminUsers.Write; !tokenHasScope(ctx, scope) {
+					ctx.Error(http.StatusForbidden, "sudo", fmt.Sprintf("token does not have required scope: %s", scope))
+					return
+				}
 				user, err := models.GetUserByName(sudo)
 				if err != nil {
 					if models.IsErrUserNotExist(err) {
@@ -116,10 +122,25 @@ func sudo() func(ctx *context.APIContext) {
 	}
 }
 
+// tokenHasScope returns true if the request is not authenticated by a token
+// or if its token carries the scope.
+func tokenHasScope(ctx *context.APIContext, scope models.AccessTokenScope) bool {
+	if isAPIToken, _ := ctx.Data["IsApiToken"].(bool); !isAPIToken {
+		return true
+	}
+	scopeList, _ := ctx.Data["ApiTokenScope"].(string)
+	return models.AccessTokenScopeContains(scopeList, scope)
+}
+
 // Contexter middleware already checks token for user sign in process.
-func reqToken() func(ctx *context.APIContext) {
+// Token requests must additionally carry the scope the category requires for
+// the request method.
+func reqToken(category models.AccessTokenScopeCategory) func(ctx *context.APIContext) {
 	return func(ctx *context.APIContext) {
 		if true == ctx.Data["IsApiToken"] {
+			if scope := category.ScopeForMethod(ctx.Req.Method); !tokenHasScope(ctx, scope) {
+				ctx.Error(http.StatusForbidden, "reqToken", fmt.Sprintf("token does not have required scope: %s", scope))
+			}
 			return
 		}
 		if ctx.Context.IsBasicAuth {
@@ -152,6 +173,16 @@ func reqBasicAuth() func(ctx *context.APIContext) {
 	}
 }
 
+// notImpersonated forbids the route while an administrator impersonates the signed in user
+func notImpersonated() func(ctx *context.APIContext) {
+	return func(ctx *context.APIContext) {
+		if ctx.Impersonator != nil {
+			ctx.Error(http.StatusForbidden, "notImpersonated", "not allowed while impersonating a user")
+			return
+		}
+	}
+}
+
 // reqSiteAdmin user should be the site admin
 func reqSiteAdmin() func(ctx *context.APIContext) {
 	return func(ctx *context.APIContext) {
@@ -194,6 +225,28 @@ func reqOrgOwnership() func(ctx *context.APIContext) {
 	}
 }
 
+// reqOrgOAuth2ApplicationsManager user should be allowed to manage the oauth2 applications of the organization, or a site admin
+func reqOrgOAuth2ApplicationsManager() func(ctx *context.APIContext) {
+	return func(ctx *context.APIContext) {
+		if !setting.OAuth2.Enable {
+			ctx.NotFound()
+			return
+		}
+		if ctx.Context.IsUserSiteAdmin() {
+			return
+		}
+
+		canManage, err := ctx.Org.Organization.CanManageOAuth2ApplicationsBy(ctx.User.ID)
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, "CanManageOAuth2ApplicationsBy", err)
+			return
+		} else if !canManage {
+			ctx.Error(http.StatusForbidden, "", "Must be allowed to manage the applications of the organization")
+			return
+		}
+	}
+}
+
 // reqTeamMembership user should be an team member, or a site admin
 func reqTeamMembership() func(ctx *context.APIContext) {
 	return func(ctx *context.APIContext) {
@@ -214,8 +267,8 @@ func reqTeamMembership() func(ctx *context.APIContext) {
 			return
 		}
 
-		if isTeamMember, err := models.IsTeamMember(orgID, ctx.Org.Team.ID, ctx.User.ID); err != nil {
-			ctx.Error(http.StatusInternalServerError, "IsTeamMember", err)
+		if isTeamMember, err := models.IsTeamEffectiveMember(orgID, ctx.Org.Team.ID, ctx.User.ID); err != nil {
+			ctx.Error(http.StatusInternalServerError, "IsTeamEffectiveMember", err)
 			return
 		} else if !isTeamMember {
 			isOrgMember, err := models.IsOrganizationMember(orgID, ctx.User.ID)
@@ -307,6 +360,41 @@ func orgAssignment(args ...bool) func(ctx *context.APIContext) {
 				return
 			}
 		}
+
+		checkOrgTwoFactor(ctx)
+	}
+}
+
+// checkOrgTwoFactor forbids the members who have not enrolled two-factor authentication
+// to use the organization if it requires it, except site admins
+func checkOrgTwoFactor(ctx *context.APIContext) {
+	if !ctx.IsSigned || ctx.User.IsAdmin {
+		return
+	}
+	org := ctx.Org.Organization
+	if org == nil && ctx.Org.Team != nil {
+		var err error
+		if org, err = models.GetUserByID(ctx.Org.Team.OrgID); err != nil {
+			ctx.Error(http.StatusInternalServerError, "GetUserByID", err)
+			return
+		}
+	}
+	if org == nil || !org.RequireTwoFactor {
+		return
+	}
+
+	isMember, err := org.IsOrgMember(ctx.User.ID)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "IsOrgMember", err)
+		return
+	} else if !isMember {
+		return
+	}
+	compliant, err := org.IsTwoFactorCompliant(ctx.User.ID)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "IsTwoFactorCompliant", err)
+	} else if !compliant {
+		ctx.Error(http.StatusForbidden, "", "the organization requires two-factor authentication, enroll it in your security settings")
 	}
 }
 
@@ -397,11 +485,11 @@ func Routes() *web.Route {
 					m.Get("/{target}", user.CheckFollowing)
 				})
 			})
-		}, reqToken())
+		}, reqToken(models.AccessTokenScopeCategoryUser))
 
 		m.Group("/user", func() {
 			m.Get("", user.GetAuthenticatedUser)
-			m.Combo("/emails").Get(user.ListEmails).
+			m.Combo("/emails", notImpersonated()).Get(user.ListEmails).
 				Post(bind(api.CreateEmailOption{}), user.AddEmail).
 				Delete(bind(api.DeleteEmailOption{}), user.DeleteEmail)
 
@@ -419,36 +507,54 @@ func Routes() *web.Route {
 					Delete(user.DeleteOauth2Application).
 					Patch(bind(api.CreateOAuth2ApplicationOptions{}), user.UpdateOauth2Application).
 					Get(user.GetOauth2Application)
-			}, reqToken())
+			}, notImpersonated())
 
-			m.Get("/teams", org.ListUserTeams)
-		}, reqToken())
+			m.Get("/teams", reqToken(models.AccessTokenScopeCategoryOrg), org.ListUserTeams)
+		}, reqToken(models.AccessTokenScopeCategoryUser))
 
 		// Organizations
-		m.Get("/user/orgs", reqToken(), org.ListMyOrgs)
+		m.Get("/user/orgs", reqToken(models.AccessTokenScopeCategoryOrg), org.ListMyOrgs)
 		m.Get("/users/{username}/orgs", org.ListUserOrgs)
-		m.Post("/orgs", reqToken(), bind(api.CreateOrgOption{}), org.Create)
+		m.Post("/orgs", reqToken(models.AccessTokenScopeCategoryOrg), bind(api.CreateOrgOption{}), org.Create)
 		m.Get("/orgs", org.GetAll)
 		m.Group("/orgs/{org}", func() {
 			m.Combo("").Get(org.Get).
-				Patch(reqToken(), reqOrgOwnership(), bind(api.EditOrgOption{}), org.Edit).
-				Delete(reqToken(), reqOrgOwnership(), org.Delete)
+				Patch(reqToken(models.AccessTokenScopeCategoryOrg), reqOrgOwnership(), bind(api.EditOrgOption{}), org.Edit).
+				Delete(reqToken(models.AccessTokenScopeCategoryOrg), reqOrgOwnership(), org.Delete)
 			m.Group("/members", func() {
 				m.Get("", org.ListMembers)
 				m.Combo("/{username}").Get(org.IsMember).
-					Delete(reqToken(), reqOrgOwnership(), org.DeleteMember)
+					Delete(reqToken(models.AccessTokenScopeCategoryOrg), reqOrgOwnership(), org.DeleteMember)
 			})
 			m.Group("/public_members", func() {
 				m.Get("", org.ListPublicMembers)
 				m.Combo("/{username}").Get(org.IsPublicMember).
-					Put(reqToken(), reqOrgMembership(), org.PublicizeMember).
-					Delete(reqToken(), reqOrgMembership(), org.ConcealMember)
+					Put(reqToken(models.AccessTokenScopeCategoryOrg), reqOrgMembership(), org.PublicizeMember).
+					Delete(reqToken(models.AccessTokenScopeCategoryOrg), reqOrgMembership(), org.ConcealMember)
 			})
 			m.Group("/teams", func() {
-				m.Combo("", reqToken()).Get(org.ListTeams).
+				m.Combo("", reqToken(models.AccessTokenScopeCategoryOrg)).Get(org.ListTeams).
 					Post(reqOrgOwnership(), bind(api.CreateTeamOption{}), org.CreateTeam)
 				m.Get("/search", org.SearchTeam)
 			}, reqOrgMembership())
+			m.Group("/join_requests", func() {
+				m.Combo("").Get(reqOrgOwnership(), org.ListJoinRequests).
+					Post(bind(api.CreateOrgJoinRequestOption{}), org.CreateJoinRequest)
+				m.Delete("/{id}", reqOrgOwnership(), org.RejectJoinRequest)
+				m.Post("/{id}/approve", reqOrgOwnership(), bind(api.ApproveOrgJoinRequestOption{}), org.ApproveJoinRequest)
+			}, reqToken(models.AccessTokenScopeCategoryOrg))
+			m.Group("/invites", func() {
+				m.Combo("").Get(org.ListInvites).
+					Post(bind(api.CreateOrgInviteOption{}), org.CreateInvite)
+				m.Delete("/{id}", org.DeleteInvite)
+			}, reqToken(models.AccessTokenScopeCategoryOrg), reqOrgOwnership())
+			m.Group("/applications", func() {
+				m.Combo("").Get(org.ListOAuth2Applications).
+					Post(bind(api.CreateOAuth2ApplicationOptions{}), org.CreateOAuth2Application)
+				m.Combo("/{id}").Get(org.GetOAuth2Application).
+					Patch(bind(api.CreateOAuth2ApplicationOptions{}), org.EditOAuth2Application).
+					Delete(org.DeleteOAuth2Application)
+			}, notImpersonated(), reqToken(models.AccessTokenScopeCategoryOrg), reqOrgOAuth2ApplicationsManager())
 		}, orgAssignment(true))
 		m.Group("/teams/{teamid}", func() {
 			m.Combo("").Get(org.GetTeam).
@@ -461,14 +567,22 @@ func Routes() *web.Route {
 					Put(reqOrgOwnership(), org.AddTeamMember).
 					Delete(reqOrgOwnership(), org.RemoveTeamMember)
 			})
-		}, orgAssignment(false, true), reqToken(), reqTeamMembership())
+		}, orgAssignment(false, true), reqToken(models.AccessTokenScopeCategoryOrg), reqTeamMembership())
 
 		m.Group("/admin", func() {
 			m.Group("/cron", func() {
 				m.Get("", admin.ListCronTasks)
 				m.Post("/{task}", admin.PostCronTask)
-			})
-			m.Get("/orgs", admin.GetAllOrgs)
+			}, reqToken(models.AccessTokenScopeCategoryAdminSystem), reqSiteAdmin())
+			m.Get("/orgs", reqToken(models.AccessTokenScopeCategoryAdminOrgs), reqSiteAdmin(), admin.GetAllOrgs)
+			m.Get("/audit", reqToken(models.AccessTokenScopeCategoryAdminSystem), reqSiteAdmin(), admin.ListAuditEvents)
+			m.Group("/oauth2", func() {
+				m.Get("", admin.ListOAuth2Applications)
+				m.Post("", bind(api.AdminCreateOAuth2ApplicationOptions{}), admin.CreateOAuth2Application)
+				m.Combo("/{id}").Get(admin.GetOAuth2Application).
+					Patch(bind(api.AdminEditOAuth2ApplicationOptions{}), admin.EditOAuth2Application).
+					Delete(admin.DeleteOAuth2Application)
+			}, reqToken(models.AccessTokenScopeCategoryAdminSystem), reqSiteAdmin())
 			m.Group("/users", func() {
 				m.Get("", admin.GetAllUsers)
 				m.Post("", bind(api.CreateUserOption{}), admin.CreateUser)
@@ -476,10 +590,10 @@ func Routes() *web.Route {
 					m.Combo("").Patch(bind(api.EditUserOption{}), admin.EditUser).
 						Delete(admin.DeleteUser)
 					m.Get("/orgs", org.ListUserOrgs)
-					m.Post("/orgs", bind(api.CreateOrgOption{}), admin.CreateOrg)
+					m.Post("/orgs", reqToken(models.AccessTokenScopeCategoryAdminOrgs), bind(api.CreateOrgOption{}), admin.CreateOrg)
 				})
-			})
-		}, reqToken(), reqSiteAdmin())
+			}, reqToken(models.AccessTokenScopeCategoryAdminUsers), reqSiteAdmin())
+		})
 	}, sudo())
 
 	return m
@@ -0,0 +1,181 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package org
+
+import (
+	"errors"
+	"net/http"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/context"
+	"go.wandrs.dev/framework/modules/convert"
+	"go.wandrs.dev/framework/modules/log"
+	"go.wandrs.dev/framework/modules/setting"
+	api "go.wandrs.dev/framework/modules/structs"
+	"go.wandrs.dev/framework/modules/web"
+	"go.wandrs.dev/framework/routers/api/v1/utils"
+	"go.wandrs.dev/framework/services/mailer"
+)
+
+// ListInvites list the pending invitations of an organization
+func ListInvites(ctx *context.APIContext) {
+	// swagger:operation GET /orgs/{org}/invites organization orgListInvites
+	// ---
+	// summary: List the pending invitations of an organization
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// - name: team_id
+	//   in: query
+	//   description: only list the invitations to this team
+	//   type: integer
+	//   format: int64
+	// - name: page
+	//   in: query
+	//   description: page number of results to return (1-based)
+	//   type: integer
+	// - name: limit
+	//   in: query
+	//   description: page size of results
+	//   type: integer
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/OrgInviteList"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+
+	invites, err := models.FindOrgInvites(&models.FindOrgInvitesOptions{
+		ListOptions: utils.GetListOptions(ctx),
+		OrgID:       ctx.Org.Organization.ID,
+		TeamID:      ctx.QueryInt64("team_id"),
+	})
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "FindOrgInvites", err)
+		return
+	}
+
+	apiInvites := make([]*api.OrgInvite, len(invites))
+	for i := range invites {
+		apiInvites[i] = convert.ToOrgInvite(invites[i])
+	}
+	ctx.JSON(http.StatusOK, &apiInvites)
+}
+
+// CreateInvite invites a person by email to join a team of an organization
+func CreateInvite(ctx *context.APIContext) {
+	// swagger:operation POST /orgs/{org}/invites organization orgCreateInvite
+	// ---
+	// summary: Invite a person by email to join a team of an organization
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// - name: body
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/CreateOrgInviteOption"
+	// responses:
+	//   "201":
+	//     "$ref": "#/responses/OrgInvite"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	form := web.GetForm(ctx).(*api.CreateOrgInviteOption)
+
+	if setting.MailService == nil {
+		ctx.Error(http.StatusUnprocessableEntity, "", errors.New("mail service is disabled"))
+		return
+	}
+
+	team, err := models.GetTeamByID(form.TeamID)
+	if err != nil && !models.IsErrTeamNotExist(err) {
+		ctx.Error(http.StatusInternalServerError, "GetTeamByID", err)
+		return
+	} else if err != nil || team.OrgID != ctx.Org.Organization.ID {
+		ctx.Error(http.StatusUnprocessableEntity, "", models.ErrTeamNotExist{OrgID: ctx.Org.Organization.ID, TeamID: form.TeamID})
+		return
+	}
+
+	inv, err := models.CreateOrgInvite(team, ctx.User, form.Email)
+	if err != nil {
+		if models.IsErrEmailInvalid(err) ||
+			models.IsErrOrgInviteAlreadyExist(err) ||
+			models.IsErrOrgInviteAlreadyMember(err) {
+			ctx.Error(http.StatusUnprocessableEntity, "", err)
+		} else {
+			ctx.Error(http.StatusInternalServerError, "CreateOrgInvite", err)
+		}
+		return
+	}
+	inv.Org = ctx.Org.Organization
+	ctx.Audit(models.TeamInviteAuditEvent(models.AuditTeamInviteCreate, inv))
+	mailer.SendOrgInviteMail(inv)
+	log.Trace("Invitation to %s/%s sent to %s", ctx.Org.Organization.Name, team.Name, inv.Email)
+
+	ctx.JSON(http.StatusCreated, convert.ToOrgInvite(inv))
+}
+
+// DeleteInvite revokes a pending invitation of an organization
+func DeleteInvite(ctx *context.APIContext) {
+	// swagger:operation DELETE /orgs/{org}/invites/{id} organization orgDeleteInvite
+	// ---
+	// summary: Revoke a pending invitation of an organization
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of the invitation
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	inv, err := models.GetOrgInviteByID(ctx.Org.Organization.ID, ctx.ParamsInt64(":id"))
+	if err != nil {
+		if models.IsErrOrgInviteNotExist(err) {
+			ctx.NotFound()
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetOrgInviteByID", err)
+		}
+		return
+	}
+	if err := inv.LoadAttributes(); err != nil {
+		ctx.Error(http.StatusInternalServerError, "LoadAttributes", err)
+		return
+	}
+
+	if err := models.DeleteOrgInvite(inv); err != nil {
+		ctx.Error(http.StatusInternalServerError, "DeleteOrgInvite", err)
+		return
+	}
+	ctx.Audit(models.TeamInviteAuditEvent(models.AuditTeamInviteRevoke, inv))
+	ctx.Status(http.StatusNoContent)
+}
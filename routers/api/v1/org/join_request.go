@@ -0,0 +1,239 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package org
+
+import (
+	"net/http"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/context"
+	"go.wandrs.dev/framework/modules/convert"
+	"go.wandrs.dev/framework/modules/log"
+	api "go.wandrs.dev/framework/modules/structs"
+	"go.wandrs.dev/framework/modules/web"
+	"go.wandrs.dev/framework/routers/api/v1/utils"
+	"go.wandrs.dev/framework/services/mailer"
+)
+
+// ListJoinRequests list the pending requests to join an organization
+func ListJoinRequests(ctx *context.APIContext) {
+	// swagger:operation GET /orgs/{org}/join_requests organization orgListJoinRequests
+	// ---
+	// summary: List the pending requests to join an organization
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// - name: page
+	//   in: query
+	//   description: page number of results to return (1-based)
+	//   type: integer
+	// - name: limit
+	//   in: query
+	//   description: page size of results
+	//   type: integer
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/OrgJoinRequestList"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+
+	requests, err := models.FindOrgJoinRequests(ctx.Org.Organization.ID, utils.GetListOptions(ctx))
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "FindOrgJoinRequests", err)
+		return
+	}
+
+	apiRequests := make([]*api.OrgJoinRequest, len(requests))
+	for i := range requests {
+		apiRequests[i] = convert.ToOrgJoinRequest(requests[i], ctx.User)
+	}
+	ctx.JSON(http.StatusOK, &apiRequests)
+}
+
+// CreateJoinRequest requests to join an organization as the authenticated user
+func CreateJoinRequest(ctx *context.APIContext) {
+	// swagger:operation POST /orgs/{org}/join_requests organization orgCreateJoinRequest
+	// ---
+	// summary: Request to join an organization as the authenticated user
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// - name: body
+	//   in: body
+	//   schema:
+	//     "$ref": "#/definitions/CreateOrgJoinRequestOption"
+	// responses:
+	//   "201":
+	//     "$ref": "#/responses/OrgJoinRequest"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	form := web.GetForm(ctx).(*api.CreateOrgJoinRequestOption)
+	org := ctx.Org.Organization
+	if !models.HasOrgVisible(org, ctx.User) {
+		ctx.NotFound()
+		return
+	}
+
+	r, err := models.CreateOrgJoinRequest(org, ctx.User, form.Message)
+	if err != nil {
+		if models.IsErrOrgJoinRequestsDisabled(err) ||
+			models.IsErrOrgJoinRequestAlreadyExist(err) ||
+			models.IsErrOrgJoinRequestAlreadyMember(err) {
+			ctx.Error(http.StatusUnprocessableEntity, "", err)
+		} else {
+			ctx.Error(http.StatusInternalServerError, "CreateOrgJoinRequest", err)
+		}
+		return
+	}
+
+	owners, err := org.GetOwners()
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "GetOwners", err)
+		return
+	}
+	mailer.SendOrgJoinRequestMail(r, owners)
+	log.Trace("Request to join %s created by %s", org.Name, ctx.User.Name)
+
+	ctx.JSON(http.StatusCreated, convert.ToOrgJoinRequest(r, ctx.User))
+}
+
+// getJoinRequestByParams returns the request of the path if it belongs to the organization
+func getJoinRequestByParams(ctx *context.APIContext) *models.OrgJoinRequest {
+	r, err := models.GetOrgJoinRequestByID(ctx.Org.Organization.ID, ctx.ParamsInt64(":id"))
+	if err != nil {
+		if models.IsErrOrgJoinRequestNotExist(err) {
+			ctx.NotFound()
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetOrgJoinRequestByID", err)
+		}
+		return nil
+	}
+	r.Org = ctx.Org.Organization
+	if err := r.LoadAttributes(); err != nil {
+		ctx.Error(http.StatusInternalServerError, "LoadAttributes", err)
+		return nil
+	}
+	return r
+}
+
+// ApproveJoinRequest approves a request to join an organization, adding the user to a team
+func ApproveJoinRequest(ctx *context.APIContext) {
+	// swagger:operation POST /orgs/{org}/join_requests/{id}/approve organization orgApproveJoinRequest
+	// ---
+	// summary: Approve a request to join an organization, adding the user to a team
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of the request
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// - name: body
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/ApproveOrgJoinRequestOption"
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	form := web.GetForm(ctx).(*api.ApproveOrgJoinRequestOption)
+	r := getJoinRequestByParams(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	team, err := models.GetTeamByID(form.TeamID)
+	if err != nil && !models.IsErrTeamNotExist(err) {
+		ctx.Error(http.StatusInternalServerError, "GetTeamByID", err)
+		return
+	} else if err != nil || team.OrgID != ctx.Org.Organization.ID {
+		ctx.Error(http.StatusUnprocessableEntity, "", models.ErrTeamNotExist{OrgID: ctx.Org.Organization.ID, TeamID: form.TeamID})
+		return
+	}
+
+	if err := models.ApproveOrgJoinRequest(r, team); err != nil {
+		ctx.Error(http.StatusInternalServerError, "ApproveOrgJoinRequest", err)
+		return
+	}
+	ctx.Audit(models.TeamMemberAuditEvent(models.AuditTeamMemberAdd, team, r.User))
+	mailer.SendOrgJoinRequestResultMail(r, team)
+	log.Trace("Request to join %s of %s approved to team %s", ctx.Org.Organization.Name, r.User.Name, team.Name)
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// RejectJoinRequest rejects a request to join an organization
+func RejectJoinRequest(ctx *context.APIContext) {
+	// swagger:operation DELETE /orgs/{org}/join_requests/{id} organization orgRejectJoinRequest
+	// ---
+	// summary: Reject a request to join an organization
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of the request
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	r := getJoinRequestByParams(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if err := models.DeleteOrgJoinRequest(r); err != nil {
+		ctx.Error(http.StatusInternalServerError, "DeleteOrgJoinRequest", err)
+		return
+	}
+	ctx.Audit(models.OrgJoinRequestRejectAuditEvent(ctx.Org.Organization, r.User))
+	mailer.SendOrgJoinRequestResultMail(r, nil)
+	log.Trace("Request to join %s of %s rejected", ctx.Org.Organization.Name, r.User.Name)
+
+	ctx.Status(http.StatusNoContent)
+}
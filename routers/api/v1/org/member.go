@@ -38,6 +38,35 @@ func listMembers(ctx *context.APIContext, publicOnly bool) {
 	ctx.JSON(http.StatusOK, apiMembers)
 }
 
+// listTwoFactorNonCompliantMembers list the members of an organization without two-factor authentication
+func listTwoFactorNonCompliantMembers(ctx *context.APIContext) {
+	if ctx.User == nil {
+		ctx.Error(http.StatusForbidden, "", "must be an owner of the organization")
+		return
+	} else if !ctx.User.IsAdmin {
+		isOwner, err := ctx.Org.Organization.IsOwnedBy(ctx.User.ID)
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, "IsOwnedBy", err)
+			return
+		} else if !isOwner {
+			ctx.Error(http.StatusForbidden, "", "must be an owner of the organization")
+			return
+		}
+	}
+
+	members, err := ctx.Org.Organization.GetTwoFactorNonCompliantMembers()
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "GetTwoFactorNonCompliantMembers", err)
+		return
+	}
+
+	apiMembers := make([]*api.User, len(members))
+	for i, member := range members {
+		apiMembers[i] = convert.ToUser(member, ctx.User)
+	}
+	ctx.JSON(http.StatusOK, apiMembers)
+}
+
 // ListMembers list an organization's members
 func ListMembers(ctx *context.APIContext) {
 	// swagger:operation GET /orgs/{org}/members organization orgListMembers
@@ -51,6 +80,11 @@ func ListMembers(ctx *context.APIContext) {
 	//   description: name of the organization
 	//   type: string
 	//   required: true
+	// - name: filter
+	//   in: query
+	//   description: "`2fa_disabled` lists the members without two-factor authentication, only available to owners"
+	//   type: string
+	//   enum: [2fa_disabled]
 	// - name: page
 	//   in: query
 	//   description: page number of results to return (1-based)
@@ -62,6 +96,13 @@ func ListMembers(ctx *context.APIContext) {
 	// responses:
 	//   "200":
 	//     "$ref": "#/responses/UserList"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+
+	if ctx.Query("filter") == "2fa_disabled" {
+		listTwoFactorNonCompliantMembers(ctx)
+		return
+	}
 
 	publicOnly := true
 	if ctx.User != nil {
@@ -297,6 +338,8 @@ func DeleteMember(ctx *context.APIContext) {
 	}
 	if err := ctx.Org.Organization.RemoveMember(member.ID); err != nil {
 		ctx.Error(http.StatusInternalServerError, "RemoveMember", err)
+		return
 	}
+	ctx.Audit(models.OrgMemberRemoveAuditEvent(ctx.Org.Organization, member))
 	ctx.Status(http.StatusNoContent)
 }
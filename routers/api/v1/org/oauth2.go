@@ -0,0 +1,262 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package org
+
+import (
+	"net/http"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/context"
+	"go.wandrs.dev/framework/modules/convert"
+	"go.wandrs.dev/framework/modules/log"
+	api "go.wandrs.dev/framework/modules/structs"
+	"go.wandrs.dev/framework/modules/web"
+	"go.wandrs.dev/framework/routers/api/v1/utils"
+)
+
+// ListOAuth2Applications list the oauth2 applications of an organization
+func ListOAuth2Applications(ctx *context.APIContext) {
+	// swagger:operation GET /orgs/{org}/applications organization orgListOAuth2Applications
+	// ---
+	// summary: List the OAuth2 applications of an organization
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// - name: page
+	//   in: query
+	//   description: page number of results to return (1-based)
+	//   type: integer
+	// - name: limit
+	//   in: query
+	//   description: page size of results
+	//   type: integer
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/OAuth2ApplicationList"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+
+	apps, err := models.ListOAuth2Applications(ctx.Org.Organization.ID, utils.GetListOptions(ctx))
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "ListOAuth2Applications", err)
+		return
+	}
+
+	apiApps := make([]*api.OAuth2Application, len(apps))
+	for i := range apps {
+		apiApps[i] = convert.ToOAuth2Application(apps[i])
+		apiApps[i].ClientSecret = "" // Hide secret on application list
+	}
+
+	ctx.JSON(http.StatusOK, &apiApps)
+}
+
+// CreateOAuth2Application creates an oauth2 application owned by an organization
+func CreateOAuth2Application(ctx *context.APIContext) {
+	// swagger:operation POST /orgs/{org}/applications organization orgCreateOAuth2Application
+	// ---
+	// summary: Create an OAuth2 application owned by an organization
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// - name: body
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/CreateOAuth2ApplicationOptions"
+	// responses:
+	//   "201":
+	//     "$ref": "#/responses/OAuth2Application"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	form := web.GetForm(ctx).(*api.CreateOAuth2ApplicationOptions)
+
+	app, err := models.CreateOAuth2Application(models.CreateOAuth2ApplicationOptions{
+		Name:                  form.Name,
+		UserID:                ctx.Org.Organization.ID,
+		RedirectURIs:          form.RedirectURIs,
+		FrontchannelLogoutURI: form.FrontchannelLogoutURI,
+	})
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "CreateOAuth2Application", err)
+		return
+	}
+	if app.ClientSecret, err = app.GenerateClientSecret(); err != nil {
+		ctx.Error(http.StatusInternalServerError, "GenerateClientSecret", err)
+		return
+	}
+	log.Trace("OAuth2 application of %s created by %s: %s", ctx.Org.Organization.Name, ctx.User.Name, app.ClientID)
+
+	ctx.JSON(http.StatusCreated, convert.ToOAuth2Application(app))
+}
+
+// getOAuth2ApplicationByParams returns the application of the request if it belongs to the organization
+func getOAuth2ApplicationByParams(ctx *context.APIContext) *models.OAuth2Application {
+	app, err := models.GetOAuth2ApplicationByID(ctx.ParamsInt64(":id"))
+	if err != nil {
+		if models.IsErrOAuthApplicationNotFound(err) {
+			ctx.NotFound()
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetOAuth2ApplicationByID", err)
+		}
+		return nil
+	}
+	if app.UID != ctx.Org.Organization.ID {
+		ctx.NotFound()
+		return nil
+	}
+	return app
+}
+
+// GetOAuth2Application get an oauth2 application of an organization
+func GetOAuth2Application(ctx *context.APIContext) {
+	// swagger:operation GET /orgs/{org}/applications/{id} organization orgGetOAuth2Application
+	// ---
+	// summary: Get an OAuth2 application of an organization
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of the application
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/OAuth2Application"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	app := getOAuth2ApplicationByParams(ctx)
+	if ctx.Written() {
+		return
+	}
+	app.ClientSecret = ""
+
+	ctx.JSON(http.StatusOK, convert.ToOAuth2Application(app))
+}
+
+// EditOAuth2Application edit an oauth2 application of an organization
+func EditOAuth2Application(ctx *context.APIContext) {
+	// swagger:operation PATCH /orgs/{org}/applications/{id} organization orgEditOAuth2Application
+	// ---
+	// summary: Edit an OAuth2 application of an organization, the client secret is kept
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of the application to edit
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// - name: body
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/CreateOAuth2ApplicationOptions"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/OAuth2Application"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	form := web.GetForm(ctx).(*api.CreateOAuth2ApplicationOptions)
+	app := getOAuth2ApplicationByParams(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	updated, err := models.UpdateOAuth2Application(models.UpdateOAuth2ApplicationOptions{
+		ID:                    app.ID,
+		Name:                  form.Name,
+		UserID:                app.UID,
+		RedirectURIs:          form.RedirectURIs,
+		FrontchannelLogoutURI: form.FrontchannelLogoutURI,
+	})
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "UpdateOAuth2Application", err)
+		return
+	}
+	updated.ClientSecret = ""
+	log.Trace("OAuth2 application of %s updated by %s: %s", ctx.Org.Organization.Name, ctx.User.Name, app.ClientID)
+
+	ctx.JSON(http.StatusOK, convert.ToOAuth2Application(updated))
+}
+
+// DeleteOAuth2Application delete an oauth2 application of an organization
+func DeleteOAuth2Application(ctx *context.APIContext) {
+	// swagger:operation DELETE /orgs/{org}/applications/{id} organization orgDeleteOAuth2Application
+	// ---
+	// summary: Delete an OAuth2 application of an organization
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of the application to delete
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	app := getOAuth2ApplicationByParams(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if err := models.DeleteOAuth2Application(app.ID, app.UID); err != nil {
+		ctx.Error(http.StatusInternalServerError, "DeleteOAuth2Application", err)
+		return
+	}
+	log.Trace("OAuth2 application of %s deleted by %s: %s", ctx.Org.Organization.Name, ctx.User.Name, app.ClientID)
+
+	ctx.Status(http.StatusNoContent)
+}
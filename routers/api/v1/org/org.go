@@ -191,6 +191,7 @@ func Create(ctx *context.APIContext) {
 		Type:                      models.UserTypeOrganization,
 		Visibility:                visibility,
 		RepoAdminChangeTeamAccess: form.RepoAdminChangeTeamAccess,
+		AllowJoinRequests:         form.AllowJoinRequests,
 	}
 	if err := models.CreateOrganization(org, ctx.User); err != nil {
 		if models.IsErrUserAlreadyExist(err) ||
@@ -254,19 +255,60 @@ func Edit(ctx *context.APIContext) {
 	// responses:
 	//   "200":
 	//     "$ref": "#/responses/Organization"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
 	form := web.GetForm(ctx).(*api.EditOrgOption)
 	org := ctx.Org.Organization
+
+	if form.RequireTwoFactor != nil || form.RemoveTwoFactorNonCompliant != nil {
+		oldRequire, oldRemove := org.RequireTwoFactor, org.RemoveTwoFactorNonCompliant
+		require, remove := oldRequire, oldRemove
+		if form.RequireTwoFactor != nil {
+			require = *form.RequireTwoFactor
+		}
+		if form.RemoveTwoFactorNonCompliant != nil {
+			remove = *form.RemoveTwoFactorNonCompliant
+		}
+		if err := models.SetOrgTwoFactorPolicy(org, ctx.User, require, remove); err != nil {
+			if models.IsErrTwoFactorNotEnrolled(err) {
+				ctx.Error(http.StatusUnprocessableEntity, "", "you must enroll two-factor authentication to require it")
+			} else {
+				ctx.Error(http.StatusInternalServerError, "SetOrgTwoFactorPolicy", err)
+			}
+			return
+		}
+		if org.RequireTwoFactor != oldRequire || org.RemoveTwoFactorNonCompliant != oldRemove {
+			ctx.Audit(models.OrgTwoFactorPolicyAuditEvent(org, oldRequire, oldRemove))
+		}
+	}
+
 	org.FullName = form.FullName
 	org.Description = form.Description
 	org.Website = form.Website
 	org.Location = form.Location
+	oldVisibility := org.Visibility
 	if form.Visibility != "" {
 		org.Visibility = api.VisibilityModes[form.Visibility]
 	}
-	if err := models.UpdateUserCols(org, "full_name", "description", "website", "location", "visibility"); err != nil {
+	cols := []string{"full_name", "description", "website", "location", "visibility"}
+	if form.AllowJoinRequests != nil {
+		org.AllowJoinRequests = *form.AllowJoinRequests
+		cols = append(cols, "allow_join_requests")
+	}
+	if err := models.UpdateUserCols(org, cols...); err != nil {
 		ctx.Error(http.StatusInternalServerError, "EditOrganization", err)
 		return
 	}
+	if org.Visibility != oldVisibility {
+		ctx.Audit(&models.AuditEvent{
+			Action:     models.AuditOrgVisibility,
+			OrgID:      org.ID,
+			TargetID:   org.ID,
+			TargetName: org.Name,
+			Before:     map[string]string{"visibility": oldVisibility.String()},
+			After:      map[string]string{"visibility": org.Visibility.String()},
+		})
+	}
 
 	ctx.JSON(http.StatusOK, convert.ToOrganization(org))
 }
@@ -152,14 +152,24 @@ func CreateTeam(ctx *context.APIContext) {
 	//     "$ref": "#/responses/validationError"
 	form := web.GetForm(ctx).(*api.CreateTeamOption)
 	team := &models.Team{
-		OrgID:       ctx.Org.Organization.ID,
-		Name:        form.Name,
-		Description: form.Description,
-		Authorize:   models.ParseAccessMode(form.Permission),
+		OrgID:               ctx.Org.Organization.ID,
+		Name:                form.Name,
+		Description:         form.Description,
+		Authorize:           models.ParseAccessMode(form.Permission),
+		CanManageOAuth2Apps: form.CanManageOAuth2Apps,
+		RoleID:              form.RoleID,
+		ParentID:            form.ParentID,
+	}
+	modes, err := models.ParseUnitAccessModes(form.Units)
+	if err != nil {
+		ctx.Error(http.StatusUnprocessableEntity, "", err)
+		return
 	}
+	team.SetUnitAccessModes(modes)
 
 	if err := models.NewTeam(team); err != nil {
-		if models.IsErrTeamAlreadyExist(err) {
+		if models.IsErrTeamAlreadyExist(err) || models.IsErrOrgRoleNotExist(err) ||
+			models.IsErrTeamNotExist(err) || models.IsErrInvalidTeamParent(err) {
 			ctx.Error(http.StatusUnprocessableEntity, "", err)
 		} else {
 			ctx.Error(http.StatusInternalServerError, "NewTeam", err)
@@ -192,6 +202,8 @@ func EditTeam(ctx *context.APIContext) {
 	// responses:
 	//   "200":
 	//     "$ref": "#/responses/Team"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
 
 	form := web.GetForm(ctx).(*api.EditTeamOption)
 
@@ -216,8 +228,34 @@ func EditTeam(ctx *context.APIContext) {
 		}
 	}
 
+	if !team.IsOwnerTeam() && form.CanManageOAuth2Apps != nil {
+		team.CanManageOAuth2Apps = *form.CanManageOAuth2Apps
+	}
+
+	if !team.IsOwnerTeam() && form.RoleID != nil {
+		team.RoleID = *form.RoleID
+		team.Role = nil
+	}
+
+	if form.ParentID != nil {
+		team.ParentID = *form.ParentID
+	}
+
+	if !team.IsOwnerTeam() && form.Units != nil {
+		modes, err := models.ParseUnitAccessModes(form.Units)
+		if err != nil {
+			ctx.Error(http.StatusUnprocessableEntity, "", err)
+			return
+		}
+		team.SetUnitAccessModes(modes)
+	}
+
 	if err := models.UpdateTeam(team, isAuthChanged, false); err != nil {
-		ctx.Error(http.StatusInternalServerError, "EditTeam", err)
+		if models.IsErrOrgRoleNotExist(err) || models.IsErrTeamNotExist(err) || models.IsErrInvalidTeamParent(err) {
+			ctx.Error(http.StatusUnprocessableEntity, "", err)
+		} else {
+			ctx.Error(http.StatusInternalServerError, "EditTeam", err)
+		}
 		return
 	}
 	ctx.JSON(http.StatusOK, convert.ToTeam(team))
@@ -268,6 +306,10 @@ func GetTeamMembers(ctx *context.APIContext) {
 	//   in: query
 	//   description: page size of results
 	//   type: integer
+	// - name: effective
+	//   in: query
+	//   description: include the members of the sub-teams, which are implicitly members of the team
+	//   type: boolean
 	// responses:
 	//   "200":
 	//     "$ref": "#/responses/UserList"
@@ -281,14 +323,24 @@ func GetTeamMembers(ctx *context.APIContext) {
 		return
 	}
 	team := ctx.Org.Team
-	if err := team.GetMembers(&models.SearchMembersOptions{
+	opts := &models.SearchMembersOptions{
 		ListOptions: utils.GetListOptions(ctx),
-	}); err != nil {
-		ctx.Error(http.StatusInternalServerError, "GetTeamMembers", err)
-		return
 	}
-	members := make([]*api.User, len(team.Members))
-	for i, member := range team.Members {
+	var teamMembers []*models.User
+	if ctx.QueryBool("effective") {
+		if teamMembers, err = team.GetEffectiveMembers(opts); err != nil {
+			ctx.Error(http.StatusInternalServerError, "GetEffectiveMembers", err)
+			return
+		}
+	} else {
+		if err = team.GetMembers(opts); err != nil {
+			ctx.Error(http.StatusInternalServerError, "GetTeamMembers", err)
+			return
+		}
+		teamMembers = team.Members
+	}
+	members := make([]*api.User, len(teamMembers))
+	for i, member := range teamMembers {
 		members[i] = convert.ToUser(member, ctx.User)
 	}
 	ctx.JSON(http.StatusOK, members)
@@ -368,6 +420,7 @@ func AddTeamMember(ctx *context.APIContext) {
 		ctx.Error(http.StatusInternalServerError, "AddMember", err)
 		return
 	}
+	ctx.Audit(models.TeamMemberAuditEvent(models.AuditTeamMemberAdd, ctx.Org.Team, u))
 	ctx.Status(http.StatusNoContent)
 }
 
@@ -405,6 +458,7 @@ func RemoveTeamMember(ctx *context.APIContext) {
 		ctx.Error(http.StatusInternalServerError, "RemoveMember", err)
 		return
 	}
+	ctx.Audit(models.TeamMemberAuditEvent(models.AuditTeamMemberRemove, ctx.Org.Team, u))
 	ctx.Status(http.StatusNoContent)
 }
 
@@ -32,6 +32,15 @@ type swaggerParameterBodies struct {
 	// in:body
 	EditTeamOption api.EditTeamOption
 
+	// in:body
+	CreateOrgInviteOption api.CreateOrgInviteOption
+
+	// in:body
+	CreateOrgJoinRequestOption api.CreateOrgJoinRequestOption
+
+	// in:body
+	ApproveOrgJoinRequestOption api.ApproveOrgJoinRequestOption
+
 	// in:body
 	CreateUserOption api.CreateUserOption
 
@@ -40,4 +49,10 @@ type swaggerParameterBodies struct {
 
 	// in:body
 	CreateOAuth2ApplicationOptions api.CreateOAuth2ApplicationOptions
+
+	// in:body
+	AdminCreateOAuth2ApplicationOptions api.AdminCreateOAuth2ApplicationOptions
+
+	// in:body
+	AdminEditOAuth2ApplicationOptions api.AdminEditOAuth2ApplicationOptions
 }
@@ -35,3 +35,31 @@ type swaggerResponseTeamList struct {
 	// in:body
 	Body []api.Team `json:"body"`
 }
+
+// OrgInvite
+// swagger:response OrgInvite
+type swaggerResponseOrgInvite struct {
+	// in:body
+	Body api.OrgInvite `json:"body"`
+}
+
+// OrgInviteList
+// swagger:response OrgInviteList
+type swaggerResponseOrgInviteList struct {
+	// in:body
+	Body []api.OrgInvite `json:"body"`
+}
+
+// OrgJoinRequest
+// swagger:response OrgJoinRequest
+type swaggerResponseOrgJoinRequest struct {
+	// in:body
+	Body api.OrgJoinRequest `json:"body"`
+}
+
+// OrgJoinRequestList
+// swagger:response OrgJoinRequestList
+type swaggerResponseOrgJoinRequestList struct {
+	// in:body
+	Body []api.OrgJoinRequest `json:"body"`
+}
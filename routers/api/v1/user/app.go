@@ -10,11 +10,13 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"go.wandrs.dev/framework/models"
 	"go.wandrs.dev/framework/modules/context"
 	"go.wandrs.dev/framework/modules/convert"
 	api "go.wandrs.dev/framework/modules/structs"
+	"go.wandrs.dev/framework/modules/timeutil"
 	"go.wandrs.dev/framework/modules/web"
 	"go.wandrs.dev/framework/routers/api/v1/utils"
 )
@@ -56,6 +58,8 @@ func ListAccessTokens(ctx *context.APIContext) {
 			ID:             tokens[i].ID,
 			Name:           tokens[i].Name,
 			TokenLastEight: tokens[i].TokenLastEight,
+			Scopes:         accessTokenScopes(tokens[i]),
+			ExpiresAt:      accessTokenExpiresAt(tokens[i]),
 		}
 	}
 	ctx.JSON(http.StatusOK, &apiTokens)
@@ -85,6 +89,13 @@ func CreateAccessToken(ctx *context.APIContext) {
 	//     properties:
 	//       name:
 	//         type: string
+	//       scopes:
+	//         type: array
+	//         items:
+	//           type: string
+	//       expires_at:
+	//         type: string
+	//         format: date-time
 	// responses:
 	//   "201":
 	//     "$ref": "#/responses/AccessToken"
@@ -93,9 +104,19 @@ func CreateAccessToken(ctx *context.APIContext) {
 
 	form := web.GetForm(ctx).(*api.CreateAccessTokenOption)
 
+	scope, err := models.ParseAccessTokenScopes(form.Scopes)
+	if err != nil {
+		ctx.Error(http.StatusBadRequest, "ParseAccessTokenScopes", err)
+		return
+	}
+
 	t := &models.AccessToken{
-		UID:  ctx.User.ID,
-		Name: form.Name,
+		UID:   ctx.User.ID,
+		Name:  form.Name,
+		Scope: scope,
+	}
+	if form.ExpiresAt != nil {
+		t.ExpiredUnix = timeutil.TimeStamp(form.ExpiresAt.Unix())
 	}
 
 	exist, err := models.AccessTokenByNameExists(t)
@@ -109,17 +130,46 @@ func CreateAccessToken(ctx *context.APIContext) {
 	}
 
 	if err := models.NewAccessToken(t); err != nil {
-		ctx.Error(http.StatusInternalServerError, "NewAccessToken", err)
+		if models.IsErrAccessTokenInvalidExpiry(err) {
+			ctx.Error(http.StatusBadRequest, "NewAccessToken", err)
+		} else {
+			ctx.Error(http.StatusInternalServerError, "NewAccessToken", err)
+		}
 		return
 	}
+	ctx.Audit(&models.AuditEvent{
+		Action:     models.AuditAccessTokenCreate,
+		TargetID:   t.ID,
+		TargetName: t.Name,
+		After:      t.AuditValues(),
+	})
 	ctx.JSON(http.StatusCreated, &api.AccessToken{
 		Name:           t.Name,
 		Token:          t.Token,
 		ID:             t.ID,
 		TokenLastEight: t.TokenLastEight,
+		Scopes:         accessTokenScopes(t),
+		ExpiresAt:      accessTokenExpiresAt(t),
 	})
 }
 
+func accessTokenExpiresAt(t *models.AccessToken) *time.Time {
+	expires := t.ExpiresUnix()
+	if expires == 0 {
+		return nil
+	}
+	return expires.AsTimePtr()
+}
+
+func accessTokenScopes(t *models.AccessToken) []string {
+	scopes := t.Scopes()
+	result := make([]string, len(scopes))
+	for i, scope := range scopes {
+		result[i] = string(scope)
+	}
+	return result
+}
+
 // DeleteAccessToken delete access tokens
 func DeleteAccessToken(ctx *context.APIContext) {
 	// swagger:operation DELETE /users/{username}/tokens/{token} user userDeleteAccessToken
@@ -148,8 +198,10 @@ func DeleteAccessToken(ctx *context.APIContext) {
 
 	token := ctx.Params(":id")
 	tokenID, _ := strconv.ParseInt(token, 0, 64)
+	var tokenName string
 
 	if tokenID == 0 {
+		tokenName = token
 		tokens, err := models.ListAccessTokens(models.ListAccessTokensOptions{
 			Name:   token,
 			UserID: ctx.User.ID,
@@ -183,6 +235,11 @@ func DeleteAccessToken(ctx *context.APIContext) {
 		}
 		return
 	}
+	ctx.Audit(&models.AuditEvent{
+		Action:     models.AuditAccessTokenDelete,
+		TargetID:   tokenID,
+		TargetName: tokenName,
+	})
 
 	ctx.Status(http.StatusNoContent)
 }
@@ -209,9 +266,10 @@ func CreateOauth2Application(ctx *context.APIContext) {
 	data := web.GetForm(ctx).(*api.CreateOAuth2ApplicationOptions)
 
 	app, err := models.CreateOAuth2Application(models.CreateOAuth2ApplicationOptions{
-		Name:         data.Name,
-		UserID:       ctx.User.ID,
-		RedirectURIs: data.RedirectURIs,
+		Name:                  data.Name,
+		UserID:                ctx.User.ID,
+		RedirectURIs:          data.RedirectURIs,
+		FrontchannelLogoutURI: data.FrontchannelLogoutURI,
 	})
 	if err != nil {
 		ctx.Error(http.StatusBadRequest, "", "error creating oauth2 application")
@@ -357,10 +415,11 @@ func UpdateOauth2Application(ctx *context.APIContext) {
 	data := web.GetForm(ctx).(*api.CreateOAuth2ApplicationOptions)
 
 	app, err := models.UpdateOAuth2Application(models.UpdateOAuth2ApplicationOptions{
-		Name:         data.Name,
-		UserID:       ctx.User.ID,
-		ID:           appID,
-		RedirectURIs: data.RedirectURIs,
+		Name:                  data.Name,
+		UserID:                ctx.User.ID,
+		ID:                    appID,
+		RedirectURIs:          data.RedirectURIs,
+		FrontchannelLogoutURI: data.FrontchannelLogoutURI,
 	})
 	if err != nil {
 		if models.IsErrOauthClientIDInvalid(err) || models.IsErrOAuthApplicationNotFound(err) {
@@ -12,6 +12,7 @@ import (
 
 	"go.wandrs.dev/framework/models"
 	"go.wandrs.dev/framework/models/migrations"
+	"go.wandrs.dev/framework/modules/auth/oauth2"
 	"go.wandrs.dev/framework/modules/auth/sso"
 	"go.wandrs.dev/framework/modules/cache"
 	"go.wandrs.dev/framework/modules/cron"
@@ -131,6 +132,11 @@ func GlobalInit(ctx context.Context) {
 	if err := models.InitOAuth2(); err != nil {
 		log.Fatal("Failed to initialize OAuth2 support: %v", err)
 	}
+	if setting.OAuth2.Enable {
+		if err := oauth2.InitSigningKey(); err != nil {
+			log.Fatal("Failed to initialize OAuth2 signing key: %v", err)
+		}
+	}
 
 	// Booting long running goroutines.
 	cron.NewContext()
@@ -61,6 +61,16 @@ func Home(ctx *context.Context) {
 			return
 		}
 		opts.PublicOnly = !isMember && !ctx.User.IsAdmin
+
+		if !isMember && org.AllowJoinRequests {
+			joinRequest, err := models.GetOrgJoinRequest(org.ID, ctx.User.ID)
+			if err != nil {
+				ctx.ServerError("GetOrgJoinRequest", err)
+				return
+			}
+			ctx.Data["CanRequestToJoin"] = !ctx.User.IsOrganization()
+			ctx.Data["JoinRequest"] = joinRequest
+		}
 	}
 
 	members, _, err := models.FindOrgMembers(&opts)
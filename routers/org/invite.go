@@ -0,0 +1,181 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package org
+
+import (
+	"net/http"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/base"
+	"go.wandrs.dev/framework/modules/context"
+	"go.wandrs.dev/framework/modules/log"
+	"go.wandrs.dev/framework/modules/setting"
+	"go.wandrs.dev/framework/modules/web"
+	"go.wandrs.dev/framework/modules/web/middleware"
+	"go.wandrs.dev/framework/services/forms"
+	"go.wandrs.dev/framework/services/mailer"
+)
+
+const (
+	// tplInvite template path for accepting an invitation
+	tplInvite base.TplName = "org/invite"
+)
+
+// TeamInvitePost response for inviting a person by email to join the team
+func TeamInvitePost(ctx *context.Context) {
+	form := web.GetForm(ctx).(*forms.TeamInviteForm)
+	teamLink := ctx.Org.OrgLink + "/teams/" + ctx.Org.Team.LowerName
+
+	if setting.MailService == nil {
+		ctx.Flash.Error(ctx.Tr("org.teams.invite_mail_disabled"))
+		ctx.Redirect(teamLink)
+		return
+	}
+	if ctx.HasError() {
+		ctx.Flash.Error(ctx.GetErrMsg())
+		ctx.Redirect(teamLink)
+		return
+	}
+
+	inv, err := models.CreateOrgInvite(ctx.Org.Team, ctx.User, form.Email)
+	if err != nil {
+		switch {
+		case models.IsErrEmailInvalid(err):
+			ctx.Flash.Error(ctx.Tr("form.email_invalid"))
+		case models.IsErrOrgInviteAlreadyExist(err):
+			ctx.Flash.Error(ctx.Tr("org.teams.invite_duplicate", form.Email))
+		case models.IsErrOrgInviteAlreadyMember(err):
+			ctx.Flash.Error(ctx.Tr("org.teams.add_duplicate_users"))
+		default:
+			ctx.ServerError("CreateOrgInvite", err)
+			return
+		}
+		ctx.Redirect(teamLink)
+		return
+	}
+	inv.Org = ctx.Org.Organization
+	ctx.Audit(models.TeamInviteAuditEvent(models.AuditTeamInviteCreate, inv))
+	mailer.SendOrgInviteMail(inv)
+	log.Trace("Invitation to %s/%s sent to %s", ctx.Org.Organization.Name, ctx.Org.Team.Name, inv.Email)
+
+	ctx.Flash.Success(ctx.Tr("org.teams.invite_success", inv.Email))
+	ctx.Redirect(teamLink)
+}
+
+// RevokeTeamInvite response for revoking an invitation to join the team
+func RevokeTeamInvite(ctx *context.Context) {
+	inv, err := models.GetOrgInviteByID(ctx.Org.Organization.ID, ctx.ParamsInt64("id"))
+	if err != nil {
+		if models.IsErrOrgInviteNotExist(err) {
+			ctx.NotFound("GetOrgInviteByID", err)
+		} else {
+			ctx.ServerError("GetOrgInviteByID", err)
+		}
+		return
+	} else if inv.TeamID != ctx.Org.Team.ID {
+		ctx.NotFound("GetOrgInviteByID", nil)
+		return
+	}
+
+	if err := models.DeleteOrgInvite(inv); err != nil {
+		ctx.ServerError("DeleteOrgInvite", err)
+		return
+	}
+	inv.Team = ctx.Org.Team
+	ctx.Audit(models.TeamInviteAuditEvent(models.AuditTeamInviteRevoke, inv))
+
+	ctx.Flash.Success(ctx.Tr("org.teams.invite_revoked", inv.Email))
+	ctx.Redirect(ctx.Org.OrgLink + "/teams/" + ctx.Org.Team.LowerName)
+}
+
+func verifyInvite(ctx *context.Context) *models.OrgInvite {
+	ctx.Data["Title"] = ctx.Tr("org.invite.title")
+	inv, err := models.VerifyOrgInviteCode(ctx.Params("code"))
+	if err != nil {
+		if !models.IsErrOrgInviteNotExist(err) {
+			ctx.ServerError("VerifyOrgInviteCode", err)
+			return nil
+		}
+		ctx.Data["IsInviteInvalid"] = true
+		ctx.HTML(http.StatusNotFound, tplInvite)
+		return nil
+	}
+	ctx.Data["Invite"] = inv
+	ctx.Data["InviteLink"] = setting.AppSubURL + "/org/invite/" + ctx.Params("code")
+	return inv
+}
+
+// Invite render the page to accept an invitation. Signed out users are asked to sign in
+// or to create an account, and are sent back to the page afterwards.
+func Invite(ctx *context.Context) {
+	inv := verifyInvite(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if !ctx.IsSigned {
+		middleware.SetRedirectToCookie(ctx.Resp, ctx.Data["InviteLink"].(string))
+		ctx.Data["ShowRegistrationButton"] = setting.Service.ShowRegistrationButton
+		ctx.HTML(http.StatusOK, tplInvite)
+		return
+	}
+
+	isMember, err := models.IsTeamMember(inv.OrgID, inv.TeamID, ctx.User.ID)
+	if err != nil {
+		ctx.ServerError("IsTeamMember", err)
+		return
+	}
+	ctx.Data["IsTeamMember"] = isMember
+	if !isMember && !checkInvitee(ctx, inv) {
+		return
+	}
+	ctx.HTML(http.StatusOK, tplInvite)
+}
+
+// checkInvitee returns true if the signed in user owns the invited email address, otherwise
+// the page asking to sign in with or to add the address is rendered
+func checkInvitee(ctx *context.Context, inv *models.OrgInvite) bool {
+	isInvitee, err := inv.IsInvitee(ctx.User)
+	if err != nil {
+		ctx.ServerError("IsInvitee", err)
+		return false
+	} else if !isInvitee {
+		ctx.Data["IsNotInvitee"] = true
+		ctx.HTML(http.StatusForbidden, tplInvite)
+		return false
+	}
+	return true
+}
+
+// InvitePost response for accepting or declining an invitation
+func InvitePost(ctx *context.Context) {
+	inv := verifyInvite(ctx)
+	if ctx.Written() {
+		return
+	}
+	if !checkInvitee(ctx, inv) {
+		return
+	}
+
+	if ctx.Query("action") == "decline" {
+		if err := models.DeleteOrgInvite(inv); err != nil {
+			ctx.ServerError("DeleteOrgInvite", err)
+			return
+		}
+		ctx.Flash.Info(ctx.Tr("org.invite.declined", inv.Org.DisplayName()))
+		ctx.Redirect(setting.AppSubURL + "/")
+		return
+	}
+
+	if err := models.AcceptOrgInvite(inv, ctx.User); err != nil {
+		ctx.ServerError("AcceptOrgInvite", err)
+		return
+	}
+	ctx.Audit(models.TeamMemberAuditEvent(models.AuditTeamMemberAdd, inv.Team, ctx.User))
+	log.Trace("Invitation to %s/%s accepted by %s", inv.Org.Name, inv.Team.Name, ctx.User.Name)
+
+	ctx.Flash.Success(ctx.Tr("org.invite.accepted", inv.Team.Name, inv.Org.DisplayName()))
+	ctx.Redirect(inv.Org.OrganisationLink() + "/teams/" + inv.Team.LowerName)
+}
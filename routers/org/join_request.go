@@ -0,0 +1,170 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package org
+
+import (
+	"net/http"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/base"
+	"go.wandrs.dev/framework/modules/context"
+	"go.wandrs.dev/framework/modules/log"
+	"go.wandrs.dev/framework/modules/web"
+	"go.wandrs.dev/framework/services/forms"
+	"go.wandrs.dev/framework/services/mailer"
+)
+
+const (
+	// tplSettingsJoinRequests template path for the pending requests to join the organization
+	tplSettingsJoinRequests base.TplName = "org/settings/join_requests"
+)
+
+// JoinRequestPost response for requesting to join the organization
+func JoinRequestPost(ctx *context.Context) {
+	form := web.GetForm(ctx).(*forms.OrgJoinRequestForm)
+	org := ctx.Org.Organization
+	if !models.HasOrgVisible(org, ctx.User) {
+		ctx.NotFound("HasOrgVisible", nil)
+		return
+	}
+	if ctx.HasError() {
+		ctx.Flash.Error(ctx.GetErrMsg())
+		ctx.Redirect(org.HomeLink())
+		return
+	}
+
+	r, err := models.CreateOrgJoinRequest(org, ctx.User, form.Message)
+	if err != nil {
+		switch {
+		case models.IsErrOrgJoinRequestsDisabled(err):
+			ctx.Flash.Error(ctx.Tr("org.join_request.disabled"))
+		case models.IsErrOrgJoinRequestAlreadyExist(err):
+			ctx.Flash.Error(ctx.Tr("org.join_request.already_exist"))
+		case models.IsErrOrgJoinRequestAlreadyMember(err):
+			ctx.Flash.Error(ctx.Tr("org.join_request.already_member"))
+		default:
+			ctx.ServerError("CreateOrgJoinRequest", err)
+			return
+		}
+		ctx.Redirect(org.HomeLink())
+		return
+	}
+
+	owners, err := org.GetOwners()
+	if err != nil {
+		ctx.ServerError("GetOwners", err)
+		return
+	}
+	mailer.SendOrgJoinRequestMail(r, owners)
+	log.Trace("Request to join %s created by %s", org.Name, ctx.User.Name)
+
+	ctx.Flash.Success(ctx.Tr("org.join_request.create_success", org.DisplayName()))
+	ctx.Redirect(org.HomeLink())
+}
+
+// CancelJoinRequest response for cancelling the pending request of the signed in user
+func CancelJoinRequest(ctx *context.Context) {
+	org := ctx.Org.Organization
+	r, err := models.GetOrgJoinRequest(org.ID, ctx.User.ID)
+	if err != nil {
+		ctx.ServerError("GetOrgJoinRequest", err)
+		return
+	} else if r != nil {
+		if err := models.DeleteOrgJoinRequest(r); err != nil {
+			ctx.ServerError("DeleteOrgJoinRequest", err)
+			return
+		}
+		ctx.Flash.Success(ctx.Tr("org.join_request.cancel_success"))
+	}
+	ctx.Redirect(org.HomeLink())
+}
+
+// JoinRequests render the pending requests to join the organization
+func JoinRequests(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("org.settings.join_requests")
+	ctx.Data["PageIsSettingsJoinRequests"] = true
+
+	org := ctx.Org.Organization
+	requests, err := models.FindOrgJoinRequests(org.ID, models.ListOptions{})
+	if err != nil {
+		ctx.ServerError("FindOrgJoinRequests", err)
+		return
+	}
+	if err := org.GetTeams(&models.SearchTeamOptions{}); err != nil {
+		ctx.ServerError("GetTeams", err)
+		return
+	}
+	ctx.Data["JoinRequests"] = requests
+	ctx.Data["Teams"] = org.Teams
+
+	ctx.HTML(http.StatusOK, tplSettingsJoinRequests)
+}
+
+func getJoinRequestByParams(ctx *context.Context) *models.OrgJoinRequest {
+	r, err := models.GetOrgJoinRequestByID(ctx.Org.Organization.ID, ctx.ParamsInt64("id"))
+	if err != nil {
+		if models.IsErrOrgJoinRequestNotExist(err) {
+			ctx.NotFound("GetOrgJoinRequestByID", err)
+		} else {
+			ctx.ServerError("GetOrgJoinRequestByID", err)
+		}
+		return nil
+	}
+	r.Org = ctx.Org.Organization
+	if err := r.LoadAttributes(); err != nil {
+		ctx.ServerError("LoadAttributes", err)
+		return nil
+	}
+	return r
+}
+
+// ApproveJoinRequest response for approving a request to join the organization to a team
+func ApproveJoinRequest(ctx *context.Context) {
+	r := getJoinRequestByParams(ctx)
+	if ctx.Written() {
+		return
+	}
+	link := ctx.Org.OrgLink + "/settings/join_requests"
+
+	team, err := models.GetTeamByID(ctx.QueryInt64("team_id"))
+	if err != nil && !models.IsErrTeamNotExist(err) {
+		ctx.ServerError("GetTeamByID", err)
+		return
+	} else if err != nil || team.OrgID != ctx.Org.Organization.ID {
+		ctx.Flash.Error(ctx.Tr("org.join_request.team_required"))
+		ctx.Redirect(link)
+		return
+	}
+
+	if err := models.ApproveOrgJoinRequest(r, team); err != nil {
+		ctx.ServerError("ApproveOrgJoinRequest", err)
+		return
+	}
+	ctx.Audit(models.TeamMemberAuditEvent(models.AuditTeamMemberAdd, team, r.User))
+	mailer.SendOrgJoinRequestResultMail(r, team)
+	log.Trace("Request to join %s of %s approved to team %s", ctx.Org.Organization.Name, r.User.Name, team.Name)
+
+	ctx.Flash.Success(ctx.Tr("org.join_request.approve_success", r.User.Name, team.Name))
+	ctx.Redirect(link)
+}
+
+// RejectJoinRequest response for rejecting a request to join the organization
+func RejectJoinRequest(ctx *context.Context) {
+	r := getJoinRequestByParams(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if err := models.DeleteOrgJoinRequest(r); err != nil {
+		ctx.ServerError("DeleteOrgJoinRequest", err)
+		return
+	}
+	ctx.Audit(models.OrgJoinRequestRejectAuditEvent(ctx.Org.Organization, r.User))
+	mailer.SendOrgJoinRequestResultMail(r, nil)
+	log.Trace("Request to join %s of %s rejected", ctx.Org.Organization.Name, r.User.Name)
+
+	ctx.Flash.Success(ctx.Tr("org.join_request.reject_success", r.User.Name))
+	ctx.Redirect(ctx.Org.OrgLink + "/settings/join_requests")
+}
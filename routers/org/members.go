@@ -96,11 +96,17 @@ func MembersAction(ctx *context.Context) {
 			ctx.Error(http.StatusNotFound)
 			return
 		}
+		var member *models.User
+		if member, err = models.GetUserByID(uid); err != nil {
+			break
+		}
 		err = org.RemoveMember(uid)
 		if models.IsErrLastOrgOwner(err) {
 			ctx.Flash.Error(ctx.Tr("form.last_org_owner"))
 			ctx.Redirect(ctx.Org.OrgLink + "/members")
 			return
+		} else if err == nil {
+			ctx.Audit(models.OrgMemberRemoveAuditEvent(org, member))
 		}
 	case "leave":
 		err = org.RemoveMember(ctx.User.ID)
@@ -108,6 +114,8 @@ func MembersAction(ctx *context.Context) {
 			ctx.Flash.Error(ctx.Tr("form.last_org_owner"))
 			ctx.Redirect(ctx.Org.OrgLink + "/members")
 			return
+		} else if err == nil {
+			ctx.Audit(models.OrgMemberRemoveAuditEvent(org, ctx.User))
 		}
 	}
 
@@ -16,6 +16,7 @@ import (
 	"go.wandrs.dev/framework/modules/setting"
 	"go.wandrs.dev/framework/modules/web"
 	userSetting "go.wandrs.dev/framework/routers/user/setting"
+	"go.wandrs.dev/framework/routers/utils"
 	"go.wandrs.dev/framework/services/forms"
 )
 
@@ -28,6 +29,8 @@ const (
 	tplSettingsHooks base.TplName = "org/settings/hooks"
 	// tplSettingsLabels template path for render labels settings
 	tplSettingsLabels base.TplName = "org/settings/labels"
+	// tplSettingsAudit template path for render the audit log
+	tplSettingsAudit base.TplName = "org/settings/audit"
 )
 
 // Settings render the main settings page
@@ -36,6 +39,7 @@ func Settings(ctx *context.Context) {
 	ctx.Data["PageIsSettingsOptions"] = true
 	ctx.Data["CurrentVisibility"] = ctx.Org.Organization.Visibility
 	ctx.Data["RepoAdminChangeTeamAccess"] = ctx.Org.Organization.RepoAdminChangeTeamAccess
+	ctx.Data["AllowJoinRequests"] = ctx.Org.Organization.AllowJoinRequests
 	ctx.HTML(http.StatusOK, tplSettingsOptions)
 }
 
@@ -86,13 +90,25 @@ func SettingsPost(ctx *context.Context) {
 	org.Website = form.Website
 	org.Location = form.Location
 	org.RepoAdminChangeTeamAccess = form.RepoAdminChangeTeamAccess
+	org.AllowJoinRequests = form.AllowJoinRequests
 
+	oldVisibility := org.Visibility
 	org.Visibility = form.Visibility
 
 	if err := models.UpdateUser(org); err != nil {
 		ctx.ServerError("UpdateUser", err)
 		return
 	}
+	if org.Visibility != oldVisibility {
+		ctx.Audit(&models.AuditEvent{
+			Action:     models.AuditOrgVisibility,
+			OrgID:      org.ID,
+			TargetID:   org.ID,
+			TargetName: org.Name,
+			Before:     map[string]string{"visibility": oldVisibility.String()},
+			After:      map[string]string{"visibility": org.Visibility.String()},
+		})
+	}
 
 	log.Trace("Organization setting updated: %s", org.Name)
 	ctx.Flash.Success(ctx.Tr("org.settings.update_setting_success"))
@@ -153,3 +169,16 @@ func Labels(ctx *context.Context) {
 	ctx.Data["RequireTribute"] = true
 	ctx.HTML(http.StatusOK, tplSettingsLabels)
 }
+
+// Audit shows the audit log of the organization
+func Audit(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("org.settings.audit")
+	ctx.Data["PageIsSettingsAudit"] = true
+
+	utils.ListAuditEvents(ctx, ctx.Org.Organization.ID)
+	if ctx.Written() {
+		return
+	}
+
+	ctx.HTML(http.StatusOK, tplSettingsAudit)
+}
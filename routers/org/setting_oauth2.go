@@ -0,0 +1,91 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package org
+
+import (
+	"net/http"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/base"
+	"go.wandrs.dev/framework/modules/context"
+	"go.wandrs.dev/framework/modules/setting"
+	userSetting "go.wandrs.dev/framework/routers/user/setting"
+)
+
+const (
+	tplSettingsApplications          base.TplName = "org/settings/applications"
+	tplSettingsOAuthApplicationsEdit base.TplName = "org/settings/applications_oauth2_edit"
+)
+
+// MustManageOAuth2Applications checks that OAuth2 is enabled and that the signed in user
+// is an owner of the organization or a member of a team allowed to manage its applications
+func MustManageOAuth2Applications(ctx *context.Context) {
+	if !setting.OAuth2.Enable {
+		ctx.NotFound("MustManageOAuth2Applications", nil)
+		return
+	}
+	if ctx.Org.IsOwner {
+		return
+	}
+	canManage, err := ctx.Org.Organization.CanManageOAuth2ApplicationsBy(ctx.User.ID)
+	if err != nil {
+		ctx.ServerError("CanManageOAuth2ApplicationsBy", err)
+		return
+	}
+	if !canManage {
+		ctx.NotFound("MustManageOAuth2Applications", nil)
+	}
+}
+
+func oauth2CommonHandlers(ctx *context.Context) *userSetting.OAuth2CommonHandlers {
+	ctx.Data["Title"] = ctx.Tr("settings.applications")
+	ctx.Data["PageIsSettingsApplications"] = true
+	return &userSetting.OAuth2CommonHandlers{
+		OwnerID:            ctx.Org.Organization.ID,
+		BasePathList:       ctx.Org.OrgLink + "/settings/applications",
+		BasePathEditPrefix: ctx.Org.OrgLink + "/settings/applications/oauth2",
+		TplAppEdit:         tplSettingsOAuthApplicationsEdit,
+	}
+}
+
+// Applications render the oauth2 applications of the organization
+func Applications(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("settings.applications")
+	ctx.Data["PageIsSettingsApplications"] = true
+
+	apps, err := models.GetOAuth2ApplicationsByUserID(ctx.Org.Organization.ID)
+	if err != nil {
+		ctx.ServerError("GetOAuth2ApplicationsByUserID", err)
+		return
+	}
+	ctx.Data["Applications"] = apps
+
+	ctx.HTML(http.StatusOK, tplSettingsApplications)
+}
+
+// OAuthApplicationsPost response for adding an oauth2 application to the organization
+func OAuthApplicationsPost(ctx *context.Context) {
+	oauth2CommonHandlers(ctx).AddApp(ctx)
+}
+
+// OAuth2ApplicationShow displays the given application of the organization
+func OAuth2ApplicationShow(ctx *context.Context) {
+	oauth2CommonHandlers(ctx).EditShow(ctx)
+}
+
+// OAuthApplicationsEdit response for editing an oauth2 application of the organization
+func OAuthApplicationsEdit(ctx *context.Context) {
+	oauth2CommonHandlers(ctx).EditSave(ctx)
+}
+
+// OAuthApplicationsRegenerateSecret handles the post request for regenerating the secret
+func OAuthApplicationsRegenerateSecret(ctx *context.Context) {
+	oauth2CommonHandlers(ctx).RegenerateSecret(ctx)
+}
+
+// DeleteOAuth2Application deletes the given oauth2 application of the organization
+func DeleteOAuth2Application(ctx *context.Context) {
+	oauth2CommonHandlers(ctx).DeleteApp(ctx)
+}
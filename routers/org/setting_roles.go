@@ -0,0 +1,174 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package org
+
+import (
+	"net/http"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/base"
+	"go.wandrs.dev/framework/modules/context"
+	"go.wandrs.dev/framework/modules/log"
+	"go.wandrs.dev/framework/modules/web"
+	"go.wandrs.dev/framework/services/forms"
+)
+
+const (
+	// tplSettingsRoles template path for the roles of the organization
+	tplSettingsRoles base.TplName = "org/settings/roles"
+	// tplSettingsRoleEdit template path for creating and editing a role
+	tplSettingsRoleEdit base.TplName = "org/settings/role_edit"
+)
+
+// Roles render the roles of the organization
+func Roles(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("org.settings.roles")
+	ctx.Data["PageIsSettingsRoles"] = true
+
+	roles, err := models.GetOrgRoles(ctx.Org.Organization.ID)
+	if err != nil {
+		ctx.ServerError("GetOrgRoles", err)
+		return
+	}
+	ctx.Data["Roles"] = roles
+	ctx.Data["Units"] = models.Units()
+
+	ctx.HTML(http.StatusOK, tplSettingsRoles)
+}
+
+func prepareRoleEdit(ctx *context.Context, r *models.OrgRole) {
+	ctx.Data["Title"] = ctx.Tr("org.settings.roles")
+	ctx.Data["PageIsSettingsRoles"] = true
+	ctx.Data["Role"] = r
+	ctx.Data["Units"] = models.Units()
+}
+
+// NewRole render the page to create a role
+func NewRole(ctx *context.Context) {
+	prepareRoleEdit(ctx, &models.OrgRole{})
+	ctx.Data["PageIsSettingsRolesNew"] = true
+	ctx.HTML(http.StatusOK, tplSettingsRoleEdit)
+}
+
+// NewRolePost response for creating a role
+func NewRolePost(ctx *context.Context) {
+	form := web.GetForm(ctx).(*forms.OrgRoleForm)
+	r := &models.OrgRole{
+		OrgID:       ctx.Org.Organization.ID,
+		Name:        form.Name,
+		Description: form.Description,
+	}
+	modes, unitsErr := queryUnitAccessModes(ctx)
+	r.SetUnitAccessModes(modes)
+	prepareRoleEdit(ctx, r)
+	ctx.Data["PageIsSettingsRolesNew"] = true
+
+	if ctx.HasError() {
+		ctx.HTML(http.StatusOK, tplSettingsRoleEdit)
+		return
+	}
+	if unitsErr != nil {
+		ctx.RenderWithErr(ctx.Tr("org.teams.invalid_unit_access", unitsErr.Error()), tplSettingsRoleEdit, form)
+		return
+	}
+
+	if err := models.NewOrgRole(r); err != nil {
+		if models.IsErrOrgRoleAlreadyExist(err) {
+			ctx.Data["Err_Name"] = true
+			ctx.RenderWithErr(ctx.Tr("org.roles.name_been_taken"), tplSettingsRoleEdit, form)
+			return
+		}
+		ctx.ServerError("NewOrgRole", err)
+		return
+	}
+	log.Trace("Role created: %s/%s", ctx.Org.Organization.Name, r.Name)
+
+	ctx.Flash.Success(ctx.Tr("org.roles.create_success", r.Name))
+	ctx.Redirect(ctx.Org.OrgLink + "/settings/roles")
+}
+
+func getRoleByParams(ctx *context.Context) *models.OrgRole {
+	r, err := models.GetOrgRoleByID(ctx.Org.Organization.ID, ctx.ParamsInt64("id"))
+	if err != nil {
+		if models.IsErrOrgRoleNotExist(err) {
+			ctx.NotFound("GetOrgRoleByID", err)
+		} else {
+			ctx.ServerError("GetOrgRoleByID", err)
+		}
+		return nil
+	}
+	return r
+}
+
+// EditRole render the page to edit a role
+func EditRole(ctx *context.Context) {
+	r := getRoleByParams(ctx)
+	if ctx.Written() {
+		return
+	}
+	prepareRoleEdit(ctx, r)
+	ctx.HTML(http.StatusOK, tplSettingsRoleEdit)
+}
+
+// EditRolePost response for editing a role
+func EditRolePost(ctx *context.Context) {
+	form := web.GetForm(ctx).(*forms.OrgRoleForm)
+	r := getRoleByParams(ctx)
+	if ctx.Written() {
+		return
+	}
+	r.Name = form.Name
+	r.Description = form.Description
+	modes, unitsErr := queryUnitAccessModes(ctx)
+	if unitsErr == nil {
+		r.SetUnitAccessModes(modes)
+	}
+	prepareRoleEdit(ctx, r)
+
+	if ctx.HasError() {
+		ctx.HTML(http.StatusOK, tplSettingsRoleEdit)
+		return
+	}
+	if unitsErr != nil {
+		ctx.RenderWithErr(ctx.Tr("org.teams.invalid_unit_access", unitsErr.Error()), tplSettingsRoleEdit, form)
+		return
+	}
+
+	if err := models.UpdateOrgRole(r); err != nil {
+		if models.IsErrOrgRoleAlreadyExist(err) {
+			ctx.Data["Err_Name"] = true
+			ctx.RenderWithErr(ctx.Tr("org.roles.name_been_taken"), tplSettingsRoleEdit, form)
+			return
+		}
+		ctx.ServerError("UpdateOrgRole", err)
+		return
+	}
+	log.Trace("Role updated: %s/%s", ctx.Org.Organization.Name, r.Name)
+
+	ctx.Flash.Success(ctx.Tr("org.roles.update_success", r.Name))
+	ctx.Redirect(ctx.Org.OrgLink + "/settings/roles")
+}
+
+// DeleteRole response for deleting a role which is not used by any team
+func DeleteRole(ctx *context.Context) {
+	r := getRoleByParams(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if err := models.DeleteOrgRole(r); err != nil {
+		if !models.IsErrOrgRoleInUse(err) {
+			ctx.ServerError("DeleteOrgRole", err)
+			return
+		}
+		ctx.Flash.Error(ctx.Tr("org.roles.delete_in_use", r.Name))
+	} else {
+		ctx.Flash.Success(ctx.Tr("org.roles.delete_success", r.Name))
+	}
+
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"redirect": ctx.Org.OrgLink + "/settings/roles",
+	})
+}
@@ -0,0 +1,69 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package org
+
+import (
+	"net/http"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/base"
+	"go.wandrs.dev/framework/modules/context"
+	"go.wandrs.dev/framework/modules/log"
+	"go.wandrs.dev/framework/modules/web"
+	"go.wandrs.dev/framework/services/forms"
+)
+
+const (
+	// tplSettingsSecurity template path for the security policies of the organization
+	tplSettingsSecurity base.TplName = "org/settings/security"
+)
+
+// SettingsSecurity render the security policies of the organization and the members
+// who do not comply with them
+func SettingsSecurity(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("org.settings.security")
+	ctx.Data["PageIsSettingsSecurity"] = true
+
+	org := ctx.Org.Organization
+	members, err := org.GetTwoFactorNonCompliantMembers()
+	if err != nil {
+		ctx.ServerError("GetTwoFactorNonCompliantMembers", err)
+		return
+	}
+	ctx.Data["NonCompliantMembers"] = members
+
+	enrolled, err := models.IsTwoFactorEnrolled(ctx.User.ID)
+	if err != nil {
+		ctx.ServerError("IsTwoFactorEnrolled", err)
+		return
+	}
+	ctx.Data["SignedUserHasTwoFactor"] = enrolled
+
+	ctx.HTML(http.StatusOK, tplSettingsSecurity)
+}
+
+// SettingsSecurityPost response for changing the security policies of the organization
+func SettingsSecurityPost(ctx *context.Context) {
+	form := web.GetForm(ctx).(*forms.OrgSecurityForm)
+	org := ctx.Org.Organization
+	oldRequire, oldRemove := org.RequireTwoFactor, org.RemoveTwoFactorNonCompliant
+
+	if err := models.SetOrgTwoFactorPolicy(org, ctx.User, form.RequireTwoFactor, form.RemoveTwoFactorNonCompliant); err != nil {
+		if !models.IsErrTwoFactorNotEnrolled(err) {
+			ctx.ServerError("SetOrgTwoFactorPolicy", err)
+			return
+		}
+		ctx.Flash.Error(ctx.Tr("org.two_factor.doer_not_enrolled"))
+		ctx.Redirect(ctx.Org.OrgLink + "/settings/security")
+		return
+	}
+	if org.RequireTwoFactor != oldRequire || org.RemoveTwoFactorNonCompliant != oldRemove {
+		ctx.Audit(models.OrgTwoFactorPolicyAuditEvent(org, oldRequire, oldRemove))
+	}
+	log.Trace("Two-factor authentication policy of %s updated: %t", org.Name, org.RequireTwoFactor)
+
+	ctx.Flash.Success(ctx.Tr("org.settings.update_setting_success"))
+	ctx.Redirect(ctx.Org.OrgLink + "/settings/security")
+}
@@ -13,6 +13,7 @@ import (
 	"go.wandrs.dev/framework/modules/base"
 	"go.wandrs.dev/framework/modules/context"
 	"go.wandrs.dev/framework/modules/log"
+	"go.wandrs.dev/framework/modules/setting"
 	"go.wandrs.dev/framework/modules/web"
 	"go.wandrs.dev/framework/routers/utils"
 	"go.wandrs.dev/framework/services/forms"
@@ -42,6 +43,7 @@ func Teams(ctx *context.Context) {
 		}
 	}
 	ctx.Data["Teams"] = org.Teams
+	ctx.Data["TeamTree"] = models.TeamTree(org.Teams)
 
 	ctx.HTML(http.StatusOK, tplTeams)
 }
@@ -62,15 +64,24 @@ func TeamsAction(ctx *context.Context) {
 			ctx.Error(http.StatusNotFound)
 			return
 		}
-		err = ctx.Org.Team.AddMember(ctx.User.ID)
+		if err = ctx.Org.Team.AddMember(ctx.User.ID); err == nil {
+			ctx.Audit(models.TeamMemberAuditEvent(models.AuditTeamMemberAdd, ctx.Org.Team, ctx.User))
+		}
 	case "leave":
-		err = ctx.Org.Team.RemoveMember(ctx.User.ID)
+		if err = ctx.Org.Team.RemoveMember(ctx.User.ID); err == nil {
+			ctx.Audit(models.TeamMemberAuditEvent(models.AuditTeamMemberRemove, ctx.Org.Team, ctx.User))
+		}
 	case "remove":
 		if !ctx.Org.IsOwner {
 			ctx.Error(http.StatusNotFound)
 			return
 		}
-		err = ctx.Org.Team.RemoveMember(uid)
+		var u *models.User
+		if u, err = models.GetUserByID(uid); err == nil {
+			if err = ctx.Org.Team.RemoveMember(uid); err == nil {
+				ctx.Audit(models.TeamMemberAuditEvent(models.AuditTeamMemberRemove, ctx.Org.Team, u))
+			}
+		}
 		page = "team"
 	case "add":
 		if !ctx.Org.IsOwner {
@@ -98,8 +109,8 @@ func TeamsAction(ctx *context.Context) {
 
 		if ctx.Org.Team.IsMember(u.ID) {
 			ctx.Flash.Error(ctx.Tr("org.teams.add_duplicate_users"))
-		} else {
-			err = ctx.Org.Team.AddMember(u.ID)
+		} else if err = ctx.Org.Team.AddMember(u.ID); err == nil {
+			ctx.Audit(models.TeamMemberAuditEvent(models.AuditTeamMemberAdd, ctx.Org.Team, u))
 		}
 
 		page = "team"
@@ -134,9 +145,67 @@ func NewTeam(ctx *context.Context) {
 	ctx.Data["PageIsOrgTeams"] = true
 	ctx.Data["PageIsOrgTeamsNew"] = true
 	ctx.Data["Team"] = &models.Team{}
+	if !prepareTeamUnits(ctx, ctx.Data["Team"].(*models.Team)) {
+		return
+	}
 	ctx.HTML(http.StatusOK, tplTeamNew)
 }
 
+// prepareTeamUnits sets the teams which may become the parent of the team, the registered
+// units, the roles of the organization and the access modes of the team to the units for
+// the team form
+func prepareTeamUnits(ctx *context.Context, t *models.Team) bool {
+	roles, err := models.GetOrgRoles(ctx.Org.Organization.ID)
+	if err != nil {
+		ctx.ServerError("GetOrgRoles", err)
+		return false
+	}
+	if t.ID > 0 {
+		if err := t.GetUnits(); err != nil {
+			ctx.ServerError("GetUnits", err)
+			return false
+		}
+	}
+
+	parents, err := t.GetParentCandidates()
+	if err != nil {
+		ctx.ServerError("GetParentCandidates", err)
+		return false
+	}
+
+	modes := make(map[string]models.AccessMode, len(t.Units))
+	for _, u := range t.Units {
+		modes[u.Unit] = u.AccessMode
+	}
+	ctx.Data["ParentTeams"] = parents
+	ctx.Data["Units"] = models.Units()
+	ctx.Data["OrgRoles"] = roles
+	ctx.Data["TeamUnitModes"] = modes
+	return true
+}
+
+// queryUnitAccessModes parses the access modes to the registered units submitted as
+// unit_<name>
+func queryUnitAccessModes(ctx *context.Context) (map[string]models.AccessMode, error) {
+	modes := make(map[string]string)
+	for _, u := range models.Units() {
+		modes[u.Name] = ctx.Query("unit_" + u.Name)
+	}
+	return models.ParseUnitAccessModes(modes)
+}
+
+// parseTeamUnits sets the submitted role and access modes to the units on the team
+func parseTeamUnits(ctx *context.Context, t *models.Team, roleID int64) error {
+	modes, err := queryUnitAccessModes(ctx)
+	if err != nil {
+		return err
+	}
+	t.RoleID = roleID
+	t.Role = nil
+	t.SetUnitAccessModes(modes)
+	return nil
+}
+
 // NewTeamPost response for create new team
 func NewTeamPost(ctx *context.Context) {
 	form := web.GetForm(ctx).(*forms.CreateTeamForm)
@@ -145,24 +214,38 @@ func NewTeamPost(ctx *context.Context) {
 	ctx.Data["PageIsOrgTeamsNew"] = true
 
 	t := &models.Team{
-		OrgID:       ctx.Org.Organization.ID,
-		Name:        form.TeamName,
-		Description: form.Description,
-		Authorize:   models.ParseAccessMode(form.Permission),
+		OrgID:               ctx.Org.Organization.ID,
+		Name:                form.TeamName,
+		Description:         form.Description,
+		Authorize:           models.ParseAccessMode(form.Permission),
+		CanManageOAuth2Apps: form.CanManageOAuth2Apps,
+		ParentID:            form.ParentID,
 	}
 
 	ctx.Data["Team"] = t
+	unitsErr := parseTeamUnits(ctx, t, form.RoleID)
+	if !prepareTeamUnits(ctx, t) {
+		return
+	}
 
 	if ctx.HasError() {
 		ctx.HTML(http.StatusOK, tplTeamNew)
 		return
 	}
+	if unitsErr != nil {
+		ctx.RenderWithErr(ctx.Tr("org.teams.invalid_unit_access", unitsErr.Error()), tplTeamNew, &form)
+		return
+	}
 
 	if err := models.NewTeam(t); err != nil {
-		ctx.Data["Err_TeamName"] = true
 		switch {
 		case models.IsErrTeamAlreadyExist(err):
+			ctx.Data["Err_TeamName"] = true
 			ctx.RenderWithErr(ctx.Tr("form.team_name_been_taken"), tplTeamNew, &form)
+		case models.IsErrOrgRoleNotExist(err):
+			ctx.RenderWithErr(ctx.Tr("org.roles.not_exist"), tplTeamNew, &form)
+		case models.IsErrTeamNotExist(err), models.IsErrInvalidTeamParent(err):
+			ctx.RenderWithErr(ctx.Tr("org.teams.invalid_parent"), tplTeamNew, &form)
 		default:
 			ctx.ServerError("NewTeam", err)
 		}
@@ -181,6 +264,40 @@ func TeamMembers(ctx *context.Context) {
 		ctx.ServerError("GetMembers", err)
 		return
 	}
+	if err := ctx.Org.Team.LoadUnitAccess(); err != nil {
+		ctx.ServerError("LoadUnitAccess", err)
+		return
+	}
+	ctx.Data["Units"] = models.Units()
+	ctx.Data["TeamUnitModes"] = ctx.Org.Team.UnitAccessModes()
+
+	if ctx.Org.Team.ParentID != 0 {
+		parent, err := models.GetTeamByID(ctx.Org.Team.ParentID)
+		if err != nil {
+			ctx.ServerError("GetTeamByID", err)
+			return
+		}
+		ctx.Data["ParentTeam"] = parent
+	}
+	subTeams, err := ctx.Org.Team.GetSubTeams()
+	if err != nil {
+		ctx.ServerError("GetSubTeams", err)
+		return
+	}
+	ctx.Data["SubTeams"] = subTeams
+
+	if ctx.Org.IsOwner {
+		invites, err := models.FindOrgInvites(&models.FindOrgInvitesOptions{
+			OrgID:  ctx.Org.Organization.ID,
+			TeamID: ctx.Org.Team.ID,
+		})
+		if err != nil {
+			ctx.ServerError("FindOrgInvites", err)
+			return
+		}
+		ctx.Data["Invites"] = invites
+		ctx.Data["CanSendEmail"] = setting.MailService != nil
+	}
 	ctx.HTML(http.StatusOK, tplTeamMembers)
 }
 
@@ -190,6 +307,9 @@ func EditTeam(ctx *context.Context) {
 	ctx.Data["PageIsOrgTeams"] = true
 	ctx.Data["team_name"] = ctx.Org.Team.Name
 	ctx.Data["desc"] = ctx.Org.Team.Description
+	if !prepareTeamUnits(ctx, ctx.Org.Team) {
+		return
+	}
 	ctx.HTML(http.StatusOK, tplTeamNew)
 }
 
@@ -203,6 +323,7 @@ func EditTeamPost(ctx *context.Context) {
 
 	isAuthChanged := false
 	isIncludeAllChanged := false
+	var unitsErr error
 	if !t.IsOwnerTeam() {
 		// Validate permission level.
 		auth := models.ParseAccessMode(form.Permission)
@@ -212,19 +333,33 @@ func EditTeamPost(ctx *context.Context) {
 			isAuthChanged = true
 			t.Authorize = auth
 		}
+		t.CanManageOAuth2Apps = form.CanManageOAuth2Apps
+		unitsErr = parseTeamUnits(ctx, t, form.RoleID)
 	}
 	t.Description = form.Description
+	t.ParentID = form.ParentID
+	if !prepareTeamUnits(ctx, t) {
+		return
+	}
 
 	if ctx.HasError() {
 		ctx.HTML(http.StatusOK, tplTeamNew)
 		return
 	}
+	if unitsErr != nil {
+		ctx.RenderWithErr(ctx.Tr("org.teams.invalid_unit_access", unitsErr.Error()), tplTeamNew, &form)
+		return
+	}
 
 	if err := models.UpdateTeam(t, isAuthChanged, isIncludeAllChanged); err != nil {
-		ctx.Data["Err_TeamName"] = true
 		switch {
 		case models.IsErrTeamAlreadyExist(err):
+			ctx.Data["Err_TeamName"] = true
 			ctx.RenderWithErr(ctx.Tr("form.team_name_been_taken"), tplTeamNew, &form)
+		case models.IsErrOrgRoleNotExist(err):
+			ctx.RenderWithErr(ctx.Tr("org.roles.not_exist"), tplTeamNew, &form)
+		case models.IsErrTeamNotExist(err), models.IsErrInvalidTeamParent(err):
+			ctx.RenderWithErr(ctx.Tr("org.teams.invalid_parent"), tplTeamNew, &form)
 		default:
 			ctx.ServerError("UpdateTeam", err)
 		}
@@ -15,6 +15,7 @@ import (
 	// to registers all internal adapters
 
 	"go.wandrs.dev/captcha"
+	"go.wandrs.dev/framework/modules/auth/webauthn"
 	"go.wandrs.dev/framework/modules/context"
 	"go.wandrs.dev/framework/modules/httpcache"
 	"go.wandrs.dev/framework/modules/log"
@@ -28,6 +29,7 @@ import (
 	"go.wandrs.dev/framework/modules/web"
 	"go.wandrs.dev/framework/routers"
 	"go.wandrs.dev/framework/routers/admin"
+	scimv2 "go.wandrs.dev/framework/routers/api/scim/v2"
 	apiv1 "go.wandrs.dev/framework/routers/api/v1"
 	"go.wandrs.dev/framework/routers/api/v1/misc"
 	"go.wandrs.dev/framework/routers/dev"
@@ -44,7 +46,6 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/tstranex/u2f"
 )
 
 const (
@@ -136,6 +137,9 @@ func NormalRoutes() *web.Route {
 	r.Mount("/", WebRoutes())
 	r.Mount("/api/v1", apiv1.Routes())
 	r.Mount("/api/internal", private.Routes())
+	if setting.SCIM.Enabled {
+		r.Mount(scimv2.Prefix, scimv2.Routes())
+	}
 	return r
 }
 
@@ -176,7 +180,7 @@ func WebRoutes() *web.Route {
 		http.Redirect(w, req, path.Join(setting.StaticURLPrefix, "/assets/img/apple-touch-icon.png"), 301)
 	})
 
-	gob.Register(&u2f.Challenge{})
+	gob.Register(&webauthn.SessionData{})
 
 	common := []interface{}{}
 
@@ -300,6 +304,10 @@ func RegisterRoutes(m *web.Route) {
 			m.Get("/{provider}", user.SignInOAuth)
 			m.Get("/{provider}/callback", user.SignInOAuthCallback)
 		})
+		m.Group("/saml/{provider}", func() {
+			m.Get("", user.SignInSAML)
+			m.Post("/acs", user.SAMLAssertionConsumer)
+		})
 		m.Get("/link_account", user.LinkAccount)
 		m.Post("/link_account_signin", bindIgnErr(forms.SignInForm{}), user.LinkAccountPostSignIn)
 		m.Post("/link_account_signup", bindIgnErr(forms.RegisterForm{}), user.LinkAccountPostRegister)
@@ -309,10 +317,12 @@ func RegisterRoutes(m *web.Route) {
 			m.Get("/scratch", user.TwoFactorScratch)
 			m.Post("/scratch", bindIgnErr(forms.TwoFactorScratchAuthForm{}), user.TwoFactorScratchPost)
 		})
-		m.Group("/u2f", func() {
-			m.Get("", user.U2F)
-			m.Get("/challenge", user.U2FChallenge)
-			m.Post("/sign", bindIgnErr(u2f.SignResponse{}), user.U2FSign)
+		m.Group("/webauthn", func() {
+			m.Get("", user.WebAuthn)
+			m.Get("/assertion", user.WebAuthnLoginAssertion)
+			m.Post("/assertion", bindIgnErr(webauthn.CredentialAssertionResponse{}), user.WebAuthnLoginAssertionPost)
+			m.Get("/passkey/assertion", user.WebAuthnPasskeyAssertion)
+			m.Post("/passkey/assertion", bindIgnErr(webauthn.CredentialAssertionResponse{}), user.WebAuthnPasskeyAssertionPost)
 		})
 	}, reqSignOut)
 
@@ -321,15 +331,25 @@ func RegisterRoutes(m *web.Route) {
 		m.Post("/grant", bindIgnErr(forms.GrantApplicationForm{}), user.GrantApplicationOAuth)
 		// TODO manage redirection
 		m.Post("/authorize", bindIgnErr(forms.AuthorizationForm{}), user.AuthorizeOAuth)
-	}, ignSignInAndCsrf, reqSignIn)
+	}, ignSignInAndCsrf, reqSignIn, context.NotImpersonated)
+	m.Combo("/login/device", reqSignIn, context.NotImpersonated).Get(user.DeviceOAuth).
+		Post(bindIgnErr(forms.DeviceUserCodeForm{}), user.DeviceOAuthPost)
+	m.Post("/login/oauth/device_authorization", corsHandler, bindIgnErr(forms.DeviceAuthorizationForm{}), ignSignInAndCsrf, user.DeviceAuthorizationOAuth)
 	m.Get("/login/oauth/userinfo", ignSignInAndCsrf, user.InfoOAuth)
+	m.Get("/login/oauth/keys", ignSignInAndCsrf, user.OIDCKeys)
+	m.Combo("/login/oauth/logout", ignSignInAndCsrf, bindIgnErr(forms.EndSessionForm{})).
+		Get(user.EndSessionOAuth).Post(user.EndSessionOAuth)
+	m.Post("/login/oauth/logout/confirm", reqSignIn, bindIgnErr(forms.EndSessionForm{}), user.EndSessionOAuthPost)
 	m.Post("/login/oauth/access_token", corsHandler, bindIgnErr(forms.AccessTokenForm{}), ignSignInAndCsrf, user.AccessTokenOAuth)
+	m.Post("/login/oauth/introspect", corsHandler, bindIgnErr(forms.OAuth2TokenForm{}), ignSignInAndCsrf, user.IntrospectOAuth)
+	m.Post("/login/oauth/revoke", corsHandler, bindIgnErr(forms.OAuth2TokenForm{}), ignSignInAndCsrf, user.RevokeOAuth)
+	m.Get("/user/saml/{provider}/metadata", ignSignInAndCsrf, user.SAMLMetadata)
 
 	m.Group("/user/settings", func() {
 		m.Get("", userSetting.Profile)
 		m.Post("", bindIgnErr(forms.UpdateProfileForm{}), userSetting.ProfilePost)
-		m.Get("/change_password", user.MustChangePassword)
-		m.Post("/change_password", bindIgnErr(forms.MustChangePasswordForm{}), user.MustChangePasswordPost)
+		m.Get("/change_password", context.NotImpersonated, user.MustChangePassword)
+		m.Post("/change_password", context.NotImpersonated, bindIgnErr(forms.MustChangePasswordForm{}), user.MustChangePasswordPost)
 		m.Post("/avatar", bindIgnErr(forms.AvatarForm{}), userSetting.AvatarPost)
 		m.Post("/avatar/delete", userSetting.DeleteAvatar)
 		m.Group("/account", func() {
@@ -338,7 +358,7 @@ func RegisterRoutes(m *web.Route) {
 			m.Post("/email/delete", userSetting.DeleteEmail)
 			m.Post("/delete", userSetting.DeleteAccount)
 			m.Post("/theme", bindIgnErr(forms.UpdateThemeForm{}), userSetting.UpdateUIThemePost)
-		})
+		}, context.NotImpersonated)
 		m.Group("/security", func() {
 			m.Get("", userSetting.Security)
 			m.Group("/two_factor", func() {
@@ -347,10 +367,10 @@ func RegisterRoutes(m *web.Route) {
 				m.Get("/enroll", userSetting.EnrollTwoFactor)
 				m.Post("/enroll", bindIgnErr(forms.TwoFactorAuthForm{}), userSetting.EnrollTwoFactorPost)
 			})
-			m.Group("/u2f", func() {
-				m.Post("/request_register", bindIgnErr(forms.U2FRegistrationForm{}), userSetting.U2FRegister)
-				m.Post("/register", bindIgnErr(u2f.RegisterResponse{}), userSetting.U2FRegisterPost)
-				m.Post("/delete", bindIgnErr(forms.U2FDeleteForm{}), userSetting.U2FDelete)
+			m.Group("/webauthn", func() {
+				m.Post("/request_register", bindIgnErr(forms.WebAuthnRegistrationForm{}), userSetting.WebAuthnRegister)
+				m.Post("/register", bindIgnErr(webauthn.CredentialCreationResponse{}), userSetting.WebAuthnRegisterPost)
+				m.Post("/delete", bindIgnErr(forms.WebAuthnDeleteForm{}), userSetting.WebAuthnDelete)
 			})
 			m.Group("/openid", func() {
 				m.Post("", bindIgnErr(forms.AddOpenIDForm{}), userSetting.OpenIDPost)
@@ -358,7 +378,11 @@ func RegisterRoutes(m *web.Route) {
 				m.Post("/toggle_visibility", userSetting.ToggleOpenIDVisibility)
 			}, openIDSignInEnabled)
 			m.Post("/account_link", userSetting.DeleteAccountLink)
-		})
+			m.Group("/sessions", func() {
+				m.Post("/revoke", userSetting.RevokeSession)
+				m.Post("/revoke_others", userSetting.RevokeOtherSessions)
+			})
+		}, context.NotImpersonated)
 		m.Group("/applications/oauth2", func() {
 			m.Get("/{id}", userSetting.OAuth2ApplicationShow)
 			m.Post("/{id}", bindIgnErr(forms.EditOAuth2ApplicationForm{}), userSetting.OAuthApplicationsEdit)
@@ -366,10 +390,10 @@ func RegisterRoutes(m *web.Route) {
 			m.Post("", bindIgnErr(forms.EditOAuth2ApplicationForm{}), userSetting.OAuthApplicationsPost)
 			m.Post("/delete", userSetting.DeleteOAuth2Application)
 			m.Post("/revoke", userSetting.RevokeOAuth2Grant)
-		})
-		m.Combo("/applications").Get(userSetting.Applications).
+		}, context.NotImpersonated)
+		m.Combo("/applications", context.NotImpersonated).Get(userSetting.Applications).
 			Post(bindIgnErr(forms.NewAccessTokenForm{}), userSetting.ApplicationsPost)
-		m.Post("/applications/delete", userSetting.DeleteApplication)
+		m.Post("/applications/delete", context.NotImpersonated, userSetting.DeleteApplication)
 		m.Get("/organization", userSetting.Organization)
 	}, reqSignIn, func(ctx *context.Context) {
 		ctx.Data["PageIsUserSettings"] = true
@@ -388,6 +412,7 @@ func RegisterRoutes(m *web.Route) {
 		m.Get("/forgot_password", user.ForgotPasswd)
 		m.Post("/forgot_password", user.ForgotPasswdPost)
 		m.Post("/logout", user.SignOut)
+		m.Post("/impersonate/stop", user.StopImpersonating)
 	})
 	// ***** END: User *****
 
@@ -418,6 +443,8 @@ func RegisterRoutes(m *web.Route) {
 			m.Combo("/new").Get(admin.NewUser).Post(bindIgnErr(forms.AdminCreateUserForm{}), admin.NewUserPost)
 			m.Combo("/{userid}").Get(admin.EditUser).Post(bindIgnErr(forms.AdminEditUserForm{}), admin.EditUserPost)
 			m.Post("/{userid}/delete", admin.DeleteUser)
+			m.Post("/{userid}/unlock", admin.UnlockUser)
+			m.Post("/{userid}/impersonate", admin.ImpersonateUser)
 		})
 
 		m.Group("/emails", func() {
@@ -437,11 +464,23 @@ func RegisterRoutes(m *web.Route) {
 			m.Post("/{authid}/delete", admin.DeleteAuthSource)
 		})
 
+		m.Group("/applications", func() {
+			m.Get("", admin.Applications)
+			m.Combo("/new").Get(admin.NewApplication).
+				Post(bindIgnErr(forms.AdminEditOAuth2ApplicationForm{}), admin.NewApplicationPost)
+			m.Combo("/{id}").Get(admin.EditApplication).
+				Post(bindIgnErr(forms.AdminEditOAuth2ApplicationForm{}), admin.EditApplicationPost)
+			m.Post("/{id}/regenerate_secret", admin.RegenerateApplicationSecret)
+			m.Post("/{id}/delete", admin.DeleteApplication)
+		})
+
 		m.Group("/notices", func() {
 			m.Get("", admin.Notices)
 			m.Post("/delete", admin.DeleteNotices)
 			m.Post("/empty", admin.EmptyNotices)
 		})
+
+		m.Get("/audit", admin.Audit)
 	}, adminReq)
 	// ***** END: Admin *****
 
@@ -458,6 +497,9 @@ func RegisterRoutes(m *web.Route) {
 	}
 
 	// ***** START: Organization *****
+	m.Combo("/org/invite/{code}").Get(org.Invite).
+		Post(reqSignIn, org.InvitePost)
+
 	m.Group("/org", func() {
 		m.Group("", func() {
 			m.Get("/create", org.Create)
@@ -471,6 +513,11 @@ func RegisterRoutes(m *web.Route) {
 			m.Get("/teams", org.Teams)
 		}, context.OrgAssignment(true, false, true))
 
+		m.Group("/{org}", func() {
+			m.Post("/join_request", bindIgnErr(forms.OrgJoinRequestForm{}), org.JoinRequestPost)
+			m.Post("/join_request/cancel", org.CancelJoinRequest)
+		}, context.OrgAssignment())
+
 		m.Group("/{org}", func() {
 			m.Get("/teams/{team}", org.TeamMembers)
 		}, context.OrgAssignment(true, false, true))
@@ -481,6 +528,8 @@ func RegisterRoutes(m *web.Route) {
 			m.Get("/teams/{team}/edit", org.EditTeam)
 			m.Post("/teams/{team}/edit", bindIgnErr(forms.CreateTeamForm{}), org.EditTeamPost)
 			m.Post("/teams/{team}/delete", org.DeleteTeam)
+			m.Post("/teams/{team}/invites", bindIgnErr(forms.TeamInviteForm{}), org.TeamInvitePost)
+			m.Post("/teams/{team}/invites/{id}/revoke", org.RevokeTeamInvite)
 
 			m.Group("/settings", func() {
 				m.Combo("").Get(org.Settings).
@@ -488,9 +537,39 @@ func RegisterRoutes(m *web.Route) {
 				m.Post("/avatar", bindIgnErr(forms.AvatarForm{}), org.SettingsAvatar)
 				m.Post("/avatar/delete", org.SettingsDeleteAvatar)
 
+				m.Group("/roles", func() {
+					m.Get("", org.Roles)
+					m.Combo("/new").Get(org.NewRole).
+						Post(bindIgnErr(forms.OrgRoleForm{}), org.NewRolePost)
+					m.Combo("/{id}").Get(org.EditRole).
+						Post(bindIgnErr(forms.OrgRoleForm{}), org.EditRolePost)
+					m.Post("/{id}/delete", org.DeleteRole)
+				})
+
+				m.Combo("/security").Get(org.SettingsSecurity).
+					Post(bindIgnErr(forms.OrgSecurityForm{}), org.SettingsSecurityPost)
+
+				m.Group("/join_requests", func() {
+					m.Get("", org.JoinRequests)
+					m.Post("/{id}/approve", org.ApproveJoinRequest)
+					m.Post("/{id}/reject", org.RejectJoinRequest)
+				})
+
+				m.Get("/audit", org.Audit)
 				m.Route("/delete", "GET,POST", org.SettingsDelete)
 			})
 		}, context.OrgAssignment(true, true))
+
+		m.Group("/{org}/settings/applications", func() {
+			m.Get("", org.Applications)
+			m.Group("/oauth2", func() {
+				m.Get("/{id}", org.OAuth2ApplicationShow)
+				m.Post("/{id}", bindIgnErr(forms.EditOAuth2ApplicationForm{}), org.OAuthApplicationsEdit)
+				m.Post("/{id}/regenerate_secret", org.OAuthApplicationsRegenerateSecret)
+				m.Post("", bindIgnErr(forms.EditOAuth2ApplicationForm{}), org.OAuthApplicationsPost)
+				m.Post("/delete", org.DeleteOAuth2Application)
+			})
+		}, context.NotImpersonated, context.OrgAssignment(true), org.MustManageOAuth2Applications)
 	}, reqSignIn)
 	// ***** END: Organization *****
 
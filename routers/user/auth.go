@@ -13,7 +13,10 @@ import (
 	"strings"
 
 	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/auth/lockout"
 	"go.wandrs.dev/framework/modules/auth/oauth2"
+	"go.wandrs.dev/framework/modules/auth/sso"
+	"go.wandrs.dev/framework/modules/auth/webauthn"
 	"go.wandrs.dev/framework/modules/base"
 	"go.wandrs.dev/framework/modules/context"
 	"go.wandrs.dev/framework/modules/hcaptcha"
@@ -30,7 +33,6 @@ import (
 	"go.wandrs.dev/framework/services/mailer"
 
 	"github.com/markbates/goth"
-	"github.com/tstranex/u2f"
 )
 
 const (
@@ -47,7 +49,8 @@ const (
 	tplTwofa          base.TplName = "user/auth/twofa"
 	tplTwofaScratch   base.TplName = "user/auth/twofa_scratch"
 	tplLinkAccount    base.TplName = "user/auth/link_account"
-	tplU2F            base.TplName = "user/auth/u2f"
+	tplWebAuthn       base.TplName = "user/auth/webauthn"
+	tplSignOut        base.TplName = "user/auth/signout"
 )
 
 // AutoSignIn reads cookie and try to auto-login.
@@ -140,6 +143,11 @@ func SignIn(ctx *context.Context) {
 	}
 	ctx.Data["OrderedOAuth2Names"] = orderedOAuth2Names
 	ctx.Data["OAuth2Providers"] = oauth2Providers
+	ctx.Data["SAMLSources"], err = models.ActiveLoginSources(models.LoginSAML)
+	if err != nil {
+		ctx.ServerError("ActiveLoginSources", err)
+		return
+	}
 	ctx.Data["Title"] = ctx.Tr("sign_in")
 	ctx.Data["SignInLink"] = setting.AppSubURL + "/user/login"
 	ctx.Data["PageIsSignIn"] = true
@@ -160,6 +168,11 @@ func SignInPost(ctx *context.Context) {
 	}
 	ctx.Data["OrderedOAuth2Names"] = orderedOAuth2Names
 	ctx.Data["OAuth2Providers"] = oauth2Providers
+	ctx.Data["SAMLSources"], err = models.ActiveLoginSources(models.LoginSAML)
+	if err != nil {
+		ctx.ServerError("ActiveLoginSources", err)
+		return
+	}
 	ctx.Data["Title"] = ctx.Tr("sign_in")
 	ctx.Data["SignInLink"] = setting.AppSubURL + "/user/login"
 	ctx.Data["PageIsSignIn"] = true
@@ -172,11 +185,20 @@ func SignInPost(ctx *context.Context) {
 	}
 
 	form := web.GetForm(ctx).(*forms.SignInForm)
+	if lockout.IsIPBlocked(ctx.RemoteAddr()) {
+		log.Info("Blocked authentication attempt for %s from %s", form.UserName, ctx.RemoteAddr())
+		ctx.RenderWithErr(ctx.Tr("auth.too_many_failed_attempts"), tplSignIn, &form)
+		return
+	}
 	u, err := models.UserSignIn(form.UserName, form.Password)
 	if err != nil {
 		if models.IsErrUserNotExist(err) {
+			models.RecordFailedSignInFromIP(ctx.RemoteAddr())
 			ctx.RenderWithErr(ctx.Tr("form.username_password_incorrect"), tplSignIn, &form)
 			log.Info("Failed authentication attempt for %s from %s: %v", form.UserName, ctx.RemoteAddr(), err)
+		} else if models.IsErrUserLockedOut(err) {
+			ctx.RenderWithErr(ctx.Tr("auth.too_many_failed_attempts"), tplSignIn, &form)
+			log.Info("Failed authentication attempt for %s from %s: %v", form.UserName, ctx.RemoteAddr(), err)
 		} else if models.IsErrEmailAlreadyUsed(err) {
 			ctx.RenderWithErr(ctx.Tr("form.email_been_used"), tplSignIn, &form)
 			log.Info("Failed authentication attempt for %s from %s: %v", form.UserName, ctx.RemoteAddr(), err)
@@ -224,13 +246,7 @@ func SignInPost(ctx *context.Context) {
 		return
 	}
 
-	regs, err := models.GetU2FRegistrationsByUID(u.ID)
-	if err == nil && len(regs) > 0 {
-		ctx.Redirect(setting.AppSubURL + "/user/u2f")
-		return
-	}
-
-	ctx.Redirect(setting.AppSubURL + "/user/two_factor")
+	redirectToSecondFactor(ctx, u.ID)
 }
 
 // TwoFactor shows the user a two-factor authentication page.
@@ -270,6 +286,11 @@ func TwoFactorPost(ctx *context.Context) {
 		return
 	}
 
+	if isSecondFactorLocked(ctx, id) {
+		ctx.RenderWithErr(ctx.Tr("auth.too_many_failed_attempts"), tplTwofa, forms.TwoFactorAuthForm{})
+		return
+	}
+
 	// Validate the passcode with the stored TOTP secret.
 	ok, err := twofa.ValidateTOTP(form.Passcode)
 	if err != nil {
@@ -309,9 +330,31 @@ func TwoFactorPost(ctx *context.Context) {
 		return
 	}
 
+	recordFailedSecondFactor(ctx, id)
 	ctx.RenderWithErr(ctx.Tr("auth.twofa_passcode_incorrect"), tplTwofa, forms.TwoFactorAuthForm{})
 }
 
+// isSecondFactorLocked returns true if the user of the 2FA session or its IP address is locked out
+func isSecondFactorLocked(ctx *context.Context, uid int64) bool {
+	if lockout.IsAccountLocked(uid) || lockout.IsIPBlocked(ctx.RemoteAddr()) {
+		log.Info("Blocked second factor attempt for user %d from %s", uid, ctx.RemoteAddr())
+		return true
+	}
+	return false
+}
+
+// recordFailedSecondFactor records a failed second factor attempt of the user of the 2FA session
+func recordFailedSecondFactor(ctx *context.Context, uid int64) {
+	models.RecordFailedSignInFromIP(ctx.RemoteAddr())
+	u, err := models.GetUserByID(uid)
+	if err != nil {
+		log.Error("GetUserByID: %v", err)
+		return
+	}
+	log.Info("Failed second factor attempt for %s from %s", u.Name, ctx.RemoteAddr())
+	models.RecordFailedSignIn(u)
+}
+
 // TwoFactorScratch shows the scratch code form for two-factor authentication.
 func TwoFactorScratch(ctx *context.Context) {
 	ctx.Data["Title"] = ctx.Tr("twofa_scratch")
@@ -349,6 +392,11 @@ func TwoFactorScratchPost(ctx *context.Context) {
 		return
 	}
 
+	if isSecondFactorLocked(ctx, id) {
+		ctx.RenderWithErr(ctx.Tr("auth.too_many_failed_attempts"), tplTwofaScratch, forms.TwoFactorScratchAuthForm{})
+		return
+	}
+
 	// Validate the passcode with the stored TOTP secret.
 	if twofa.VerifyScratchToken(form.Token) {
 		// Invalidate the scratch token.
@@ -375,13 +423,25 @@ func TwoFactorScratchPost(ctx *context.Context) {
 		return
 	}
 
+	recordFailedSecondFactor(ctx, id)
 	ctx.RenderWithErr(ctx.Tr("auth.twofa_scratch_token_incorrect"), tplTwofaScratch, forms.TwoFactorScratchAuthForm{})
 }
 
-// U2F shows the U2F login page
-func U2F(ctx *context.Context) {
+// redirectToSecondFactor sends the user to the second factor page, WebAuthn is preferred over TOTP when credentials are registered
+func redirectToSecondFactor(ctx *context.Context, uid int64) {
+	if has, err := models.ExistsWebAuthnCredentialsForUID(uid); err != nil {
+		log.Error("ExistsWebAuthnCredentialsForUID: %v", err)
+	} else if has {
+		ctx.Redirect(setting.AppSubURL + "/user/webauthn")
+		return
+	}
+
+	ctx.Redirect(setting.AppSubURL + "/user/two_factor")
+}
+
+// WebAuthn shows the WebAuthn login page
+func WebAuthn(ctx *context.Context) {
 	ctx.Data["Title"] = ctx.Tr("twofa")
-	ctx.Data["RequireU2F"] = true
 	// Check auto-login.
 	if checkAutoLogin(ctx) {
 		return
@@ -389,105 +449,194 @@ func U2F(ctx *context.Context) {
 
 	// Ensure user is in a 2FA session.
 	if ctx.Session.Get("twofaUid") == nil {
-		ctx.ServerError("UserSignIn", errors.New("not in U2F session"))
+		ctx.ServerError("UserSignIn", errors.New("not in WebAuthn session"))
 		return
 	}
 
-	ctx.HTML(http.StatusOK, tplU2F)
+	ctx.HTML(http.StatusOK, tplWebAuthn)
 }
 
-// U2FChallenge submits a sign challenge to the browser
-func U2FChallenge(ctx *context.Context) {
-	// Ensure user is in a U2F session.
-	idSess := ctx.Session.Get("twofaUid")
-	if idSess == nil {
-		ctx.ServerError("UserSignIn", errors.New("not in U2F session"))
+// WebAuthnLoginAssertion submits the authentication options to the browser
+func WebAuthnLoginAssertion(ctx *context.Context) {
+	// Ensure user is in a WebAuthn session.
+	id, ok := ctx.Session.Get("twofaUid").(int64)
+	if !ok {
+		ctx.ServerError("UserSignIn", errors.New("not in WebAuthn session"))
 		return
 	}
-	id := idSess.(int64)
-	regs, err := models.GetU2FRegistrationsByUID(id)
+	creds, err := models.GetWebAuthnCredentialsByUID(id)
 	if err != nil {
 		ctx.ServerError("UserSignIn", err)
 		return
 	}
-	if len(regs) == 0 {
+	if len(creds) == 0 {
 		ctx.ServerError("UserSignIn", errors.New("no device registered"))
 		return
 	}
-	challenge, err := u2f.NewChallenge(setting.U2F.AppID, setting.U2F.TrustedFacets)
+	assertion, sessionData, err := webauthn.NewConfig().BeginLogin(id, creds.ToCredentialDescriptors(), creds.HasLegacy())
 	if err != nil {
-		ctx.ServerError("u2f.NewChallenge", err)
+		ctx.ServerError("webauthn.BeginLogin", err)
 		return
 	}
-	if err := ctx.Session.Set("u2fChallenge", challenge); err != nil {
-		ctx.ServerError("UserSignIn: unable to set u2fChallenge in session", err)
+	if err := ctx.Session.Set("webauthnAssertion", sessionData); err != nil {
+		ctx.ServerError("UserSignIn: unable to set webauthnAssertion in session", err)
 		return
 	}
 	if err := ctx.Session.Release(); err != nil {
 		ctx.ServerError("UserSignIn: unable to store session", err)
+		return
 	}
 
-	ctx.JSON(http.StatusOK, challenge.SignRequest(regs.ToRegistrations()))
+	ctx.JSON(http.StatusOK, assertion)
 }
 
-// U2FSign authenticates the user by signResp
-func U2FSign(ctx *context.Context) {
-	signResp := web.GetForm(ctx).(*u2f.SignResponse)
-	challSess := ctx.Session.Get("u2fChallenge")
-	idSess := ctx.Session.Get("twofaUid")
-	if challSess == nil || idSess == nil {
-		ctx.ServerError("UserSignIn", errors.New("not in U2F session"))
+// WebAuthnLoginAssertionPost authenticates the user by the assertion of the authenticator
+func WebAuthnLoginAssertionPost(ctx *context.Context) {
+	resp := web.GetForm(ctx).(*webauthn.CredentialAssertionResponse)
+	sessionData, ok := ctx.Session.Get("webauthnAssertion").(*webauthn.SessionData)
+	id, idOk := ctx.Session.Get("twofaUid").(int64)
+	if !ok || !idOk || sessionData.UserID != id {
+		ctx.ServerError("UserSignIn", errors.New("not in WebAuthn session"))
 		return
 	}
-	challenge := challSess.(*u2f.Challenge)
-	id := idSess.(int64)
-	regs, err := models.GetU2FRegistrationsByUID(id)
+	// A challenge can only be answered once
+	_ = ctx.Session.Delete("webauthnAssertion")
+
+	cred, err := models.GetWebAuthnCredentialByCredID(id, resp.RawID)
 	if err != nil {
+		if models.IsErrWebAuthnCredentialNotExist(err) {
+			ctx.Error(http.StatusUnauthorized)
+			return
+		}
 		ctx.ServerError("UserSignIn", err)
 		return
 	}
-	for _, reg := range regs {
-		r, err := reg.Parse()
+	signCount, err := webauthn.NewConfig().FinishLogin(sessionData, resp, cred.PublicKey, cred.SignCount)
+	if err != nil {
+		log.Info("WebAuthn assertion of credential %d of user %d failed: %v", cred.ID, id, err)
+		ctx.Error(http.StatusUnauthorized)
+		return
+	}
+	cred.SignCount = signCount
+	if err := cred.UpdateSignCount(); err != nil {
+		ctx.ServerError("UserSignIn", err)
+		return
+	}
+
+	user, err := models.GetUserByID(id)
+	if err != nil {
+		ctx.ServerError("UserSignIn", err)
+		return
+	}
+	remember := ctx.Session.Get("twofaRemember").(bool)
+
+	if ctx.Session.Get("linkAccount") != nil {
+		gothUser := ctx.Session.Get("linkAccountGothUser")
+		if gothUser == nil {
+			ctx.ServerError("UserSignIn", errors.New("not in LinkAccount session"))
+			return
+		}
+
+		err = externalaccount.LinkAccountToUser(user, gothUser.(goth.User))
 		if err != nil {
-			log.Fatal("parsing u2f registration: %v", err)
-			continue
+			ctx.ServerError("UserSignIn", err)
+			return
 		}
-		newCounter, authErr := r.Authenticate(*signResp, *challenge, reg.Counter)
-		if authErr == nil {
-			reg.Counter = newCounter
-			user, err := models.GetUserByID(id)
-			if err != nil {
-				ctx.ServerError("UserSignIn", err)
-				return
-			}
-			remember := ctx.Session.Get("twofaRemember").(bool)
-			if err := reg.UpdateCounter(); err != nil {
-				ctx.ServerError("UserSignIn", err)
-				return
-			}
+	}
+	redirect := handleSignInFull(ctx, user, remember, false)
+	if redirect == "" {
+		redirect = setting.AppSubURL + "/"
+	}
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"redirect": redirect,
+	})
+}
 
-			if ctx.Session.Get("linkAccount") != nil {
-				gothUser := ctx.Session.Get("linkAccountGothUser")
-				if gothUser == nil {
-					ctx.ServerError("UserSignIn", errors.New("not in LinkAccount session"))
-					return
-				}
+// WebAuthnPasskeyAssertion submits the options of a passwordless sign in with a passkey to the browser
+func WebAuthnPasskeyAssertion(ctx *context.Context) {
+	assertion, sessionData, err := webauthn.NewConfig().BeginDiscoverableLogin()
+	if err != nil {
+		ctx.ServerError("webauthn.BeginDiscoverableLogin", err)
+		return
+	}
+	if err := ctx.Session.Set("webauthnPasskeyAssertion", sessionData); err != nil {
+		ctx.ServerError("UserSignIn: unable to set webauthnPasskeyAssertion in session", err)
+		return
+	}
+	if err := ctx.Session.Release(); err != nil {
+		ctx.ServerError("UserSignIn: unable to store session", err)
+		return
+	}
 
-				err = externalaccount.LinkAccountToUser(user, gothUser.(goth.User))
-				if err != nil {
-					ctx.ServerError("UserSignIn", err)
-					return
-				}
-			}
-			redirect := handleSignInFull(ctx, user, remember, false)
-			if redirect == "" {
-				redirect = setting.AppSubURL + "/"
-			}
-			ctx.PlainText(200, []byte(redirect))
+	ctx.JSON(http.StatusOK, assertion)
+}
+
+// WebAuthnPasskeyAssertionPost signs in the user the passkey of the assertion belongs to
+func WebAuthnPasskeyAssertionPost(ctx *context.Context) {
+	resp := web.GetForm(ctx).(*webauthn.CredentialAssertionResponse)
+	sessionData, ok := ctx.Session.Get("webauthnPasskeyAssertion").(*webauthn.SessionData)
+	if !ok {
+		ctx.ServerError("UserSignIn", errors.New("not in WebAuthn session"))
+		return
+	}
+	// A challenge can only be answered once
+	_ = ctx.Session.Delete("webauthnPasskeyAssertion")
+
+	// Only discoverable credentials carry the handle of the user they were created for
+	uid, ok := models.WebAuthnUserIDFromHandle(resp.Response.UserHandle)
+	if !ok {
+		ctx.Error(http.StatusUnauthorized)
+		return
+	}
+	cred, err := models.GetWebAuthnCredentialByCredID(uid, resp.RawID)
+	if err != nil {
+		if models.IsErrWebAuthnCredentialNotExist(err) {
+			ctx.Error(http.StatusUnauthorized)
 			return
 		}
+		ctx.ServerError("UserSignIn", err)
+		return
+	}
+	signCount, err := webauthn.NewConfig().FinishLogin(sessionData, resp, cred.PublicKey, cred.SignCount)
+	if err != nil {
+		log.Info("Failed passkey authentication attempt with credential %d of user %d from %s: %v", cred.ID, uid, ctx.RemoteAddr(), err)
+		ctx.Error(http.StatusUnauthorized)
+		return
+	}
+	cred.SignCount = signCount
+	if err := cred.UpdateSignCount(); err != nil {
+		ctx.ServerError("UserSignIn", err)
+		return
+	}
+
+	u, err := models.GetUserByID(uid)
+	if err != nil {
+		ctx.ServerError("UserSignIn", err)
+		return
+	}
+	if u.ProhibitLogin {
+		log.Info("Failed passkey authentication attempt for %s from %s: %v", u.Name, ctx.RemoteAddr(), models.ErrUserProhibitLogin{UID: u.ID, Name: u.Name})
+		ctx.JSON(http.StatusForbidden, map[string]interface{}{
+			"error": ctx.Tr("auth.prohibit_login_desc"),
+		})
+		return
+	}
+	if !u.IsActive {
+		log.Info("Failed passkey authentication attempt for %s from %s: %v", u.Name, ctx.RemoteAddr(), models.ErrUserInactive{UID: u.ID, Name: u.Name})
+		ctx.JSON(http.StatusForbidden, map[string]interface{}{
+			"error": ctx.Tr("auth.passkey_account_inactive"),
+		})
+		return
 	}
-	ctx.Error(http.StatusUnauthorized)
+
+	// The passkey proves possession and user verification, so no second factor is asked for
+	redirect := handleSignInFull(ctx, u, ctx.QueryBool("remember"), false)
+	if redirect == "" {
+		redirect = setting.AppSubURL + "/"
+	}
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"redirect": redirect,
+	})
 }
 
 // This handles the final part of the sign-in process of the user.
@@ -509,8 +658,10 @@ func handleSignInFull(ctx *context.Context, u *models.User, remember bool, obeyR
 	_ = ctx.Session.Delete("openid_determined_username")
 	_ = ctx.Session.Delete("twofaUid")
 	_ = ctx.Session.Delete("twofaRemember")
-	_ = ctx.Session.Delete("u2fChallenge")
+	_ = ctx.Session.Delete("webauthnAssertion")
+	_ = ctx.Session.Delete("webauthnPasskeyAssertion")
 	_ = ctx.Session.Delete("linkAccount")
+	lockout.ResetAccount(u.ID)
 	if err := ctx.Session.Set("uid", u.ID); err != nil {
 		log.Error("Error setting uid %d in session: %v", u.ID, err)
 	}
@@ -654,12 +805,19 @@ func SignInOAuthCallback(ctx *context.Context) {
 			showLinkingLogin(ctx, gothUser)
 			return
 		}
+	} else if err := models.SyncOAuth2UserGroups(loginSource, u, gothUser); err != nil {
+		ctx.ServerError("SyncOAuth2UserGroups", err)
+		return
 	}
 
 	handleOAuth2SignIn(ctx, u, gothUser)
 }
 
 func getUserName(gothUser *goth.User) string {
+	// SAML login sources map the user name from the assertion themselves
+	if loginSource, err := models.GetActiveExternalLoginSourceByName(gothUser.Provider); err == nil && loginSource.IsSAML() {
+		return gothUser.NickName
+	}
 	switch setting.OAuth2Client.Username {
 	case setting.OAuth2UsernameEmail:
 		return strings.Split(gothUser.Email, "@")[0]
@@ -756,14 +914,7 @@ func handleOAuth2SignIn(ctx *context.Context, u *models.User, gothUser goth.User
 		log.Error("Error storing session: %v", err)
 	}
 
-	// If U2F is enrolled -> Redirect to U2F instead
-	regs, err := models.GetU2FRegistrationsByUID(u.ID)
-	if err == nil && len(regs) > 0 {
-		ctx.Redirect(setting.AppSubURL + "/user/u2f")
-		return
-	}
-
-	ctx.Redirect(setting.AppSubURL + "/user/two_factor")
+	redirectToSecondFactor(ctx, u.ID)
 }
 
 // OAuth2UserLoginCallback attempts to handle the callback from the OAuth2 provider and if successful
@@ -896,11 +1047,20 @@ func LinkAccountPostSignIn(ctx *context.Context) {
 		return
 	}
 
+	if lockout.IsIPBlocked(ctx.RemoteAddr()) {
+		ctx.Data["user_exists"] = true
+		ctx.RenderWithErr(ctx.Tr("auth.too_many_failed_attempts"), tplLinkAccount, &signInForm)
+		return
+	}
 	u, err := models.UserSignIn(signInForm.UserName, signInForm.Password)
 	if err != nil {
 		if models.IsErrUserNotExist(err) {
+			models.RecordFailedSignInFromIP(ctx.RemoteAddr())
 			ctx.Data["user_exists"] = true
 			ctx.RenderWithErr(ctx.Tr("form.username_password_incorrect"), tplLinkAccount, &signInForm)
+		} else if models.IsErrUserLockedOut(err) {
+			ctx.Data["user_exists"] = true
+			ctx.RenderWithErr(ctx.Tr("auth.too_many_failed_attempts"), tplLinkAccount, &signInForm)
 		} else {
 			ctx.ServerError("UserLinkAccount", err)
 		}
@@ -946,14 +1106,7 @@ func linkAccount(ctx *context.Context, u *models.User, gothUser goth.User, remem
 		log.Error("Error storing session: %v", err)
 	}
 
-	// If U2F is enrolled -> Redirect to U2F instead
-	regs, err := models.GetU2FRegistrationsByUID(u.ID)
-	if err == nil && len(regs) > 0 {
-		ctx.Redirect(setting.AppSubURL + "/user/u2f")
-		return
-	}
-
-	ctx.Redirect(setting.AppSubURL + "/user/two_factor")
+	redirectToSecondFactor(ctx, u.ID)
 }
 
 // LinkAccountPostRegister handle the creation of a new account for an external account using signUp
@@ -1049,9 +1202,10 @@ func LinkAccountPostRegister(ctx *context.Context) {
 		}
 	}
 
-	loginSource, err := models.GetActiveOAuth2LoginSourceByName(gothUser.Provider)
+	loginSource, err := models.GetActiveExternalLoginSourceByName(gothUser.Provider)
 	if err != nil {
 		ctx.ServerError("CreateUser", err)
+		return
 	}
 
 	u := &models.User{
@@ -1059,7 +1213,7 @@ func LinkAccountPostRegister(ctx *context.Context) {
 		Email:       form.Email,
 		Passwd:      form.Password,
 		IsActive:    !(setting.Service.RegisterEmailConfirm || setting.Service.RegisterManualConfirm),
-		LoginType:   models.LoginOAuth2,
+		LoginType:   loginSource.Type,
 		LoginSource: loginSource.ID,
 		LoginName:   gothUser.UserID,
 	}
@@ -1074,6 +1228,16 @@ func LinkAccountPostRegister(ctx *context.Context) {
 
 // HandleSignOut resets the session and sets the cookies
 func HandleSignOut(ctx *context.Context) {
+	if id, ok := ctx.Session.Get(sso.UserSessionKey).(int64); ok && ctx.User != nil {
+		// while impersonating, the tracked session is the one of the administrator
+		uid := ctx.User.ID
+		if impersonator, ok := ctx.Session.Get(sso.ImpersonatorKey).(int64); ok {
+			uid = impersonator
+		}
+		if err := models.DeleteUserSession(uid, id); err != nil && !models.IsErrUserSessionNotExist(err) {
+			log.Error("DeleteUserSession: %v", err)
+		}
+	}
 	_ = ctx.Session.Flush()
 	_ = ctx.Session.Destroy(ctx.Resp, ctx.Req)
 	ctx.DeleteCookie(setting.CookieUserName)
@@ -1092,8 +1256,32 @@ func SignOut(ctx *context.Context) {
 	// 		Data: ctx.Session.ID(),
 	// 	})
 	// }
+	signOutAndRedirect(ctx, setting.AppSubURL+"/")
+}
+
+// signOutAndRedirect signs out the user and redirects to redirectTo. If OAuth2 applications
+// authorized by the user have a front-channel logout URI, a page loading these URIs is
+// rendered first so the applications can end their sessions too.
+func signOutAndRedirect(ctx *context.Context, redirectTo string) {
+	var logoutURIs []string
+	if ctx.User != nil && setting.OAuth2.Enable {
+		var err error
+		if logoutURIs, err = models.GetFrontchannelLogoutURIs(ctx.User.ID); err != nil {
+			log.Error("GetFrontchannelLogoutURIs: %v", err)
+		}
+	}
 	HandleSignOut(ctx)
-	ctx.Redirect(setting.AppSubURL + "/")
+	if len(logoutURIs) == 0 {
+		ctx.Redirect(redirectTo)
+		return
+	}
+
+	ctx.Data["IsSigned"] = false
+	delete(ctx.Data, "SignedUser")
+	ctx.Data["Title"] = ctx.Tr("auth.signing_out")
+	ctx.Data["FrontchannelLogoutURIs"] = logoutURIs
+	ctx.Data["RedirectTo"] = redirectTo
+	ctx.HTML(http.StatusOK, tplSignOut)
 }
 
 // SignUp render the register page
@@ -1306,6 +1494,19 @@ func handleUserCreated(ctx *context.Context, u *models.User, gothUser *goth.User
 		if err := models.UpdateExternalUser(u, *gothUser); err != nil {
 			log.Error("UpdateExternalUser failed: %v", err)
 		}
+
+		// existing users are synchronized when they sign in, see SignInOAuthCallback
+		loginSource, err := models.GetActiveExternalLoginSourceByName(gothUser.Provider)
+		if err != nil {
+			ctx.ServerError("GetActiveExternalLoginSourceByName", err)
+			return
+		}
+		if loginSource.IsOAuth2() {
+			if err := models.SyncOAuth2UserGroups(loginSource, u, *gothUser); err != nil {
+				ctx.ServerError("SyncOAuth2UserGroups", err)
+				return
+			}
+		}
 	}
 
 	// Send confirmation email
@@ -1512,7 +1713,7 @@ func ForgotPasswdPost(ctx *context.Context) {
 		return
 	}
 
-	if !u.IsLocal() && !u.IsOAuth2() {
+	if !u.IsLocal() && !u.IsOAuth2() && !u.IsSAML() {
 		ctx.Data["Err_Email"] = true
 		ctx.RenderWithErr(ctx.Tr("auth.non_local_account"), tplForgotPassword, nil)
 		return
@@ -10,6 +10,7 @@ import (
 	"net/url"
 
 	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/auth/lockout"
 	"go.wandrs.dev/framework/modules/auth/openid"
 	"go.wandrs.dev/framework/modules/base"
 	"go.wandrs.dev/framework/modules/context"
@@ -288,10 +289,17 @@ func ConnectOpenIDPost(ctx *context.Context) {
 	ctx.Data["EnableOpenIDSignUp"] = setting.Service.EnableOpenIDSignUp
 	ctx.Data["OpenID"] = oid
 
+	if lockout.IsIPBlocked(ctx.RemoteAddr()) {
+		ctx.RenderWithErr(ctx.Tr("auth.too_many_failed_attempts"), tplConnectOID, &form)
+		return
+	}
 	u, err := models.UserSignIn(form.UserName, form.Password)
 	if err != nil {
 		if models.IsErrUserNotExist(err) {
+			models.RecordFailedSignInFromIP(ctx.RemoteAddr())
 			ctx.RenderWithErr(ctx.Tr("form.username_password_incorrect"), tplConnectOID, &form)
+		} else if models.IsErrUserLockedOut(err) {
+			ctx.RenderWithErr(ctx.Tr("auth.too_many_failed_attempts"), tplConnectOID, &form)
 		} else {
 			ctx.ServerError("ConnectOpenIDPost", err)
 		}
@@ -0,0 +1,269 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package user
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/auth/saml"
+	"go.wandrs.dev/framework/modules/base"
+	"go.wandrs.dev/framework/modules/context"
+	"go.wandrs.dev/framework/modules/log"
+	"go.wandrs.dev/framework/modules/setting"
+	"go.wandrs.dev/framework/modules/util"
+
+	"github.com/markbates/goth"
+)
+
+// samlRequestTimeout is the time in seconds the user has to sign in at the identity provider
+const samlRequestTimeout = 10 * 60
+
+// samlServiceProvider returns the service provider of this instance for the SAML login source
+func samlServiceProvider(loginSource *models.LoginSource) *saml.ServiceProvider {
+	cfg := loginSource.SAML()
+	baseURL := setting.AppURL + "user/saml/" + url.PathEscape(loginSource.Name)
+	sp := &saml.ServiceProvider{
+		EntityID:          cfg.SPEntityID,
+		ACSURL:            baseURL + "/acs",
+		NameIDFormat:      cfg.NameIDFormat,
+		IdPEntityID:       cfg.IdPEntityID,
+		IdPSSOURL:         cfg.IdPSSOURL,
+		AllowIdPInitiated: cfg.AllowIdPInitiated,
+	}
+	if sp.EntityID == "" {
+		sp.EntityID = baseURL + "/metadata"
+	}
+	return sp
+}
+
+func getActiveSAMLLoginSource(ctx *context.Context) *models.LoginSource {
+	loginSource, err := models.GetActiveSAMLLoginSourceByName(ctx.Params(":provider"))
+	if err != nil {
+		ctx.ServerError("GetActiveSAMLLoginSourceByName", err)
+		return nil
+	} else if loginSource == nil {
+		ctx.NotFound("GetActiveSAMLLoginSourceByName", nil)
+		return nil
+	}
+	return loginSource
+}
+
+// SAMLMetadata serves the metadata of this service provider for the SAML login source
+func SAMLMetadata(ctx *context.Context) {
+	loginSource := getActiveSAMLLoginSource(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	metadata, err := samlServiceProvider(loginSource).Metadata()
+	if err != nil {
+		ctx.ServerError("Metadata", err)
+		return
+	}
+	ctx.Resp.Header().Set("Content-Type", "application/samlmetadata+xml")
+	ctx.Resp.WriteHeader(http.StatusOK)
+	if _, err := ctx.Resp.Write(metadata); err != nil {
+		log.Error("Unable to write SAML metadata: %v", err)
+	}
+}
+
+// SignInSAML redirects the user to the identity provider of the SAML login source
+func SignInSAML(ctx *context.Context) {
+	loginSource := getActiveSAMLLoginSource(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	// the response is posted cross-site, so the pending request is tracked through the
+	// relay state instead of the session cookie which is not sent along
+	relayState, err := util.RandomString(32)
+	if err != nil {
+		ctx.ServerError("RandomString", err)
+		return
+	}
+	redirect, requestID, err := samlServiceProvider(loginSource).AuthnRequestURL(relayState)
+	if err != nil {
+		ctx.ServerError("AuthnRequestURL", err)
+		return
+	}
+	if err := ctx.Cache.Put("saml_request_"+relayState, requestID, samlRequestTimeout); err != nil {
+		ctx.ServerError("Put", err)
+		return
+	}
+	ctx.Redirect(redirect)
+}
+
+// samlGothUser maps the assertion to the external user linked through ExternalLoginUser
+func samlGothUser(loginSource *models.LoginSource, assertion *saml.Assertion) goth.User {
+	cfg := loginSource.SAML()
+	rawData := make(map[string]interface{}, len(assertion.Attributes))
+	for name, values := range assertion.Attributes {
+		rawData[name] = values
+	}
+
+	gothUser := goth.User{
+		Provider: loginSource.Name,
+		UserID:   assertion.NameID,
+		NickName: assertion.NameID,
+		RawData:  rawData,
+	}
+	if cfg.AttributeUsername != "" {
+		gothUser.NickName = assertion.Attribute(cfg.AttributeUsername)
+	}
+	if cfg.AttributeEmail != "" {
+		gothUser.Email = assertion.Attribute(cfg.AttributeEmail)
+	} else if assertion.NameIDFormat == saml.NameIDFormatEmailAddress {
+		gothUser.Email = assertion.NameID
+	}
+	if cfg.AttributeFullName != "" {
+		gothUser.Name = assertion.Attribute(cfg.AttributeFullName)
+	}
+	return gothUser
+}
+
+// samlIsAdmin returns whether the assertion grants administrator permissions,
+// mapped is false if the login source does not map them
+func samlIsAdmin(cfg *models.SAMLConfig, assertion *saml.Assertion) (isAdmin, mapped bool) {
+	if cfg.AttributeAdmin == "" {
+		return false, false
+	}
+	for _, value := range assertion.Attributes[cfg.AttributeAdmin] {
+		if value == cfg.AdminValue {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+// samlUserLoginCallback returns the user the subject of the assertion is or was linked to
+func samlUserLoginCallback(loginSource *models.LoginSource, gothUser goth.User) (*models.User, error) {
+	user := &models.User{
+		LoginName:   gothUser.UserID,
+		LoginType:   models.LoginSAML,
+		LoginSource: loginSource.ID,
+	}
+	hasUser, err := models.GetUser(user)
+	if err != nil {
+		return nil, err
+	} else if hasUser {
+		return user, nil
+	}
+
+	externalLoginUser := &models.ExternalLoginUser{
+		ExternalID:    gothUser.UserID,
+		LoginSourceID: loginSource.ID,
+	}
+	hasUser, err = models.GetExternalLogin(externalLoginUser)
+	if err != nil {
+		return nil, err
+	} else if hasUser {
+		return models.GetUserByID(externalLoginUser.UserID)
+	}
+	return nil, nil
+}
+
+// SAMLAssertionConsumer receives the response of the identity provider and signs the user in
+func SAMLAssertionConsumer(ctx *context.Context) {
+	loginSource := getActiveSAMLLoginSource(ctx)
+	if ctx.Written() {
+		return
+	}
+	cfg := loginSource.SAML()
+
+	sp := samlServiceProvider(loginSource)
+	var err error
+	if sp.IdPCertificate, err = saml.ParseCertificate(cfg.IdPCertificate); err != nil {
+		ctx.ServerError("ParseCertificate", err)
+		return
+	}
+
+	var requestID string
+	if relayState := ctx.Query("RelayState"); relayState != "" {
+		if id, ok := ctx.Cache.Get("saml_request_" + relayState).(string); ok {
+			requestID = id
+			_ = ctx.Cache.Delete("saml_request_" + relayState)
+		}
+	}
+
+	assertion, err := sp.ParseResponse(ctx.Query("SAMLResponse"), requestID)
+	if err != nil {
+		log.Warn("SAML response for %s from %s rejected: %v", loginSource.Name, ctx.RemoteAddr(), err)
+		ctx.Flash.Error(ctx.Tr("auth.saml_sign_in_failed", loginSource.Name))
+		ctx.Redirect(setting.AppSubURL + "/user/login")
+		return
+	}
+
+	// bearer assertions must only be used once
+	replayKey := fmt.Sprintf("saml_assertion_%d_%s", loginSource.ID, assertion.ID)
+	if ctx.Cache.IsExist(replayKey) {
+		log.Warn("SAML assertion %s for %s from %s replayed", assertion.ID, loginSource.Name, ctx.RemoteAddr())
+		ctx.Flash.Error(ctx.Tr("auth.saml_sign_in_failed", loginSource.Name))
+		ctx.Redirect(setting.AppSubURL + "/user/login")
+		return
+	}
+	ttl := int64(time.Until(assertion.NotOnOrAfter.Add(saml.DefaultClockSkew)).Seconds()) + 1
+	if err := ctx.Cache.Put(replayKey, assertion.NameID, ttl); err != nil {
+		ctx.ServerError("Put", err)
+		return
+	}
+
+	gothUser := samlGothUser(loginSource, assertion)
+	isAdmin, adminMapped := samlIsAdmin(cfg, assertion)
+
+	u, err := samlUserLoginCallback(loginSource, gothUser)
+	if err != nil {
+		ctx.ServerError("UserSignIn", err)
+		return
+	}
+
+	if u == nil {
+		if !(setting.Service.DisableRegistration || setting.Service.AllowOnlyInternalRegistration) && cfg.EnableAutoRegistration {
+			var missingFields []string
+			if strings.TrimSpace(gothUser.NickName) == "" {
+				missingFields = append(missingFields, "username")
+			}
+			if gothUser.Email == "" {
+				missingFields = append(missingFields, "email")
+			}
+			if len(missingFields) > 0 {
+				log.Error("SAML identity provider of %s returned empty or missing fields: %s", loginSource.Name, missingFields)
+				ctx.ServerError("CreateUser", fmt.Errorf("SAML identity provider of %s returned empty or missing fields: %s", loginSource.Name, missingFields))
+				return
+			}
+			u = &models.User{
+				Name:        gothUser.NickName,
+				FullName:    gothUser.Name,
+				Email:       gothUser.Email,
+				IsActive:    !setting.Service.RegisterEmailConfirm,
+				IsAdmin:     isAdmin,
+				LoginType:   models.LoginSAML,
+				LoginSource: loginSource.ID,
+				LoginName:   gothUser.UserID,
+			}
+
+			if !createAndHandleCreatedUser(ctx, base.TplName(""), nil, u, &gothUser, setting.OAuth2Client.AccountLinking != setting.OAuth2AccountLinkingDisabled) {
+				// error already handled
+				return
+			}
+		} else {
+			// no existing user is found, request attach or new account
+			showLinkingLogin(ctx, gothUser)
+			return
+		}
+	} else if adminMapped && u.IsAdmin != isAdmin {
+		u.IsAdmin = isAdmin
+		if err := models.UpdateUserCols(u, "is_admin"); err != nil {
+			ctx.ServerError("UpdateUserCols", err)
+			return
+		}
+	}
+
+	handleOAuth2SignIn(ctx, u, gothUser)
+}
@@ -0,0 +1,36 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package user
+
+import (
+	"strconv"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/context"
+	"go.wandrs.dev/framework/modules/log"
+	"go.wandrs.dev/framework/modules/setting"
+)
+
+// StopImpersonating switches the session back to the administrator impersonating the user
+func StopImpersonating(ctx *context.Context) {
+	if ctx.Impersonator == nil {
+		ctx.Redirect(setting.AppSubURL + "/")
+		return
+	}
+
+	u := ctx.User
+	if err := ctx.StopImpersonating(); err != nil {
+		ctx.ServerError("StopImpersonating", err)
+		return
+	}
+	log.Trace("Impersonation of user %s by admin %s stopped", u.Name, ctx.Impersonator.Name)
+	ctx.Audit(&models.AuditEvent{
+		Action:     models.AuditImpersonateStop,
+		TargetID:   u.ID,
+		TargetName: u.Name,
+	})
+
+	ctx.Redirect(setting.AppSubURL + "/admin/users/" + strconv.FormatInt(u.ID, 10))
+}
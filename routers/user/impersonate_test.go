@@ -0,0 +1,50 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package user
+
+import (
+	"net/http"
+	"testing"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/auth/sso"
+	"go.wandrs.dev/framework/modules/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStopImpersonating(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	ctx := test.MockContext(t, "user/impersonate/stop")
+	test.LoadUser(t, ctx, 2)
+	ctx.Impersonator = models.AssertExistsAndLoadBean(t, &models.User{ID: 1}).(*models.User)
+	assert.NoError(t, ctx.Session.Set("uid", int64(2)))
+	assert.NoError(t, ctx.Session.Set(sso.ImpersonatorKey, int64(1)))
+
+	StopImpersonating(ctx)
+	assert.EqualValues(t, http.StatusFound, ctx.Resp.Status())
+	assert.EqualValues(t, "/admin/users/2", test.RedirectURL(ctx.Resp))
+	assert.EqualValues(t, 1, ctx.Session.Get("uid"))
+	assert.EqualValues(t, "user1", ctx.Session.Get("uname"))
+	assert.Nil(t, ctx.Session.Get(sso.ImpersonatorKey))
+	models.AssertExistsAndLoadBean(t, &models.AuditEvent{Action: models.AuditImpersonateStop, ActorID: 1, TargetID: 2})
+}
+
+func TestSignOutWhileImpersonating(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	session := &models.UserSession{UID: 1}
+	assert.NoError(t, models.CreateUserSession(session))
+
+	ctx := test.MockContext(t, "user/logout")
+	test.LoadUser(t, ctx, 2)
+	assert.NoError(t, ctx.Session.Set(sso.UserSessionKey, session.ID))
+	assert.NoError(t, ctx.Session.Set(sso.ImpersonatorKey, int64(1)))
+
+	// the tracked session of the administrator is signed out
+	HandleSignOut(ctx)
+	models.AssertNotExistsBean(t, &models.UserSession{ID: session.ID})
+}
@@ -14,12 +14,14 @@ import (
 
 	"go.wandrs.dev/binding"
 	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/auth/oauth2"
 	"go.wandrs.dev/framework/modules/auth/sso"
 	"go.wandrs.dev/framework/modules/base"
 	"go.wandrs.dev/framework/modules/context"
 	"go.wandrs.dev/framework/modules/log"
 	"go.wandrs.dev/framework/modules/setting"
 	"go.wandrs.dev/framework/modules/timeutil"
+	"go.wandrs.dev/framework/modules/util"
 	"go.wandrs.dev/framework/modules/web"
 	"go.wandrs.dev/framework/services/forms"
 
@@ -79,6 +81,14 @@ const (
 	AccessTokenErrorCodeUnauthorizedClient = "unauthorized_client"
 	// AccessTokenErrorCodeUnsupportedGrantType represents an error code specified in RFC 6749
 	AccessTokenErrorCodeUnsupportedGrantType = "unsupported_grant_type"
+	// AccessTokenErrorCodeAuthorizationPending represents an error code specified in RFC 8628
+	AccessTokenErrorCodeAuthorizationPending = "authorization_pending"
+	// AccessTokenErrorCodeSlowDown represents an error code specified in RFC 8628
+	AccessTokenErrorCodeSlowDown = "slow_down"
+	// AccessTokenErrorCodeAccessDenied represents an error code specified in RFC 8628
+	AccessTokenErrorCodeAccessDenied = "access_denied"
+	// AccessTokenErrorCodeExpiredToken represents an error code specified in RFC 8628
+	AccessTokenErrorCodeExpiredToken = "expired_token"
 	// AccessTokenErrorCodeInvalidScope represents an error code specified in RFC 6749
 	AccessTokenErrorCodeInvalidScope = "invalid_scope"
 )
@@ -127,21 +137,11 @@ type AccessTokenResponse struct {
 	AccessToken  string    `json:"access_token"`
 	TokenType    TokenType `json:"token_type"`
 	ExpiresIn    int64     `json:"expires_in"`
-	RefreshToken string    `json:"refresh_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
 	IDToken      string    `json:"id_token,omitempty"`
 }
 
-func newAccessTokenResponse(grant *models.OAuth2Grant, clientSecret string) (*AccessTokenResponse, *AccessTokenError) {
-	if setting.OAuth2.InvalidateRefreshTokens {
-		if err := grant.IncreaseCounter(); err != nil {
-			return nil, &AccessTokenError{
-				ErrorCode:        AccessTokenErrorCodeInvalidGrant,
-				ErrorDescription: "cannot increase the grant counter",
-			}
-		}
-	}
-	// generate access token to access the API
-	expirationDate := timeutil.TimeStampNow().Add(setting.OAuth2.AccessTokenExpirationTime)
+func newSignedAccessToken(grant *models.OAuth2Grant, expirationDate timeutil.TimeStamp) (string, *AccessTokenError) {
 	accessToken := &models.OAuth2Token{
 		GrantID: grant.ID,
 		Type:    models.TypeAccessToken,
@@ -151,11 +151,29 @@ func newAccessTokenResponse(grant *models.OAuth2Grant, clientSecret string) (*Ac
 	}
 	signedAccessToken, err := accessToken.SignToken()
 	if err != nil {
-		return nil, &AccessTokenError{
+		return "", &AccessTokenError{
 			ErrorCode:        AccessTokenErrorCodeInvalidRequest,
 			ErrorDescription: "cannot sign token",
 		}
 	}
+	return signedAccessToken, nil
+}
+
+func newAccessTokenResponse(grant *models.OAuth2Grant, clientSecret string) (*AccessTokenResponse, *AccessTokenError) {
+	if setting.OAuth2.InvalidateRefreshTokens {
+		if err := grant.IncreaseCounter(); err != nil {
+			return nil, &AccessTokenError{
+				ErrorCode:        AccessTokenErrorCodeInvalidGrant,
+				ErrorDescription: "cannot increase the grant counter",
+			}
+		}
+	}
+	// generate access token to access the API
+	expirationDate := timeutil.TimeStampNow().Add(setting.OAuth2.AccessTokenExpirationTime)
+	signedAccessToken, tokenErr := newSignedAccessToken(grant, expirationDate)
+	if tokenErr != nil {
+		return nil, tokenErr
+	}
 
 	// generate refresh token to request an access token after it expired later
 	refreshExpirationDate := timeutil.TimeStampNow().Add(setting.OAuth2.RefreshTokenExpirationTime * 60 * 60).AsTime().Unix()
@@ -175,9 +193,11 @@ func newAccessTokenResponse(grant *models.OAuth2Grant, clientSecret string) (*Ac
 		}
 	}
 
-	// generate OpenID Connect id_token
+	// generate OpenID Connect id_token. Public clients have no secret to verify a
+	// symmetric id_token with, and one signed with an empty key could be forged.
 	signedIDToken := ""
-	if grant.ScopeContains("openid") {
+	signingKey := oauth2.DefaultSigningKey()
+	if grant.ScopeContains("openid") && (!signingKey.IsSymmetric() || clientSecret != "") {
 		app, err := models.GetOAuth2ApplicationByID(grant.ApplicationID)
 		if err != nil {
 			return nil, &AccessTokenError{
@@ -194,7 +214,48 @@ func newAccessTokenResponse(grant *models.OAuth2Grant, clientSecret string) (*Ac
 			},
 			Nonce: grant.Nonce,
 		}
-		signedIDToken, err = idToken.SignToken(clientSecret)
+		user, err := models.GetUserByID(grant.UserID)
+		if err != nil {
+			return nil, &AccessTokenError{
+				ErrorCode:        AccessTokenErrorCodeInvalidRequest,
+				ErrorDescription: "cannot find user",
+			}
+		}
+		if grant.ScopeContains("profile") {
+			idToken.Name = user.FullName
+			idToken.PreferredUsername = user.Name
+			idToken.Profile = user.HTMLURL()
+			idToken.Picture = user.AvatarLink()
+			idToken.Website = user.Website
+			idToken.Locale = user.Language
+			idToken.UpdatedAt = user.UpdatedUnix
+		}
+		if grant.ScopeContains("email") {
+			idToken.Email = user.Email
+			idToken.EmailVerified = user.IsActive
+		}
+		if grant.ScopeContains("groups") {
+			groups, err := getOAuthGroupsForUser(user)
+			if err != nil {
+				log.Error("Error getting groups: %v", err)
+				return nil, &AccessTokenError{
+					ErrorCode:        AccessTokenErrorCodeInvalidRequest,
+					ErrorDescription: "cannot find groups",
+				}
+			}
+			idToken.Groups = groups
+		}
+		if signingKey.IsSymmetric() {
+			// relying parties can only verify symmetric id_tokens with their client secret
+			signingKey, err = oauth2.CreateJWTSigningKey(signingKey.SigningMethod().Alg(), []byte(clientSecret))
+			if err != nil {
+				return nil, &AccessTokenError{
+					ErrorCode:        AccessTokenErrorCodeInvalidRequest,
+					ErrorDescription: "cannot create signing key",
+				}
+			}
+		}
+		signedIDToken, err = idToken.SignToken(signingKey)
 		if err != nil {
 			return nil, &AccessTokenError{
 				ErrorCode:        AccessTokenErrorCodeInvalidRequest,
@@ -213,14 +274,17 @@ func newAccessTokenResponse(grant *models.OAuth2Grant, clientSecret string) (*Ac
 }
 
 type userInfoResponse struct {
-	Sub      string `json:"sub"`
-	Name     string `json:"name"`
-	Username string `json:"preferred_username"`
-	Email    string `json:"email"`
-	Picture  string `json:"picture"`
+	Sub           string   `json:"sub"`
+	Name          string   `json:"name,omitempty"`
+	Username      string   `json:"preferred_username,omitempty"`
+	Profile       string   `json:"profile,omitempty"`
+	Picture       string   `json:"picture,omitempty"`
+	Email         string   `json:"email,omitempty"`
+	EmailVerified bool     `json:"email_verified,omitempty"`
+	Groups        []string `json:"groups,omitempty"`
 }
 
-// InfoOAuth manages request for userinfo endpoint
+// InfoOAuth manages request for userinfo endpoint, the claims depend on the scope of the grant
 func InfoOAuth(ctx *context.Context) {
 	header := ctx.Req.Header.Get("Authorization")
 	auths := strings.Fields(header)
@@ -228,29 +292,62 @@ func InfoOAuth(ctx *context.Context) {
 		ctx.HandleText(http.StatusUnauthorized, "no valid auth token authorization")
 		return
 	}
-	uid := sso.CheckOAuthAccessToken(auths[1])
-	if uid == 0 {
+	grant := sso.GetOAuthAccessTokenGrant(auths[1])
+	if grant == nil {
 		handleBearerTokenError(ctx, BearerTokenError{
 			ErrorCode:        BearerTokenErrorCodeInvalidToken,
 			ErrorDescription: "Access token not assigned to any user",
 		})
 		return
 	}
-	authUser, err := models.GetUserByID(uid)
+	authUser, err := models.GetUserByID(grant.UserID)
 	if err != nil {
 		ctx.ServerError("GetUserByID", err)
 		return
 	}
 	response := &userInfoResponse{
-		Sub:      fmt.Sprint(authUser.ID),
-		Name:     authUser.FullName,
-		Username: authUser.Name,
-		Email:    authUser.Email,
-		Picture:  authUser.AvatarLink(),
+		Sub: fmt.Sprint(authUser.ID),
+	}
+	if grant.ScopeContains("profile") {
+		response.Name = authUser.FullName
+		response.Username = authUser.Name
+		response.Profile = authUser.HTMLURL()
+		response.Picture = authUser.AvatarLink()
+	}
+	if grant.ScopeContains("email") {
+		response.Email = authUser.Email
+		response.EmailVerified = authUser.IsActive
+	}
+	if grant.ScopeContains("groups") {
+		if response.Groups, err = getOAuthGroupsForUser(authUser); err != nil {
+			ctx.ServerError("getOAuthGroupsForUser", err)
+			return
+		}
 	}
 	ctx.JSON(http.StatusOK, response)
 }
 
+// getOAuthGroupsForUser returns the organizations ("org") and teams ("org:team") of the user
+func getOAuthGroupsForUser(user *models.User) ([]string, error) {
+	orgs, err := models.GetOrgsByUserID(user.ID, true)
+	if err != nil {
+		return nil, fmt.Errorf("GetOrgsByUserID: %v", err)
+	}
+
+	var groups []string
+	for _, org := range orgs {
+		groups = append(groups, org.Name)
+		teams, err := models.GetUserOrgEffectiveTeams(org.ID, user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("GetUserOrgEffectiveTeams: %v", err)
+		}
+		for _, team := range teams {
+			groups = append(groups, org.Name+":"+team.LowerName)
+		}
+	}
+	return groups, nil
+}
+
 // AuthorizeOAuth manages authorize requests
 func AuthorizeOAuth(ctx *context.Context) {
 	form := web.GetForm(ctx).(*forms.AuthorizationForm)
@@ -343,8 +440,21 @@ func AuthorizeOAuth(ctx *context.Context) {
 		return
 	}
 
-	// Redirect if user already granted access
-	if grant != nil {
+	// Trusted applications are granted access without asking the user
+	if app.Trusted {
+		if grant == nil {
+			grant, err = app.CreateGrant(ctx.User.ID, form.Scope)
+		} else {
+			err = grant.AddScope(form.Scope)
+		}
+		if err != nil {
+			handleServerError(ctx, form.State, form.RedirectURI)
+			return
+		}
+	}
+
+	// Redirect if user already granted access to all requested scopes
+	if grant != nil && grant.ScopeContainsAll(form.Scope) {
 		code, err := grant.GenerateNewAuthorizationCode(form.RedirectURI, form.CodeChallenge, form.CodeChallengeMethod)
 		if err != nil {
 			handleServerError(ctx, form.State, form.RedirectURI)
@@ -414,7 +524,15 @@ func GrantApplicationOAuth(ctx *context.Context) {
 		ctx.ServerError("GetOAuth2ApplicationByClientID", err)
 		return
 	}
-	grant, err := app.CreateGrant(ctx.User.ID, form.Scope)
+	// the user may have consented to additional scopes of an existing grant
+	grant, err := app.GetGrantByUserID(ctx.User.ID)
+	if err == nil {
+		if grant == nil {
+			grant, err = app.CreateGrant(ctx.User.ID, form.Scope)
+		} else {
+			err = grant.AddScope(form.Scope)
+		}
+	}
 	if err != nil {
 		handleAuthorizeError(ctx, AuthorizeError{
 			State:            form.State,
@@ -449,6 +567,7 @@ func GrantApplicationOAuth(ctx *context.Context) {
 
 // OIDCWellKnown generates JSON so OIDC clients know Gitea's capabilities
 func OIDCWellKnown(ctx *context.Context) {
+	ctx.Data["SigningKey"] = oauth2.DefaultSigningKey()
 	t := ctx.Render.TemplateLookup("user/auth/oidc_wellknown")
 	ctx.Resp.Header().Set("Content-Type", "application/json")
 	if err := t.Execute(ctx.Resp, ctx.Data); err != nil {
@@ -457,31 +576,194 @@ func OIDCWellKnown(ctx *context.Context) {
 	}
 }
 
+// OIDCKeys generates the JSON Web Key Set of all keys which may have signed a token
+func OIDCKeys(ctx *context.Context) {
+	keys := make([]map[string]string, 0, 2)
+	for _, signingKey := range oauth2.VerificationKeys() {
+		if signingKey.IsSymmetric() {
+			continue
+		}
+		jwk, err := signingKey.ToJWK()
+		if err != nil {
+			log.Error("Error converting signing key to JWK: %v", err)
+			ctx.Error(http.StatusInternalServerError)
+			return
+		}
+		keys = append(keys, jwk)
+	}
+
+	ctx.JSON(http.StatusOK, map[string][]map[string]string{
+		"keys": keys,
+	})
+}
+
+// parseClientBasicAuth returns the client credentials sent in a basic authorization header.
+// A request without basic authorization header returns empty credentials.
+func parseClientBasicAuth(ctx *context.Context) (clientID, clientSecret string, ok bool) {
+	authHeader := ctx.Req.Header.Get("Authorization")
+	authContent := strings.SplitN(authHeader, " ", 2)
+	if len(authContent) != 2 || authContent[0] != "Basic" {
+		return "", "", true
+	}
+	payload, err := base64.StdEncoding.DecodeString(authContent[1])
+	if err != nil {
+		return "", "", false
+	}
+	pair := strings.SplitN(string(payload), ":", 2)
+	if len(pair) != 2 {
+		return "", "", false
+	}
+	return pair[0], pair[1], true
+}
+
+// authenticateClient returns the application of the client credentials sent
+// in the form or the basic authorization header of a request.
+func authenticateClient(ctx *context.Context, clientID, clientSecret string) *models.OAuth2Application {
+	if clientID == "" {
+		var ok bool
+		if clientID, clientSecret, ok = parseClientBasicAuth(ctx); !ok {
+			return nil
+		}
+	}
+	if clientID == "" {
+		return nil
+	}
+	app, err := models.GetOAuth2ApplicationByClientID(clientID)
+	if err != nil {
+		if !models.IsErrOauthClientIDInvalid(err) {
+			log.Error("GetOAuth2ApplicationByClientID: %v", err)
+		}
+		return nil
+	}
+	if !app.ValidateClientSecret([]byte(clientSecret)) {
+		return nil
+	}
+	return app
+}
+
+func handleInvalidClient(ctx *context.Context) {
+	ctx.Resp.Header().Set("WWW-Authenticate", `Basic realm=""`)
+	ctx.JSON(http.StatusUnauthorized, AccessTokenError{
+		ErrorCode:        AccessTokenErrorCodeInvalidClient,
+		ErrorDescription: "client authentication failed",
+	})
+}
+
+// IntrospectTokenResponse represents a token introspection response specified in RFC 7662
+type IntrospectTokenResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+}
+
+// loadActiveOAuth2Token parses the token and returns it along its grant if it is still valid
+func loadActiveOAuth2Token(signedToken string) (*models.OAuth2Token, *models.OAuth2Grant) {
+	token, err := models.ParseOAuth2Token(signedToken)
+	if err != nil {
+		return nil, nil
+	}
+	grant, err := models.GetOAuth2GrantByID(token.GrantID)
+	if err != nil {
+		log.Error("GetOAuth2GrantByID: %v", err)
+		return nil, nil
+	} else if grant == nil {
+		return nil, nil
+	}
+	if token.Type == models.TypeRefreshToken && setting.OAuth2.InvalidateRefreshTokens && grant.Counter != token.Counter {
+		return nil, nil
+	}
+	return token, grant
+}
+
+// IntrospectOAuth introspects an access or refresh token for an authenticated client
+func IntrospectOAuth(ctx *context.Context) {
+	form := web.GetForm(ctx).(*forms.OAuth2TokenForm)
+	client := authenticateClient(ctx, form.ClientID, form.ClientSecret)
+	if client == nil {
+		handleInvalidClient(ctx)
+		return
+	}
+
+	response := &IntrospectTokenResponse{}
+	token, grant := loadActiveOAuth2Token(form.Token)
+	// tokens of other applications are only revealed to the allowed resource servers
+	if token != nil && (grant.ApplicationID == client.ID || util.IsStringInSlice(client.ClientID, setting.OAuth2.IntrospectionClientIDs)) {
+		app, err := models.GetOAuth2ApplicationByID(grant.ApplicationID)
+		if err != nil && !models.IsErrOAuthApplicationNotFound(err) {
+			ctx.ServerError("GetOAuth2ApplicationByID", err)
+			return
+		}
+		user, err := models.GetUserByID(grant.UserID)
+		if err != nil && !models.IsErrUserNotExist(err) {
+			ctx.ServerError("GetUserByID", err)
+			return
+		}
+		if app != nil && user != nil {
+			response.Active = true
+			response.Scope = grant.Scope
+			response.ClientID = app.ClientID
+			response.Username = user.Name
+			response.ExpiresAt = token.ExpiresAt
+			response.IssuedAt = token.IssuedAt
+			response.Subject = fmt.Sprint(grant.UserID)
+			response.Issuer = setting.AppURL
+			if token.Type == models.TypeAccessToken {
+				response.TokenType = string(TokenTypeBearer)
+			} else {
+				response.TokenType = "refresh_token"
+			}
+		}
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// RevokeOAuth revokes the grant of an access or refresh token issued to the authenticated client
+func RevokeOAuth(ctx *context.Context) {
+	form := web.GetForm(ctx).(*forms.OAuth2TokenForm)
+	app := authenticateClient(ctx, form.ClientID, form.ClientSecret)
+	if app == nil {
+		handleInvalidClient(ctx)
+		return
+	}
+
+	// RFC 7009 section 2.2: invalid tokens do not cause an error response
+	_, grant := loadActiveOAuth2Token(form.Token)
+	if grant == nil {
+		ctx.Status(http.StatusOK)
+		return
+	}
+	if grant.ApplicationID != app.ID {
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeUnauthorizedClient,
+			ErrorDescription: "token was not issued to the client",
+		})
+		return
+	}
+	// tokens are stateless JWTs, revoking one of them invalidates all tokens of the grant
+	if err := models.RevokeOAuth2Grant(grant.ID, grant.UserID); err != nil {
+		ctx.ServerError("RevokeOAuth2Grant", err)
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
+
 // AccessTokenOAuth manages all access token requests by the client
 func AccessTokenOAuth(ctx *context.Context) {
 	form := *web.GetForm(ctx).(*forms.AccessTokenForm)
 	if form.ClientID == "" {
-		authHeader := ctx.Req.Header.Get("Authorization")
-		authContent := strings.SplitN(authHeader, " ", 2)
-		if len(authContent) == 2 && authContent[0] == "Basic" {
-			payload, err := base64.StdEncoding.DecodeString(authContent[1])
-			if err != nil {
-				handleAccessTokenError(ctx, AccessTokenError{
-					ErrorCode:        AccessTokenErrorCodeInvalidRequest,
-					ErrorDescription: "cannot parse basic auth header",
-				})
-				return
-			}
-			pair := strings.SplitN(string(payload), ":", 2)
-			if len(pair) != 2 {
-				handleAccessTokenError(ctx, AccessTokenError{
-					ErrorCode:        AccessTokenErrorCodeInvalidRequest,
-					ErrorDescription: "cannot parse basic auth header",
-				})
-				return
-			}
-			form.ClientID = pair[0]
-			form.ClientSecret = pair[1]
+		var ok bool
+		if form.ClientID, form.ClientSecret, ok = parseClientBasicAuth(ctx); !ok {
+			handleAccessTokenError(ctx, AccessTokenError{
+				ErrorCode:        AccessTokenErrorCodeInvalidRequest,
+				ErrorDescription: "cannot parse basic auth header",
+			})
+			return
 		}
 	}
 	switch form.GrantType {
@@ -491,10 +773,16 @@ func AccessTokenOAuth(ctx *context.Context) {
 	case "authorization_code":
 		handleAuthorizationCode(ctx, form)
 		return
+	case "client_credentials":
+		handleClientCredentials(ctx, form)
+		return
+	case DeviceCodeGrantType:
+		handleDeviceCode(ctx, form)
+		return
 	default:
 		handleAccessTokenError(ctx, AccessTokenError{
 			ErrorCode:        AccessTokenErrorCodeUnsupportedGrantType,
-			ErrorDescription: "Only refresh_token or authorization_code grant type is supported",
+			ErrorDescription: "Only refresh_token, authorization_code, client_credentials or device_code grant type is supported",
 		})
 	}
 }
@@ -598,6 +886,93 @@ func handleAuthorizationCode(ctx *context.Context, form forms.AccessTokenForm) {
 	ctx.JSON(http.StatusOK, resp)
 }
 
+// clientCredentialsScopes are the scopes an application may request for itself,
+// it cannot authenticate anyone by OpenID Connect
+var clientCredentialsScopes = []string{"profile", "email", "groups"}
+
+// parseClientCredentialsScope normalizes the requested scope and returns false if
+// it contains a scope which cannot be requested with client credentials
+func parseClientCredentialsScope(scope string) (string, bool) {
+	requested := strings.Fields(scope)
+	for _, s := range requested {
+		if !util.IsStringInSlice(s, clientCredentialsScopes) {
+			return "", false
+		}
+	}
+	normalized := make([]string, 0, len(requested))
+	for _, allowed := range clientCredentialsScopes {
+		if util.IsStringInSlice(allowed, requested) {
+			normalized = append(normalized, allowed)
+		}
+	}
+	return strings.Join(normalized, " "), true
+}
+
+// handleClientCredentials issues an access token acting as the user or organization owning the application
+func handleClientCredentials(ctx *context.Context, form forms.AccessTokenForm) {
+	app, err := models.GetOAuth2ApplicationByClientID(form.ClientID)
+	if err != nil {
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeInvalidClient,
+			ErrorDescription: fmt.Sprintf("cannot load client with client id: '%s'", form.ClientID),
+		})
+		return
+	}
+	if !app.ValidateClientSecret([]byte(form.ClientSecret)) {
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeUnauthorizedClient,
+			ErrorDescription: "client is not authorized",
+		})
+		return
+	}
+	if err := app.LoadUser(); err != nil || app.User == nil {
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeInvalidClient,
+			ErrorDescription: "cannot load client owner",
+		})
+		return
+	}
+	if !app.User.IsOrganization() && (!app.User.IsActive || app.User.ProhibitLogin) {
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeUnauthorizedClient,
+			ErrorDescription: "client owner is not allowed to sign in",
+		})
+		return
+	}
+
+	scope, ok := parseClientCredentialsScope(form.Scope)
+	if !ok {
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeInvalidScope,
+			ErrorDescription: fmt.Sprintf("client credentials may only request the scopes: %s", strings.Join(clientCredentialsScopes, " ")),
+		})
+		return
+	}
+
+	// the grant is distinct from the one the owner may have consented to as a user
+	grant, err := app.GetClientCredentialsGrant(scope)
+	if err != nil {
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeInvalidRequest,
+			ErrorDescription: "cannot proceed your request",
+		})
+		return
+	}
+
+	// RFC 6749 section 4.4.3: no refresh token is issued as the client can always request a new token
+	expirationDate := timeutil.TimeStampNow().Add(setting.OAuth2.AccessTokenExpirationTime)
+	signedAccessToken, tokenErr := newSignedAccessToken(grant, expirationDate)
+	if tokenErr != nil {
+		handleAccessTokenError(ctx, *tokenErr)
+		return
+	}
+	ctx.JSON(http.StatusOK, &AccessTokenResponse{
+		AccessToken: signedAccessToken,
+		TokenType:   TokenTypeBearer,
+		ExpiresIn:   setting.OAuth2.AccessTokenExpirationTime,
+	})
+}
+
 func handleAccessTokenError(ctx *context.Context, acErr AccessTokenError) {
 	ctx.JSON(http.StatusBadRequest, acErr)
 }
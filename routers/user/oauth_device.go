@@ -0,0 +1,263 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package user
+
+import (
+	"html"
+	"net/http"
+	"net/url"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/base"
+	"go.wandrs.dev/framework/modules/context"
+	"go.wandrs.dev/framework/modules/log"
+	"go.wandrs.dev/framework/modules/setting"
+	"go.wandrs.dev/framework/modules/web"
+	"go.wandrs.dev/framework/services/forms"
+)
+
+const (
+	tplDeviceAuthorize base.TplName = "user/auth/device"
+
+	// DeviceCodeGrantType is the grant type of the device authorization grant specified in RFC 8628
+	DeviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+)
+
+// DeviceAuthorizationResponse represents a device authorization response specified in RFC 8628
+type DeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval"`
+}
+
+// DeviceAuthorizationOAuth starts a device authorization request of a client
+func DeviceAuthorizationOAuth(ctx *context.Context) {
+	form := web.GetForm(ctx).(*forms.DeviceAuthorizationForm)
+	if form.ClientID == "" {
+		var ok bool
+		if form.ClientID, form.ClientSecret, ok = parseClientBasicAuth(ctx); !ok {
+			handleAccessTokenError(ctx, AccessTokenError{
+				ErrorCode:        AccessTokenErrorCodeInvalidRequest,
+				ErrorDescription: "cannot parse basic auth header",
+			})
+			return
+		}
+	}
+	app, err := models.GetOAuth2ApplicationByClientID(form.ClientID)
+	if err != nil {
+		handleInvalidClient(ctx)
+		return
+	}
+	// devices usually cannot keep a secret, it is only checked if provided
+	if form.ClientSecret != "" && !app.ValidateClientSecret([]byte(form.ClientSecret)) {
+		handleInvalidClient(ctx)
+		return
+	}
+
+	auth, err := app.CreateDeviceAuthorization(form.Scope)
+	if err != nil {
+		log.Error("CreateDeviceAuthorization: %v", err)
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeInvalidRequest,
+			ErrorDescription: "cannot proceed your request",
+		})
+		return
+	}
+
+	verificationURI := setting.AppURL + "login/device"
+	ctx.JSON(http.StatusOK, &DeviceAuthorizationResponse{
+		DeviceCode:              auth.DeviceCode,
+		UserCode:                auth.FormattedUserCode(),
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + "?user_code=" + url.QueryEscape(auth.FormattedUserCode()),
+		ExpiresIn:               setting.OAuth2.DeviceCodeExpirationTime,
+		Interval:                auth.PollInterval,
+	})
+}
+
+// handleDeviceCode answers a poll of a device for its access token
+func handleDeviceCode(ctx *context.Context, form forms.AccessTokenForm) {
+	auth, err := models.GetOAuth2DeviceAuthorizationByDeviceCode(form.DeviceCode)
+	if err != nil {
+		log.Error("GetOAuth2DeviceAuthorizationByDeviceCode: %v", err)
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeInvalidRequest,
+			ErrorDescription: "cannot proceed your request",
+		})
+		return
+	}
+	if auth == nil || auth.LoadApplication() != nil || auth.Application.ClientID != form.ClientID {
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeInvalidGrant,
+			ErrorDescription: "invalid device code",
+		})
+		return
+	}
+	if form.ClientSecret != "" && !auth.Application.ValidateClientSecret([]byte(form.ClientSecret)) {
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeUnauthorizedClient,
+			ErrorDescription: "client is not authorized",
+		})
+		return
+	}
+	if auth.IsExpired() {
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeExpiredToken,
+			ErrorDescription: "the device code has expired",
+		})
+		return
+	}
+
+	switch auth.Status {
+	case models.OAuth2DeviceAuthorizationPending:
+		slowDown, err := auth.Poll()
+		if err != nil {
+			log.Error("Poll: %v", err)
+		}
+		if slowDown {
+			handleAccessTokenError(ctx, AccessTokenError{
+				ErrorCode:        AccessTokenErrorCodeSlowDown,
+				ErrorDescription: "polling too fast",
+			})
+			return
+		}
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeAuthorizationPending,
+			ErrorDescription: "the user has not yet approved the device",
+		})
+		return
+	case models.OAuth2DeviceAuthorizationDenied:
+		if _, err := auth.Invalidate(); err != nil {
+			log.Error("Invalidate: %v", err)
+		}
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeAccessDenied,
+			ErrorDescription: "the user denied the device",
+		})
+		return
+	}
+
+	grant, err := auth.Application.GetGrantByUserID(auth.UserID)
+	if err != nil || grant == nil {
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeInvalidGrant,
+			ErrorDescription: "grant does not exist",
+		})
+		return
+	}
+	// remove the device authorization to deny duplicate usage
+	if invalidated, err := auth.Invalidate(); err != nil {
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeInvalidRequest,
+			ErrorDescription: "cannot proceed your request",
+		})
+		return
+	} else if !invalidated {
+		handleAccessTokenError(ctx, AccessTokenError{
+			ErrorCode:        AccessTokenErrorCodeInvalidGrant,
+			ErrorDescription: "device code has already been used",
+		})
+		return
+	}
+	resp, tokenErr := newAccessTokenResponse(grant, form.ClientSecret)
+	if tokenErr != nil {
+		handleAccessTokenError(ctx, *tokenErr)
+		return
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// loadPendingDeviceAuthorization returns the pending device authorization of the user code or nil
+func loadPendingDeviceAuthorization(ctx *context.Context, userCode string) *models.OAuth2DeviceAuthorization {
+	auth, err := models.GetOAuth2DeviceAuthorizationByUserCode(userCode)
+	if err != nil {
+		ctx.ServerError("GetOAuth2DeviceAuthorizationByUserCode", err)
+		return nil
+	}
+	if auth == nil || auth.IsExpired() || auth.Status != models.OAuth2DeviceAuthorizationPending {
+		ctx.RenderWithErr(ctx.Tr("auth.device_code_invalid"), tplDeviceAuthorize, &forms.DeviceUserCodeForm{UserCode: userCode})
+		return nil
+	}
+	if err := auth.LoadApplication(); err != nil {
+		ctx.ServerError("LoadApplication", err)
+		return nil
+	}
+	if err := auth.Application.LoadUser(); err != nil {
+		ctx.ServerError("LoadUser", err)
+		return nil
+	}
+	return auth
+}
+
+func renderDeviceAuthorization(ctx *context.Context, auth *models.OAuth2DeviceAuthorization) {
+	app := auth.Application
+	ctx.Data["DeviceAuthorization"] = auth
+	ctx.Data["Application"] = app
+	ctx.Data["ApplicationUserLink"] = "<a href=\"" + html.EscapeString(setting.AppURL) + html.EscapeString(url.PathEscape(app.User.LowerName)) + "\">@" + html.EscapeString(app.User.Name) + "</a>"
+	ctx.HTML(http.StatusOK, tplDeviceAuthorize)
+}
+
+// DeviceOAuth shows the page to enter or confirm the user code of a device
+func DeviceOAuth(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("auth.device_title")
+	userCode := ctx.Query("user_code")
+	if userCode == "" {
+		ctx.HTML(http.StatusOK, tplDeviceAuthorize)
+		return
+	}
+
+	auth := loadPendingDeviceAuthorization(ctx, userCode)
+	if auth == nil {
+		return
+	}
+	renderDeviceAuthorization(ctx, auth)
+}
+
+// DeviceOAuthPost looks up a user code and approves or denies the device authorization once confirmed
+func DeviceOAuthPost(ctx *context.Context) {
+	form := web.GetForm(ctx).(*forms.DeviceUserCodeForm)
+	ctx.Data["Title"] = ctx.Tr("auth.device_title")
+	if ctx.HasError() {
+		ctx.HTML(http.StatusOK, tplDeviceAuthorize)
+		return
+	}
+
+	auth := loadPendingDeviceAuthorization(ctx, form.UserCode)
+	if auth == nil {
+		return
+	}
+
+	switch form.Granted {
+	case "true":
+		grant, err := auth.Application.GetGrantByUserID(ctx.User.ID)
+		if err == nil && grant == nil {
+			_, err = auth.Application.CreateGrant(ctx.User.ID, auth.Scope)
+		} else if err == nil {
+			err = grant.AddScope(auth.Scope)
+		}
+		if err != nil {
+			ctx.ServerError("CreateGrant", err)
+			return
+		}
+		if err := auth.Approve(ctx.User.ID); err != nil {
+			ctx.ServerError("Approve", err)
+			return
+		}
+		ctx.Flash.Success(ctx.Tr("auth.device_approved", auth.Application.Name))
+	case "false":
+		if err := auth.Deny(ctx.User.ID); err != nil {
+			ctx.ServerError("Deny", err)
+			return
+		}
+		ctx.Flash.Info(ctx.Tr("auth.device_denied", auth.Application.Name))
+	default:
+		renderDeviceAuthorization(ctx, auth)
+		return
+	}
+	ctx.Redirect(setting.AppSubURL + "/login/device")
+}
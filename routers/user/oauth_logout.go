@@ -0,0 +1,118 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package user
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/base"
+	"go.wandrs.dev/framework/modules/context"
+	"go.wandrs.dev/framework/modules/setting"
+	"go.wandrs.dev/framework/modules/web"
+	"go.wandrs.dev/framework/services/forms"
+)
+
+const tplEndSession base.TplName = "user/auth/logout"
+
+// endSessionRequest is a validated logout request of a relying party
+type endSessionRequest struct {
+	Application *models.OAuth2Application
+	RedirectTo  string
+	// SubjectVerified is true if the request carries a verified id_token of the signed in user
+	SubjectVerified bool
+}
+
+// parseEndSessionRequest validates the id_token_hint and post_logout_redirect_uri of a logout request.
+// The redirect URI must be registered for the application identified by the hint or client_id.
+// Nil is returned and an error page rendered if the request is invalid.
+func parseEndSessionRequest(ctx *context.Context, form *forms.EndSessionForm) *endSessionRequest {
+	req := &endSessionRequest{RedirectTo: setting.AppSubURL + "/"}
+
+	if form.IDTokenHint != "" {
+		hint, verified, err := models.ParseOIDCTokenHint(form.IDTokenHint)
+		if err != nil || (form.ClientID != "" && hint.Audience != form.ClientID) {
+			renderInvalidEndSession(ctx)
+			return nil
+		}
+		form.ClientID = hint.Audience
+		req.SubjectVerified = verified && ctx.User != nil && hint.Subject == strconv.FormatInt(ctx.User.ID, 10)
+	}
+
+	if form.ClientID != "" {
+		app, err := models.GetOAuth2ApplicationByClientID(form.ClientID)
+		if err != nil {
+			if models.IsErrOauthClientIDInvalid(err) {
+				renderInvalidEndSession(ctx)
+				return nil
+			}
+			ctx.ServerError("GetOAuth2ApplicationByClientID", err)
+			return nil
+		}
+		req.Application = app
+	}
+
+	if form.PostLogoutRedirectURI != "" {
+		if req.Application == nil || !req.Application.ContainsRedirectURI(form.PostLogoutRedirectURI) {
+			renderInvalidEndSession(ctx)
+			return nil
+		}
+		redirect, err := url.Parse(form.PostLogoutRedirectURI)
+		if err != nil {
+			renderInvalidEndSession(ctx)
+			return nil
+		}
+		if form.State != "" {
+			q := redirect.Query()
+			q.Set("state", form.State)
+			redirect.RawQuery = q.Encode()
+		}
+		req.RedirectTo = redirect.String()
+	}
+	return req
+}
+
+func renderInvalidEndSession(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("auth.logout_confirm_title")
+	ctx.Data["InvalidRequest"] = true
+	ctx.HTML(http.StatusBadRequest, tplEndSession)
+}
+
+// EndSessionOAuth implements the end session endpoint of OpenID Connect RP-Initiated Logout.
+// The user is signed out right away if the request carries a verified id_token of the user,
+// otherwise the sign out has to be confirmed.
+func EndSessionOAuth(ctx *context.Context) {
+	form := web.GetForm(ctx).(*forms.EndSessionForm)
+	req := parseEndSessionRequest(ctx, form)
+	if req == nil {
+		return
+	}
+
+	if ctx.User == nil {
+		ctx.Redirect(req.RedirectTo)
+		return
+	}
+	if req.SubjectVerified {
+		signOutAndRedirect(ctx, req.RedirectTo)
+		return
+	}
+
+	ctx.Data["Title"] = ctx.Tr("auth.logout_confirm_title")
+	ctx.Data["Application"] = req.Application
+	ctx.Data["Form"] = form
+	ctx.HTML(http.StatusOK, tplEndSession)
+}
+
+// EndSessionOAuthPost signs out the user after the logout request has been confirmed
+func EndSessionOAuthPost(ctx *context.Context) {
+	form := web.GetForm(ctx).(*forms.EndSessionForm)
+	req := parseEndSessionRequest(ctx, form)
+	if req == nil {
+		return
+	}
+	signOutAndRedirect(ctx, req.RedirectTo)
+}
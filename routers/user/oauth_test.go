@@ -0,0 +1,37 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package user
+
+import (
+	"testing"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/auth/oauth2"
+	"go.wandrs.dev/framework/modules/setting"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAccessTokenResponseSymmetricIDToken(t *testing.T) {
+	models.PrepareTestEnv(t)
+	defer func(alg string, secret []byte) {
+		setting.OAuth2.JWTSigningAlgorithm, setting.OAuth2.JWTSecretBytes = alg, secret
+		assert.NoError(t, oauth2.InitSigningKey())
+	}(setting.OAuth2.JWTSigningAlgorithm, setting.OAuth2.JWTSecretBytes)
+	setting.OAuth2.JWTSigningAlgorithm = "HS256"
+	setting.OAuth2.JWTSecretBytes = []byte("jwt-secret-of-the-test-instance!")
+	assert.NoError(t, oauth2.InitSigningKey())
+
+	grant := models.AssertExistsAndLoadBean(t, &models.OAuth2Grant{ID: 1}).(*models.OAuth2Grant)
+	resp, tokenErr := newAccessTokenResponse(grant, "client-secret")
+	assert.Nil(t, tokenErr)
+	assert.NotEmpty(t, resp.IDToken)
+
+	// without a client secret the id_token could be forged by anyone
+	resp, tokenErr = newAccessTokenResponse(grant, "")
+	assert.Nil(t, tokenErr)
+	assert.NotEmpty(t, resp.AccessToken)
+	assert.Empty(t, resp.IDToken)
+}
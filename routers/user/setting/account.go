@@ -75,6 +75,10 @@ func AccountPost(ctx *context.Context) {
 			ctx.ServerError("UpdateUser", err)
 			return
 		}
+		if err := models.DeleteOtherUserSessions(ctx.User.ID, currentSessionID(ctx)); err != nil {
+			ctx.ServerError("DeleteOtherUserSessions", err)
+			return
+		}
 		log.Trace("User password updated: %s", ctx.User.Name)
 		ctx.Flash.Success(ctx.Tr("settings.change_password_success"))
 	}
@@ -232,6 +236,10 @@ func DeleteAccount(ctx *context.Context) {
 			loadAccountData(ctx)
 
 			ctx.RenderWithErr(ctx.Tr("form.enterred_invalid_password"), tplSettingsAccount, nil)
+		} else if models.IsErrUserLockedOut(err) {
+			loadAccountData(ctx)
+
+			ctx.RenderWithErr(ctx.Tr("auth.too_many_failed_attempts"), tplSettingsAccount, nil)
 		} else {
 			ctx.ServerError("UserSignIn", err)
 		}
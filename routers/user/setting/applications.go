@@ -7,11 +7,13 @@ package setting
 
 import (
 	"net/http"
+	"time"
 
 	"go.wandrs.dev/framework/models"
 	"go.wandrs.dev/framework/modules/base"
 	"go.wandrs.dev/framework/modules/context"
 	"go.wandrs.dev/framework/modules/setting"
+	"go.wandrs.dev/framework/modules/timeutil"
 	"go.wandrs.dev/framework/modules/web"
 	"go.wandrs.dev/framework/services/forms"
 )
@@ -43,9 +45,35 @@ func ApplicationsPost(ctx *context.Context) {
 		return
 	}
 
+	if len(form.Scope) == 0 {
+		ctx.Flash.Error(ctx.Tr("settings.token_scope_required"))
+		ctx.Redirect(setting.AppSubURL + "/user/settings/applications")
+		return
+	}
+	scope, err := models.ParseAccessTokenScopes(form.Scope)
+	if err != nil {
+		ctx.Flash.Error(ctx.Tr("settings.token_scope_invalid"))
+		ctx.Redirect(setting.AppSubURL + "/user/settings/applications")
+		return
+	}
+
 	t := &models.AccessToken{
-		UID:  ctx.User.ID,
-		Name: form.Name,
+		UID:   ctx.User.ID,
+		Name:  form.Name,
+		Scope: scope,
+	}
+	if len(form.ExpiresAt) > 0 {
+		expiresAt, err := time.ParseInLocation("2006-01-02", form.ExpiresAt, setting.DefaultUILocation)
+		if err != nil {
+			ctx.Flash.Error(ctx.Tr("settings.token_expiry_invalid"))
+			ctx.Redirect(setting.AppSubURL + "/user/settings/applications")
+			return
+		}
+		// the token stays valid until the end of the chosen day, as far as the maximum lifetime allows
+		t.ExpiredUnix = timeutil.TimeStamp(expiresAt.AddDate(0, 0, 1).Unix() - 1)
+		if maxExpiry := timeutil.TimeStampNow().AddDuration(setting.AccessTokenMaxLifetime); setting.AccessTokenMaxLifetime > 0 && t.ExpiredUnix > maxExpiry {
+			t.ExpiredUnix = maxExpiry
+		}
 	}
 
 	exist, err := models.AccessTokenByNameExists(t)
@@ -60,9 +88,20 @@ func ApplicationsPost(ctx *context.Context) {
 	}
 
 	if err := models.NewAccessToken(t); err != nil {
+		if models.IsErrAccessTokenInvalidExpiry(err) {
+			ctx.Flash.Error(ctx.Tr("settings.token_expiry_invalid"))
+			ctx.Redirect(setting.AppSubURL + "/user/settings/applications")
+			return
+		}
 		ctx.ServerError("NewAccessToken", err)
 		return
 	}
+	ctx.Audit(&models.AuditEvent{
+		Action:     models.AuditAccessTokenCreate,
+		TargetID:   t.ID,
+		TargetName: t.Name,
+		After:      t.AuditValues(),
+	})
 
 	ctx.Flash.Success(ctx.Tr("settings.generate_token_success"))
 	ctx.Flash.Info(t.Token)
@@ -72,9 +111,14 @@ func ApplicationsPost(ctx *context.Context) {
 
 // DeleteApplication response for delete user access token
 func DeleteApplication(ctx *context.Context) {
-	if err := models.DeleteAccessTokenByID(ctx.QueryInt64("id"), ctx.User.ID); err != nil {
+	id := ctx.QueryInt64("id")
+	if err := models.DeleteAccessTokenByID(id, ctx.User.ID); err != nil {
 		ctx.Flash.Error("DeleteAccessTokenByID: " + err.Error())
 	} else {
+		ctx.Audit(&models.AuditEvent{
+			Action:   models.AuditAccessTokenDelete,
+			TargetID: id,
+		})
 		ctx.Flash.Success(ctx.Tr("settings.delete_token_success"))
 	}
 
@@ -90,6 +134,10 @@ func loadApplicationsData(ctx *context.Context) {
 		return
 	}
 	ctx.Data["Tokens"] = tokens
+	ctx.Data["AccessTokenScopes"] = models.AllAccessTokenScopes
+	if setting.AccessTokenMaxLifetime > 0 {
+		ctx.Data["AccessTokenMaxLifetime"] = timeutil.MinutesToFriendly(int(setting.AccessTokenMaxLifetime.Minutes()), ctx.Locale.Language())
+	}
 	ctx.Data["EnableOAuth2"] = setting.OAuth2.Enable
 	if setting.OAuth2.Enable {
 		ctx.Data["Applications"], err = models.GetOAuth2ApplicationsByUserID(ctx.User.ID)
@@ -0,0 +1,43 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package setting
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/setting"
+	"go.wandrs.dev/framework/modules/test"
+	"go.wandrs.dev/framework/modules/timeutil"
+	"go.wandrs.dev/framework/modules/web"
+	"go.wandrs.dev/framework/services/forms"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplicationsPostMaxLifetime(t *testing.T) {
+	models.PrepareTestEnv(t)
+	defer func(maxLifetime time.Duration) {
+		setting.AccessTokenMaxLifetime = maxLifetime
+	}(setting.AccessTokenMaxLifetime)
+	setting.AccessTokenMaxLifetime = 24 * time.Hour
+
+	// the end of the chosen day is capped at the maximum lifetime
+	ctx := test.MockContext(t, "user/settings/applications")
+	test.LoadUser(t, ctx, 2)
+	web.SetForm(ctx, &forms.NewAccessTokenForm{
+		Name:      "capped",
+		Scope:     []string{string(models.AccessTokenScopeAll)},
+		ExpiresAt: time.Now().In(setting.DefaultUILocation).AddDate(0, 0, 1).Format("2006-01-02"),
+	})
+	ApplicationsPost(ctx)
+	assert.EqualValues(t, http.StatusFound, ctx.Resp.Status())
+	assert.Empty(t, ctx.Flash.ErrorMsg)
+
+	token := models.AssertExistsAndLoadBean(t, &models.AccessToken{UID: 2, Name: "capped"}).(*models.AccessToken)
+	assert.LessOrEqual(t, int64(token.ExpiredUnix), int64(timeutil.TimeStampNow().AddDuration(24*time.Hour)))
+}
@@ -0,0 +1,151 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package setting
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/base"
+	"go.wandrs.dev/framework/modules/context"
+	"go.wandrs.dev/framework/modules/log"
+	"go.wandrs.dev/framework/modules/web"
+	"go.wandrs.dev/framework/services/forms"
+)
+
+// OAuth2CommonHandlers manages the oauth2 applications of a user or an organization
+type OAuth2CommonHandlers struct {
+	OwnerID            int64        // the user or organization owning the applications
+	BasePathList       string       // the page listing the applications
+	BasePathEditPrefix string       // the prefix of the pages of the applications, followed by the application id
+	TplAppEdit         base.TplName // the template of the page of an application
+}
+
+func (oa *OAuth2CommonHandlers) renderEditPage(ctx *context.Context) {
+	app := ctx.Data["App"].(*models.OAuth2Application)
+	ctx.Data["FormActionPath"] = fmt.Sprintf("%s/%d", oa.BasePathEditPrefix, app.ID)
+	ctx.HTML(http.StatusOK, oa.TplAppEdit)
+}
+
+// getApp returns the application of the request if it belongs to the owner, otherwise it renders not found
+func (oa *OAuth2CommonHandlers) getApp(ctx *context.Context) *models.OAuth2Application {
+	app, err := models.GetOAuth2ApplicationByID(ctx.ParamsInt64("id"))
+	if err != nil {
+		if models.IsErrOAuthApplicationNotFound(err) {
+			ctx.NotFound("Application not found", err)
+			return nil
+		}
+		ctx.ServerError("GetOAuth2ApplicationByID", err)
+		return nil
+	}
+	if app.UID != oa.OwnerID {
+		ctx.NotFound("Application not found", nil)
+		return nil
+	}
+	return app
+}
+
+// AddApp adds an oauth2 application and shows its client secret
+func (oa *OAuth2CommonHandlers) AddApp(ctx *context.Context) {
+	form := web.GetForm(ctx).(*forms.EditOAuth2ApplicationForm)
+	if ctx.HasError() {
+		ctx.Flash.Error(ctx.GetErrMsg())
+		ctx.Redirect(oa.BasePathList)
+		return
+	}
+
+	// TODO validate redirect URI
+	app, err := models.CreateOAuth2Application(models.CreateOAuth2ApplicationOptions{
+		Name:                  form.Name,
+		RedirectURIs:          []string{form.RedirectURI},
+		FrontchannelLogoutURI: form.FrontchannelLogoutURI,
+		UserID:                oa.OwnerID,
+	})
+	if err != nil {
+		ctx.ServerError("CreateOAuth2Application", err)
+		return
+	}
+	log.Trace("OAuth2 Application created by %s: %s", ctx.User.Name, app.ClientID)
+
+	ctx.Flash.Success(ctx.Tr("settings.create_oauth2_application_success"))
+	ctx.Data["App"] = app
+	ctx.Data["ClientSecret"], err = app.GenerateClientSecret()
+	if err != nil {
+		ctx.ServerError("GenerateClientSecret", err)
+		return
+	}
+	oa.renderEditPage(ctx)
+}
+
+// EditShow displays an oauth2 application
+func (oa *OAuth2CommonHandlers) EditShow(ctx *context.Context) {
+	app := oa.getApp(ctx)
+	if app == nil {
+		return
+	}
+	ctx.Data["App"] = app
+	oa.renderEditPage(ctx)
+}
+
+// EditSave saves the changes of an oauth2 application
+func (oa *OAuth2CommonHandlers) EditSave(ctx *context.Context) {
+	form := web.GetForm(ctx).(*forms.EditOAuth2ApplicationForm)
+	app := oa.getApp(ctx)
+	if app == nil {
+		return
+	}
+	if ctx.HasError() {
+		ctx.Flash.Error(ctx.GetErrMsg())
+		ctx.Redirect(fmt.Sprintf("%s/%d", oa.BasePathEditPrefix, app.ID))
+		return
+	}
+
+	// TODO validate redirect URI
+	var err error
+	if ctx.Data["App"], err = models.UpdateOAuth2Application(models.UpdateOAuth2ApplicationOptions{
+		ID:                    app.ID,
+		Name:                  form.Name,
+		RedirectURIs:          []string{form.RedirectURI},
+		FrontchannelLogoutURI: form.FrontchannelLogoutURI,
+		UserID:                oa.OwnerID,
+	}); err != nil {
+		ctx.ServerError("UpdateOAuth2Application", err)
+		return
+	}
+	ctx.Flash.Success(ctx.Tr("settings.update_oauth2_application_success"))
+	oa.renderEditPage(ctx)
+}
+
+// RegenerateSecret generates and shows a new client secret of an oauth2 application
+func (oa *OAuth2CommonHandlers) RegenerateSecret(ctx *context.Context) {
+	app := oa.getApp(ctx)
+	if app == nil {
+		return
+	}
+	ctx.Data["App"] = app
+	var err error
+	ctx.Data["ClientSecret"], err = app.GenerateClientSecret()
+	if err != nil {
+		ctx.ServerError("GenerateClientSecret", err)
+		return
+	}
+	ctx.Flash.Success(ctx.Tr("settings.update_oauth2_application_success"))
+	oa.renderEditPage(ctx)
+}
+
+// DeleteApp deletes an oauth2 application
+func (oa *OAuth2CommonHandlers) DeleteApp(ctx *context.Context) {
+	if err := models.DeleteOAuth2Application(ctx.QueryInt64("id"), oa.OwnerID); err != nil {
+		ctx.ServerError("DeleteOAuth2Application", err)
+		return
+	}
+	log.Trace("OAuth2 Application deleted by %s: %d", ctx.User.Name, ctx.QueryInt64("id"))
+
+	ctx.Flash.Success(ctx.Tr("settings.remove_oauth2_application_success"))
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"redirect": oa.BasePathList,
+	})
+}
@@ -23,7 +23,6 @@ const (
 func Security(ctx *context.Context) {
 	ctx.Data["Title"] = ctx.Tr("settings")
 	ctx.Data["PageIsSettingsSecurity"] = true
-	ctx.Data["RequireU2F"] = true
 
 	if ctx.Query("openid.return_to") != "" {
 		settingsOpenIDVerify(ctx)
@@ -66,9 +65,9 @@ func loadSecurityData(ctx *context.Context) {
 	}
 	ctx.Data["TwofaEnrolled"] = enrolled
 	if enrolled {
-		ctx.Data["U2FRegistrations"], err = models.GetU2FRegistrationsByUID(ctx.User.ID)
+		ctx.Data["WebAuthnCredentials"], err = models.GetWebAuthnCredentialsByUID(ctx.User.ID)
 		if err != nil {
-			ctx.ServerError("GetU2FRegistrationsByUID", err)
+			ctx.ServerError("GetWebAuthnCredentialsByUID", err)
 			return
 		}
 	}
@@ -108,4 +107,12 @@ func loadSecurityData(ctx *context.Context) {
 		return
 	}
 	ctx.Data["OpenIDs"] = openid
+
+	sessions, err := models.ListUserSessions(ctx.User.ID)
+	if err != nil {
+		ctx.ServerError("ListUserSessions", err)
+		return
+	}
+	ctx.Data["Sessions"] = sessions
+	ctx.Data["CurrentSessionID"] = currentSessionID(ctx)
 }
@@ -0,0 +1,84 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package setting
+
+import (
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/auth/sso"
+	"go.wandrs.dev/framework/modules/base"
+	"go.wandrs.dev/framework/modules/context"
+	"go.wandrs.dev/framework/modules/log"
+	"go.wandrs.dev/framework/modules/setting"
+)
+
+// currentSessionID returns the ID of the tracked session of the request
+func currentSessionID(ctx *context.Context) int64 {
+	id, _ := ctx.Session.Get(sso.UserSessionKey).(int64)
+	return id
+}
+
+// RevokeSession signs out one of the sessions of the user
+func RevokeSession(ctx *context.Context) {
+	id := ctx.QueryInt64("id")
+	if id == currentSessionID(ctx) {
+		ctx.Flash.Error(ctx.Tr("settings.sessions_revoke_current"))
+		ctx.Redirect(setting.AppSubURL + "/user/settings/security")
+		return
+	}
+
+	if err := models.DeleteUserSession(ctx.User.ID, id); err != nil {
+		if models.IsErrUserSessionNotExist(err) {
+			ctx.NotFound("DeleteUserSession", err)
+		} else {
+			ctx.ServerError("DeleteUserSession", err)
+		}
+		return
+	}
+	// the browser of the session would otherwise sign in again with its auto-login cookie
+	if err := rotateRememberCookie(ctx); err != nil {
+		ctx.ServerError("rotateRememberCookie", err)
+		return
+	}
+	log.Trace("Session %d of user %s signed out", id, ctx.User.Name)
+
+	ctx.Flash.Success(ctx.Tr("settings.sessions_revoke_success"))
+	ctx.Redirect(setting.AppSubURL + "/user/settings/security")
+}
+
+// rotateRememberCookie invalidates the auto-login cookies of all browsers of the user by
+// changing the secret they are signed with, and issues the cookie again to the current browser.
+func rotateRememberCookie(ctx *context.Context) error {
+	val, remembered := ctx.GetSuperSecureCookie(base.EncodeMD5(ctx.User.Rands+ctx.User.Passwd), setting.CookieRememberName)
+	var err error
+	if ctx.User.Rands, err = models.GetUserSalt(); err != nil {
+		return err
+	}
+	if err = models.UpdateUserCols(ctx.User, "rands"); err != nil {
+		return err
+	}
+	if remembered && val == ctx.User.Name {
+		days := 86400 * setting.LogInRememberDays
+		ctx.SetSuperSecureCookie(base.EncodeMD5(ctx.User.Rands+ctx.User.Passwd),
+			setting.CookieRememberName, ctx.User.Name, days)
+	}
+	return nil
+}
+
+// RevokeOtherSessions signs out all sessions of the user but the current one. The auto-login
+// cookies of the other browsers are invalidated by changing the secret they are signed with.
+func RevokeOtherSessions(ctx *context.Context) {
+	if err := models.DeleteOtherUserSessions(ctx.User.ID, currentSessionID(ctx)); err != nil {
+		ctx.ServerError("DeleteOtherUserSessions", err)
+		return
+	}
+	if err := rotateRememberCookie(ctx); err != nil {
+		ctx.ServerError("rotateRememberCookie", err)
+		return
+	}
+	log.Trace("Other sessions of user %s signed out", ctx.User.Name)
+
+	ctx.Flash.Success(ctx.Tr("settings.sessions_revoke_others_success"))
+	ctx.Redirect(setting.AppSubURL + "/user/settings/security")
+}
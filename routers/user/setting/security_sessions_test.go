@@ -0,0 +1,69 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package setting
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/auth/sso"
+	"go.wandrs.dev/framework/modules/context"
+	"go.wandrs.dev/framework/modules/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevokeSessions(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	current := &models.UserSession{UID: 2}
+	assert.NoError(t, models.CreateUserSession(current))
+	other := &models.UserSession{UID: 2}
+	assert.NoError(t, models.CreateUserSession(other))
+	another := &models.UserSession{UID: 2}
+	assert.NoError(t, models.CreateUserSession(another))
+	foreign := &models.UserSession{UID: 4}
+	assert.NoError(t, models.CreateUserSession(foreign))
+
+	newContext := func(id int64) *context.Context {
+		ctx := test.MockContext(t, "user/settings/security/sessions/revoke")
+		test.LoadUser(t, ctx, 2)
+		assert.NoError(t, ctx.Session.Set(sso.UserSessionKey, current.ID))
+		ctx.Req.Form.Set("id", strconv.FormatInt(id, 10))
+		return ctx
+	}
+
+	// the current session is signed out by signing out
+	ctx := newContext(current.ID)
+	RevokeSession(ctx)
+	assert.EqualValues(t, http.StatusFound, ctx.Resp.Status())
+	assert.EqualValues(t, "settings.sessions_revoke_current", ctx.Flash.ErrorMsg)
+	models.AssertExistsAndLoadBean(t, &models.UserSession{ID: current.ID})
+
+	// sessions of other users cannot be signed out
+	ctx = newContext(foreign.ID)
+	RevokeSession(ctx)
+	assert.EqualValues(t, http.StatusNotFound, ctx.Resp.Status())
+	models.AssertExistsAndLoadBean(t, &models.UserSession{ID: foreign.ID})
+
+	// the auto-login cookie of the signed out browser is invalidated
+	rands := models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User).Rands
+	ctx = newContext(other.ID)
+	RevokeSession(ctx)
+	assert.EqualValues(t, http.StatusFound, ctx.Resp.Status())
+	models.AssertNotExistsBean(t, &models.UserSession{ID: other.ID})
+	assert.NotEqual(t, rands, models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User).Rands)
+
+	rands = models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User).Rands
+	ctx = newContext(0)
+	RevokeOtherSessions(ctx)
+	assert.EqualValues(t, http.StatusFound, ctx.Resp.Status())
+	models.AssertNotExistsBean(t, &models.UserSession{ID: another.ID})
+	models.AssertExistsAndLoadBean(t, &models.UserSession{ID: current.ID})
+	models.AssertExistsAndLoadBean(t, &models.UserSession{ID: foreign.ID})
+	assert.NotEqual(t, rands, models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User).Rands)
+}
@@ -78,6 +78,11 @@ func DisableTwoFactor(ctx *context.Context) {
 		ctx.ServerError("SettingsTwoFactor: Failed to DeleteTwoFactorByID", err)
 		return
 	}
+	ctx.Audit(&models.AuditEvent{
+		Action:     models.AuditTwoFactorDisable,
+		TargetID:   ctx.User.ID,
+		TargetName: ctx.User.Name,
+	})
 
 	ctx.Flash.Success(ctx.Tr("settings.twofa_disabled"))
 	ctx.Redirect(setting.AppSubURL + "/user/settings/security")
@@ -0,0 +1,100 @@
+// Copyright 2018 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package setting
+
+import (
+	"errors"
+	"net/http"
+
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/auth/webauthn"
+	"go.wandrs.dev/framework/modules/context"
+	"go.wandrs.dev/framework/modules/log"
+	"go.wandrs.dev/framework/modules/setting"
+	"go.wandrs.dev/framework/modules/web"
+	"go.wandrs.dev/framework/services/forms"
+)
+
+// WebAuthnRegister initializes the webauthn registration procedure
+func WebAuthnRegister(ctx *context.Context) {
+	form := web.GetForm(ctx).(*forms.WebAuthnRegistrationForm)
+	if form.Name == "" {
+		ctx.Error(http.StatusConflict)
+		return
+	}
+	creds, err := models.GetWebAuthnCredentialsByUID(ctx.User.ID)
+	if err != nil {
+		ctx.ServerError("GetWebAuthnCredentialsByUID", err)
+		return
+	}
+	for _, cred := range creds {
+		if cred.Name == form.Name {
+			ctx.Error(http.StatusConflict, "Name already taken")
+			return
+		}
+	}
+
+	user := webauthn.UserEntity{
+		ID:          models.WebAuthnUserHandle(ctx.User.ID),
+		Name:        ctx.User.Name,
+		DisplayName: ctx.User.DisplayName(),
+	}
+	creation, sessionData, err := webauthn.NewConfig().BeginRegistration(user, ctx.User.ID, creds.ToCredentialDescriptors())
+	if err != nil {
+		ctx.ServerError("BeginRegistration", err)
+		return
+	}
+	if err := ctx.Session.Set("webauthnRegistration", sessionData); err != nil {
+		ctx.ServerError("Unable to set session key for webauthnRegistration", err)
+		return
+	}
+	if err := ctx.Session.Set("webauthnName", form.Name); err != nil {
+		ctx.ServerError("Unable to set session key for webauthnName", err)
+		return
+	}
+	// Here we're just going to try to release the session early
+	if err := ctx.Session.Release(); err != nil {
+		// we'll tolerate errors here as they *should* get saved elsewhere
+		log.Error("Unable to save changes to the session: %v", err)
+	}
+	ctx.JSON(http.StatusOK, creation)
+}
+
+// WebAuthnRegisterPost receives the credential created by the authenticator
+func WebAuthnRegisterPost(ctx *context.Context) {
+	response := web.GetForm(ctx).(*webauthn.CredentialCreationResponse)
+	sessionData, ok := ctx.Session.Get("webauthnRegistration").(*webauthn.SessionData)
+	name, nameOk := ctx.Session.Get("webauthnName").(string)
+	if !ok || !nameOk || sessionData.UserID != ctx.User.ID {
+		ctx.ServerError("WebAuthnRegisterPost", errors.New("not in WebAuthn session"))
+		return
+	}
+	_ = ctx.Session.Delete("webauthnRegistration")
+	_ = ctx.Session.Delete("webauthnName")
+
+	cred, err := webauthn.NewConfig().FinishRegistration(sessionData, response)
+	if err != nil {
+		log.Debug("WebAuthn registration of user %d failed: %v", ctx.User.ID, err)
+		ctx.Error(http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, err = models.CreateCredential(ctx.User.ID, name, cred); err != nil {
+		ctx.ServerError("CreateCredential", err)
+		return
+	}
+	ctx.Status(http.StatusCreated)
+}
+
+// WebAuthnDelete deletes a security key by id
+func WebAuthnDelete(ctx *context.Context) {
+	form := web.GetForm(ctx).(*forms.WebAuthnDeleteForm)
+	if _, err := models.DeleteCredential(form.ID, ctx.User.ID); err != nil {
+		ctx.ServerError("DeleteCredential", err)
+		return
+	}
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"redirect": setting.AppSubURL + "/user/settings/security",
+	})
+}
@@ -0,0 +1,45 @@
+// Copyright 2021 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package utils
+
+import (
+	"go.wandrs.dev/framework/models"
+	"go.wandrs.dev/framework/modules/context"
+	"go.wandrs.dev/framework/modules/setting"
+)
+
+// ListAuditEvents loads the page of audit events of the organization, or of all events if orgID is 0,
+// which match the action and actor filters of the query
+func ListAuditEvents(ctx *context.Context, orgID int64) {
+	page := ctx.QueryInt("page")
+	if page <= 1 {
+		page = 1
+	}
+	opts := &models.SearchAuditEventsOptions{
+		ListOptions: models.ListOptions{
+			Page:     page,
+			PageSize: setting.UI.Admin.NoticePagingNum,
+		},
+		Action:    models.AuditAction(ctx.Query("action")),
+		ActorName: ctx.Query("actor"),
+		OrgID:     orgID,
+	}
+
+	events, count, err := models.SearchAuditEvents(opts)
+	if err != nil {
+		ctx.ServerError("SearchAuditEvents", err)
+		return
+	}
+	ctx.Data["AuditEvents"] = events
+	ctx.Data["AuditActions"] = models.AuditActions
+	ctx.Data["Action"] = opts.Action
+	ctx.Data["Actor"] = opts.ActorName
+	ctx.Data["Total"] = count
+
+	pager := context.NewPagination(int(count), opts.PageSize, page, 5)
+	pager.AddParam(ctx, "action", "Action")
+	pager.AddParam(ctx, "actor", "Actor")
+	ctx.Data["Page"] = pager
+}
@@ -66,3 +66,18 @@ func (f *AdminDashboardForm) Validate(req *http.Request, errs binding.Errors) bi
 	ctx := context.GetContext(req)
 	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
 }
+
+// AdminEditOAuth2ApplicationForm form for admin to create or edit an oauth2 application of any user
+type AdminEditOAuth2ApplicationForm struct {
+	Owner                 string `binding:"MaxSize(40)"`
+	Name                  string `binding:"Required;MaxSize(255)" form:"application_name"`
+	RedirectURI           string `binding:"Required" form:"redirect_uri"`
+	FrontchannelLogoutURI string `binding:"ValidUrl" form:"frontchannel_logout_uri"`
+	Trusted               bool
+}
+
+// Validate validates form fields
+func (f *AdminEditOAuth2ApplicationForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
@@ -15,7 +15,7 @@ import (
 // AuthenticationForm form for authentication
 type AuthenticationForm struct {
 	ID                            int64
-	Type                          int    `binding:"Range(2,7)"`
+	Type                          int    `binding:"Range(2,8)"`
 	Name                          string `binding:"Required;MaxSize(30)"`
 	Host                          string
 	Port                          int
@@ -38,6 +38,8 @@ type AuthenticationForm struct {
 	GroupFilter                   string
 	GroupMemberUID                string
 	UserUID                       string
+	GroupTeamMap                  string
+	GroupTeamMapRemoval           bool
 	RestrictedFilter              string
 	AllowDeactivateAll            bool
 	IsActive                      bool
@@ -61,11 +63,28 @@ type AuthenticationForm struct {
 	Oauth2ProfileURL              string
 	Oauth2EmailURL                string
 	Oauth2IconURL                 string
+	Oauth2GroupClaimName          string
+	Oauth2AdminGroup              string
+	Oauth2RestrictedGroup         string
+	Oauth2GroupTeamMap            string
+	Oauth2GroupTeamMapRemoval     bool
 	SSPIAutoCreateUsers           bool
 	SSPIAutoActivateUsers         bool
 	SSPIStripDomainNames          bool
 	SSPISeparatorReplacement      string `binding:"AlphaDashDot;MaxSize(5)"`
 	SSPIDefaultLanguage           string
+	SAMLIdPEntityID               string `form:"saml_idp_entity_id"`
+	SAMLIdPSSOURL                 string `form:"saml_idp_sso_url"`
+	SAMLIdPCertificate            string `form:"saml_idp_certificate"`
+	SAMLSPEntityID                string `form:"saml_sp_entity_id"`
+	SAMLNameIDFormat              string `form:"saml_name_id_format"`
+	SAMLAttributeUsername         string `form:"saml_attribute_username"`
+	SAMLAttributeEmail            string `form:"saml_attribute_email"`
+	SAMLAttributeFullName         string `form:"saml_attribute_full_name"`
+	SAMLAttributeAdmin            string `form:"saml_attribute_admin"`
+	SAMLAdminValue                string `form:"saml_admin_value"`
+	SAMLAllowIdPInitiated         bool   `form:"saml_allow_idp_initiated"`
+	SAMLEnableAutoRegistration    bool   `form:"saml_enable_auto_registration"`
 }
 
 // Validate validates fields
@@ -43,6 +43,7 @@ type UpdateOrgSettingForm struct {
 	Location                  string `binding:"MaxSize(50)"`
 	Visibility                structs.VisibleType
 	RepoAdminChangeTeamAccess bool
+	AllowJoinRequests         bool
 }
 
 // Validate validates the fields
@@ -60,10 +61,13 @@ func (f *UpdateOrgSettingForm) Validate(req *http.Request, errs binding.Errors)
 
 // CreateTeamForm form for creating team
 type CreateTeamForm struct {
-	TeamName    string `binding:"Required;AlphaDashDot;MaxSize(30)"`
-	Description string `binding:"MaxSize(255)"`
-	Permission  string
-	RepoAccess  string
+	TeamName            string `binding:"Required;AlphaDashDot;MaxSize(30)"`
+	Description         string `binding:"MaxSize(255)"`
+	Permission          string
+	RepoAccess          string
+	CanManageOAuth2Apps bool  `form:"can_manage_oauth2_apps"`
+	RoleID              int64 `form:"role_id"`
+	ParentID            int64 `form:"parent_id"`
 }
 
 // Validate validates the fields
@@ -71,3 +75,49 @@ func (f *CreateTeamForm) Validate(req *http.Request, errs binding.Errors) bindin
 	ctx := context.GetContext(req)
 	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
 }
+
+// TeamInviteForm form for inviting a person by email to join a team
+type TeamInviteForm struct {
+	Email string `binding:"Required;Email;MaxSize(254)"`
+}
+
+// Validate validates the fields
+func (f *TeamInviteForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
+// OrgJoinRequestForm form for requesting to join an organization
+type OrgJoinRequestForm struct {
+	Message string `binding:"MaxSize(1000)"`
+}
+
+// Validate validates the fields
+func (f *OrgJoinRequestForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
+// OrgSecurityForm form for changing the security policies of an organization
+type OrgSecurityForm struct {
+	RequireTwoFactor            bool
+	RemoveTwoFactorNonCompliant bool
+}
+
+// Validate validates the fields
+func (f *OrgSecurityForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
+// OrgRoleForm form for creating and editing a role of an organization
+type OrgRoleForm struct {
+	Name        string `binding:"Required;MaxSize(50)" locale:"org.roles.name"`
+	Description string `binding:"MaxSize(255)"`
+}
+
+// Validate validates the fields
+func (f *OrgRoleForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
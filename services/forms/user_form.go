@@ -192,7 +192,7 @@ func (f *GrantApplicationForm) Validate(req *http.Request, errs binding.Errors)
 	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
 }
 
-// AccessTokenForm for issuing access tokens from authorization codes or refresh tokens
+// AccessTokenForm for issuing access tokens from authorization codes, refresh tokens or client credentials
 type AccessTokenForm struct {
 	GrantType    string `json:"grant_type"`
 	ClientID     string `json:"client_id"`
@@ -200,6 +200,8 @@ type AccessTokenForm struct {
 	RedirectURI  string `json:"redirect_uri"`
 	Code         string `json:"code"`
 	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+	DeviceCode   string `json:"device_code"`
 
 	// PKCE support
 	CodeVerifier string `json:"code_verifier"`
@@ -211,6 +213,59 @@ func (f *AccessTokenForm) Validate(req *http.Request, errs binding.Errors) bindi
 	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
 }
 
+// DeviceAuthorizationForm for starting a device authorization request
+type DeviceAuthorizationForm struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	Scope        string `json:"scope"`
+}
+
+// Validate validates the fields
+func (f *DeviceAuthorizationForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
+// DeviceUserCodeForm for entering the user code of a device and approving or denying it
+type DeviceUserCodeForm struct {
+	UserCode string `binding:"Required"`
+	Granted  string
+}
+
+// Validate validates the fields
+func (f *DeviceUserCodeForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
+// OAuth2TokenForm for token introspection and revocation requests
+type OAuth2TokenForm struct {
+	Token         string `json:"token"`
+	TokenTypeHint string `json:"token_type_hint"`
+	ClientID      string `json:"client_id"`
+	ClientSecret  string `json:"client_secret"`
+}
+
+// Validate validates the fields
+func (f *OAuth2TokenForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
+// EndSessionForm for OpenID Connect RP-initiated logout requests
+type EndSessionForm struct {
+	IDTokenHint           string `form:"id_token_hint"`
+	PostLogoutRedirectURI string `form:"post_logout_redirect_uri"`
+	State                 string `form:"state"`
+	ClientID              string `form:"client_id"`
+}
+
+// Validate validates the fields
+func (f *EndSessionForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
 //   __________________________________________.___ _______    ________  _________
 //  /   _____/\_   _____/\__    ___/\__    ___/|   |\      \  /  _____/ /   _____/
 //  \_____  \  |    __)_   |    |     |    |   |   |/   |   \/   \  ___ \_____  \
@@ -332,7 +387,9 @@ func (f *AddKeyForm) Validate(req *http.Request, errs binding.Errors) binding.Er
 
 // NewAccessTokenForm form for creating access token
 type NewAccessTokenForm struct {
-	Name string `binding:"Required;MaxSize(255)"`
+	Name      string `binding:"Required;MaxSize(255)"`
+	Scope     []string
+	ExpiresAt string
 }
 
 // Validate validates the fields
@@ -343,8 +400,9 @@ func (f *NewAccessTokenForm) Validate(req *http.Request, errs binding.Errors) bi
 
 // EditOAuth2ApplicationForm form for editing oauth2 applications
 type EditOAuth2ApplicationForm struct {
-	Name        string `binding:"Required;MaxSize(255)" form:"application_name"`
-	RedirectURI string `binding:"Required" form:"redirect_uri"`
+	Name                  string `binding:"Required;MaxSize(255)" form:"application_name"`
+	RedirectURI           string `binding:"Required" form:"redirect_uri"`
+	FrontchannelLogoutURI string `binding:"ValidUrl" form:"frontchannel_logout_uri"`
 }
 
 // Validate validates the fields
@@ -375,24 +433,24 @@ func (f *TwoFactorScratchAuthForm) Validate(req *http.Request, errs binding.Erro
 	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
 }
 
-// U2FRegistrationForm for reserving an U2F name
-type U2FRegistrationForm struct {
+// WebAuthnRegistrationForm for reserving a WebAuthn credential name
+type WebAuthnRegistrationForm struct {
 	Name string `binding:"Required"`
 }
 
 // Validate validates the fields
-func (f *U2FRegistrationForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+func (f *WebAuthnRegistrationForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
 	ctx := context.GetContext(req)
 	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
 }
 
-// U2FDeleteForm for deleting U2F keys
-type U2FDeleteForm struct {
+// WebAuthnDeleteForm for deleting WebAuthn credentials
+type WebAuthnDeleteForm struct {
 	ID int64 `binding:"Required"`
 }
 
 // Validate validates the fields
-func (f *U2FDeleteForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+func (f *WebAuthnDeleteForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
 	ctx := context.GetContext(req)
 	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
 }
@@ -29,6 +29,11 @@ const (
 	mailAuthActivateEmail  base.TplName = "auth/activate_email"
 	mailAuthResetPassword  base.TplName = "auth/reset_passwd"
 	mailAuthRegisterNotify base.TplName = "auth/register_notify"
+	mailAuthTokenExpiry    base.TplName = "auth/token_expiry"
+
+	mailOrgInvite            base.TplName = "org/invite"
+	mailOrgJoinRequest       base.TplName = "org/join_request"
+	mailOrgJoinRequestResult base.TplName = "org/join_request_result"
 
 	// There's no actual limit for subject in RFC 5322
 	mailMaxSubjectRunes = 256
@@ -147,3 +152,129 @@ func sanitizeSubject(subject string) string {
 	// Encode non-ASCII characters
 	return mime.QEncoding.Encode("utf-8", string(runes))
 }
+
+// SendAccessTokenExpiryMail notifies the owner of an access token that it is about to expire.
+func SendAccessTokenExpiryMail(u *models.User, t *models.AccessToken) {
+	locale := translation.NewLocale(u.Language)
+
+	data := map[string]interface{}{
+		"DisplayName": u.DisplayName(),
+		"TokenName":   t.Name,
+		"ExpiresAt":   t.ExpiresUnix().FormatLong(),
+		"i18n":        locale,
+		"Language":    locale.Language(),
+	}
+
+	var content bytes.Buffer
+
+	// TODO: i18n templates?
+	if err := bodyTemplates.ExecuteTemplate(&content, string(mailAuthTokenExpiry), data); err != nil {
+		log.Error("Template: %v", err)
+		return
+	}
+
+	msg := NewMessage([]string{u.Email}, locale.Tr("mail.token_expiry", t.Name), content.String())
+	msg.Info = fmt.Sprintf("UID: %d, access token expiry", u.ID)
+
+	SendAsync(msg)
+}
+
+// SendOrgInviteMail sends the invitation to join a team to the invited email address. The
+// invited person may not have an account yet, so the mail uses the language of the inviter.
+func SendOrgInviteMail(inv *models.OrgInvite) {
+	locale := translation.NewLocale(inv.Inviter.Language)
+
+	data := map[string]interface{}{
+		"Inviter":   inv.Inviter.DisplayName(),
+		"OrgName":   inv.Org.DisplayName(),
+		"TeamName":  inv.Team.Name,
+		"Code":      inv.GenerateCode(),
+		"ExpiresAt": inv.ExpiresUnix.FormatLong(),
+		"i18n":      locale,
+		"Language":  locale.Language(),
+	}
+
+	var content bytes.Buffer
+
+	// TODO: i18n templates?
+	if err := bodyTemplates.ExecuteTemplate(&content, string(mailOrgInvite), data); err != nil {
+		log.Error("Template: %v", err)
+		return
+	}
+
+	msg := NewMessage([]string{inv.Email}, locale.Tr("mail.org_invite", inv.Inviter.DisplayName(), inv.Org.DisplayName()), content.String())
+	msg.Info = fmt.Sprintf("OrgInviteID: %d, organization invitation", inv.ID)
+
+	SendAsync(msg)
+}
+
+// SendOrgJoinRequestMail notifies the owners of the organization of a new request to join it,
+// if the mail service is enabled. The attributes of the request are expected to be loaded.
+func SendOrgJoinRequestMail(r *models.OrgJoinRequest, owners []*models.User) {
+	if setting.MailService == nil {
+		return
+	}
+	for _, owner := range owners {
+		locale := translation.NewLocale(owner.Language)
+
+		data := map[string]interface{}{
+			"DisplayName": owner.DisplayName(),
+			"Requester":   r.User.DisplayName(),
+			"Message":     r.Message,
+			"OrgName":     r.Org.DisplayName(),
+			"Link":        setting.AppURL + "org/" + r.Org.Name + "/settings/join_requests",
+			"i18n":        locale,
+			"Language":    locale.Language(),
+		}
+
+		var content bytes.Buffer
+
+		// TODO: i18n templates?
+		if err := bodyTemplates.ExecuteTemplate(&content, string(mailOrgJoinRequest), data); err != nil {
+			log.Error("Template: %v", err)
+			return
+		}
+
+		msg := NewMessage([]string{owner.Email}, locale.Tr("mail.org_join_request", r.User.DisplayName(), r.Org.DisplayName()), content.String())
+		msg.Info = fmt.Sprintf("UID: %d, organization join request %d", owner.ID, r.ID)
+
+		SendAsync(msg)
+	}
+}
+
+// SendOrgJoinRequestResultMail notifies the user of the request that it has been approved
+// to the team, or rejected if the team is nil, if the mail service is enabled. The attributes
+// of the request are expected to be loaded.
+func SendOrgJoinRequestResultMail(r *models.OrgJoinRequest, team *models.Team) {
+	if setting.MailService == nil {
+		return
+	}
+	locale := translation.NewLocale(r.User.Language)
+
+	data := map[string]interface{}{
+		"DisplayName": r.User.DisplayName(),
+		"OrgName":     r.Org.DisplayName(),
+		"Link":        setting.AppURL + r.Org.Name,
+		"IsApproved":  team != nil,
+		"i18n":        locale,
+		"Language":    locale.Language(),
+	}
+	subject := locale.Tr("mail.org_join_request_rejected", r.Org.DisplayName())
+	if team != nil {
+		data["TeamName"] = team.Name
+		subject = locale.Tr("mail.org_join_request_approved", r.Org.DisplayName())
+	}
+
+	var content bytes.Buffer
+
+	// TODO: i18n templates?
+	if err := bodyTemplates.ExecuteTemplate(&content, string(mailOrgJoinRequestResult), data); err != nil {
+		log.Error("Template: %v", err)
+		return
+	}
+
+	msg := NewMessage([]string{r.User.Email}, subject, content.String())
+	msg.Info = fmt.Sprintf("UID: %d, organization join request %d result", r.User.ID, r.ID)
+
+	SendAsync(msg)
+}
@@ -0,0 +1,219 @@
+const {AppSubUrl, csrf} = window.config;
+
+// binary fields are transmitted as unpadded base64url, as done by PublicKeyCredential.toJSON()
+function decodeURLEncodedBase64(value) {
+  const base64 = value.replace(/-/g, '+').replace(/_/g, '/');
+  return Uint8Array.from(atob(base64), (c) => c.charCodeAt(0));
+}
+
+function encodeURLEncodedBase64(value) {
+  const bytes = new Uint8Array(value);
+  let binary = '';
+  for (const byte of bytes) binary += String.fromCharCode(byte);
+  return btoa(binary).replace(/\+/g, '-').replace(/\//g, '_').replace(/=+$/, '');
+}
+
+function decodeCredentialDescriptors(descriptors) {
+  return (descriptors || []).map((desc) => ({...desc, id: decodeURLEncodedBase64(desc.id)}));
+}
+
+function detectWebAuthnSupport() {
+  if (!window.isSecureContext) {
+    webAuthnError('insecure');
+    return false;
+  }
+  if (typeof window.PublicKeyCredential !== 'function') {
+    webAuthnError('browser');
+    return false;
+  }
+  return true;
+}
+
+function webAuthnError(errorType, message) {
+  const $errors = $('#webauthn-error .webauthn-error-message');
+  $errors.addClass('hide');
+  const $error = $errors.filter(`[data-webauthn-error-msg=${errorType}]`);
+  if ($error.length) {
+    $error.removeClass('hide');
+  } else {
+    const $unknown = $errors.filter('[data-webauthn-error-msg=unknown]');
+    $unknown.text(message || $unknown.data('default-text') || $unknown.text());
+    $unknown.removeClass('hide');
+  }
+  $('.webauthn-reload').toggleClass('hide', errorType !== 'timeout');
+  $('#webauthn-error').modal('show');
+}
+
+function errorTypeOf(err) {
+  switch (err && err.name) {
+    case 'NotAllowedError':
+      return 'timeout';
+    case 'InvalidStateError':
+      return 'duplicated';
+    case 'SecurityError':
+      return 'insecure';
+    default:
+      return 'unknown';
+  }
+}
+
+function encodeAssertion(credential) {
+  const extensions = credential.getClientExtensionResults();
+  const {response} = credential;
+  return JSON.stringify({
+    id: credential.id,
+    rawId: encodeURLEncodedBase64(credential.rawId),
+    type: credential.type,
+    clientExtensionResults: {appid: Boolean(extensions.appid)},
+    response: {
+      authenticatorData: encodeURLEncodedBase64(response.authenticatorData),
+      clientDataJSON: encodeURLEncodedBase64(response.clientDataJSON),
+      signature: encodeURLEncodedBase64(response.signature),
+      userHandle: response.userHandle ? encodeURLEncodedBase64(response.userHandle) : null,
+    },
+  });
+}
+
+async function assertWebAuthn(url) {
+  const options = await $.getJSON(url);
+  options.publicKey.challenge = decodeURLEncodedBase64(options.publicKey.challenge);
+  options.publicKey.allowCredentials = decodeCredentialDescriptors(options.publicKey.allowCredentials);
+
+  let credential;
+  try {
+    credential = await navigator.credentials.get({publicKey: options.publicKey});
+  } catch (err) {
+    webAuthnError(errorTypeOf(err), err.message);
+    return null;
+  }
+  return credential;
+}
+
+async function postAssertion(url, credential) {
+  try {
+    const res = await $.ajax({
+      url,
+      type: 'POST',
+      headers: {'X-Csrf-Token': csrf},
+      contentType: 'application/json; charset=utf-8',
+      data: encodeAssertion(credential),
+    });
+    window.location.href = res.redirect;
+  } catch (xhr) {
+    webAuthnError('unknown', xhr.responseJSON && xhr.responseJSON.error);
+  }
+}
+
+async function loginWebAuthn() {
+  const credential = await assertWebAuthn(`${AppSubUrl}/user/webauthn/assertion`);
+  if (!credential) return;
+  await postAssertion(`${AppSubUrl}/user/webauthn/assertion`, credential);
+}
+
+export function initWebAuthnAuth() {
+  if ($('#wait-for-key').length === 0) {
+    return;
+  }
+  $('#webauthn-error').modal({allowMultiple: false});
+  if (!detectWebAuthnSupport()) {
+    return;
+  }
+  loginWebAuthn();
+}
+
+async function loginPasskey() {
+  const credential = await assertWebAuthn(`${AppSubUrl}/user/webauthn/passkey/assertion`);
+  if (!credential) return;
+  const remember = $('input[name=remember]').is(':checked');
+  await postAssertion(`${AppSubUrl}/user/webauthn/passkey/assertion?remember=${remember}`, credential);
+}
+
+export function initWebAuthnPasskeyLogin() {
+  if ($('#passkey-sign-in').length === 0) {
+    return;
+  }
+  $('#webauthn-error').modal({allowMultiple: false});
+  $('#passkey-sign-in').on('click', (e) => {
+    e.preventDefault();
+    if (!detectWebAuthnSupport()) {
+      return;
+    }
+    loginPasskey();
+  });
+}
+
+async function registerWebAuthn(name) {
+  let options;
+  try {
+    options = await $.post(`${AppSubUrl}/user/settings/security/webauthn/request_register`, {
+      _csrf: csrf,
+      name,
+    });
+  } catch (xhr) {
+    if (xhr.status === 409) {
+      $('#nickname').closest('div.field').addClass('error');
+      return;
+    }
+    webAuthnError('unknown');
+    return;
+  }
+  $('#nickname').closest('div.field').removeClass('error');
+  $('#register-device').modal('show');
+
+  options.publicKey.challenge = decodeURLEncodedBase64(options.publicKey.challenge);
+  options.publicKey.user.id = decodeURLEncodedBase64(options.publicKey.user.id);
+  options.publicKey.excludeCredentials = decodeCredentialDescriptors(options.publicKey.excludeCredentials);
+
+  let credential;
+  try {
+    credential = await navigator.credentials.create({publicKey: options.publicKey});
+  } catch (err) {
+    $('#register-device').modal('hide');
+    webAuthnError(errorTypeOf(err), err.message);
+    return;
+  }
+
+  const {response} = credential;
+  try {
+    await $.ajax({
+      url: `${AppSubUrl}/user/settings/security/webauthn/register`,
+      type: 'POST',
+      headers: {'X-Csrf-Token': csrf},
+      contentType: 'application/json; charset=utf-8',
+      data: JSON.stringify({
+        id: credential.id,
+        rawId: encodeURLEncodedBase64(credential.rawId),
+        type: credential.type,
+        response: {
+          attestationObject: encodeURLEncodedBase64(response.attestationObject),
+          clientDataJSON: encodeURLEncodedBase64(response.clientDataJSON),
+          transports: typeof response.getTransports === 'function' ? response.getTransports() : [],
+        },
+      }),
+    });
+    window.location.reload();
+  } catch {
+    $('#register-device').modal('hide');
+    webAuthnError('unable-to-process');
+  }
+}
+
+export function initWebAuthnRegister() {
+  if ($('#register-security-key').length === 0) {
+    return;
+  }
+  $('#register-device').modal({allowMultiple: false});
+  $('#webauthn-error').modal({allowMultiple: false});
+  $('#register-security-key').on('click', (e) => {
+    e.preventDefault();
+    if (!detectWebAuthnSupport()) {
+      return;
+    }
+    const name = $('#nickname').val();
+    if (!name) {
+      webAuthnError('empty');
+      return;
+    }
+    registerWebAuthn(name);
+  });
+}